@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxevent"
+	"go.uber.org/zap"
+
+	"peasydeal-product-miner/config"
+	dbfx "peasydeal-product-miner/db/fx"
+	"peasydeal-product-miner/internal/app/amqp/crawlworker"
+	productdraftsfx "peasydeal-product-miner/internal/app/amqp/productdrafts/fx"
+	catalogfx "peasydeal-product-miner/internal/app/catalog/fx"
+	appfx "peasydeal-product-miner/internal/app/fx"
+	httpapifx "peasydeal-product-miner/internal/app/httpapi/fx"
+	amqpclientfx "peasydeal-product-miner/internal/pkg/amqpclient/fx"
+)
+
+func main() {
+	if _, err := config.NewConfig(config.NewViper()); err != nil {
+		log.Fatal(err)
+	}
+
+	app := fx.New(
+		fx.WithLogger(func(logger *zap.Logger) fxevent.Logger {
+			return &fxevent.ZapLogger{Logger: logger}
+		}),
+		appfx.CoreAppOptions,
+		dbfx.SQLiteModule,
+		productdraftsfx.Module,
+		amqpclientfx.Module,
+		// Publishes the crawl requests of drafts created over HTTP.
+		fx.Provide(crawlworker.NewEventPublisher),
+		// Postgres catalog that approved drafts are published to.
+		dbfx.Module,
+		catalogfx.Module,
+		httpapifx.Module,
+	)
+
+	app.Run()
+}
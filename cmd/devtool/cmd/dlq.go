@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/app/amqp/crawlworker"
+	appfx "peasydeal-product-miner/internal/app/fx"
+	"peasydeal-product-miner/internal/pkg/amqpclient"
+	amqpclientfx "peasydeal-product-miner/internal/pkg/amqpclient/fx"
+)
+
+type dlqOptions struct {
+	filter crawlworker.DLQFilter
+	limit  int
+}
+
+func newDLQCmd() *cobra.Command {
+	var opts dlqOptions
+
+	cmd := &cobra.Command{
+		Use:   "dlq",
+		Short: "Inspect, replay or purge messages in the crawl dead-letter queue",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_ = cmd.Help()
+			return errUsage
+		},
+	}
+
+	f := cmd.PersistentFlags()
+	f.StringVar(&opts.filter.EventName, "event-name", "", "Only messages with this event_name")
+	f.StringArrayVar(&opts.filter.EventIDs, "event-id", nil, "Only messages with this event_id (repeatable)")
+	f.StringVar(&opts.filter.Host, "host", "", "Only messages whose URL host is this host or one of its subdomains")
+	f.DurationVar(&opts.filter.OlderThan, "older-than", 0, "Only messages dead-lettered at least this long ago (e.g. 24h)")
+	f.DurationVar(&opts.filter.NewerThan, "newer-than", 0, "Only messages dead-lettered at most this long ago")
+	f.IntVar(&opts.limit, "limit", 1000, "Read at most this many messages from the queue (0 = all)")
+
+	cmd.AddCommand(
+		newDLQListCmd(&opts),
+		newDLQShowCmd(&opts),
+		newDLQReplayCmd(&opts),
+		newDLQPurgeCmd(&opts),
+	)
+	return cmd
+}
+
+func newDLQListCmd(opts *dlqOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List dead-lettered messages with their rejection reasons",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withDLQ(cmd, opts, func(q *crawlworker.DLQ, msgs []crawlworker.DeadLetter, _ *zap.SugaredLogger) error {
+				defer crawlworker.Release(msgs)
+				return printDeadLetters(cmd.OutOrStdout(), q.Queue(), msgs, time.Now())
+			})
+		},
+	}
+}
+
+func newDLQShowCmd(opts *dlqOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show [event-id...]",
+		Short: "Print the headers, x-death history and body of dead-lettered messages",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.filter.EventIDs = append(opts.filter.EventIDs, args...)
+			return withDLQ(cmd, opts, func(q *crawlworker.DLQ, msgs []crawlworker.DeadLetter, _ *zap.SugaredLogger) error {
+				defer crawlworker.Release(msgs)
+				if len(msgs) == 0 {
+					return fmt.Errorf("no matching messages in %s", q.Queue())
+				}
+				for i, m := range msgs {
+					if i > 0 {
+						fmt.Fprintln(cmd.OutOrStdout())
+					}
+					showDeadLetter(cmd.OutOrStdout(), m)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+func newDLQReplayCmd(opts *dlqOptions) *cobra.Command {
+	var (
+		all    bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Republish matching messages to the events exchange with a fresh retry counter",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireSelection(opts, all); err != nil {
+				return err
+			}
+			return withDLQ(cmd, opts, func(q *crawlworker.DLQ, msgs []crawlworker.DeadLetter, logger *zap.SugaredLogger) error {
+				if dryRun {
+					defer crawlworker.Release(msgs)
+					fmt.Fprintf(cmd.OutOrStdout(), "would replay %d message(s):\n", len(msgs))
+					return printDeadLetters(cmd.OutOrStdout(), q.Queue(), msgs, time.Now())
+				}
+
+				n, err := q.Replay(cmd.Context(), msgs)
+				for _, m := range msgs[:n] {
+					logger.Infow("devtool_dlq_replayed", "event_id", m.EventID, "queue", q.Queue(), "reason", m.Reason)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%d replayed from %s\n", n, q.Queue())
+				if err != nil {
+					crawlworker.Release(msgs[n:])
+					return err
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Replay every message when no filter is set")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the messages that would be replayed and leave them in place")
+	return cmd
+}
+
+func newDLQPurgeCmd(opts *dlqOptions) *cobra.Command {
+	var (
+		all    bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "purge",
+		Short: "Delete matching messages from the dead-letter queue",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := requireSelection(opts, all); err != nil {
+				return err
+			}
+			return withDLQ(cmd, opts, func(q *crawlworker.DLQ, msgs []crawlworker.DeadLetter, logger *zap.SugaredLogger) error {
+				if dryRun {
+					defer crawlworker.Release(msgs)
+					fmt.Fprintf(cmd.OutOrStdout(), "would purge %d message(s):\n", len(msgs))
+					return printDeadLetters(cmd.OutOrStdout(), q.Queue(), msgs, time.Now())
+				}
+
+				n, err := q.Purge(msgs)
+				for _, m := range msgs[:n] {
+					logger.Infow("devtool_dlq_purged", "event_id", m.EventID, "queue", q.Queue(), "reason", m.Reason)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%d purged from %s\n", n, q.Queue())
+				if err != nil {
+					crawlworker.Release(msgs[n:])
+					return err
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&all, "all", false, "Purge every message when no filter is set")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the messages that would be purged and leave them in place")
+	return cmd
+}
+
+// requireSelection keeps replay and purge from touching the whole queue by
+// accident.
+func requireSelection(opts *dlqOptions, all bool) error {
+	f := opts.filter
+	filtered := f.EventName != "" || len(f.EventIDs) > 0 || f.Host != "" || f.OlderThan > 0 || f.NewerThan > 0
+	if filtered == all {
+		return errors.New("pass a filter (--event-name, --event-id, --host, --older-than, --newer-than) or --all, not both")
+	}
+	return nil
+}
+
+// withDLQ fetches up to opts.limit messages, puts the ones that do not match
+// opts.filter back and hands the rest to fn. Whatever fn leaves unsettled is
+// released when the channel closes.
+func withDLQ(cmd *cobra.Command, opts *dlqOptions, fn func(q *crawlworker.DLQ, msgs []crawlworker.DeadLetter, logger *zap.SugaredLogger) error) error {
+	app := fx.New(
+		appfx.CoreAppOptions,
+		amqpclientfx.Module,
+		fx.Invoke(func(cfg *config.Config, m *amqpclient.Manager, logger *zap.SugaredLogger) error {
+			defer m.Close()
+			ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+			defer cancel()
+			q, closeDLQ, err := crawlworker.OpenDLQ(ctx, m, cfg)
+			if err != nil {
+				return err
+			}
+			defer closeDLQ()
+
+			msgs, err := q.Fetch(opts.limit)
+			if err != nil {
+				return err
+			}
+			var matched, rest []crawlworker.DeadLetter
+			now := time.Now()
+			for _, msg := range msgs {
+				if opts.filter.Match(msg, now) {
+					matched = append(matched, msg)
+				} else {
+					rest = append(rest, msg)
+				}
+			}
+			crawlworker.Release(rest)
+			if opts.limit > 0 && len(msgs) == opts.limit {
+				fmt.Fprintf(cmd.ErrOrStderr(), "read the first %d messages of %s; raise --limit to see more\n", opts.limit, q.Queue())
+			}
+			return fn(q, matched, logger)
+		}),
+	)
+	return app.Start(cmd.Context())
+}
+
+func printDeadLetters(w io.Writer, queue string, msgs []crawlworker.DeadLetter, now time.Time) error {
+	if len(msgs) == 0 {
+		fmt.Fprintf(w, "no matching messages in %s\n", queue)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EVENT_ID\tEVENT\tHOST\tAGE\tDEATHS\tRETRIES\tREASON")
+	for _, m := range msgs {
+		deaths := int64(0)
+		for _, d := range m.Deaths {
+			deaths += d.Count
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			dashIfEmpty(m.EventID),
+			dashIfEmpty(m.EventName),
+			dashIfEmpty(m.Host()),
+			deadLetterAge(m, now),
+			deaths,
+			m.Retries,
+			dashIfEmpty(m.Reason),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d message(s) in %s\n", len(msgs), queue)
+	return nil
+}
+
+func showDeadLetter(w io.Writer, m crawlworker.DeadLetter) {
+	d := m.Delivery()
+	fmt.Fprintf(w, "event_id:   %s\n", dashIfEmpty(m.EventID))
+	fmt.Fprintf(w, "event_name: %s\n", dashIfEmpty(m.EventName))
+	fmt.Fprintf(w, "url:        %s\n", dashIfEmpty(m.URL))
+	fmt.Fprintf(w, "reason:     %s\n", dashIfEmpty(m.Reason))
+	fmt.Fprintf(w, "dead at:    %s\n", deadLetterTime(m.DeadAt))
+	fmt.Fprintf(w, "retries:    %d\n", m.Retries)
+
+	if len(m.Deaths) > 0 {
+		fmt.Fprintln(w, "x-death:")
+		for _, death := range m.Deaths {
+			fmt.Fprintf(w, "  - queue=%s reason=%s count=%d exchange=%s routing_keys=%s time=%s\n",
+				death.Queue, death.Reason, death.Count, dashIfEmpty(death.Exchange),
+				strings.Join(death.RoutingKeys, ","), deadLetterTime(death.Time))
+		}
+	}
+
+	keys := make([]string, 0, len(d.Headers))
+	for k := range d.Headers {
+		if k != "x-death" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > 0 {
+		fmt.Fprintln(w, "headers:")
+		for _, k := range keys {
+			fmt.Fprintf(w, "  %s: %v\n", k, d.Headers[k])
+		}
+	}
+
+	fmt.Fprintln(w, "body:")
+	var body any
+	if err := json.Unmarshal(d.Body, &body); err == nil {
+		pretty, _ := json.MarshalIndent(body, "  ", "  ")
+		fmt.Fprintf(w, "  %s\n", pretty)
+	} else {
+		fmt.Fprintf(w, "  %s\n", d.Body)
+	}
+}
+
+func deadLetterAge(m crawlworker.DeadLetter, now time.Time) string {
+	if m.DeadAt.IsZero() {
+		return "-"
+	}
+	return now.Sub(m.DeadAt).Truncate(time.Second).String()
+}
+
+func deadLetterTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
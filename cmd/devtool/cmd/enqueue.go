@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	"peasydeal-product-miner/config"
+	dbfx "peasydeal-product-miner/db/fx"
+	"peasydeal-product-miner/internal/app/amqp/crawlworker"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	productdraftsfx "peasydeal-product-miner/internal/app/amqp/productdrafts/fx"
+	appfx "peasydeal-product-miner/internal/app/fx"
+	"peasydeal-product-miner/internal/pkg/amqpclient"
+	amqpclientfx "peasydeal-product-miner/internal/pkg/amqpclient/fx"
+	"peasydeal-product-miner/internal/source"
+)
+
+func newEnqueueCmd() *cobra.Command {
+	var (
+		urls        []string
+		file        string
+		outDir      string
+		requestedBy string
+		dryRun      bool
+		skipQueued  bool
+		force       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "enqueue",
+		Short: "Publish crawler/url.requested events for one or more URLs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(urls) == 0) == (strings.TrimSpace(file) == "") {
+				return errors.New("pass --url or --file")
+			}
+
+			var reqs []crawlworker.CrawlRequestedEnvelope
+			for _, u := range urls {
+				reqs = append(reqs, crawlworker.NewCrawlRequest(crawlworker.CrawlRequestedEventData{URL: strings.TrimSpace(u)}))
+			}
+			if file != "" {
+				f, err := os.Open(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				if reqs, err = crawlworker.ReadCrawlRequests(f); err != nil {
+					return fmt.Errorf("read %s: %w", file, err)
+				}
+			}
+			for i := range reqs {
+				d := &reqs[i].Data
+				if d.OutDir == "" {
+					d.OutDir = strings.TrimSpace(outDir)
+				}
+				if d.RequestedBy == "" {
+					d.RequestedBy = strings.TrimSpace(requestedBy)
+				}
+				d.Force = d.Force || force
+			}
+
+			if dryRun {
+				return printRequests(cmd.OutOrStdout(), reqs)
+			}
+
+			app := fx.New(
+				appfx.CoreAppOptions,
+				dbfx.SQLiteModule,
+				productdraftsfx.Module,
+				amqpclientfx.Module,
+				fx.Provide(crawlworker.NewEventPublisher),
+				fx.Invoke(func(p enqueueParams) error {
+					if p.Events == nil {
+						return errors.New("RABBITMQ_URL is not set")
+					}
+					// The app is never stopped, so close the connection here.
+					defer p.AMQP.Close()
+					defer p.Events.Close()
+
+					res := enqueueRequests(cmd.Context(), cmd.OutOrStdout(), p, reqs, skipQueued)
+					fmt.Fprintf(cmd.OutOrStdout(), "%d published, %d skipped, %d failed\n", res.published, res.skipped, res.failed)
+					if res.failed > 0 {
+						return fmt.Errorf("%d of %d requests failed", res.failed, len(reqs))
+					}
+					return nil
+				}),
+			)
+			return app.Start(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&urls, "url", nil, "Product URL to crawl (repeatable)")
+	cmd.Flags().StringVar(&file, "file", "", "File with one URL or JSON request per line (urls.txt or requests.jsonl)")
+	cmd.Flags().StringVar(&outDir, "out-dir", "", "out_dir for requests that do not set one (worker default when empty)")
+	cmd.Flags().StringVar(&requestedBy, "requested-by", "devtool", "requested_by for requests that do not set one")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the envelopes instead of publishing them")
+	cmd.Flags().BoolVar(&skipQueued, "skip-queued", true, "Skip URLs that already have a queued or in-progress draft")
+	cmd.Flags().BoolVar(&force, "force", false, "Set force on every request so completed drafts are re-crawled")
+	return cmd
+}
+
+type enqueueParams struct {
+	fx.In
+
+	Config *config.Config
+	Store  *productdrafts.ProductDraftStore
+	AMQP   *amqpclient.Manager
+	Events *crawlworker.EventPublisher `optional:"true"`
+	Logger *zap.SugaredLogger
+}
+
+type enqueueResult struct {
+	published, skipped, failed int
+}
+
+// enqueueRequests marks each request's draft QUEUED_FOR_DRAFT and then publishes
+// it, so the draft exists before any worker can pick the message up. Requests
+// the worker would reject are not queued, and the draft of a request that could
+// not be published is failed.
+func enqueueRequests(ctx context.Context, w io.Writer, p enqueueParams, reqs []crawlworker.CrawlRequestedEnvelope, skipQueued bool) enqueueResult {
+	var res enqueueResult
+	seen := map[string]bool{}
+	validate := validator.New()
+	overrides := crawlworker.NewOverrideAllowlist(p.Config)
+
+	for _, req := range reqs {
+		url := strings.TrimSpace(req.Data.URL)
+		req.Data.URL = url
+		src, err := source.Detect(url)
+		if err != nil {
+			res.failed++
+			fmt.Fprintf(w, "FAILED  %s: %v\n", url, err)
+			continue
+		}
+
+		// A request the worker would dead-letter must not leave a queued draft.
+		if err := validate.Struct(req.Data); err != nil {
+			res.failed++
+			fmt.Fprintf(w, "FAILED  %s: %v\n", url, err)
+			continue
+		}
+		if err := overrides.Check(req.Data); err != nil {
+			res.failed++
+			fmt.Fprintf(w, "FAILED  %s: %v\n", url, err)
+			continue
+		}
+
+		if skipQueued && seen[url] {
+			res.skipped++
+			fmt.Fprintf(w, "SKIP    %s: duplicate in input\n", url)
+			continue
+		}
+		seen[url] = true
+
+		in := productdrafts.UpsertQueuedForDraftInput{
+			EventID:   req.EventID,
+			CreatedBy: "devtool-enqueue",
+			URL:       url,
+			Source:    string(src),
+		}
+		var draftID string
+		if skipQueued {
+			d, existing, err := p.Store.QueueUnlessActive(ctx, in)
+			if err != nil {
+				res.failed++
+				fmt.Fprintf(w, "FAILED  %s: %v\n", url, err)
+				continue
+			}
+			if existing {
+				res.skipped++
+				fmt.Fprintf(w, "SKIP    %s: draft %s is %s (event %s)\n", url, d.ID, d.Status, d.EventID)
+				continue
+			}
+			draftID = d.ID
+		} else {
+			id, err := p.Store.UpsertQueuedForDraft(ctx, in)
+			if err != nil {
+				res.failed++
+				fmt.Fprintf(w, "FAILED  %s: %v\n", url, err)
+				continue
+			}
+			draftID = id
+		}
+		if err := p.Events.PublishRequest(ctx, req); err != nil {
+			res.failed++
+			fmt.Fprintf(w, "FAILED  %s: draft %s not published: %v\n", url, draftID, err)
+			p.Logger.Errorw("devtool_enqueue_publish_failed", "event_id", req.EventID, "draft_id", draftID, "err", err)
+			if ferr := p.Store.FailUnpublished(context.WithoutCancel(ctx), draftID, "devtool-enqueue", err); ferr != nil {
+				p.Logger.Errorw("devtool_enqueue_fail_unpublished_draft_failed", "draft_id", draftID, "err", ferr)
+			}
+			continue
+		}
+		res.published++
+		fmt.Fprintf(w, "QUEUED  %s: event %s, draft %s\n", url, req.EventID, draftID)
+	}
+	return res
+}
+
+func printRequests(w io.Writer, reqs []crawlworker.CrawlRequestedEnvelope) error {
+	enc := json.NewEncoder(w)
+	for _, req := range reqs {
+		if err := enc.Encode(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
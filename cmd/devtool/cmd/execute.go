@@ -45,9 +45,15 @@ func newRootCmd() *cobra.Command {
 
 	rootCmd.AddCommand(
 		newChromeCmd(),
+		newDLQCmd(),
 		newDoctorCmd(),
 		newDockerDoctorCmd(),
+		newEnqueueCmd(),
+		newInspectCmd(),
 		newOnceCmd(),
+		newPublishCmd(),
+		newReplayCmd(),
+		newSnapshotCmd(),
 	)
 	return rootCmd
 }
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"peasydeal-product-miner/internal/runner/extract"
+)
+
+func newInspectCmd() *cobra.Command {
+	var (
+		runID  string
+		outDir string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "inspect",
+		Short: "Show the pipeline stage timeline of one run",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runID = strings.TrimSpace(runID)
+			if runID == "" {
+				return errors.New("missing required flag: --run-id")
+			}
+			return inspectRun(cmd.OutOrStdout(), filepath.Join(outDir, "artifacts", filepath.FromSlash(runID)))
+		},
+	}
+
+	cmd.Flags().StringVar(&runID, "run-id", "", "Run ID under <out-dir>/artifacts")
+	cmd.Flags().StringVar(&outDir, "out-dir", "out", "Output directory holding artifacts/")
+	return cmd
+}
+
+func inspectRun(w io.Writer, dir string) error {
+	st, err := extract.ReadState(dir)
+	if err != nil {
+		return err
+	}
+	if st == nil {
+		return fmt.Errorf("no %s in %s", extract.PipelineStateFile, dir)
+	}
+	stages, err := extract.ReadStages(dir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "run:     %s\n", st.RunID)
+	fmt.Fprintf(w, "url:     %s\n", st.URL)
+	fmt.Fprintf(w, "status:  %s (current stage %s, updated %s)\n", st.Status, st.CurrentStage, st.UpdatedAt)
+	salvage := map[string]string{}
+	if b, err := os.ReadFile(filepath.Join(dir, extract.FinalFile)); err == nil {
+		var final struct {
+			Status       string                 `json:"status"`
+			Notes        string                 `json:"notes"`
+			Error        string                 `json:"error"`
+			ResultSource string                 `json:"result_source"`
+			Stages       []extract.StageSummary `json:"stages"`
+		}
+		if err := json.Unmarshal(b, &final); err == nil {
+			for _, s := range final.Stages {
+				salvage[s.Stage] = s.Salvage
+			}
+			fmt.Fprintf(w, "final:   %s\n", strings.Join(nonEmptyStrings(final.Status, final.ResultSource, final.Notes, final.Error), " | "))
+		}
+	} else {
+		fmt.Fprintf(w, "final:   (no %s)\n", extract.FinalFile)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tSTATUS\tSTARTED\tDURATION\tERROR")
+	for _, s := range stages {
+		status := s.Status
+		if outcome := salvage[s.Stage]; outcome != "" {
+			status += " (" + outcome + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			s.Stage,
+			status,
+			dashIfEmpty(s.StartedAt),
+			time.Duration(s.DurationMS)*time.Millisecond,
+			dashIfEmpty(s.Error),
+		)
+	}
+	return tw.Flush()
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func nonEmptyStrings(items ...string) []string {
+	out := make([]string, 0, len(items))
+	for _, s := range items {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
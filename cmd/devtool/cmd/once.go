@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
@@ -23,6 +25,9 @@ func newOnceCmd() *cobra.Command {
 		tool      string
 		skillName string
 		runID     string
+		timeout   time.Duration
+		fallback  []string
+		offline   bool
 	)
 
 	cmd := &cobra.Command{
@@ -32,11 +37,15 @@ func newOnceCmd() *cobra.Command {
 			if strings.TrimSpace(url) == "" {
 				return errors.New("missing required flag: --url")
 			}
+			if offline && strings.TrimSpace(runID) == "" {
+				return errors.New("--offline requires --run-id of an existing snapshot")
+			}
 
 			app := fx.New(
 				appfx.CoreAppOptions,
 				runnerFx.AsRunner(runnerPkg.NewCodexRunner),
 				runnerFx.AsRunner(runnerPkg.NewGeminiRunner),
+				runnerFx.AsCommandRunners(runnerFx.NewCommandRunners),
 
 				fx.Provide(
 					func(cfg *config.Config, logger *zap.SugaredLogger) runnerPkg.CodexRunnerConfig {
@@ -54,21 +63,43 @@ func newOnceCmd() *cobra.Command {
 							Logger: logger,
 						}
 					},
+					runnerFx.NewSchemaValidator,
 					runnerPkg.NewRunners,
 					runnerPkg.NewRunner,
 				),
 
 				fx.Invoke(func(
 					r *runnerPkg.Runner,
+					cfg *config.Config,
 					logger *zap.SugaredLogger,
-				) {
+				) error {
+					runTimeout := timeout
+					if runTimeout <= 0 {
+						runTimeout = cfg.CrawlTimeout
+					}
+					// An explicit --fallback wins over both fallback settings.
+					var fallbackBySource map[string][]string
+					if !cmd.Flags().Changed("fallback") {
+						fallback = runnerPkg.ParseToolList(cfg.CrawlToolFallback)
+						bySource, err := runnerPkg.ParseToolChainsBySource(cfg.CrawlToolFallbackBySource)
+						if err != nil {
+							return fmt.Errorf("parse CRAWL_TOOL_FALLBACK_BY_SOURCE: %w", err)
+						}
+						fallbackBySource = bySource
+					}
+
 					outPath, _, err := r.RunOnce(
+						cmd.Context(),
 						runnerPkg.Options{
-							URL:       url,
-							OutDir:    outDir,
-							Tool:      tool,
-							SkillName: skillName,
-							RunID:     resolveRunID(runID),
+							URL:              url,
+							OutDir:           outDir,
+							Tool:             tool,
+							SkillName:        skillName,
+							RunID:            resolveRunID(runID),
+							Timeout:          runTimeout,
+							Fallback:         fallback,
+							Offline:          offline,
+							FallbackBySource: fallbackBySource,
 						},
 					)
 
@@ -77,7 +108,7 @@ func newOnceCmd() *cobra.Command {
 							"output_path", outPath,
 							"err", err,
 						)
-						return
+						return nil
 					}
 
 					if outPath != "" {
@@ -86,6 +117,7 @@ func newOnceCmd() *cobra.Command {
 							"output_path", outPath,
 						)
 					}
+					return nil
 				}),
 			)
 
@@ -100,9 +132,12 @@ func newOnceCmd() *cobra.Command {
 	cmd.Flags().StringVar(&url, "url", "", "Product URL (Shopee/Taobao/Tmall)")
 	cmd.Flags().StringVar(&outDir, "out-dir", "out", "Output directory for result JSON")
 	cmd.Flags().StringVar(&model, "model", "", "Model override for the selected tool (optional; defaults to CODEX_MODEL/GEMINI_MODEL config)")
-	cmd.Flags().StringVar(&tool, "tool", "codex", "Tool to use (codex or gemini)")
+	cmd.Flags().StringVar(&tool, "tool", "codex", "Tool to use (codex, gemini, or a name from CRAWL_TOOLS_FILE)")
 	cmd.Flags().StringVar(&skillName, "skill-name", "", "Skill name override (optional; defaults by URL source)")
 	cmd.Flags().StringVar(&runID, "run-id", "", "Run ID for artifact correlation (optional; auto-generated when empty)")
+	cmd.Flags().StringSliceVar(&fallback, "fallback", nil, "Tools to try in order when --tool fails (optional; defaults to CRAWL_TOOL_FALLBACK and CRAWL_TOOL_FALLBACK_BY_SOURCE config)")
+	cmd.Flags().BoolVar(&offline, "offline", false, "Run the Go extraction stages on the existing snapshot for --run-id instead of a tool")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "Hard timeout for the whole run, fallback tools included (optional; defaults to CRAWL_TIMEOUT config)")
 	return cmd
 }
 
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	dbfx "peasydeal-product-miner/db/fx"
+	productdraftsfx "peasydeal-product-miner/internal/app/amqp/productdrafts/fx"
+	"peasydeal-product-miner/internal/app/catalog"
+	catalogfx "peasydeal-product-miner/internal/app/catalog/fx"
+	appfx "peasydeal-product-miner/internal/app/fx"
+)
+
+func newPublishCmd() *cobra.Command {
+	var (
+		actor  string
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "publish <draft-id>...",
+		Short: "Publish approved product drafts to the Postgres product catalog",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("pass at least one draft id")
+			}
+
+			app := fx.New(
+				appfx.CoreAppOptions,
+				dbfx.SQLiteModule,
+				dbfx.Module,
+				productdraftsfx.Module,
+				catalogfx.Module,
+				fx.Invoke(func(pub *catalog.Publisher) error {
+					w := cmd.OutOrStdout()
+					failed := 0
+					for _, id := range args {
+						res, err := pub.Publish(cmd.Context(), catalog.PublishInput{
+							DraftID: strings.TrimSpace(id),
+							Actor:   strings.TrimSpace(actor),
+							DryRun:  dryRun,
+						})
+						switch {
+						case err != nil:
+							failed++
+							fmt.Fprintf(w, "FAILED     %s: %v\n", id, err)
+						case dryRun:
+							pretty, _ := json.MarshalIndent(res.Rows, "", "  ")
+							fmt.Fprintf(w, "DRY-RUN    %s: product %s\n%s\n", id, res.ProductID, pretty)
+						case res.AlreadyPublished:
+							fmt.Fprintf(w, "UNCHANGED  %s: already published as product %s\n", id, res.ProductID)
+						default:
+							fmt.Fprintf(w, "PUBLISHED  %s: product %s (%d variants, %d images)\n", id, res.ProductID, len(res.Rows.Variants), len(res.Rows.Images))
+						}
+					}
+					if failed > 0 {
+						return fmt.Errorf("%d of %d drafts failed", failed, len(args))
+					}
+					return nil
+				}),
+			)
+			return app.Start(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVar(&actor, "actor", "devtool", "Actor recorded in the draft's audit log")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the catalog rows instead of writing them (no approval needed)")
+	return cmd
+}
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	dbfx "peasydeal-product-miner/db/fx"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	productdraftsfx "peasydeal-product-miner/internal/app/amqp/productdrafts/fx"
+	appfx "peasydeal-product-miner/internal/app/fx"
+	runnerPkg "peasydeal-product-miner/internal/runner"
+	"peasydeal-product-miner/internal/runner/extract"
+	runnerFx "peasydeal-product-miner/internal/runner/fx"
+)
+
+func newReplayCmd() *cobra.Command {
+	var (
+		runID     string
+		outDir    string
+		replayDir string
+		eventID   string
+		all       bool
+		persist   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-run the offline extraction stages on saved S0 snapshots and diff final.json",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runID = strings.TrimSpace(runID)
+			if all == (runID != "") {
+				return errors.New("pass exactly one of --run-id or --all")
+			}
+			if all && strings.TrimSpace(eventID) != "" {
+				return errors.New("--event-id cannot be used with --all")
+			}
+
+			runIDs := []string{runID}
+			if all {
+				var err error
+				if runIDs, err = findSnapshotRuns(outDir); err != nil {
+					return err
+				}
+				if len(runIDs) == 0 {
+					return fmt.Errorf("no snapshots found under %s", filepath.Join(outDir, "artifacts"))
+				}
+			}
+
+			if replayDir = strings.TrimSpace(replayDir); replayDir == "" {
+				replayDir = filepath.Join(outDir, "replay")
+			}
+
+			storeOpts := fx.Options()
+			if persist {
+				storeOpts = fx.Options(dbfx.SQLiteModule, productdraftsfx.Module)
+			}
+
+			app := fx.New(
+				appfx.CoreAppOptions,
+				storeOpts,
+				fx.Provide(
+					// Offline runs never start a tool.
+					func() map[string]runnerPkg.ToolRunner { return map[string]runnerPkg.ToolRunner{} },
+					runnerFx.NewSchemaValidator,
+					runnerPkg.NewRunner,
+				),
+				fx.Invoke(func(p replayParams) error {
+					failed := 0
+					for _, id := range runIDs {
+						if err := replayRun(cmd.Context(), cmd.OutOrStdout(), p, outDir, replayDir, id, eventID); err != nil {
+							failed++
+							fmt.Fprintf(cmd.OutOrStdout(), "  FAILED: %v\n", err)
+							p.Logger.Errorw("devtool_replay_failed", "run_id", id, "err", err)
+						}
+					}
+					if failed > 0 {
+						return fmt.Errorf("%d of %d replays failed", failed, len(runIDs))
+					}
+					return nil
+				}),
+			)
+			return app.Start(cmd.Context())
+		},
+	}
+
+	cmd.Flags().StringVar(&runID, "run-id", "", "Run ID whose snapshot under <out-dir>/artifacts/<run-id> is replayed")
+	cmd.Flags().StringVar(&outDir, "out-dir", "out", "Output directory holding artifacts/")
+	cmd.Flags().StringVar(&replayDir, "replay-dir", "", "Output directory the replays write their artifacts/ to (default <out-dir>/replay)")
+	cmd.Flags().BoolVar(&all, "all", false, "Replay every run with a snapshot under <out-dir>/artifacts")
+	cmd.Flags().BoolVar(&persist, "persist", false, "Re-persist the corrected result into product_drafts under the original event id")
+	cmd.Flags().StringVar(&eventID, "event-id", "", "Event id to persist under (optional; defaults to the first segment of --run-id)")
+	return cmd
+}
+
+type replayParams struct {
+	fx.In
+
+	Runner *runnerPkg.Runner
+	Store  *productdrafts.ProductDraftStore `optional:"true"`
+	Logger *zap.SugaredLogger
+}
+
+// replayRun re-extracts one run into replayDir, prints the diff against the
+// run's original final.json and optionally re-persists the result. The original
+// run's artifacts are left as they are.
+func replayRun(ctx context.Context, w io.Writer, p replayParams, outDir, replayDir, runID, eventID string) error {
+	artifactDir := filepath.Join(outDir, "artifacts", filepath.FromSlash(runID))
+	replayArtifactDir := filepath.Join(replayDir, "artifacts", filepath.FromSlash(runID))
+	if eventID = strings.TrimSpace(eventID); eventID == "" {
+		eventID = eventIDFromRunID(runID)
+	}
+	fmt.Fprintf(w, "== %s (event %s)\n", runID, eventID)
+
+	finalPath := filepath.Join(artifactDir, extract.FinalFile)
+	oldFinal, err := os.ReadFile(finalPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	url := firstJSONString(oldFinal, "url")
+	if url == "" {
+		b, err := os.ReadFile(filepath.Join(artifactDir, extract.PointerFile))
+		if err != nil {
+			return fmt.Errorf("read snapshot pointer: %w", err)
+		}
+		url = firstJSONString(b, "url")
+	}
+	if url == "" {
+		return errors.New("no url in final.json or snapshot pointer")
+	}
+
+	if err := copySnapshot(artifactDir, replayArtifactDir); err != nil {
+		return err
+	}
+	_, res, runErr := p.Runner.RunOnce(ctx, runnerPkg.Options{
+		URL:     url,
+		OutDir:  replayDir,
+		RunID:   runID,
+		Offline: true,
+	})
+
+	newFinal, err := os.ReadFile(filepath.Join(replayArtifactDir, extract.FinalFile))
+	if err != nil {
+		return errors.Join(runErr, err)
+	}
+	changes, err := extract.Diff(oldFinal, newFinal)
+	if err != nil {
+		return err
+	}
+	// artifact_dir names the replay directory, so it always differs.
+	changes = slices.DeleteFunc(changes, func(c extract.Change) bool { return c.Path == "artifact_dir" })
+	status, _ := res["status"].(string)
+	fmt.Fprintf(w, "  status %s, %d change(s) in final.json (replayed into %s)\n", status, len(changes), replayArtifactDir)
+	for _, c := range changes {
+		fmt.Fprintf(w, "  %s\n", c)
+	}
+	if runErr != nil {
+		// Never overwrite a stored draft with a failed replay.
+		return runErr
+	}
+
+	if p.Store == nil {
+		return nil
+	}
+	prod, err := res.Product()
+	if err != nil {
+		return fmt.Errorf("decode replayed result: %w", err)
+	}
+	draftID, err := p.Store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
+		EventID:   eventID,
+		CreatedBy: "replay",
+		URL:       url,
+		Product:   &prod,
+	})
+	if err != nil {
+		return fmt.Errorf("persist product draft: %w", err)
+	}
+	fmt.Fprintf(w, "  persisted draft %s\n", draftID)
+	return nil
+}
+
+// copySnapshot replaces dst with a copy of the S0 snapshot files (s0-*) of src,
+// so the stages replay from the captured pages only.
+func copySnapshot(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("clear replay dir: %w", err)
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("create replay dir: %w", err)
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), "s0-") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			return fmt.Errorf("copy snapshot: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, e.Name()), b, 0o644); err != nil {
+			return fmt.Errorf("copy snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// eventIDFromRunID maps a run id back to the crawl event it belongs to:
+// failover attempts live under <event_id>/attempt-<n>-<tool>.
+func eventIDFromRunID(runID string) string {
+	first, _, _ := strings.Cut(filepath.ToSlash(runID), "/")
+	return first
+}
+
+// findSnapshotRuns lists run ids (relative to <outDir>/artifacts) of every
+// directory holding an S0 snapshot pointer.
+func findSnapshotRuns(outDir string) ([]string, error) {
+	root := filepath.Join(outDir, "artifacts")
+	var runs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != extract.PointerFile {
+			return nil
+		}
+		rel, err := filepath.Rel(root, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		runs = append(runs, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan %s: %w", root, err)
+	}
+	return runs, nil
+}
+
+func firstJSONString(b []byte, key string) string {
+	var obj map[string]any
+	if len(b) == 0 || json.Unmarshal(b, &obj) != nil {
+		return ""
+	}
+	s, _ := obj[key].(string)
+	return strings.TrimSpace(s)
+}
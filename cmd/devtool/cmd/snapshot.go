@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"peasydeal-product-miner/internal/envutil"
+	"peasydeal-product-miner/internal/pkg/chromedevtools"
+	"peasydeal-product-miner/internal/snapshot"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	var (
+		url           string
+		outDir        string
+		runID         string
+		host          string
+		port          string
+		readyTimeout  time.Duration
+		maxVariations int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Capture S0 HTML snapshots for one URL over Chrome DevTools (no LLM)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if strings.TrimSpace(url) == "" {
+				return errors.New("missing required flag: --url")
+			}
+
+			m, err := snapshot.Capture(cmd.Context(), snapshot.Options{
+				URL:           url,
+				RunID:         resolveRunID(runID),
+				OutDir:        outDir,
+				DevToolsURL:   chromedevtools.BaseURL(host, port),
+				ReadyTimeout:  readyTimeout,
+				MaxVariations: maxVariations,
+			})
+			if m != nil {
+				b, _ := json.Marshal(m.Pointer())
+				fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&url, "url", "", "Product URL (Shopee/Taobao/Tmall)")
+	cmd.Flags().StringVar(&outDir, "out-dir", "out", "Output directory; artifacts go to <out-dir>/artifacts/<run-id>")
+	cmd.Flags().StringVar(&runID, "run-id", "", "Run ID for artifact correlation (optional; auto-generated when empty)")
+	cmd.Flags().StringVar(&host, "host", envutil.String(os.Getenv, "CHROME_DEBUG_HOST", chromedevtools.DefaultHost), "Chrome DevTools host")
+	cmd.Flags().StringVar(&port, "port", envutil.String(os.Getenv, "CHROME_DEBUG_PORT", chromedevtools.DefaultPort), "Chrome DevTools remote debugging port")
+	cmd.Flags().DurationVar(&readyTimeout, "ready-timeout", 15*time.Second, "How long to wait for product content before capturing anyway")
+	cmd.Flags().IntVar(&maxVariations, "max-variations", snapshot.MaxVariations, "Maximum variation options to click through")
+	return cmd
+}
@@ -1,35 +1,50 @@
 package main
 
 import (
+	"log"
+
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
 	"go.uber.org/zap"
 
+	"peasydeal-product-miner/config"
 	dbfx "peasydeal-product-miner/db/fx"
+	"peasydeal-product-miner/internal/app/amqp/crawlworker"
 	crawlworkerfx "peasydeal-product-miner/internal/app/amqp/crawlworker/fx"
 	productdraftsfx "peasydeal-product-miner/internal/app/amqp/productdrafts/fx"
 	appfx "peasydeal-product-miner/internal/app/fx"
+	amqpclientfx "peasydeal-product-miner/internal/pkg/amqpclient/fx"
 	"peasydeal-product-miner/internal/runner"
 	runnerfx "peasydeal-product-miner/internal/runner/fx"
 )
 
 func main() {
+	cfg, err := config.NewConfig(config.NewViper())
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	app := fx.New(
+		// fx's default 15s would cut the crawl drain short.
+		fx.StopTimeout(crawlworker.StopTimeout(cfg)),
 		fx.WithLogger(func(logger *zap.Logger) fxevent.Logger {
 			return &fxevent.ZapLogger{Logger: logger}
 		}),
 		appfx.CoreAppOptions,
 		dbfx.SQLiteModule,
 		productdraftsfx.Module,
+		amqpclientfx.Module,
 		fx.Provide(
 			// Runner wiring (same as Inngest domain).
 			runnerfx.NewCodexRunnerConfig,
 			runnerfx.NewGeminiRunnerConfig,
+			runnerfx.NewSchemaValidator,
 			runner.NewRunners,
 			runner.NewRunner,
 		),
 		runnerfx.AsRunner(runner.NewCodexRunner),
 		runnerfx.AsRunner(runner.NewGeminiRunner),
+		runnerfx.AsCommandRunners(runnerfx.NewCommandRunners),
 		crawlworkerfx.Module,
 	)
 
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -52,6 +53,10 @@ func NewViper() *viper.Viper {
 	vp.SetDefault("rabbitmq.queue", "crawler.url.requested.v1")
 	vp.SetDefault("rabbitmq.routing_key", "crawler.url.requested.v1")
 	vp.SetDefault("rabbitmq.prefetch", 1)
+	vp.SetDefault("rabbitmq.workers", 1)
+	vp.SetDefault("rabbitmq.max_retries", 3)
+	vp.SetDefault("rabbitmq.retry_delay", "30s")
+	vp.SetDefault("rabbitmq.drain_timeout", "2m")
 	vp.SetDefault("rabbitmq.declare_topology", true)
 
 	vp.SetDefault("turso.sqlite_dsn", "")
@@ -60,6 +65,14 @@ func NewViper() *viper.Viper {
 	vp.SetDefault("turso.sqlite_driver", "sqlite")
 
 	vp.SetDefault("crawl_tool", "codex")
+	vp.SetDefault("crawl_timeout", "20m")
+	vp.SetDefault("crawl_tools_file", "")
+	vp.SetDefault("crawl_tool_fallback", "")
+	vp.SetDefault("crawl_tool_fallback_by_source", "")
+	vp.SetDefault("crawl_schema_file", "")
+	vp.SetDefault("crawl_schema_mode", "warn")
+	vp.SetDefault("crawl_override_tools", "codex,gemini")
+	vp.SetDefault("crawl_override_models", "")
 	vp.SetDefault("codex_model", "gpt-5.2")
 	vp.SetDefault("gemini_model", "gemini-3-flash")
 
@@ -116,6 +129,19 @@ type Config struct {
 		RoutingKey      string `mapstructure:"routing_key"`
 		Prefetch        int    `mapstructure:"prefetch"`
 		DeclareTopology bool   `mapstructure:"declare_topology"`
+
+		// Workers is how many deliveries are crawled concurrently. Keep it <= Prefetch,
+		// otherwise the broker never hands out enough messages to fill the pool.
+		Workers int `mapstructure:"workers"`
+
+		// MaxRetries is how many times a retryable failure is re-queued through the
+		// TTL delay queues (RetryDelay, doubling each step) before it is dead-lettered.
+		MaxRetries int           `mapstructure:"max_retries"`
+		RetryDelay time.Duration `mapstructure:"retry_delay"`
+
+		// DrainTimeout is how long shutdown waits for in-flight crawls to finish
+		// before their tool processes are killed and the deliveries requeued.
+		DrainTimeout time.Duration `mapstructure:"drain_timeout"`
 	} `mapstructure:"rabbitmq"`
 
 	Turso struct {
@@ -125,7 +151,26 @@ type Config struct {
 		Driver string `mapstructure:"sqlite_driver"`
 	} `mapstructure:"turso"`
 
-	CrawlTool   string `mapstructure:"crawl_tool"`
+	CrawlTool string `mapstructure:"crawl_tool"`
+	// CrawlTimeout bounds one crawl request, fallback tools included.
+	CrawlTimeout   time.Duration `mapstructure:"crawl_timeout"`
+	CrawlToolsFile string        `mapstructure:"crawl_tools_file"` // JSON file of runner.CommandRunnerSpec tools
+
+	// CrawlToolFallback is a comma-separated list tried after CrawlTool fails (e.g. "gemini").
+	// CrawlToolFallbackBySource overrides it per source (e.g. "shopee=gemini;taobao=gemini,claude").
+	CrawlToolFallback         string `mapstructure:"crawl_tool_fallback"`
+	CrawlToolFallbackBySource string `mapstructure:"crawl_tool_fallback_by_source"`
+
+	// CrawlSchemaFile is a JSON Schema (e.g. config/schema.product.json) checked against
+	// every result; CrawlSchemaMode is off, warn or enforce.
+	CrawlSchemaFile string `mapstructure:"crawl_schema_file"`
+	CrawlSchemaMode string `mapstructure:"crawl_schema_mode"`
+
+	// CrawlOverrideTools and CrawlOverrideModels are comma-separated allowlists for the
+	// per-message tool and model overrides of crawl requests; an empty list allows none.
+	CrawlOverrideTools  string `mapstructure:"crawl_override_tools"`
+	CrawlOverrideModels string `mapstructure:"crawl_override_models"`
+
 	CodexModel  string `mapstructure:"codex_model"`
 	GeminiModel string `mapstructure:"gemini_model"`
 }
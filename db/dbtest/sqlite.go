@@ -0,0 +1,36 @@
+// Package dbtest opens throwaway SQLite databases for tests.
+package dbtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"peasydeal-product-miner/db/migrations"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pressly/goose/v3"
+
+	_ "modernc.org/sqlite"
+)
+
+// NewSQLite returns a local SQLite file with every migration applied. It is
+// closed when the test ends.
+func NewSQLite(t testing.TB) *sqlx.DB {
+	t.Helper()
+
+	conn, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	provider, err := goose.NewProvider(goose.DialectSQLite3, conn.DB, migrations.FS)
+	if err != nil {
+		t.Fatalf("goose provider: %v", err)
+	}
+	if _, err := provider.Up(context.Background()); err != nil {
+		t.Fatalf("migrate sqlite: %v", err)
+	}
+	return conn
+}
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per crawl run (runner.RunOnce call) of a draft, so retries and
+-- failovers keep their history after draft_payload/error are overwritten.
+CREATE TABLE IF NOT EXISTS crawl_attempts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+  draft_id TEXT NOT NULL REFERENCES product_drafts(id) ON DELETE CASCADE,
+  event_id TEXT,
+
+  run_id TEXT NOT NULL,
+  tool TEXT NOT NULL,
+  model TEXT,
+  skill_name TEXT,
+
+  started_at_ms INTEGER NOT NULL,
+  finished_at_ms INTEGER,
+
+  -- Result status of the crawl (product.Status*).
+  status TEXT NOT NULL CHECK (status IN ('ok', 'needs_manual', 'error')),
+  error TEXT,
+  artifact_path TEXT,
+  worker_hostname TEXT,
+
+  created_at_ms INTEGER NOT NULL DEFAULT (unixepoch('now') * 1000),
+
+  CHECK (finished_at_ms IS NULL OR finished_at_ms >= started_at_ms)
+);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_attempts_draft_started
+  ON crawl_attempts(draft_id, started_at_ms DESC);
+
+CREATE INDEX IF NOT EXISTS idx_crawl_attempts_event
+  ON crawl_attempts(event_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_crawl_attempts_event;
+DROP INDEX IF EXISTS idx_crawl_attempts_draft_started;
+
+DROP TABLE IF EXISTS crawl_attempts;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Append-only audit log of product_drafts changes, written by
+-- ProductDraftStore in the same transaction as the change itself. Rows can be
+-- neither updated nor deleted, and a draft with events cannot be deleted.
+CREATE TABLE IF NOT EXISTS product_draft_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+  draft_id TEXT NOT NULL REFERENCES product_drafts(id) ON DELETE RESTRICT,
+  event_id TEXT,
+
+  -- Who made the change (created_by of the write, or the reviewer).
+  actor TEXT NOT NULL CHECK (length(trim(actor)) > 0),
+  action TEXT NOT NULL CHECK (action IN ('created', 'updated')),
+
+  from_status TEXT,
+  to_status TEXT NOT NULL,
+
+  -- Payload snapshots, only set when the write changed draft_payload.
+  payload_before TEXT CHECK (payload_before IS NULL OR json_valid(payload_before)),
+  payload_after TEXT CHECK (payload_after IS NULL OR json_valid(payload_after)),
+
+  reason TEXT,
+
+  created_at_ms INTEGER NOT NULL DEFAULT (unixepoch('now') * 1000),
+
+  CHECK (action != 'created' OR from_status IS NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_draft_events_draft
+  ON product_draft_events(draft_id, id);
+
+CREATE TRIGGER IF NOT EXISTS trg_product_draft_events_append_only
+BEFORE UPDATE ON product_draft_events
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'product_draft_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_product_draft_events_no_delete
+BEFORE DELETE ON product_draft_events
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'product_draft_events is append-only');
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_product_draft_events_no_delete;
+
+DROP TRIGGER IF EXISTS trg_product_draft_events_append_only;
+
+DROP INDEX IF EXISTS idx_product_draft_events_draft;
+
+DROP TABLE IF EXISTS product_draft_events;
+-- +goose StatementEnd
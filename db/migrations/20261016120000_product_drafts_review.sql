@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Reviewer decision on a draft. An approved draft stays READY_FOR_REVIEW until
+-- the publisher moves it to PUBLISHED; a rejected one moves to REJECTED.
+ALTER TABLE product_drafts
+ADD COLUMN review_decision TEXT CHECK (review_decision IS NULL OR review_decision IN ('approved', 'rejected'));
+
+ALTER TABLE product_drafts
+ADD COLUMN reviewed_by TEXT;
+
+ALTER TABLE product_drafts
+ADD COLUMN reviewed_at_ms INTEGER;
+
+ALTER TABLE product_drafts
+ADD COLUMN review_notes TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_product_drafts_review_decision
+  ON product_drafts(review_decision, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_product_drafts_review_decision;
+
+-- Note: the review columns are intentionally not dropped; see
+-- 20260127053509_product_drafts_event_id.sql.
+-- +goose StatementEnd
@@ -0,0 +1,77 @@
+-- Crawl attempts (Turso / SQLite)
+
+-- name: CreateCrawlAttempt :one
+INSERT INTO crawl_attempts (
+  draft_id,
+  event_id,
+  run_id,
+  tool,
+  model,
+  skill_name,
+  started_at_ms,
+  finished_at_ms,
+  status,
+  error,
+  artifact_path,
+  worker_hostname
+) VALUES (
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?
+)
+RETURNING id;
+
+-- name: ListCrawlAttemptsByDraft :many
+SELECT
+  id,
+  draft_id,
+  event_id,
+  run_id,
+  tool,
+  model,
+  skill_name,
+  started_at_ms,
+  finished_at_ms,
+  status,
+  error,
+  artifact_path,
+  worker_hostname,
+  created_at_ms
+FROM crawl_attempts
+WHERE draft_id = ?
+ORDER BY started_at_ms DESC, id DESC;
+
+-- name: ListCrawlAttemptsByURL :many
+SELECT
+  a.id,
+  a.draft_id,
+  a.event_id,
+  a.run_id,
+  a.tool,
+  a.model,
+  a.skill_name,
+  a.started_at_ms,
+  a.finished_at_ms,
+  a.status,
+  a.error,
+  a.artifact_path,
+  a.worker_hostname,
+  a.created_at_ms
+FROM crawl_attempts a
+JOIN product_drafts d ON d.id = a.draft_id
+WHERE d.url = ?
+ORDER BY a.started_at_ms DESC, a.id DESC
+LIMIT ?;
+
+-- name: CountCrawlAttemptsByDraft :one
+SELECT COUNT(*) FROM crawl_attempts
+WHERE draft_id = ?;
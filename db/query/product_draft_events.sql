@@ -0,0 +1,42 @@
+-- Product draft events (Turso / SQLite)
+
+-- name: CreateProductDraftEvent :one
+INSERT INTO product_draft_events (
+  draft_id,
+  event_id,
+  actor,
+  action,
+  from_status,
+  to_status,
+  payload_before,
+  payload_after,
+  reason
+) VALUES (
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?,
+  ?
+)
+RETURNING id;
+
+-- name: ListProductDraftEventsByDraft :many
+SELECT
+  id,
+  draft_id,
+  event_id,
+  actor,
+  action,
+  from_status,
+  to_status,
+  payload_before,
+  payload_after,
+  reason,
+  created_at_ms
+FROM product_draft_events
+WHERE draft_id = ?
+ORDER BY id ASC;
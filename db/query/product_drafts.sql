@@ -18,14 +18,21 @@ INSERT INTO product_drafts (
 -- name: GetProductDraft :one
 SELECT
   id,
+  event_id,
   status,
   draft_payload,
+  url,
+  source,
   error,
   created_by,
   created_at_ms,
   updated_at_ms,
   published_at_ms,
-  published_product_id
+  published_product_id,
+  review_decision,
+  reviewed_by,
+  reviewed_at_ms,
+  review_notes
 FROM product_drafts
 WHERE id = ?;
 
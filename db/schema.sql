@@ -49,6 +49,13 @@ CREATE TABLE product_drafts (
   published_at_ms INTEGER NULL,
   published_product_id TEXT NULL, -- Supabase product UUID
 
+  event_id TEXT,
+
+  review_decision TEXT CHECK (review_decision IS NULL OR review_decision IN ('approved', 'rejected')),
+  reviewed_by TEXT,
+  reviewed_at_ms INTEGER,
+  review_notes TEXT,
+
   CHECK (url IS NOT NULL AND length(trim(url)) > 0),
   CHECK (source IS NULL OR source IN ('shopee', 'taobao')),
   CHECK (currency IS NULL OR length(currency) = 3),
@@ -64,6 +71,10 @@ CREATE INDEX idx_product_drafts_source
   ON product_drafts(source);
 CREATE INDEX idx_product_drafts_creator_created
   ON product_drafts(created_by, created_at_ms DESC);
+CREATE UNIQUE INDEX idx_product_drafts_event_id
+  ON product_drafts(event_id);
+CREATE INDEX idx_product_drafts_review_decision
+  ON product_drafts(review_decision, status);
 CREATE TRIGGER trg_product_drafts_touch_updated_at
 AFTER UPDATE ON product_drafts
 FOR EACH ROW
@@ -72,3 +83,68 @@ BEGIN
   SET updated_at_ms = (unixepoch('now') * 1000)
   WHERE id = NEW.id;
 END;
+CREATE TABLE crawl_attempts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+  draft_id TEXT NOT NULL REFERENCES product_drafts(id) ON DELETE CASCADE,
+  event_id TEXT,
+
+  run_id TEXT NOT NULL,
+  tool TEXT NOT NULL,
+  model TEXT,
+  skill_name TEXT,
+
+  started_at_ms INTEGER NOT NULL,
+  finished_at_ms INTEGER,
+
+  -- Result status of the crawl (product.Status*).
+  status TEXT NOT NULL CHECK (status IN ('ok', 'needs_manual', 'error')),
+  error TEXT,
+  artifact_path TEXT,
+  worker_hostname TEXT,
+
+  created_at_ms INTEGER NOT NULL DEFAULT (unixepoch('now') * 1000),
+
+  CHECK (finished_at_ms IS NULL OR finished_at_ms >= started_at_ms)
+);
+CREATE INDEX idx_crawl_attempts_draft_started
+  ON crawl_attempts(draft_id, started_at_ms DESC);
+CREATE INDEX idx_crawl_attempts_event
+  ON crawl_attempts(event_id);
+CREATE TABLE product_draft_events (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+  draft_id TEXT NOT NULL REFERENCES product_drafts(id) ON DELETE RESTRICT,
+  event_id TEXT,
+
+  -- Who made the change (created_by of the write, or the reviewer).
+  actor TEXT NOT NULL CHECK (length(trim(actor)) > 0),
+  action TEXT NOT NULL CHECK (action IN ('created', 'updated')),
+
+  from_status TEXT,
+  to_status TEXT NOT NULL,
+
+  -- Payload snapshots, only set when the write changed draft_payload.
+  payload_before TEXT CHECK (payload_before IS NULL OR json_valid(payload_before)),
+  payload_after TEXT CHECK (payload_after IS NULL OR json_valid(payload_after)),
+
+  reason TEXT,
+
+  created_at_ms INTEGER NOT NULL DEFAULT (unixepoch('now') * 1000),
+
+  CHECK (action != 'created' OR from_status IS NULL)
+);
+CREATE INDEX idx_product_draft_events_draft
+  ON product_draft_events(draft_id, id);
+CREATE TRIGGER trg_product_draft_events_append_only
+BEFORE UPDATE ON product_draft_events
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'product_draft_events is append-only');
+END;
+CREATE TRIGGER trg_product_draft_events_no_delete
+BEFORE DELETE ON product_draft_events
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'product_draft_events is append-only');
+END;
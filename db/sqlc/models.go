@@ -8,6 +8,23 @@ import (
 	"time"
 )
 
+type CrawlAttempt struct {
+	ID             int64   `json:"id"`
+	DraftID        string  `json:"draft_id"`
+	EventID        *string `json:"event_id"`
+	RunID          string  `json:"run_id"`
+	Tool           string  `json:"tool"`
+	Model          *string `json:"model"`
+	SkillName      *string `json:"skill_name"`
+	StartedAtMs    int64   `json:"started_at_ms"`
+	FinishedAtMs   *int64  `json:"finished_at_ms"`
+	Status         string  `json:"status"`
+	Error          *string `json:"error"`
+	ArtifactPath   *string `json:"artifact_path"`
+	WorkerHostname *string `json:"worker_hostname"`
+	CreatedAtMs    int64   `json:"created_at_ms"`
+}
+
 type GooseDbVersion struct {
 	ID        int64      `json:"id"`
 	VersionID int64      `json:"version_id"`
@@ -31,4 +48,23 @@ type ProductDraft struct {
 	UpdatedAtMs        int64       `json:"updated_at_ms"`
 	PublishedAtMs      interface{} `json:"published_at_ms"`
 	PublishedProductID interface{} `json:"published_product_id"`
+	EventID            *string     `json:"event_id"`
+	ReviewDecision     *string     `json:"review_decision"`
+	ReviewedBy         *string     `json:"reviewed_by"`
+	ReviewedAtMs       *int64      `json:"reviewed_at_ms"`
+	ReviewNotes        *string     `json:"review_notes"`
+}
+
+type ProductDraftEvent struct {
+	ID            int64   `json:"id"`
+	DraftID       string  `json:"draft_id"`
+	EventID       *string `json:"event_id"`
+	Actor         string  `json:"actor"`
+	Action        string  `json:"action"`
+	FromStatus    *string `json:"from_status"`
+	ToStatus      string  `json:"to_status"`
+	PayloadBefore *string `json:"payload_before"`
+	PayloadAfter  *string `json:"payload_after"`
+	Reason        *string `json:"reason"`
+	CreatedAtMs   int64   `json:"created_at_ms"`
 }
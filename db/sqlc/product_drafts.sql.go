@@ -49,28 +49,42 @@ func (q *Queries) CreateProductDraft(ctx context.Context, arg CreateProductDraft
 const getProductDraft = `-- name: GetProductDraft :one
 SELECT
   id,
+  event_id,
   status,
   draft_payload,
+  url,
+  source,
   error,
   created_by,
   created_at_ms,
   updated_at_ms,
   published_at_ms,
-  published_product_id
+  published_product_id,
+  review_decision,
+  reviewed_by,
+  reviewed_at_ms,
+  review_notes
 FROM product_drafts
 WHERE id = ?
 `
 
 type GetProductDraftRow struct {
 	ID                 string      `json:"id"`
+	EventID            *string     `json:"event_id"`
 	Status             string      `json:"status"`
 	DraftPayload       string      `json:"draft_payload"`
+	Url                *string     `json:"url"`
+	Source             *string     `json:"source"`
 	Error              interface{} `json:"error"`
 	CreatedBy          interface{} `json:"created_by"`
 	CreatedAtMs        int64       `json:"created_at_ms"`
 	UpdatedAtMs        int64       `json:"updated_at_ms"`
 	PublishedAtMs      interface{} `json:"published_at_ms"`
 	PublishedProductID interface{} `json:"published_product_id"`
+	ReviewDecision     *string     `json:"review_decision"`
+	ReviewedBy         *string     `json:"reviewed_by"`
+	ReviewedAtMs       *int64      `json:"reviewed_at_ms"`
+	ReviewNotes        *string     `json:"review_notes"`
 }
 
 func (q *Queries) GetProductDraft(ctx context.Context, id string) (GetProductDraftRow, error) {
@@ -78,14 +92,21 @@ func (q *Queries) GetProductDraft(ctx context.Context, id string) (GetProductDra
 	var i GetProductDraftRow
 	err := row.Scan(
 		&i.ID,
+		&i.EventID,
 		&i.Status,
 		&i.DraftPayload,
+		&i.Url,
+		&i.Source,
 		&i.Error,
 		&i.CreatedBy,
 		&i.CreatedAtMs,
 		&i.UpdatedAtMs,
 		&i.PublishedAtMs,
 		&i.PublishedProductID,
+		&i.ReviewDecision,
+		&i.ReviewedBy,
+		&i.ReviewedAtMs,
+		&i.ReviewNotes,
 	)
 	return i, err
 }
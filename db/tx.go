@@ -10,21 +10,19 @@ type TxFunc func(tx *sqlx.Tx) (any, error)
 
 type TxFuncFormatResp func(tx *sqlx.Tx) (any, error)
 
-func Tx(db *sqlx.DB, txFunc TxFuncFormatResp) (any, error) {
-	var (
-		tx  *sqlx.Tx
-		err error
-		res any
-	)
+// Tx runs txFunc in a Postgres transaction, committing when it returns nil and
+// rolling back otherwise. A failed commit is returned as the error.
+func Tx(db *sqlx.DB, txFunc TxFuncFormatResp) (res any, err error) {
+	var tx *sqlx.Tx
 
 	tx, err = db.Beginx()
 	if err != nil {
 		return nil, err
 	}
 
-	_, deallocErr := tx.Exec("DEALLOCATE ALL")
-	if deallocErr != nil {
+	if _, err = tx.Exec("DEALLOCATE ALL"); err != nil {
 		_ = tx.Rollback()
+		return nil, fmt.Errorf("deallocate prepared statements: %w", err)
 	}
 
 	defer func() {
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/pkg/amqpclient"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/fx"
@@ -18,51 +19,71 @@ import (
 
 var ErrHandlerMissing = errors.New("crawlworker handler missing")
 
+// killGrace is how long Stop waits for handlers to return after their tool
+// processes were killed.
+const killGrace = 10 * time.Second
+
 type Handler interface {
 	Handle(ctx context.Context, msg CrawlRequestedEnvelope) error
 }
 
 type Consumer struct {
 	cfg     *config.Config
-	conn    *amqp.Connection
+	amqp    *amqpclient.Manager
 	channel *amqp.Channel
-	handler Handler
+	router  *Router
 	logger  *zap.SugaredLogger
-
-	consumerTag string
-	consumeCtx  context.Context
-	cancel      context.CancelFunc
-	mu          sync.Mutex
+	retry   retryPolicy
+
+	consumerTag  string
+	drainTimeout time.Duration
+	consumeCtx   context.Context
+	cancel       context.CancelFunc
+	// workCtx outlives consumeCtx so in-flight handlers can finish while draining;
+	// cancelling it kills their tool processes.
+	workCtx  context.Context
+	stopWork context.CancelFunc
+	done     chan struct{}
+	mu       sync.Mutex
 }
 
 type NewConsumerParams struct {
 	fx.In
 
-	Config  *config.Config
-	Conn    *amqp.Connection
-	Channel *amqp.Channel
-	Handler Handler `optional:"true"`
-	Logger  *zap.SugaredLogger
+	Config *config.Config
+	AMQP   *amqpclient.Manager
+	Router *Router
+	Logger *zap.SugaredLogger
 }
 
 func NewConsumer(p NewConsumerParams) *Consumer {
-	h := p.Handler
-	if h == nil {
-		h = missingHandler{}
+	return &Consumer{
+		cfg:          p.Config,
+		amqp:         p.AMQP,
+		router:       p.Router,
+		logger:       p.Logger,
+		retry:        newRetryPolicy(p.Config),
+		consumerTag:  "crawlworker",
+		drainTimeout: DrainTimeout(p.Config),
 	}
+}
 
-	return &Consumer{
-		cfg:         p.Config,
-		conn:        p.Conn,
-		channel:     p.Channel,
-		handler:     h,
-		logger:      p.Logger,
-		consumerTag: "crawlworker",
+// DrainTimeout is the configured shutdown drain window (2m when unset).
+func DrainTimeout(cfg *config.Config) time.Duration {
+	if cfg != nil && cfg.RabbitMQ.DrainTimeout > 0 {
+		return cfg.RabbitMQ.DrainTimeout
 	}
+	return 2 * time.Minute
+}
+
+// StopTimeout is the fx stop timeout the worker needs to drain and then kill
+// in-flight crawls.
+func StopTimeout(cfg *config.Config) time.Duration {
+	return DrainTimeout(cfg) + killGrace + 15*time.Second
 }
 
 func (c *Consumer) Start(ctx context.Context) error {
-	if c.cfg == nil || strings.TrimSpace(c.cfg.RabbitMQ.URL) == "" {
+	if !c.amqp.Enabled() {
 		c.logger.Infow("crawlworker_disabled", "reason", "missing rabbitmq config")
 		return nil
 	}
@@ -70,8 +91,15 @@ func (c *Consumer) Start(ctx context.Context) error {
 	if c.consumeCtx == nil || c.cancel == nil {
 		c.consumeCtx, c.cancel = context.WithCancel(context.Background())
 	}
+	if c.workCtx == nil || c.stopWork == nil {
+		c.workCtx, c.stopWork = context.WithCancel(context.Background())
+	}
 
-	go c.runConsumeLoop(c.consumeCtx)
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		c.runConsumeLoop(c.consumeCtx)
+	}()
 
 	return nil
 }
@@ -110,32 +138,67 @@ func (c *Consumer) runConsumeLoop(ctx context.Context) {
 	}
 }
 
+// Stop stops taking deliveries, then waits up to the drain timeout (and ctx)
+// for in-flight handlers to ack. Handlers still running after that have their
+// tool processes killed and their deliveries requeued. The channel is closed
+// last so late acks still reach the broker.
 func (c *Consumer) Stop(ctx context.Context) error {
-	_ = ctx
 	if c.cancel != nil {
 		c.cancel()
 	}
+	c.cancelConsumer()
+
+	if c.done != nil {
+		drain := c.drainTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			// Keep killGrace of the stop budget for the handlers to wind down.
+			drain = min(drain, max(time.Until(deadline)-killGrace, 0))
+		}
+		c.logger.Infow("crawlworker_draining", "timeout", drain.String())
+
+		timer := time.NewTimer(drain)
+		select {
+		case <-c.done:
+			timer.Stop()
+			c.logger.Infow("crawlworker_drained")
+		case <-timer.C:
+			c.logger.Warnw("crawlworker_drain_timeout", "timeout", drain.String())
+			c.stopWork()
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+				c.logger.Warnw("crawlworker_stop_abandoned_handlers", "err", ctx.Err())
+			}
+		}
+	}
+
+	if c.stopWork != nil {
+		c.stopWork()
+	}
 	c.closeCurrent()
 	return nil
 }
 
+// cancelConsumer asks the broker to stop sending deliveries to this consumer.
+// Any it already sent and serve did not requeue stay unacked until the channel
+// closes, which hands them back to the queue.
+func (c *Consumer) cancelConsumer() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.channel != nil && !c.channel.IsClosed() {
+		_ = c.channel.Cancel(c.consumerTag, false)
+	}
+}
+
 func (c *Consumer) declareTopology(ctx context.Context, ch *amqp.Channel) error {
 	_ = ctx
 
-	ex := strings.TrimSpace(c.cfg.RabbitMQ.Exchange)
-	if ex == "" {
-		ex = "events"
-	}
+	ex := exchangeName(c.cfg)
 
-	queueName := strings.TrimSpace(c.cfg.RabbitMQ.Queue)
-	if queueName == "" {
-		queueName = "crawler.url.requested.v1"
-	}
+	queueName := workQueue(c.cfg)
 
-	routingKey := strings.TrimSpace(c.cfg.RabbitMQ.RoutingKey)
-	if routingKey == "" {
-		routingKey = "crawler.url.requested.v1"
-	}
+	routingKey := requestRoutingKey(c.cfg)
 
 	dlx := ex + ".dlx"
 	dlq := queueName + ".dlq"
@@ -163,6 +226,9 @@ func (c *Consumer) declareTopology(ctx context.Context, ch *amqp.Channel) error
 	if err := ch.QueueBind(dlq, routingKey, dlx, false, nil); err != nil {
 		return fmt.Errorf("rabbitmq dlq bind queue=%q key=%q ex=%q: %w", dlq, routingKey, dlx, err)
 	}
+	if err := c.retry.declare(ch); err != nil {
+		return err
+	}
 
 	c.logger.Infow(
 		"crawlworker_topology_declared",
@@ -171,13 +237,23 @@ func (c *Consumer) declareTopology(ctx context.Context, ch *amqp.Channel) error
 		"routing_key", routingKey,
 		"dlx", dlx,
 		"dlq", dlq,
+		"max_retries", c.retry.max,
 	)
 
 	return nil
 }
 
+func workQueue(cfg *config.Config) string {
+	if cfg != nil {
+		if q := strings.TrimSpace(cfg.RabbitMQ.Queue); q != "" {
+			return q
+		}
+	}
+	return "crawler.url.requested.v1"
+}
+
 func (c *Consumer) consumeOnce(ctx context.Context) error {
-	conn, ch, err := c.ensureChannel()
+	ch, err := c.openChannel(ctx)
 	if err != nil {
 		return err
 	}
@@ -197,7 +273,7 @@ func (c *Consumer) consumeOnce(ctx context.Context) error {
 	}
 
 	deliveries, err := ch.Consume(
-		c.cfg.RabbitMQ.Queue,
+		workQueue(c.cfg),
 		c.consumerTag,
 		false, // autoAck
 		false, // exclusive
@@ -209,27 +285,84 @@ func (c *Consumer) consumeOnce(ctx context.Context) error {
 		return fmt.Errorf("rabbitmq consume: %w", err)
 	}
 
-	connClose := conn.NotifyClose(make(chan *amqp.Error, 1))
 	chClose := ch.NotifyClose(make(chan *amqp.Error, 1))
 	chCancel := ch.NotifyCancel(make(chan string, 1))
 
+	workers := c.cfg.RabbitMQ.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > prefetch {
+		c.logger.Warnw("crawlworker_workers_exceed_prefetch",
+			"workers", workers,
+			"prefetch", prefetch,
+		)
+	}
+
 	c.logger.Infow(
 		"crawlworker_started",
-		"queue", c.cfg.RabbitMQ.Queue,
+		"queue", workQueue(c.cfg),
 		"prefetch", prefetch,
+		"workers", workers,
 	)
 
+	return c.serve(ctx, c.workCtx, workers, confirmingChannel{ch}, deliveries, chClose, chCancel)
+}
+
+// serve hands deliveries to a pool of workers, each of which acks, retries or
+// rejects its own delivery. It returns when ctx is done or the channel (which
+// closes with its connection) or consumer goes away, once the in-flight
+// deliveries have been handled. Handlers run on work, so a done ctx stops
+// dispatching without interrupting them.
+//
+// The reconnect waits for serve to return, so after a broker blip no new
+// deliveries are consumed until the in-flight crawls finish, up to
+// CRAWL_TIMEOUT. Their deliveries died with the channel and are redelivered by
+// the broker; only the draft writes of those crawls still count.
+func (c *Consumer) serve(
+	ctx context.Context,
+	work context.Context,
+	workers int,
+	pub publisher,
+	deliveries <-chan amqp.Delivery,
+	chClose <-chan *amqp.Error,
+	chCancel <-chan string,
+) error {
+	jobs := make(chan amqp.Delivery)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range jobs {
+				c.handleDelivery(work, pub, d)
+			}
+		}()
+	}
+
+	// At most one delivery waits for a free worker; while it does, no more are
+	// read so prefetch keeps the rest on the broker.
+	var (
+		pending  amqp.Delivery
+		dispatch chan<- amqp.Delivery
+		incoming = deliveries
+	)
+	defer func() {
+		if dispatch != nil {
+			// Never handed to a worker: give it back so another consumer can take it.
+			_ = pending.Nack(false, true)
+		}
+		close(jobs)
+		wg.Wait()
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
+			requeueBuffered(deliveries)
 			return nil
-		case err := <-connClose:
-			if err != nil {
-				c.logger.Warnw("crawlworker_connection_closed", "err", err)
-			} else {
-				c.logger.Warnw("crawlworker_connection_closed")
-			}
-			return fmt.Errorf("rabbitmq connection closed")
+		case dispatch <- pending:
+			dispatch, incoming = nil, deliveries
 		case err := <-chClose:
 			if err != nil {
 				c.logger.Warnw("crawlworker_channel_closed", "err", err)
@@ -240,40 +373,48 @@ func (c *Consumer) consumeOnce(ctx context.Context) error {
 		case reason := <-chCancel:
 			c.logger.Warnw("crawlworker_channel_cancelled", "reason", reason)
 			return fmt.Errorf("rabbitmq channel cancelled")
-		case d, ok := <-deliveries:
+		case d, ok := <-incoming:
 			if !ok {
 				c.logger.Warnw("crawlworker_deliveries_closed")
 				return fmt.Errorf("rabbitmq deliveries closed")
 			}
-			c.handleDelivery(ctx, d)
+			pending, dispatch, incoming = d, jobs, nil
 		}
 	}
 }
 
-func (c *Consumer) ensureChannel() (*amqp.Connection, *amqp.Channel, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// requeueBuffered nacks the deliveries the broker already pushed to this
+// consumer but serve never read, so another consumer can take them right away.
+func requeueBuffered(deliveries <-chan amqp.Delivery) {
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+			_ = d.Nack(false, true)
+		default:
+			return
+		}
+	}
+}
 
-	if c.conn != nil && !c.conn.IsClosed() && c.channel != nil && !c.channel.IsClosed() {
-		return c.conn, c.channel, nil
+// openChannel replaces the consumer's channel with a fresh confirm-mode one from
+// the shared connection, waiting while the manager reconnects. Retries and DLQ
+// copies are published on it, and their originals acked only once confirmed.
+func (c *Consumer) openChannel(ctx context.Context) (*amqp.Channel, error) {
+	ch, err := c.amqp.ConfirmChannel(ctx)
+	if err != nil {
+		return nil, err
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.channel != nil {
 		_ = c.channel.Close()
-		c.channel = nil
 	}
-	if c.conn != nil {
-		_ = c.conn.Close()
-		c.conn = nil
-	}
-
-	conn, ch, err := c.dial()
-	if err != nil {
-		return nil, nil, err
-	}
-	c.conn = conn
 	c.channel = ch
-	return conn, ch, nil
+	return ch, nil
 }
 
 func (c *Consumer) closeCurrent() {
@@ -285,44 +426,15 @@ func (c *Consumer) closeCurrent() {
 		_ = c.channel.Close()
 		c.channel = nil
 	}
-	if c.conn != nil {
-		_ = c.conn.Close()
-		c.conn = nil
-	}
-}
-
-func (c *Consumer) dial() (*amqp.Connection, *amqp.Channel, error) {
-	url := ""
-	if c.cfg != nil {
-		url = strings.TrimSpace(c.cfg.RabbitMQ.URL)
-	}
-	if url == "" {
-		return nil, nil, fmt.Errorf("rabbitmq url missing")
-	}
-
-	conn, err := amqp.DialConfig(url, amqp.Config{
-		Heartbeat: 10 * time.Second,
-	})
-	if err != nil {
-		return nil, nil, fmt.Errorf("rabbitmq dial: %w", err)
-	}
-
-	ch, err := conn.Channel()
-	if err != nil {
-		_ = conn.Close()
-		return nil, nil, fmt.Errorf("rabbitmq channel: %w", err)
-	}
-
-	return conn, ch, nil
 }
 
-func (c *Consumer) handleDelivery(ctx context.Context, d amqp.Delivery) {
+func (c *Consumer) handleDelivery(ctx context.Context, pub publisher, d amqp.Delivery) {
 	eventID := strings.TrimSpace(d.MessageId)
 	if eventID == "" {
 		eventID = strings.TrimSpace(d.CorrelationId)
 	}
 
-	var msg CrawlRequestedEnvelope
+	var msg Envelope
 	if err := json.Unmarshal(d.Body, &msg); err != nil {
 		c.logger.Errorw("crawlworker_invalid_json",
 			"err", err,
@@ -336,6 +448,10 @@ func (c *Consumer) handleDelivery(ctx context.Context, d amqp.Delivery) {
 		msg.EventID = eventID
 	}
 
+	if strings.TrimSpace(msg.EventName) == "" {
+		msg.EventName = strings.TrimSpace(d.Type)
+	}
+
 	if strings.TrimSpace(msg.EventID) == "" {
 		c.logger.Errorw("crawlworker_missing_event_id",
 			"message_id", eventID,
@@ -345,13 +461,29 @@ func (c *Consumer) handleDelivery(ctx context.Context, d amqp.Delivery) {
 		return
 	}
 
-	if err := c.handler.Handle(ctx, msg); err != nil {
+	if err := c.router.Dispatch(ctx, msg); err != nil {
+		if errors.Is(err, ErrUnknownEvent) || errors.Is(err, ErrInvalidPayload) {
+			c.logger.Errorw("crawlworker_event_rejected",
+				"err", err,
+				"event_id", msg.EventID,
+				"event_name", msg.EventName,
+				"schema_version", msg.version(),
+			)
+			if c.deadLetter(ctx, pub, d, err.Error()) {
+				c.router.Abandon(context.WithoutCancel(ctx), msg, err)
+			}
+			return
+		}
+
 		c.logger.Errorw("crawlworker_handle_failed",
 			"err", err,
 			"event_id", msg.EventID,
 			"event_name", msg.EventName,
+			"retryable", isRetryable(err),
 		)
-		_ = d.Reject(false)
+		if c.retryOrReject(ctx, pub, d, msg.EventID, err) {
+			c.router.Abandon(context.WithoutCancel(ctx), msg, err)
+		}
 		return
 	}
 
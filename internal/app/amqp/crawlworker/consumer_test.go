@@ -0,0 +1,238 @@
+package crawlworker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/config"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeAcknowledger records the outcome of each delivery tag.
+type fakeAcknowledger struct {
+	mu       sync.Mutex
+	acked    []uint64
+	rejected []uint64
+	nacked   []uint64
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, _ bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = append(a.acked, tag)
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, _ bool, _ bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacked = append(a.nacked, tag)
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, _ bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rejected = append(a.rejected, tag)
+	return nil
+}
+
+func (a *fakeAcknowledger) counts() (acked, rejected, nacked int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.acked), len(a.rejected), len(a.nacked)
+}
+
+// blockingHandler holds every message until release is closed.
+type blockingHandler struct {
+	mu      sync.Mutex
+	active  int
+	peak    int
+	started chan string
+	release chan struct{}
+}
+
+func (h *blockingHandler) Handle(ctx context.Context, msg CrawlRequestedEnvelope) error {
+	h.mu.Lock()
+	h.active++
+	if h.active > h.peak {
+		h.peak = h.active
+	}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		h.active--
+		h.mu.Unlock()
+	}()
+
+	h.started <- msg.EventID
+	select {
+	case <-h.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newTestConsumer(t *testing.T, h Handler) *Consumer {
+	t.Helper()
+	r, err := NewRouterWith(CrawlRequestedRoute(h))
+	require.NoError(t, err)
+	return &Consumer{cfg: &config.Config{}, router: r, logger: zap.NewNop().Sugar()}
+}
+
+func delivery(t *testing.T, ack amqp.Acknowledger, tag uint64, eventID string) amqp.Delivery {
+	t.Helper()
+	body, err := json.Marshal(CrawlRequestedEnvelope{EventName: CrawlRequestedEventName, EventID: eventID, Data: CrawlRequestedEventData{URL: "https://shopee.tw/i.1.2"}})
+	require.NoError(t, err)
+	return amqp.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: body}
+}
+
+func TestConsumerServe_HandlesDeliveriesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	h := &blockingHandler{started: make(chan string, 3), release: make(chan struct{})}
+	c := newTestConsumer(t, h)
+	ack := &fakeAcknowledger{}
+
+	deliveries := make(chan amqp.Delivery, 3)
+	for i := 1; i <= 3; i++ {
+		deliveries <- delivery(t, ack, uint64(i), fmt.Sprintf("evt-%d", i))
+	}
+	close(deliveries)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.serve(context.Background(), context.Background(), 3, nil, deliveries, nil, nil)
+	}()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-h.started:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only %d of 3 deliveries started concurrently", i)
+		}
+	}
+	select {
+	case <-done:
+		t.Fatalf("serve returned while deliveries were still in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(h.release)
+	require.Error(t, <-done, "closed deliveries should end the consume cycle")
+
+	acked, rejected, nacked := ack.counts()
+	require.Equal(t, 3, acked)
+	require.Zero(t, rejected)
+	require.Zero(t, nacked)
+	require.Equal(t, 3, h.peak)
+}
+
+func TestConsumerServe_RequeuesUndispatchedDeliveryOnClose(t *testing.T) {
+	t.Parallel()
+
+	h := &blockingHandler{started: make(chan string, 2), release: make(chan struct{})}
+	c := newTestConsumer(t, h)
+	ack := &fakeAcknowledger{}
+
+	deliveries := make(chan amqp.Delivery, 2)
+	deliveries <- delivery(t, ack, 1, "evt-1")
+	deliveries <- delivery(t, ack, 2, "evt-2")
+	chClose := make(chan *amqp.Error, 1)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.serve(context.Background(), context.Background(), 1, nil, deliveries, chClose, nil)
+	}()
+
+	require.Equal(t, "evt-1", <-h.started)
+	// Wait until the second delivery is read and waiting for the busy worker.
+	require.Eventually(t, func() bool { return len(deliveries) == 0 }, 2*time.Second, 5*time.Millisecond)
+
+	chClose <- amqp.ErrClosed
+	require.Eventually(t, func() bool { _, _, nacked := ack.counts(); return nacked == 1 }, 2*time.Second, 5*time.Millisecond)
+	close(h.release)
+	require.Error(t, <-done)
+
+	acked, rejected, nacked := ack.counts()
+	require.Equal(t, 1, acked, "the in-flight delivery still acks itself")
+	require.Zero(t, rejected)
+	require.Equal(t, 1, nacked, "the undispatched delivery is requeued")
+	require.Equal(t, 1, h.peak)
+}
+
+// startServing wires c the way Start does, with serve in place of the consume loop.
+func startServing(c *Consumer, workers int, deliveries <-chan amqp.Delivery) {
+	c.consumeCtx, c.cancel = context.WithCancel(context.Background())
+	c.workCtx, c.stopWork = context.WithCancel(context.Background())
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		_ = c.serve(c.consumeCtx, c.workCtx, workers, nil, deliveries, nil, nil)
+	}()
+}
+
+func TestConsumerStop_DrainsInFlightDeliveries(t *testing.T) {
+	t.Parallel()
+
+	h := &blockingHandler{started: make(chan string, 1), release: make(chan struct{})}
+	c := newTestConsumer(t, h)
+	c.drainTimeout = time.Minute
+	ack := &fakeAcknowledger{}
+
+	deliveries := make(chan amqp.Delivery, 3)
+	for i := 1; i <= 3; i++ {
+		deliveries <- delivery(t, ack, uint64(i), fmt.Sprintf("evt-%d", i))
+	}
+	startServing(c, 1, deliveries)
+	require.Equal(t, "evt-1", <-h.started)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- c.Stop(context.Background()) }()
+
+	require.Eventually(t, func() bool { _, _, nacked := ack.counts(); return nacked == 2 }, 2*time.Second, 5*time.Millisecond)
+	select {
+	case <-stopped:
+		t.Fatalf("Stop returned while a delivery was still in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(h.release)
+	require.NoError(t, <-stopped)
+
+	acked, rejected, nacked := ack.counts()
+	require.Equal(t, 1, acked, "the in-flight delivery finishes and acks")
+	require.Zero(t, rejected)
+	require.Equal(t, 2, nacked, "undispatched and buffered deliveries are requeued")
+}
+
+func TestConsumerStop_KillsHandlersAfterDrainTimeout(t *testing.T) {
+	t.Parallel()
+
+	h := &blockingHandler{started: make(chan string, 1), release: make(chan struct{})}
+	c := newTestConsumer(t, h)
+	c.drainTimeout = 50 * time.Millisecond
+	ack := &fakeAcknowledger{}
+
+	deliveries := make(chan amqp.Delivery, 1)
+	deliveries <- delivery(t, ack, 1, "evt-1")
+	startServing(c, 1, deliveries)
+	require.Equal(t, "evt-1", <-h.started)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, c.Stop(ctx))
+
+	acked, rejected, nacked := ack.counts()
+	require.Zero(t, acked)
+	require.Zero(t, rejected)
+	require.Equal(t, 1, nacked, "the killed delivery is requeued, not dead-lettered")
+}
@@ -0,0 +1,314 @@
+package crawlworker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/pkg/amqpclient"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// replayCountHeader counts how many times a message was replayed out of the DLQ.
+const replayCountHeader = "x-dlq-replays"
+
+// DLQChannel is the part of a channel the DLQ tools need. Deliveries it returns
+// are settled through their own Acknowledger.
+type DLQChannel interface {
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+	publisher
+}
+
+// Death is one x-death entry: a queue the message was dead-lettered from.
+type Death struct {
+	Queue       string
+	Reason      string
+	Exchange    string
+	RoutingKeys []string
+	Count       int64
+	Time        time.Time
+}
+
+// DeadLetter is a message fetched from the DLQ and held unacked until it is
+// replayed, purged or released.
+type DeadLetter struct {
+	EventName string
+	EventID   string
+	URL       string
+	// Reason is the x-reject-reason header when the worker set one, else the
+	// reason of the first x-death entry (e.g. "rejected", "expired").
+	Reason  string
+	Deaths  []Death
+	DeadAt  time.Time // first dead-lettering, or the publish timestamp; zero if unknown
+	Retries int
+
+	delivery amqp.Delivery
+}
+
+// Delivery is the raw message.
+func (m DeadLetter) Delivery() amqp.Delivery { return m.delivery }
+
+// Host is the URL host of the crawl request, if the body has one.
+func (m DeadLetter) Host() string {
+	u, err := url.Parse(m.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// DLQFilter selects dead letters; zero fields match everything.
+type DLQFilter struct {
+	EventName string
+	EventIDs  []string
+	Host      string // matches the host or any of its subdomains
+	OlderThan time.Duration
+	NewerThan time.Duration
+}
+
+// Match reports whether m passes every set field of f. Messages of unknown age
+// never match an age filter.
+func (f DLQFilter) Match(m DeadLetter, now time.Time) bool {
+	if f.EventName != "" && m.EventName != f.EventName {
+		return false
+	}
+	if len(f.EventIDs) > 0 && !containsString(f.EventIDs, m.EventID) {
+		return false
+	}
+	if host := strings.ToLower(strings.TrimSpace(f.Host)); host != "" {
+		if h := m.Host(); h != host && !strings.HasSuffix(h, "."+host) {
+			return false
+		}
+	}
+	if f.OlderThan > 0 || f.NewerThan > 0 {
+		if m.DeadAt.IsZero() {
+			return false
+		}
+		age := now.Sub(m.DeadAt)
+		if f.OlderThan > 0 && age < f.OlderThan {
+			return false
+		}
+		if f.NewerThan > 0 && age > f.NewerThan {
+			return false
+		}
+	}
+	return true
+}
+
+// DLQ reads and settles the work queue's dead-letter queue.
+type DLQ struct {
+	ch         DLQChannel
+	queue      string
+	exchange   string
+	routingKey string
+}
+
+// NewDLQ targets <queue>.dlq of cfg; replays go to the events exchange.
+func NewDLQ(ch DLQChannel, cfg *config.Config) *DLQ {
+	return &DLQ{
+		ch:         ch,
+		queue:      workQueue(cfg) + ".dlq",
+		exchange:   exchangeName(cfg),
+		routingKey: requestRoutingKey(cfg),
+	}
+}
+
+// OpenDLQ opens a confirm-mode channel on m for the DLQ tools. Replays on the
+// returned DLQ wait for the broker's confirm before acking the DLQ copy. close
+// releases the channel and with it any message still held.
+func OpenDLQ(ctx context.Context, m *amqpclient.Manager, cfg *config.Config) (q *DLQ, close func(), err error) {
+	ch, err := m.ConfirmChannel(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewDLQ(confirmingChannel{ch}, cfg), func() { _ = ch.Close() }, nil
+}
+
+// confirmingChannel turns publishes on a confirm-mode channel into synchronous
+// ones. A nack or a confirm that does not arrive fails with ErrPublishNacked.
+type confirmingChannel struct {
+	*amqp.Channel
+}
+
+func (c confirmingChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	dc, err := c.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+	if err != nil {
+		return err
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, publishConfirmTimeout)
+	defer cancel()
+	acked, err := dc.WaitContext(waitCtx)
+	if err != nil {
+		return fmt.Errorf("%w: %s: wait for confirm: %w", ErrPublishNacked, key, err)
+	}
+	if !acked {
+		return fmt.Errorf("%w: %s", ErrPublishNacked, key)
+	}
+	return nil
+}
+
+// Queue is the dead-letter queue name.
+func (q *DLQ) Queue() string { return q.queue }
+
+// Fetch takes up to limit messages off the DLQ without acking them. Every
+// message must end up in Replay, Purge or Release; closing the channel releases
+// whatever is left.
+func (q *DLQ) Fetch(limit int) ([]DeadLetter, error) {
+	var out []DeadLetter
+	for limit <= 0 || len(out) < limit {
+		d, ok, err := q.ch.Get(q.queue, false)
+		if err != nil {
+			Release(out)
+			return nil, fmt.Errorf("rabbitmq get %q: %w", q.queue, err)
+		}
+		if !ok {
+			break
+		}
+		out = append(out, newDeadLetter(d))
+	}
+	return out, nil
+}
+
+// Replay republishes msgs to the events exchange under their original routing key
+// with the retry counter reset, acking each DLQ copy once it is published. It
+// stops at the first failure; the rest stay unacked.
+func (q *DLQ) Replay(ctx context.Context, msgs []DeadLetter) (int, error) {
+	for i, m := range msgs {
+		d := m.delivery
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			if k == "x-death" || k == retryCountHeader || k == rejectReasonHeader || strings.HasPrefix(k, "x-first-death-") || strings.HasPrefix(k, "x-last-death-") {
+				continue
+			}
+			headers[k] = v
+		}
+		headers[replayCountHeader] = int32(headerInt(d.Headers[replayCountHeader]) + 1)
+
+		if err := q.ch.PublishWithContext(ctx, q.exchange, q.replayKey(m), false, false, republishing(d, headers)); err != nil {
+			return i, fmt.Errorf("replay %s: %w", m.EventID, err)
+		}
+		if err := d.Ack(false); err != nil {
+			return i, fmt.Errorf("ack replayed %s: %w", m.EventID, err)
+		}
+	}
+	return len(msgs), nil
+}
+
+// Purge acks msgs, deleting them from the DLQ.
+func (q *DLQ) Purge(msgs []DeadLetter) (int, error) {
+	for i, m := range msgs {
+		if err := m.delivery.Ack(false); err != nil {
+			return i, fmt.Errorf("ack %s: %w", m.EventID, err)
+		}
+	}
+	return len(msgs), nil
+}
+
+// Release puts msgs back on the DLQ untouched.
+func Release(msgs []DeadLetter) {
+	for _, m := range msgs {
+		_ = m.delivery.Nack(false, true)
+	}
+}
+
+// replayKey is the routing key the message was first published with, so
+// replays reach the same bindings; the configured request key otherwise.
+func (q *DLQ) replayKey(m DeadLetter) string {
+	if n := len(m.Deaths); n > 0 {
+		// x-death lists the most recent death first.
+		if keys := m.Deaths[n-1].RoutingKeys; len(keys) > 0 && m.Deaths[n-1].Exchange != "" {
+			return keys[0]
+		}
+	}
+	return q.routingKey
+}
+
+func newDeadLetter(d amqp.Delivery) DeadLetter {
+	m := DeadLetter{EventID: strings.TrimSpace(d.MessageId), EventName: strings.TrimSpace(d.Type), delivery: d}
+
+	var body struct {
+		EventName string `json:"event_name"`
+		EventID   string `json:"event_id"`
+		Data      struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(d.Body, &body) == nil {
+		if body.EventName != "" {
+			m.EventName = body.EventName
+		}
+		if body.EventID != "" {
+			m.EventID = body.EventID
+		}
+		m.URL = strings.TrimSpace(body.Data.URL)
+	}
+
+	m.Deaths = parseDeaths(d.Headers)
+	m.Retries = headerInt(d.Headers[retryCountHeader])
+	if reason, ok := d.Headers[rejectReasonHeader].(string); ok {
+		m.Reason = reason
+	}
+	if n := len(m.Deaths); n > 0 {
+		first := m.Deaths[n-1]
+		if m.Reason == "" {
+			m.Reason = first.Reason
+		}
+		m.DeadAt = first.Time
+	}
+	if m.DeadAt.IsZero() {
+		m.DeadAt = d.Timestamp
+	}
+	return m
+}
+
+func parseDeaths(headers amqp.Table) []Death {
+	list, _ := headers["x-death"].([]any)
+	out := make([]Death, 0, len(list))
+	for _, item := range list {
+		t, ok := item.(amqp.Table)
+		if !ok {
+			continue
+		}
+		death := Death{}
+		death.Queue, _ = t["queue"].(string)
+		death.Reason, _ = t["reason"].(string)
+		death.Exchange, _ = t["exchange"].(string)
+		death.Count, _ = t["count"].(int64)
+		death.Time, _ = t["time"].(time.Time)
+		if keys, ok := t["routing-keys"].([]any); ok {
+			for _, k := range keys {
+				if s, ok := k.(string); ok {
+					death.RoutingKeys = append(death.RoutingKeys, s)
+				}
+			}
+		}
+		out = append(out, death)
+	}
+	return out
+}
+
+func headerInt(v any) int {
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case int:
+		return n
+	}
+	return 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
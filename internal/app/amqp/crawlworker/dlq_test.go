@@ -0,0 +1,243 @@
+package crawlworker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/config"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryDLQ is an in-memory stand-in for a channel on the DLQ: Get hands out
+// queued messages unacked, Nack with requeue puts them back.
+type memoryDLQ struct {
+	queue     string
+	ready     []amqp.Delivery
+	unacked   map[uint64]amqp.Delivery
+	nextTag   uint64
+	exchanges []string
+	published []publishedMsg
+	pubErr    error
+}
+
+func newMemoryDLQ(queue string, msgs ...amqp.Delivery) *memoryDLQ {
+	return &memoryDLQ{queue: queue, ready: msgs, unacked: map[uint64]amqp.Delivery{}}
+}
+
+func (m *memoryDLQ) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	if queue != m.queue {
+		return amqp.Delivery{}, false, fmt.Errorf("no queue %q", queue)
+	}
+	if autoAck {
+		return amqp.Delivery{}, false, errors.New("autoAck not expected")
+	}
+	if len(m.ready) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	d := m.ready[0]
+	m.ready = m.ready[1:]
+	m.nextTag++
+	d.DeliveryTag = m.nextTag
+	d.Acknowledger = m
+	m.unacked[d.DeliveryTag] = d
+	return d, true, nil
+}
+
+func (m *memoryDLQ) PublishWithContext(_ context.Context, exchange, key string, _ bool, _ bool, msg amqp.Publishing) error {
+	if m.pubErr != nil {
+		return m.pubErr
+	}
+	m.exchanges = append(m.exchanges, exchange)
+	m.published = append(m.published, publishedMsg{key: key, msg: msg})
+	return nil
+}
+
+func (m *memoryDLQ) Ack(tag uint64, _ bool) error {
+	delete(m.unacked, tag)
+	return nil
+}
+
+func (m *memoryDLQ) Nack(tag uint64, _ bool, requeue bool) error {
+	if d, ok := m.unacked[tag]; ok && requeue {
+		d.Redelivered = true
+		m.ready = append(m.ready, d)
+	}
+	delete(m.unacked, tag)
+	return nil
+}
+
+func (m *memoryDLQ) Reject(tag uint64, requeue bool) error {
+	return m.Nack(tag, false, requeue)
+}
+
+func deadLetterDelivery(eventID, url string, headers amqp.Table) amqp.Delivery {
+	body := fmt.Sprintf(`{"event_name":%q,"event_id":%q,"data":{"url":%q}}`, CrawlRequestedEventName, eventID, url)
+	return amqp.Delivery{MessageId: eventID, Type: CrawlRequestedEventName, Headers: headers, Body: []byte(body)}
+}
+
+func rejectedDeath(at time.Time) amqp.Table {
+	return amqp.Table{
+		"x-death": []any{amqp.Table{
+			"queue":        "crawl",
+			"reason":       "rejected",
+			"exchange":     "events",
+			"routing-keys": []any{"crawler.url.requested.v1"},
+			"count":        int64(1),
+			"time":         at,
+		}},
+		"x-first-death-reason": "rejected",
+		retryCountHeader:       int32(3),
+	}
+}
+
+func TestDLQ_FetchParsesDeathsAndReasons(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	ch := newMemoryDLQ("crawl.dlq",
+		deadLetterDelivery("evt-1", "https://shopee.tw/i.1.2", rejectedDeath(at)),
+		deadLetterDelivery("evt-2", "https://www.taobao.com/item?id=1", amqp.Table{rejectReasonHeader: "crawlworker: unknown event"}),
+	)
+	cfg := &config.Config{}
+	cfg.RabbitMQ.Queue = "crawl"
+	q := NewDLQ(ch, cfg)
+	require.Equal(t, "crawl.dlq", q.Queue())
+
+	msgs, err := q.Fetch(0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	require.Equal(t, "evt-1", msgs[0].EventID)
+	require.Equal(t, CrawlRequestedEventName, msgs[0].EventName)
+	require.Equal(t, "shopee.tw", msgs[0].Host())
+	require.Equal(t, "rejected", msgs[0].Reason)
+	require.Equal(t, at, msgs[0].DeadAt)
+	require.Equal(t, 3, msgs[0].Retries)
+	require.Len(t, msgs[0].Deaths, 1)
+	require.Equal(t, []string{"crawler.url.requested.v1"}, msgs[0].Deaths[0].RoutingKeys)
+
+	require.Equal(t, "www.taobao.com", msgs[1].Host())
+	require.Equal(t, "crawlworker: unknown event", msgs[1].Reason)
+	require.True(t, msgs[1].DeadAt.IsZero())
+
+	Release(msgs)
+	require.Len(t, ch.ready, 2)
+	require.Empty(t, ch.unacked)
+}
+
+func TestDLQFilter_Match(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	m := DeadLetter{EventName: CrawlRequestedEventName, EventID: "evt-1", URL: "https://m.shopee.tw/i.1.2", DeadAt: now.Add(-2 * time.Hour)}
+
+	tests := []struct {
+		name   string
+		filter DLQFilter
+		want   bool
+	}{
+		{name: "empty", filter: DLQFilter{}, want: true},
+		{name: "event name", filter: DLQFilter{EventName: CrawlRequestedEventName}, want: true},
+		{name: "other event name", filter: DLQFilter{EventName: "crawler/other"}, want: false},
+		{name: "event id", filter: DLQFilter{EventIDs: []string{"evt-0", "evt-1"}}, want: true},
+		{name: "other event id", filter: DLQFilter{EventIDs: []string{"evt-2"}}, want: false},
+		{name: "exact host", filter: DLQFilter{Host: "m.shopee.tw"}, want: true},
+		{name: "parent host", filter: DLQFilter{Host: "Shopee.tw"}, want: true},
+		{name: "suffix is not a subdomain", filter: DLQFilter{Host: "hopee.tw"}, want: false},
+		{name: "older than", filter: DLQFilter{OlderThan: time.Hour}, want: true},
+		{name: "not older than", filter: DLQFilter{OlderThan: 3 * time.Hour}, want: false},
+		{name: "newer than", filter: DLQFilter{NewerThan: 3 * time.Hour}, want: true},
+		{name: "not newer than", filter: DLQFilter{NewerThan: time.Hour}, want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.filter.Match(m, now))
+		})
+	}
+
+	require.False(t, DLQFilter{OlderThan: time.Hour}.Match(DeadLetter{}, now), "unknown age never matches an age filter")
+}
+
+func TestDLQ_ReplayResetsAttemptsAndAcks(t *testing.T) {
+	t.Parallel()
+
+	at := time.Now().Add(-time.Hour)
+	ch := newMemoryDLQ("crawler.url.requested.v1.dlq",
+		deadLetterDelivery("evt-1", "https://shopee.tw/i.1.2", rejectedDeath(at)),
+		deadLetterDelivery("evt-2", "https://shopee.tw/i.1.3", amqp.Table{rejectReasonHeader: "invalid payload", replayCountHeader: int32(1), "x-trace": "abc"}),
+	)
+	q := NewDLQ(ch, &config.Config{})
+
+	msgs, err := q.Fetch(10)
+	require.NoError(t, err)
+
+	n, err := q.Replay(context.Background(), msgs)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Empty(t, ch.unacked)
+	require.Empty(t, ch.ready)
+
+	require.Equal(t, []string{"events", "events"}, ch.exchanges)
+	require.Equal(t, "crawler.url.requested.v1", ch.published[0].key)
+	first := ch.published[0].msg
+	require.Equal(t, "evt-1", first.MessageId)
+	require.Equal(t, msgs[0].Delivery().Body, first.Body)
+	require.Equal(t, amqp.Table{replayCountHeader: int32(1)}, first.Headers)
+
+	second := ch.published[1].msg
+	require.Equal(t, amqp.Table{replayCountHeader: int32(2), "x-trace": "abc"}, second.Headers)
+}
+
+func TestDLQ_ReplayStopsAtPublishFailure(t *testing.T) {
+	t.Parallel()
+
+	ch := newMemoryDLQ("crawler.url.requested.v1.dlq",
+		deadLetterDelivery("evt-1", "https://shopee.tw/i.1.2", nil),
+		deadLetterDelivery("evt-2", "https://shopee.tw/i.1.3", nil),
+	)
+	ch.pubErr = errors.New("channel closed")
+	q := NewDLQ(ch, &config.Config{})
+
+	msgs, err := q.Fetch(0)
+	require.NoError(t, err)
+
+	n, err := q.Replay(context.Background(), msgs)
+	require.Error(t, err)
+	require.Zero(t, n)
+	require.Len(t, ch.unacked, 2, "nothing is acked unless it was republished")
+}
+
+func TestDLQ_PurgeAcksOnlySelected(t *testing.T) {
+	t.Parallel()
+
+	ch := newMemoryDLQ("crawler.url.requested.v1.dlq",
+		deadLetterDelivery("evt-1", "https://shopee.tw/i.1.2", nil),
+		deadLetterDelivery("evt-2", "https://www.taobao.com/item?id=1", nil),
+	)
+	q := NewDLQ(ch, &config.Config{})
+
+	msgs, err := q.Fetch(0)
+	require.NoError(t, err)
+
+	var keep, drop []DeadLetter
+	for _, m := range msgs {
+		if (DLQFilter{Host: "taobao.com"}).Match(m, time.Now()) {
+			drop = append(drop, m)
+		} else {
+			keep = append(keep, m)
+		}
+	}
+	n, err := q.Purge(drop)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	Release(keep)
+
+	require.Len(t, ch.ready, 1)
+	require.Equal(t, "evt-1", ch.ready[0].MessageId)
+	require.Empty(t, ch.published)
+}
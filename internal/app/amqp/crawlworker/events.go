@@ -0,0 +1,241 @@
+package crawlworker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/pkg/amqpclient"
+	"peasydeal-product-miner/internal/product"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Routing keys and event names of the crawl outcome events.
+const (
+	CrawlCompletedRoutingKey = "crawler.url.completed.v1"
+	CrawlFailedRoutingKey    = "crawler.url.failed.v1"
+
+	CrawlCompletedEventName = "crawler/url.completed"
+	CrawlFailedEventName    = "crawler/url.failed"
+)
+
+const publishConfirmTimeout = 10 * time.Second
+
+// ErrPublishNacked is returned when the broker does not confirm a publish.
+var ErrPublishNacked = errors.New("rabbitmq publish not confirmed")
+
+// CrawlResultSummary is the small slice of the crawled product carried by outcome
+// events; the full result stays in the draft.
+type CrawlResultSummary struct {
+	Title          string `json:"title,omitempty"`
+	Currency       string `json:"currency,omitempty"`
+	Price          string `json:"price,omitempty"`
+	ImageCount     int    `json:"image_count"`
+	VariationCount int    `json:"variation_count"`
+}
+
+type CrawlResultEventData struct {
+	DraftID string             `json:"draft_id"`
+	URL     string             `json:"url"`
+	Status  string             `json:"status"`
+	Error   string             `json:"error,omitempty"`
+	Source  string             `json:"source,omitempty"`
+	Summary CrawlResultSummary `json:"summary"`
+}
+
+// CrawlResultEnvelope is published once a crawl request's draft is persisted.
+// EventID is the id of the crawler/url.requested event it answers.
+type CrawlResultEnvelope struct {
+	EventName string               `json:"event_name"`
+	EventID   string               `json:"event_id"`
+	TS        time.Time            `json:"ts"`
+	Data      CrawlResultEventData `json:"data"`
+}
+
+// RoutingKey is crawler.url.failed.v1 for error results and
+// crawler.url.completed.v1 otherwise.
+func (e CrawlResultEnvelope) RoutingKey() string {
+	if e.EventName == CrawlFailedEventName {
+		return CrawlFailedRoutingKey
+	}
+	return CrawlCompletedRoutingKey
+}
+
+// NewCrawlResultEnvelope builds the outcome event for a persisted draft. A nil
+// product is reported as a failure.
+func NewCrawlResultEnvelope(eventID, draftID, url string, p *product.Product) CrawlResultEnvelope {
+	data := CrawlResultEventData{DraftID: draftID, URL: url, Status: product.StatusError, Error: "missing runner result"}
+	if p != nil {
+		data.Status = p.Status
+		data.Error = p.Error
+		data.Source = p.Source
+		data.Summary = CrawlResultSummary{
+			Title:          p.Title,
+			Currency:       p.Currency,
+			Price:          p.Price.String(),
+			ImageCount:     len(p.ImageURLs()),
+			VariationCount: len(p.Variations),
+		}
+	}
+
+	name := CrawlCompletedEventName
+	if data.Status == product.StatusError {
+		name = CrawlFailedEventName
+	}
+	return CrawlResultEnvelope{
+		EventName: name,
+		EventID:   eventID,
+		TS:        time.Now().UTC(),
+		Data:      data,
+	}
+}
+
+// EventPublisher publishes crawl events (outcomes, and requests for enqueue tools)
+// to the events exchange on its own confirm-mode channel of the shared connection,
+// opening a new one on the next publish after a failure or reconnect.
+type EventPublisher struct {
+	cfg    *config.Config
+	amqp   *amqpclient.Manager
+	logger *zap.SugaredLogger
+
+	mu sync.Mutex
+	ch *amqp.Channel
+}
+
+type NewEventPublisherParams struct {
+	fx.In
+
+	Config *config.Config
+	AMQP   *amqpclient.Manager
+	Logger *zap.SugaredLogger
+}
+
+// NewEventPublisher returns nil when RabbitMQ is not configured.
+func NewEventPublisher(p NewEventPublisherParams) *EventPublisher {
+	if !p.AMQP.Enabled() {
+		return nil
+	}
+	return &EventPublisher{cfg: p.Config, amqp: p.AMQP, logger: p.Logger}
+}
+
+// Publish sends env and waits for the broker to confirm it.
+func (p *EventPublisher) Publish(ctx context.Context, env CrawlResultEnvelope) error {
+	return p.publish(ctx, env.RoutingKey(), env.EventName, env.EventID, env.TS, env)
+}
+
+// PublishRequest sends a crawl request to the configured routing key and waits
+// for the broker to confirm it.
+func (p *EventPublisher) PublishRequest(ctx context.Context, env CrawlRequestedEnvelope) error {
+	return p.publish(ctx, requestRoutingKey(p.cfg), env.EventName, env.EventID, env.TS, env)
+}
+
+func (p *EventPublisher) publish(ctx context.Context, key, eventName, eventID string, ts time.Time, env any) error {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", eventName, err)
+	}
+
+	ch, err := p.channel(ctx)
+	if err != nil {
+		return err
+	}
+
+	exchange := exchangeName(p.cfg)
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    eventID,
+		Type:         eventName,
+		Timestamp:    ts,
+		Body:         body,
+	})
+	if err != nil {
+		p.reset()
+		return fmt.Errorf("rabbitmq publish %s: %w", key, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, publishConfirmTimeout)
+	defer cancel()
+	acked, err := dc.WaitContext(waitCtx)
+	if err != nil {
+		// The confirm may still arrive, or the channel may be dead; either way
+		// the next publish starts on a fresh channel.
+		p.reset()
+		return fmt.Errorf("rabbitmq publish %s: wait for confirm: %w", key, err)
+	}
+	if !acked {
+		return fmt.Errorf("%w: %s", ErrPublishNacked, key)
+	}
+
+	p.logger.Infow("crawlworker_event_published",
+		"event_id", eventID,
+		"event_name", eventName,
+		"routing_key", key,
+		"exchange", exchange,
+	)
+	return nil
+}
+
+func (p *EventPublisher) channel(ctx context.Context) (*amqp.Channel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch != nil && !p.ch.IsClosed() {
+		return p.ch, nil
+	}
+	p.closeLocked()
+
+	ch, err := p.amqp.ConfirmChannel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.ch = ch
+	return ch, nil
+}
+
+func (p *EventPublisher) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeLocked()
+}
+
+// Close releases the publisher's channel; the connection belongs to the manager.
+func (p *EventPublisher) Close() {
+	if p == nil {
+		return
+	}
+	p.reset()
+}
+
+func (p *EventPublisher) closeLocked() {
+	if p.ch != nil {
+		_ = p.ch.Close()
+		p.ch = nil
+	}
+}
+
+func exchangeName(cfg *config.Config) string {
+	if cfg != nil {
+		if ex := strings.TrimSpace(cfg.RabbitMQ.Exchange); ex != "" {
+			return ex
+		}
+	}
+	return "events"
+}
+
+func requestRoutingKey(cfg *config.Config) string {
+	if cfg != nil {
+		if key := strings.TrimSpace(cfg.RabbitMQ.RoutingKey); key != "" {
+			return key
+		}
+	}
+	return "crawler.url.requested.v1"
+}
@@ -0,0 +1,65 @@
+package crawlworker
+
+import (
+	"encoding/json"
+	"testing"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewCrawlResultEnvelope_Completed(t *testing.T) {
+	t.Parallel()
+
+	p := &product.Product{
+		URL:        "https://shopee.tw/i.1.2",
+		Status:     product.StatusNeedsManual,
+		Source:     "shopee",
+		Title:      "Socks",
+		Currency:   "TWD",
+		Price:      product.MoneyString("199"),
+		Images:     []product.Image{{URL: "https://img/1"}, {URL: "https://img/2"}},
+		Variations: []product.Variation{{Title: "red"}},
+	}
+	env := NewCrawlResultEnvelope("evt-1", "draft-1", p.URL, p)
+
+	require.Equal(t, CrawlCompletedEventName, env.EventName)
+	require.Equal(t, CrawlCompletedRoutingKey, env.RoutingKey())
+	require.Equal(t, "evt-1", env.EventID)
+
+	b, err := json.Marshal(env.Data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"draft_id": "draft-1",
+		"url": "https://shopee.tw/i.1.2",
+		"status": "needs_manual",
+		"source": "shopee",
+		"summary": {"title": "Socks", "currency": "TWD", "price": "199", "image_count": 2, "variation_count": 1}
+	}`, string(b))
+}
+
+func TestNewCrawlResultEnvelope_Failed(t *testing.T) {
+	t.Parallel()
+
+	env := NewCrawlResultEnvelope("evt-2", "draft-2", "https://shopee.tw/i.1.2", &product.Product{
+		Status: product.StatusError,
+		Source: "shopee",
+		Error:  "tool run timed out",
+	})
+	require.Equal(t, CrawlFailedEventName, env.EventName)
+	require.Equal(t, CrawlFailedRoutingKey, env.RoutingKey())
+	require.Equal(t, "tool run timed out", env.Data.Error)
+
+	missing := NewCrawlResultEnvelope("evt-3", "draft-3", "https://shopee.tw/i.1.2", nil)
+	require.Equal(t, CrawlFailedRoutingKey, missing.RoutingKey())
+	require.Equal(t, "missing runner result", missing.Data.Error)
+}
+
+func TestNewEventPublisher_DisabledWithoutURL(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, NewEventPublisher(NewEventPublisherParams{Config: &config.Config{}, Logger: zap.NewNop().Sugar()}))
+}
@@ -4,30 +4,44 @@ import (
 	"context"
 
 	"peasydeal-product-miner/internal/app/amqp/crawlworker"
-	"peasydeal-product-miner/internal/pkg/amqpclient"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+// Module wires the consumer and event publisher; they need the
+// *amqpclient.Manager from amqpclientfx.Module.
 var Module = fx.Module(
 	"amqp-crawlworker",
 	fx.Provide(
-		amqpclient.NewAMQP,
 		fx.Annotate(
 			crawlworker.NewCrawlHandler,
 			fx.As(new(crawlworker.Handler)),
 		),
+		crawlworker.NewEventPublisher,
+		crawlworker.NewRouter,
 		crawlworker.NewConsumer,
 	),
 	fx.Invoke(registerLifecycleHooks),
 )
 
+// AsRoute registers a constructor returning a crawlworker.Route with the
+// consumer's router, next to the built-in crawler/url.requested v1 route.
+func AsRoute(f any) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			f,
+			fx.ResultTags(`group:"crawlworker_routes"`),
+		),
+	)
+}
+
 type hooksParams struct {
 	fx.In
 
 	Lifecycle fx.Lifecycle
 	Consumer  *crawlworker.Consumer
+	Events    *crawlworker.EventPublisher `optional:"true"`
 	Logger    *zap.SugaredLogger
 }
 
@@ -39,7 +53,9 @@ func registerLifecycleHooks(p hooksParams) {
 		},
 		OnStop: func(ctx context.Context) error {
 			p.Logger.Infow("crawlworker_stopping")
-			return p.Consumer.Stop(ctx)
+			err := p.Consumer.Stop(ctx)
+			p.Events.Close()
+			return err
 		},
 	})
 }
@@ -2,24 +2,42 @@ package crawlworker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"peasydeal-product-miner/config"
 	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
 	"peasydeal-product-miner/internal/pkg/chromedevtools"
+	"peasydeal-product-miner/internal/product"
 	"peasydeal-product-miner/internal/runner"
+	"peasydeal-product-miner/internal/source"
 
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+// resultPublisher is the part of EventPublisher the handler needs.
+type resultPublisher interface {
+	Publish(ctx context.Context, env CrawlResultEnvelope) error
+}
+
 type CrawlHandler struct {
 	cfg    *config.Config
 	runner *runner.Runner
 	store  *productdrafts.ProductDraftStore
+	events resultPublisher
 	logger *zap.SugaredLogger
+
+	// hostname is recorded on every crawl attempt.
+	hostname string
+
+	fallback         []string
+	fallbackBySource map[string][]string
+	overrides        OverrideAllowlist
 }
 
 type NewCrawlHandlerParams struct {
@@ -28,30 +46,72 @@ type NewCrawlHandlerParams struct {
 	Cfg    *config.Config
 	Runner *runner.Runner
 	Store  *productdrafts.ProductDraftStore
+	Events *EventPublisher `optional:"true"`
 	Logger *zap.SugaredLogger
 }
 
-func NewCrawlHandler(p NewCrawlHandlerParams) *CrawlHandler {
-	return &CrawlHandler{
-		cfg:    p.Cfg,
-		runner: p.Runner,
-		store:  p.Store,
-		logger: p.Logger,
+func NewCrawlHandler(p NewCrawlHandlerParams) (*CrawlHandler, error) {
+	fallbackBySource, err := runner.ParseToolChainsBySource(p.Cfg.CrawlToolFallbackBySource)
+	if err != nil {
+		return nil, fmt.Errorf("parse CRAWL_TOOL_FALLBACK_BY_SOURCE: %w", err)
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		p.Logger.Warnw("crawlworker_hostname_unavailable", "err", err)
+	}
+
+	h := &CrawlHandler{
+		cfg:              p.Cfg,
+		runner:           p.Runner,
+		store:            p.Store,
+		logger:           p.Logger,
+		hostname:         hostname,
+		fallback:         runner.ParseToolList(p.Cfg.CrawlToolFallback),
+		fallbackBySource: fallbackBySource,
+		overrides:        NewOverrideAllowlist(p.Cfg),
 	}
+	// Events is nil when RabbitMQ events are not configured; keep the interface nil too.
+	if p.Events != nil {
+		h.events = p.Events
+	}
+	return h, nil
 }
 
 func (h *CrawlHandler) Handle(ctx context.Context, msg CrawlRequestedEnvelope) error {
 	url := strings.TrimSpace(msg.Data.URL)
 	if url == "" {
-		return fmt.Errorf("missing url")
+		return Permanent(fmt.Errorf("missing url"))
 	}
 	if strings.TrimSpace(msg.EventID) == "" {
-		return fmt.Errorf("missing event_id")
+		return Permanent(fmt.Errorf("missing event_id"))
 	}
-	if strings.TrimSpace(msg.EventName) != "" && msg.EventName != "crawler/url.requested" {
-		return fmt.Errorf("unexpected event_name: %s", msg.EventName)
+	src, err := source.Detect(url)
+	if err != nil {
+		return Permanent(err)
+	}
+	if err := h.overrides.Check(msg.Data); err != nil {
+		return err
+	}
+
+	if !msg.Data.Force {
+		existing, err := h.store.FindByEventID(ctx, msg.EventID)
+		if err != nil {
+			return Retryable(err)
+		}
+		if existing != nil && existing.Completed() {
+			h.logger.Infow("crawlworker_skip_completed_event",
+				"event_id", msg.EventID,
+				"draft_id", existing.ID,
+				"status", existing.Status,
+			)
+			// The delivery that completed the draft may have failed to publish its
+			// result event, so publish the stored result again.
+			return h.publishStoredResult(ctx, msg.EventID, existing.ID)
+		}
 	}
 
+	// Check DevTools before the draft is marked CRAWLING, so a worker without
+	// Chrome leaves it untouched while the delivery is retried.
 	checkURL, effectiveHost := chromedevtools.VersionURLResolved(ctx, h.cfg.Chrome.DebugHost, h.cfg.Chrome.DebugPort)
 	if strings.TrimSpace(h.cfg.Chrome.DebugHost) != "" && effectiveHost != strings.TrimSpace(h.cfg.Chrome.DebugHost) {
 		h.logger.Infow("chrome_devtools_host_resolved",
@@ -66,7 +126,22 @@ func (h *CrawlHandler) Handle(ctx context.Context, msg CrawlRequestedEnvelope) e
 			"host", effectiveHost,
 			"err", err,
 		)
-		return err
+		return Retryable(fmt.Errorf("devtools unreachable: %w", err))
+	}
+
+	draftID, err := h.store.MarkCrawling(ctx, productdrafts.MarkCrawlingInput{
+		EventID:   msg.EventID,
+		CreatedBy: "rabbitmq",
+		URL:       url,
+		Source:    string(src),
+	})
+	if err != nil {
+		h.logger.Errorw("crawlworker_mark_crawling_failed",
+			"event_id", msg.EventID,
+			"url", url,
+			"err", err,
+		)
+		return draftError(err)
 	}
 
 	outDir := strings.TrimSpace(msg.Data.OutDir)
@@ -74,20 +149,57 @@ func (h *CrawlHandler) Handle(ctx context.Context, msg CrawlRequestedEnvelope) e
 		outDir = "/out"
 	}
 
-	outPath, result, err := h.runner.RunOnce(runner.Options{
-		URL:    url,
-		OutDir: outDir,
-		Tool:   h.cfg.CrawlTool,
-		RunID:  msg.EventID,
+	tool := strings.TrimSpace(msg.Data.Tool)
+	if tool == "" {
+		tool = h.cfg.CrawlTool
+	}
+	h.logger.Infow("crawlworker_crawl_requested",
+		"event_id", msg.EventID,
+		"draft_id", draftID,
+		"url", url,
+		"tool", tool,
+		"model", msg.Data.Model,
+		"skill_name", msg.Data.SkillName,
+		"priority", msg.Data.Priority,
+		"requested_by", msg.Data.RequestedBy,
+	)
+
+	startedAt := time.Now()
+	outPath, result, runErr := h.runner.RunOnce(ctx, runner.Options{
+		URL:       url,
+		OutDir:    outDir,
+		Tool:      tool,
+		Model:     msg.Data.Model,
+		SkillName: msg.Data.SkillName,
+		RunID:     msg.EventID,
+		Timeout:   h.cfg.CrawlTimeout,
+		Hints: runner.Hints{
+			Category: msg.Data.Hints.Category,
+			Locale:   msg.Data.Hints.Locale,
+			Notes:    msg.Data.Hints.Notes,
+		},
+
+		Fallback:         h.fallback,
+		FallbackBySource: h.fallbackBySource,
 	})
-	if err != nil {
+	run := crawlRun{
+		draftID:    draftID,
+		tool:       tool,
+		startedAt:  startedAt,
+		finishedAt: time.Now(),
+		outPath:    outPath,
+		result:     result,
+		err:        runErr,
+	}
+	if runErr != nil {
 		h.logger.Errorw("crawlworker_run_crawler_failed",
 			"event_id", msg.EventID,
 			"url", url,
 			"out_path", outPath,
-			"err", err,
+			"err", runErr,
 		)
-		// Intentionally swallow crawler failures so we can persist the failure result (same as Inngest).
+		// Crawler failures are persisted as the draft's result (same as Inngest); only
+		// timeouts are handed back below so the delivery is retried.
 	} else {
 		h.logger.Infow("crawlworker_run_crawler_ok",
 			"event_id", msg.EventID,
@@ -96,11 +208,38 @@ func (h *CrawlHandler) Handle(ctx context.Context, msg CrawlRequestedEnvelope) e
 		)
 	}
 
-	draftID, err := h.store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
+	if ctx.Err() != nil {
+		// Killed by a worker shutdown, not a crawl failure: keep the draft as it
+		// was and let the delivery be requeued.
+		run.err = fmt.Errorf("crawl interrupted: %w", ctx.Err())
+		h.recordAttempt(context.WithoutCancel(ctx), msg, run, nil)
+		return run.err
+	}
+
+	var crawled *product.Product
+	if result != nil {
+		p, err := result.Product()
+		if err != nil {
+			h.logger.Errorw("crawlworker_decode_product_failed",
+				"event_id", msg.EventID,
+				"url", url,
+				"err", err,
+			)
+			p = product.Product{
+				URL:    url,
+				Status: product.StatusError,
+				Error:  fmt.Sprintf("decode runner result: %v", err),
+			}
+		}
+		crawled = &p
+	}
+	h.recordAttempt(ctx, msg, run, crawled)
+
+	draftID, err = h.store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
 		EventID:   msg.EventID,
 		CreatedBy: "rabbitmq",
 		URL:       url,
-		Result:    result,
+		Product:   crawled,
 	})
 	if err != nil {
 		h.logger.Errorw("crawlworker_persist_product_draft_failed",
@@ -108,15 +247,196 @@ func (h *CrawlHandler) Handle(ctx context.Context, msg CrawlRequestedEnvelope) e
 			"url", url,
 			"err", err,
 		)
-		return err
+		return draftError(err)
 	}
 
+	status := ""
+	if crawled != nil {
+		status = crawled.Status
+	}
 	h.logger.Infow("crawlworker_finished",
 		"event_id", msg.EventID,
 		"url", url,
 		"draft_id", draftID,
+		"status", status,
 		"out_path", outPath,
 	)
 
+	if errors.Is(runErr, runner.ErrToolTimeout) {
+		// Retried, so the FAILED draft is not the outcome yet: a later attempt
+		// publishes its own result, or Abandon the failure once retries run out.
+		return Retryable(runErr)
+	}
+
+	// The draft is saved. If this publish fails, the redelivery publishes the
+	// stored result of a completed draft, or re-crawls a failed one.
+	return h.publishResult(ctx, NewCrawlResultEnvelope(msg.EventID, draftID, url, crawled))
+}
+
+// Abandon runs once the consumer gives up on msg: it was rejected without a
+// retry or its retries ran out. A draft still queued or in progress is moved to
+// FAILED so its URL can be queued again, and a FAILED draft's result event is
+// published.
+func (h *CrawlHandler) Abandon(ctx context.Context, msg CrawlRequestedEnvelope, cause error) {
+	existing, err := h.store.FindByEventID(ctx, msg.EventID)
+	if err != nil {
+		h.logger.Errorw("crawlworker_abandon_failed", "event_id", msg.EventID, "err", err)
+		return
+	}
+	if existing == nil {
+		return
+	}
+
+	switch existing.Status {
+	case productdrafts.StatusQueuedForDraft, productdrafts.StatusCrawling, productdrafts.StatusDrafting:
+		if err := h.store.Transition(ctx, productdrafts.TransitionInput{
+			DraftID: existing.ID,
+			From:    existing.Status,
+			To:      productdrafts.StatusFailed,
+			Error:   fmt.Sprintf("crawl request abandoned: %v", cause),
+			Actor:   "rabbitmq",
+		}); err != nil {
+			h.logger.Errorw("crawlworker_abandon_failed",
+				"event_id", msg.EventID,
+				"draft_id", existing.ID,
+				"err", err,
+			)
+			return
+		}
+		h.logger.Warnw("crawlworker_draft_abandoned",
+			"event_id", msg.EventID,
+			"draft_id", existing.ID,
+			"from", existing.Status,
+			"cause", cause,
+		)
+	case productdrafts.StatusFailed:
+	default:
+		// Completed, rejected or published: the draft already has its outcome.
+		return
+	}
+
+	if err := h.publishStoredResult(ctx, msg.EventID, existing.ID); err != nil {
+		h.logger.Errorw("crawlworker_abandon_failed",
+			"event_id", msg.EventID,
+			"draft_id", existing.ID,
+			"err", err,
+		)
+	}
+}
+
+// publishResult publishes the outcome event of a saved draft. A failed publish
+// is retryable.
+func (h *CrawlHandler) publishResult(ctx context.Context, env CrawlResultEnvelope) error {
+	if h.events == nil {
+		return nil
+	}
+	if err := h.events.Publish(ctx, env); err != nil {
+		h.logger.Errorw("crawlworker_publish_result_event_failed",
+			"event_id", env.EventID,
+			"event_name", env.EventName,
+			"err", err,
+		)
+		return Retryable(err)
+	}
 	return nil
 }
+
+// publishStoredResult publishes the outcome event of draftID from what the
+// draft holds. A FAILED draft is always reported as a failure, even when it
+// holds no crawl result.
+func (h *CrawlHandler) publishStoredResult(ctx context.Context, eventID, draftID string) error {
+	if h.events == nil {
+		return nil
+	}
+	d, err := h.store.GetDraft(ctx, draftID)
+	if err != nil {
+		return Retryable(err)
+	}
+	p, err := product.Parse(d.Payload)
+	if err != nil {
+		p = product.Product{URL: d.URL}
+	}
+	if d.Status == productdrafts.StatusFailed && p.Status != product.StatusError {
+		p.Status = product.StatusError
+		p.Error = d.Error
+	}
+	return h.publishResult(ctx, NewCrawlResultEnvelope(eventID, d.ID, d.URL, &p))
+}
+
+// crawlRun is what the handler knows about one runner.RunOnce call.
+type crawlRun struct {
+	draftID    string
+	tool       string
+	startedAt  time.Time
+	finishedAt time.Time
+	outPath    string
+	result     runner.Result
+	err        error
+}
+
+// recordAttempt appends run to the draft's crawl history. The history is
+// diagnostic only, so a failed write is logged and the crawl carries on.
+func (h *CrawlHandler) recordAttempt(ctx context.Context, msg CrawlRequestedEnvelope, run crawlRun, crawled *product.Product) {
+	in := newCrawlAttemptInput(msg, run, crawled)
+	in.WorkerHostname = h.hostname
+	if _, err := h.store.RecordCrawlAttempt(ctx, in); err != nil {
+		h.logger.Errorw("crawlworker_record_attempt_failed",
+			"event_id", msg.EventID,
+			"draft_id", run.draftID,
+			"err", err,
+		)
+	}
+}
+
+// newCrawlAttemptInput describes run as a crawl_attempts row. When the runner
+// failed over, the row names the tool and run id of the last tool tried; the
+// model override only applies to the requested tool.
+func newCrawlAttemptInput(msg CrawlRequestedEnvelope, run crawlRun, crawled *product.Product) productdrafts.RecordCrawlAttemptInput {
+	in := productdrafts.RecordCrawlAttemptInput{
+		DraftID:      run.draftID,
+		EventID:      msg.EventID,
+		RunID:        msg.EventID,
+		Tool:         run.tool,
+		Model:        msg.Data.Model,
+		SkillName:    msg.Data.SkillName,
+		StartedAt:    run.startedAt,
+		FinishedAt:   run.finishedAt,
+		Status:       productdrafts.CrawlAttemptStatus(crawled),
+		ArtifactPath: run.outPath,
+	}
+	if attempts, ok := run.result["attempts"].([]runner.Attempt); ok && len(attempts) > 0 {
+		last := attempts[len(attempts)-1]
+		if last.Tool != "" && last.Tool != run.tool {
+			in.Tool = last.Tool
+			in.Model = ""
+		}
+		if last.RunID != "" {
+			in.RunID = last.RunID
+		}
+	}
+
+	switch {
+	case crawled != nil && crawled.Status == product.StatusNeedsManual:
+		in.Error = crawled.Notes
+	case crawled != nil && crawled.Status != product.StatusOK:
+		in.Error = crawled.Error
+	}
+	if run.err != nil {
+		in.Status = product.StatusError
+		if in.Error == "" {
+			in.Error = run.err.Error()
+		}
+	}
+	return in
+}
+
+// draftError classifies a draft store failure: inputs the store rejects and
+// lifecycle moves it forbids (e.g. re-crawling a PUBLISHED draft) will not
+// succeed on a retry; a concurrent status change or a database error may.
+func draftError(err error) error {
+	var verr validator.ValidationErrors
+	if errors.As(err, &verr) || errors.Is(err, productdrafts.ErrInvalidInput) || errors.Is(err, productdrafts.ErrIllegalTransition) {
+		return Permanent(err)
+	}
+	return Retryable(err)
+}
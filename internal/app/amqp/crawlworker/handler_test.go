@@ -0,0 +1,231 @@
+package crawlworker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/db/dbtest"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	"peasydeal-product-miner/internal/product"
+	"peasydeal-product-miner/internal/runner"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCrawlHandler_SkipsCompletedEventsUnlessForced(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop().Sugar()
+	store := productdrafts.NewProductDraftStore(productdrafts.NewProductDraftStoreParams{Conn: dbtest.NewSQLite(t), Logger: logger})
+	url := "https://shopee.tw/i.1.2"
+	_, err := store.UpsertFromCrawlResult(context.Background(), productdrafts.UpsertFromCrawlResultInput{
+		EventID: "evt-1",
+		URL:     url,
+		Product: &product.Product{URL: url, Status: product.StatusOK, Title: "t"},
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	// Nothing listens here, so a crawl that is not skipped fails at the DevTools check.
+	cfg.Chrome.DebugHost = "127.0.0.1"
+	cfg.Chrome.DebugPort = "1"
+	h, err := NewCrawlHandler(NewCrawlHandlerParams{Cfg: cfg, Store: store, Logger: logger})
+	require.NoError(t, err)
+
+	msg := CrawlRequestedEnvelope{EventName: CrawlRequestedEventName, EventID: "evt-1", Data: CrawlRequestedEventData{URL: url}}
+	require.NoError(t, h.Handle(context.Background(), msg), "a READY_FOR_REVIEW draft is not crawled again")
+
+	msg.Data.Force = true
+	err = h.Handle(context.Background(), msg)
+	require.ErrorIs(t, err, ErrRetryable, "force re-crawls and reaches the DevTools check")
+	got, err := store.FindByEventID(context.Background(), "evt-1")
+	require.NoError(t, err)
+	require.Equal(t, productdrafts.StatusReadyForReview, got.Status, "without DevTools the draft is left alone")
+
+	msg = CrawlRequestedEnvelope{EventName: CrawlRequestedEventName, EventID: "evt-2", Data: CrawlRequestedEventData{URL: url}}
+	require.ErrorIs(t, h.Handle(context.Background(), msg), ErrRetryable, "unknown events are crawled")
+	got, err = store.FindByEventID(context.Background(), "evt-2")
+	require.NoError(t, err)
+	require.Nil(t, got, "no draft is created before DevTools is reachable")
+}
+
+func TestCrawlHandler_RepublishesResultOfCompletedEvents(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop().Sugar()
+	store := productdrafts.NewProductDraftStore(productdrafts.NewProductDraftStoreParams{Conn: dbtest.NewSQLite(t), Logger: logger})
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+	draftID, err := store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
+		EventID: "evt-1",
+		URL:     url,
+		Product: &product.Product{URL: url, Status: product.StatusOK, Title: "Cat Tree"},
+	})
+	require.NoError(t, err)
+
+	// The first delivery saved the draft but failed to publish, so this one
+	// finds it completed.
+	events := &fakeEvents{err: errors.New("channel closed")}
+	h := &CrawlHandler{cfg: &config.Config{}, store: store, events: events, logger: logger}
+	msg := CrawlRequestedEnvelope{EventName: CrawlRequestedEventName, EventID: "evt-1", Data: CrawlRequestedEventData{URL: url}}
+	require.ErrorIs(t, h.Handle(ctx, msg), ErrRetryable, "a failed publish is retried")
+
+	events.err = nil
+	require.NoError(t, h.Handle(ctx, msg))
+	require.Len(t, events.published, 1)
+	env := events.published[0]
+	require.Equal(t, CrawlCompletedEventName, env.EventName)
+	require.Equal(t, "evt-1", env.EventID)
+	require.Equal(t, draftID, env.Data.DraftID)
+	require.Equal(t, product.StatusOK, env.Data.Status)
+}
+
+func TestCrawlHandler_RejectsRecrawlOfPublishedDraft(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop().Sugar()
+	store := productdrafts.NewProductDraftStore(productdrafts.NewProductDraftStoreParams{Conn: dbtest.NewSQLite(t), Logger: logger})
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+	draftID, err := store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
+		EventID: "evt-1",
+		URL:     url,
+		Product: &product.Product{URL: url, Status: product.StatusOK, Title: "t"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.Transition(ctx, productdrafts.TransitionInput{
+		DraftID: draftID,
+		From:    productdrafts.StatusReadyForReview,
+		To:      productdrafts.StatusPublished,
+	}))
+
+	h, err := NewCrawlHandler(NewCrawlHandlerParams{Cfg: fakeDevToolsConfig(t), Store: store, Logger: logger})
+	require.NoError(t, err)
+
+	msg := CrawlRequestedEnvelope{EventName: CrawlRequestedEventName, EventID: "evt-1", Data: CrawlRequestedEventData{URL: url, Force: true}}
+	err = h.Handle(ctx, msg)
+	require.ErrorIs(t, err, ErrPermanent)
+	require.ErrorIs(t, err, productdrafts.ErrIllegalTransition)
+
+	got, err := store.FindByEventID(ctx, "evt-1")
+	require.NoError(t, err)
+	require.Equal(t, productdrafts.StatusPublished, got.Status)
+}
+
+func TestCrawlHandler_AbandonFailsUnfinishedDrafts(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop().Sugar()
+	store := productdrafts.NewProductDraftStore(productdrafts.NewProductDraftStoreParams{Conn: dbtest.NewSQLite(t), Logger: logger})
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+	draftID, err := store.MarkCrawling(ctx, productdrafts.MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+	_, err = store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
+		EventID: "evt-2",
+		URL:     url,
+		Product: &product.Product{URL: url, Status: product.StatusOK, Title: "t"},
+	})
+	require.NoError(t, err)
+
+	events := &fakeEvents{}
+	h := &CrawlHandler{cfg: &config.Config{}, store: store, events: events, logger: logger}
+	cause := Retryable(errors.New("devtools unreachable"))
+
+	h.Abandon(ctx, CrawlRequestedEnvelope{EventID: "evt-1", Data: CrawlRequestedEventData{URL: url}}, cause)
+	got, err := store.GetDraft(ctx, draftID)
+	require.NoError(t, err)
+	require.Equal(t, productdrafts.StatusFailed, got.Status)
+	require.Contains(t, got.Error, "devtools unreachable")
+	require.Len(t, events.published, 1)
+	require.Equal(t, CrawlFailedEventName, events.published[0].EventName)
+	require.Equal(t, "evt-1", events.published[0].EventID)
+	require.Equal(t, draftID, events.published[0].Data.DraftID)
+
+	// A finished draft keeps its outcome, and unknown events are ignored.
+	h.Abandon(ctx, CrawlRequestedEnvelope{EventID: "evt-2"}, cause)
+	h.Abandon(ctx, CrawlRequestedEnvelope{EventID: "evt-3"}, cause)
+	ready, err := store.FindByEventID(ctx, "evt-2")
+	require.NoError(t, err)
+	require.Equal(t, productdrafts.StatusReadyForReview, ready.Status)
+	require.Len(t, events.published, 1)
+}
+
+func TestNewCrawlAttemptInput(t *testing.T) {
+	t.Parallel()
+
+	started := time.Now()
+	msg := CrawlRequestedEnvelope{EventID: "evt-1", Data: CrawlRequestedEventData{Model: "gpt-5", SkillName: "shopee-orchestrator-pipeline"}}
+	run := crawlRun{draftID: "d-1", tool: "codex", startedAt: started, finishedAt: started.Add(time.Minute), outPath: "/out/evt-1.json"}
+
+	in := newCrawlAttemptInput(msg, run, &product.Product{Status: product.StatusOK})
+	require.Equal(t, productdrafts.RecordCrawlAttemptInput{
+		DraftID:      "d-1",
+		EventID:      "evt-1",
+		RunID:        "evt-1",
+		Tool:         "codex",
+		Model:        "gpt-5",
+		SkillName:    "shopee-orchestrator-pipeline",
+		StartedAt:    started,
+		FinishedAt:   started.Add(time.Minute),
+		Status:       product.StatusOK,
+		ArtifactPath: "/out/evt-1.json",
+	}, in)
+
+	// After a failover the row names the fallback tool, which ran without the model override.
+	run.result = runner.Result{"attempts": []runner.Attempt{
+		{Tool: "codex", RunID: "evt-1/attempt-1-codex", Status: "error"},
+		{Tool: "gemini", RunID: "evt-1/attempt-2-gemini", Status: "needs_manual"},
+	}}
+	in = newCrawlAttemptInput(msg, run, &product.Product{Status: product.StatusNeedsManual, Notes: "captcha"})
+	require.Equal(t, "gemini", in.Tool)
+	require.Empty(t, in.Model)
+	require.Equal(t, "evt-1/attempt-2-gemini", in.RunID)
+	require.Equal(t, product.StatusNeedsManual, in.Status)
+	require.Equal(t, "captcha", in.Error)
+
+	run.result = nil
+	run.err = errors.New("crawl interrupted: context canceled")
+	in = newCrawlAttemptInput(msg, run, nil)
+	require.Equal(t, product.StatusError, in.Status)
+	require.Equal(t, "crawl interrupted: context canceled", in.Error)
+}
+
+// fakeEvents records the result events a handler publishes.
+type fakeEvents struct {
+	err       error
+	published []CrawlResultEnvelope
+}
+
+func (e *fakeEvents) Publish(_ context.Context, env CrawlResultEnvelope) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.published = append(e.published, env)
+	return nil
+}
+
+// fakeDevToolsConfig points the Chrome settings at a server that answers the
+// DevTools version check, so a crawl gets past it.
+func fakeDevToolsConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"Browser":"Chrome/140.0.0.0"}`))
+	}))
+	t.Cleanup(srv.Close)
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	cfg := &config.Config{}
+	cfg.Chrome.DebugHost = host
+	cfg.Chrome.DebugPort = port
+	return cfg
+}
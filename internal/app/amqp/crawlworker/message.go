@@ -2,14 +2,39 @@ package crawlworker
 
 import "time"
 
+const CrawlRequestedEventName = "crawler/url.requested"
+
 type CrawlRequestedEventData struct {
-	URL    string `json:"url"`
+	URL    string `json:"url" validate:"required"`
 	OutDir string `json:"out_dir,omitempty"`
+
+	// Optional per-message overrides of CRAWL_TOOL, the tool's model and
+	// CRAWL_SKILL_NAME; each must be on the worker's allowlist.
+	Tool      string `json:"tool,omitempty"`
+	Model     string `json:"model,omitempty"`
+	SkillName string `json:"skill_name,omitempty"`
+
+	// Priority (0-9) and RequestedBy are informational and only logged.
+	Priority    int        `json:"priority,omitempty" validate:"min=0,max=9"`
+	Hints       CrawlHints `json:"hints,omitzero"`
+	RequestedBy string     `json:"requested_by,omitempty" validate:"max=128"`
+
+	// Force re-crawls even when the event already has a READY_FOR_REVIEW or
+	// PUBLISHED draft.
+	Force bool `json:"force,omitempty"`
+}
+
+// CrawlHints are folded into the skill prompt.
+type CrawlHints struct {
+	Category string `json:"category,omitempty" validate:"max=128"`
+	Notes    string `json:"notes,omitempty" validate:"max=2000"`
+	Locale   string `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
 }
 
 type CrawlRequestedEnvelope struct {
-	EventName string                  `json:"event_name"`
-	EventID   string                  `json:"event_id"`
-	TS        time.Time               `json:"ts"`
-	Data      CrawlRequestedEventData `json:"data"`
+	EventName     string                  `json:"event_name"`
+	EventID       string                  `json:"event_id"`
+	SchemaVersion int                     `json:"schema_version,omitempty"`
+	TS            time.Time               `json:"ts"`
+	Data          CrawlRequestedEventData `json:"data"`
 }
@@ -0,0 +1,55 @@
+package crawlworker
+
+import (
+	"fmt"
+	"strings"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/runner"
+)
+
+// OverrideAllowlist holds the tools, models and skills a crawl request may pick.
+// The worker checks every request against it; the enqueue paths check it too,
+// so a request the worker would reject never gets a queued draft.
+type OverrideAllowlist struct {
+	tools  map[string]bool
+	models map[string]bool
+	skills map[string]bool
+}
+
+func NewOverrideAllowlist(cfg *config.Config) OverrideAllowlist {
+	a := OverrideAllowlist{
+		tools:  map[string]bool{},
+		models: map[string]bool{},
+		skills: map[string]bool{},
+	}
+	if cfg != nil {
+		for _, tool := range runner.ParseToolList(cfg.CrawlOverrideTools) {
+			a.tools[tool] = true
+		}
+		for _, model := range strings.Split(cfg.CrawlOverrideModels, ",") {
+			if model = strings.TrimSpace(model); model != "" {
+				a.models[model] = true
+			}
+		}
+	}
+	for _, skill := range runner.SkillNames() {
+		a.skills[skill] = true
+	}
+	return a
+}
+
+// Check returns an ErrInvalidPayload error for the first override that is not
+// allowed, so the request is dead-lettered with the reason.
+func (a OverrideAllowlist) Check(data CrawlRequestedEventData) error {
+	if tool := strings.TrimSpace(data.Tool); tool != "" && !a.tools[tool] {
+		return fmt.Errorf("%w: tool %q is not allowed", ErrInvalidPayload, tool)
+	}
+	if model := strings.TrimSpace(data.Model); model != "" && !a.models[model] {
+		return fmt.Errorf("%w: model %q is not allowed", ErrInvalidPayload, model)
+	}
+	if skill := strings.TrimSpace(data.SkillName); skill != "" && !a.skills[skill] {
+		return fmt.Errorf("%w: skill %q is not allowed", ErrInvalidPayload, skill)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package crawlworker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"peasydeal-product-miner/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrideAllowlist_Check(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{}
+	cfg.CrawlOverrideTools = "codex, gemini"
+	cfg.CrawlOverrideModels = "gpt-5.2,gpt-5.2-mini"
+	a := NewOverrideAllowlist(cfg)
+
+	require.NoError(t, a.Check(CrawlRequestedEventData{URL: "u"}))
+	require.NoError(t, a.Check(CrawlRequestedEventData{Tool: "gemini", Model: "gpt-5.2-mini", SkillName: "taobao-orchestrator-pipeline"}))
+	require.ErrorIs(t, a.Check(CrawlRequestedEventData{Tool: "claude"}), ErrInvalidPayload)
+	require.ErrorIs(t, a.Check(CrawlRequestedEventData{Model: "o3"}), ErrInvalidPayload)
+	require.ErrorIs(t, a.Check(CrawlRequestedEventData{SkillName: "shopee-page-snapshot"}), ErrInvalidPayload)
+
+	require.ErrorIs(t, NewOverrideAllowlist(&config.Config{}).Check(CrawlRequestedEventData{Model: "gpt-5.2"}), ErrInvalidPayload,
+		"no model overrides unless configured")
+}
+
+func TestCrawlRequestedRoute_ValidatesOverrides(t *testing.T) {
+	t.Parallel()
+
+	var got CrawlRequestedEnvelope
+	r, err := NewRouterWith(CrawlRequestedRoute(handlerFunc(func(_ context.Context, msg CrawlRequestedEnvelope) error {
+		got = msg
+		return nil
+	})))
+	require.NoError(t, err)
+
+	dispatch := func(data string) error {
+		return r.Dispatch(context.Background(), Envelope{EventName: CrawlRequestedEventName, EventID: "evt-1", Data: json.RawMessage(data)})
+	}
+
+	require.NoError(t, dispatch(`{"url":"https://shopee.tw/i.1.2","tool":"gemini","priority":5,"requested_by":"ops","hints":{"category":"snacks","locale":"zh-TW","notes":"bundle"}}`))
+	require.Equal(t, "gemini", got.Data.Tool)
+	require.Equal(t, 5, got.Data.Priority)
+	require.Equal(t, CrawlHints{Category: "snacks", Locale: "zh-TW", Notes: "bundle"}, got.Data.Hints)
+
+	require.ErrorIs(t, dispatch(`{"url":"https://shopee.tw/i.1.2","priority":12}`), ErrInvalidPayload)
+	require.ErrorIs(t, dispatch(`{"url":"https://shopee.tw/i.1.2","hints":{"locale":"not a locale"}}`), ErrInvalidPayload)
+}
+
+type handlerFunc func(ctx context.Context, msg CrawlRequestedEnvelope) error
+
+func (f handlerFunc) Handle(ctx context.Context, msg CrawlRequestedEnvelope) error {
+	return f(ctx, msg)
+}
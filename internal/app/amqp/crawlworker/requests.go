@@ -0,0 +1,82 @@
+package crawlworker
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewCrawlRequest wraps data in a crawler/url.requested v1 envelope with a fresh
+// event id.
+func NewCrawlRequest(data CrawlRequestedEventData) CrawlRequestedEnvelope {
+	return CrawlRequestedEnvelope{
+		EventName:     CrawlRequestedEventName,
+		EventID:       uuid.NewString(),
+		SchemaVersion: 1,
+		TS:            time.Now().UTC(),
+		Data:          data,
+	}
+}
+
+// ReadCrawlRequests reads one crawl request per line: either a bare URL or a JSON
+// object. A JSON object is a request payload ({"url": ...}) or a whole envelope
+// ({"event_id": ..., "data": {...}}), whose event id is kept. Blank lines and
+// lines starting with # are skipped.
+func ReadCrawlRequests(r io.Reader) ([]CrawlRequestedEnvelope, error) {
+	var out []CrawlRequestedEnvelope
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, "{") {
+			out = append(out, NewCrawlRequest(CrawlRequestedEventData{URL: line}))
+			continue
+		}
+
+		env, err := parseCrawlRequestLine([]byte(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		out = append(out, env)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func parseCrawlRequestLine(line []byte) (CrawlRequestedEnvelope, error) {
+	var probe struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(line, &probe); err != nil {
+		return CrawlRequestedEnvelope{}, err
+	}
+
+	if len(probe.Data) == 0 {
+		var data CrawlRequestedEventData
+		if err := json.Unmarshal(line, &data); err != nil {
+			return CrawlRequestedEnvelope{}, err
+		}
+		return NewCrawlRequest(data), nil
+	}
+
+	var env CrawlRequestedEnvelope
+	if err := json.Unmarshal(line, &env); err != nil {
+		return CrawlRequestedEnvelope{}, err
+	}
+	fresh := NewCrawlRequest(env.Data)
+	if strings.TrimSpace(env.EventID) != "" {
+		fresh.EventID = strings.TrimSpace(env.EventID)
+	}
+	return fresh, nil
+}
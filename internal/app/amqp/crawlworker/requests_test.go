@@ -0,0 +1,39 @@
+package crawlworker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCrawlRequests(t *testing.T) {
+	t.Parallel()
+
+	in := strings.Join([]string{
+		"# urls to crawl",
+		"https://shopee.tw/i.1.2",
+		"",
+		`{"url":"https://item.taobao.com/item.htm?id=1","tool":"gemini","hints":{"locale":"zh-TW"}}`,
+		`{"event_name":"crawler/url.requested","event_id":"evt-keep","data":{"url":"https://shopee.tw/i.1.3","force":true}}`,
+	}, "\n")
+
+	got, err := ReadCrawlRequests(strings.NewReader(in))
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+
+	require.Equal(t, "https://shopee.tw/i.1.2", got[0].Data.URL)
+	require.Equal(t, CrawlRequestedEventName, got[0].EventName)
+	require.Equal(t, 1, got[0].SchemaVersion)
+	require.NotEmpty(t, got[0].EventID)
+	require.NotEqual(t, got[0].EventID, got[1].EventID, "every request gets its own event id")
+
+	require.Equal(t, "gemini", got[1].Data.Tool)
+	require.Equal(t, "zh-TW", got[1].Data.Hints.Locale)
+
+	require.Equal(t, "evt-keep", got[2].EventID)
+	require.True(t, got[2].Data.Force)
+
+	_, err = ReadCrawlRequests(strings.NewReader("https://shopee.tw/i.1.2\n{not json"))
+	require.ErrorContains(t, err, "line 2")
+}
@@ -0,0 +1,248 @@
+package crawlworker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/runner"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrRetryable and ErrPermanent classify Handler errors; wrap errors with
+// Retryable or Permanent. Unmarked errors are permanent unless they are network
+// errors or tool timeouts.
+var (
+	ErrRetryable = errors.New("retryable")
+	ErrPermanent = errors.New("permanent")
+)
+
+// Retryable marks err as transient: the delivery goes through the delay queues
+// before it is dead-lettered.
+func Retryable(err error) error {
+	return fmt.Errorf("%w: %w", ErrRetryable, err)
+}
+
+// Permanent marks err as one retrying cannot fix: the delivery is dead-lettered
+// right away.
+func Permanent(err error) error {
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+func isRetryable(err error) bool {
+	switch {
+	case errors.Is(err, ErrPermanent):
+		return false
+	case errors.Is(err, ErrRetryable), errors.Is(err, runner.ErrToolTimeout):
+		return true
+	}
+	// DevTools or Turso briefly unreachable.
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryCountHeader counts the retries already published for a message. x-death
+// counts from the delay queues are used when the header is missing or lower.
+const retryCountHeader = "x-retry-count"
+
+// retryPolicy describes the TTL delay queues a retryable failure walks through
+// before the message is dead-lettered. Step i waits baseDelay * 2^i.
+type retryPolicy struct {
+	queue     string
+	max       int
+	baseDelay time.Duration
+}
+
+func newRetryPolicy(cfg *config.Config) retryPolicy {
+	p := retryPolicy{queue: workQueue(cfg), baseDelay: time.Second}
+	if cfg == nil {
+		return p
+	}
+	if cfg.RabbitMQ.MaxRetries > 0 {
+		p.max = cfg.RabbitMQ.MaxRetries
+	}
+	if cfg.RabbitMQ.RetryDelay >= time.Second {
+		p.baseDelay = cfg.RabbitMQ.RetryDelay
+	}
+	return p
+}
+
+func (p retryPolicy) prefix() string {
+	return p.queue + ".retry."
+}
+
+// step returns the delay queue and its TTL for retry number i (0-based).
+func (p retryPolicy) step(i int) (string, time.Duration) {
+	delay := p.baseDelay << i
+	return fmt.Sprintf("%s%ds", p.prefix(), int64(delay/time.Second)), delay
+}
+
+// attempts reports how many retries the delivery has already been through.
+func (p retryPolicy) attempts(headers amqp.Table) int {
+	n := 0
+	switch v := headers[retryCountHeader].(type) {
+	case int32:
+		n = int(v)
+	case int64:
+		n = int(v)
+	case int:
+		n = v
+	}
+
+	deaths := 0
+	if list, ok := headers["x-death"].([]any); ok {
+		for _, item := range list {
+			death, ok := item.(amqp.Table)
+			if !ok {
+				continue
+			}
+			if queue, _ := death["queue"].(string); !strings.HasPrefix(queue, p.prefix()) {
+				continue
+			}
+			if count, ok := death["count"].(int64); ok {
+				deaths += int(count)
+			}
+		}
+	}
+	return max(n, deaths)
+}
+
+// declare creates one delay queue per retry step. Expired messages go back to
+// the work queue through the default exchange, so no other binding sees them.
+func (p retryPolicy) declare(ch *amqp.Channel) error {
+	for i := 0; i < p.max; i++ {
+		name, delay := p.step(i)
+		args := amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": p.queue,
+		}
+		if _, err := ch.QueueDeclare(name, true, false, false, false, args); err != nil {
+			return fmt.Errorf("rabbitmq retry queue declare %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// publisher is the part of *amqp.Channel used to schedule retries. The consumer
+// passes a confirmingChannel, so a nil error means the broker stored the copy.
+type publisher interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+}
+
+// rejectReasonHeader tells DLQ readers why deadLetter parked a message.
+const rejectReasonHeader = "x-reject-reason"
+
+// deadLetter publishes d straight to <queue>.dlq with reason in the
+// x-reject-reason header and acks it once the broker confirms the copy. If the
+// publish fails, the delivery is rejected instead and still reaches the DLQ
+// through the dead-letter exchange, but without the header. If it was sent but
+// not confirmed, the delivery is requeued, since the copy may be lost. It
+// reports whether the delivery was given up on, i.e. not requeued.
+func (c *Consumer) deadLetter(ctx context.Context, pub publisher, d amqp.Delivery, reason string) (gaveUp bool) {
+	if pub == nil {
+		_ = d.Reject(false)
+		return true
+	}
+
+	headers := copyHeaders(d.Headers)
+	headers[rejectReasonHeader] = reason
+	dlq := c.retry.queue + ".dlq"
+	if err := pub.PublishWithContext(ctx, "", dlq, false, false, republishing(d, headers)); err != nil {
+		c.logger.Errorw("crawlworker_dead_letter_publish_failed",
+			"message_id", d.MessageId,
+			"queue", dlq,
+			"err", err,
+		)
+		if errors.Is(err, ErrPublishNacked) {
+			_ = d.Nack(false, true)
+			return false
+		}
+		_ = d.Reject(false)
+		return true
+	}
+	_ = d.Ack(false)
+	return true
+}
+
+// retryOrReject settles a delivery whose handler failed: retryable failures are
+// republished to the next delay queue until the attempts run out, everything
+// else is rejected into <queue>.dlq. The delivery is acked only once the broker
+// confirms the delay queue copy, and requeued if it does not. It reports whether the delivery was given
+// up on, i.e. rejected rather than retried or requeued.
+func (c *Consumer) retryOrReject(ctx context.Context, pub publisher, d amqp.Delivery, eventID string, err error) (gaveUp bool) {
+	if ctx.Err() != nil {
+		// Interrupted by shutdown rather than failed: let the broker redeliver it.
+		_ = d.Nack(false, true)
+		return false
+	}
+
+	attempt := c.retry.attempts(d.Headers)
+	if !isRetryable(err) || pub == nil {
+		_ = d.Reject(false)
+		return true
+	}
+	if attempt >= c.retry.max {
+		c.logger.Warnw("crawlworker_retries_exhausted",
+			"event_id", eventID,
+			"attempts", attempt,
+			"err", err,
+		)
+		_ = d.Reject(false)
+		return true
+	}
+
+	queue, delay := c.retry.step(attempt)
+	headers := copyHeaders(d.Headers)
+	headers[retryCountHeader] = int32(attempt + 1)
+
+	if perr := pub.PublishWithContext(ctx, "", queue, false, false, republishing(d, headers)); perr != nil {
+		c.logger.Errorw("crawlworker_retry_publish_failed",
+			"event_id", eventID,
+			"queue", queue,
+			"err", perr,
+		)
+		_ = d.Nack(false, true)
+		return false
+	}
+
+	c.logger.Infow("crawlworker_retry_scheduled",
+		"event_id", eventID,
+		"attempt", attempt+1,
+		"max_retries", c.retry.max,
+		"delay", delay.String(),
+		"queue", queue,
+	)
+	_ = d.Ack(false)
+	return false
+}
+
+func copyHeaders(in amqp.Table) amqp.Table {
+	out := amqp.Table{}
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+// republishing copies d into a persistent message carrying headers.
+func republishing(d amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    amqp.Persistent,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	}
+}
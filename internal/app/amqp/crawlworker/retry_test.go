@@ -0,0 +1,139 @@
+package crawlworker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/runner"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type publishedMsg struct {
+	key string
+	msg amqp.Publishing
+}
+
+type fakePublisher struct {
+	err       error
+	published []publishedMsg
+}
+
+func (p *fakePublisher) PublishWithContext(_ context.Context, _ string, key string, _ bool, _ bool, msg amqp.Publishing) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.published = append(p.published, publishedMsg{key: key, msg: msg})
+	return nil
+}
+
+func newRetryConsumer(maxRetries int) *Consumer {
+	cfg := &config.Config{}
+	cfg.RabbitMQ.Queue = "crawl"
+	cfg.RabbitMQ.MaxRetries = maxRetries
+	cfg.RabbitMQ.RetryDelay = 10 * time.Second
+	return &Consumer{cfg: cfg, logger: zap.NewNop().Sugar(), retry: newRetryPolicy(cfg)}
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isRetryable(Retryable(errors.New("devtools down"))))
+	require.True(t, isRetryable(fmt.Errorf("codex: %w", runner.ErrToolTimeout)))
+	require.True(t, isRetryable(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	require.False(t, isRetryable(Permanent(errors.New("unsupported source"))))
+	require.False(t, isRetryable(Permanent(Retryable(errors.New("both")))), "permanent wins")
+	require.False(t, isRetryable(errors.New("unclassified")))
+}
+
+func TestRetryPolicy_StepsAndAttempts(t *testing.T) {
+	t.Parallel()
+
+	p := newRetryConsumer(3).retry
+	for i, want := range []string{"crawl.retry.10s", "crawl.retry.20s", "crawl.retry.40s"} {
+		name, delay := p.step(i)
+		require.Equal(t, want, name)
+		require.Equal(t, time.Duration(10<<i)*time.Second, delay)
+	}
+
+	require.Equal(t, 0, p.attempts(nil))
+	require.Equal(t, 2, p.attempts(amqp.Table{retryCountHeader: int32(2)}))
+	require.Equal(t, 3, p.attempts(amqp.Table{
+		retryCountHeader: int32(1),
+		"x-death": []any{
+			amqp.Table{"queue": "crawl.retry.10s", "count": int64(1)},
+			amqp.Table{"queue": "crawl.retry.20s", "count": int64(2)},
+			amqp.Table{"queue": "crawl", "count": int64(5)},
+		},
+	}), "x-death of the delay queues wins over a lower header")
+}
+
+func TestRetryOrReject(t *testing.T) {
+	t.Parallel()
+
+	retryable := Retryable(errors.New("devtools down"))
+	cases := []struct {
+		name       string
+		headers    amqp.Table
+		err        error
+		wantQueue  string
+		wantAcked  int
+		wantReject int
+		wantNacked int
+		publishErr error
+	}{
+		{name: "first retry", err: retryable, wantQueue: "crawl.retry.10s", wantAcked: 1},
+		{name: "second retry", headers: amqp.Table{retryCountHeader: int32(1)}, err: retryable, wantQueue: "crawl.retry.20s", wantAcked: 1},
+		{name: "exhausted", headers: amqp.Table{retryCountHeader: int32(2)}, err: retryable, wantReject: 1},
+		{name: "permanent", err: Permanent(errors.New("bad url")), wantReject: 1},
+		{name: "publish fails", err: retryable, publishErr: errors.New("channel closed"), wantNacked: 1},
+		{name: "publish not confirmed", err: retryable, publishErr: ErrPublishNacked, wantNacked: 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newRetryConsumer(2)
+			ack := &fakeAcknowledger{}
+			pub := &fakePublisher{err: tc.publishErr}
+
+			d := amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, MessageId: "evt-1", Headers: tc.headers, Body: []byte(`{}`)}
+			gaveUp := c.retryOrReject(context.Background(), pub, d, "evt-1", tc.err)
+			require.Equal(t, tc.wantReject == 1, gaveUp)
+
+			acked, rejected, nacked := ack.counts()
+			require.Equal(t, tc.wantAcked, acked)
+			require.Equal(t, tc.wantReject, rejected)
+			require.Equal(t, tc.wantNacked, nacked)
+			if tc.wantQueue == "" {
+				require.Empty(t, pub.published)
+				return
+			}
+			require.Len(t, pub.published, 1)
+			require.Equal(t, tc.wantQueue, pub.published[0].key)
+			require.Equal(t, "evt-1", pub.published[0].msg.MessageId)
+			require.Equal(t, int32(c.retry.attempts(tc.headers)+1), pub.published[0].msg.Headers[retryCountHeader])
+		})
+	}
+}
+
+func TestRetryOrReject_RequeuesOnShutdown(t *testing.T) {
+	t.Parallel()
+
+	c := newRetryConsumer(2)
+	ack := &fakeAcknowledger{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.False(t, c.retryOrReject(ctx, &fakePublisher{}, amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}, "evt-1", ctx.Err()))
+
+	acked, rejected, nacked := ack.counts()
+	require.Zero(t, acked)
+	require.Zero(t, rejected)
+	require.Equal(t, 1, nacked)
+}
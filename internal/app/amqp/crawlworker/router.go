@@ -0,0 +1,187 @@
+package crawlworker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownEvent and ErrInvalidPayload are returned by Router.Dispatch for
+// deliveries no route can handle; both are dead-lettered without retrying.
+var (
+	ErrUnknownEvent   = errors.New("unknown event")
+	ErrInvalidPayload = errors.New("invalid payload")
+)
+
+// Envelope is the routing view of an incoming event: Data stays raw until the
+// matching route decodes it. A missing schema_version means version 1.
+type Envelope struct {
+	EventName     string          `json:"event_name"`
+	EventID       string          `json:"event_id"`
+	SchemaVersion int             `json:"schema_version,omitempty"`
+	TS            time.Time       `json:"ts"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func (e Envelope) version() int {
+	if e.SchemaVersion <= 0 {
+		return 1
+	}
+	return e.SchemaVersion
+}
+
+// Route handles one event_name at one schema_version.
+type Route struct {
+	EventName string
+	Version   int
+	handle    func(ctx context.Context, v *validator.Validate, env Envelope) error
+	// abandon is optional; see Router.Abandon.
+	abandon func(ctx context.Context, env Envelope, cause error)
+}
+
+// Abandoner is implemented by handlers that clean up after the consumer gives up
+// on a delivery: it was rejected without a retry, or its retries ran out.
+type Abandoner interface {
+	Abandon(ctx context.Context, msg CrawlRequestedEnvelope, cause error)
+}
+
+// NewRoute builds a Route whose Data is decoded into T and checked against T's
+// validate tags before h runs.
+func NewRoute[T any](eventName string, version int, h func(ctx context.Context, env Envelope, data T) error) Route {
+	return Route{
+		EventName: eventName,
+		Version:   version,
+		handle: func(ctx context.Context, v *validator.Validate, env Envelope) error {
+			var data T
+			if len(env.Data) == 0 {
+				return fmt.Errorf("%w: %s v%d: missing data", ErrInvalidPayload, eventName, version)
+			}
+			if err := json.Unmarshal(env.Data, &data); err != nil {
+				return fmt.Errorf("%w: %s v%d: %w", ErrInvalidPayload, eventName, version, err)
+			}
+			if err := v.Struct(data); err != nil {
+				return fmt.Errorf("%w: %s v%d: %w", ErrInvalidPayload, eventName, version, err)
+			}
+			return h(ctx, env, data)
+		},
+	}
+}
+
+type routeKey struct {
+	name    string
+	version int
+}
+
+// Router dispatches envelopes to the route registered for their event_name and
+// schema_version.
+type Router struct {
+	routes   map[routeKey]Route
+	validate *validator.Validate
+}
+
+type NewRouterParams struct {
+	fx.In
+
+	Handler Handler `optional:"true"`
+	Routes  []Route `group:"crawlworker_routes"`
+	Logger  *zap.SugaredLogger
+}
+
+// NewRouter registers crawler/url.requested v1 on Handler plus every Route
+// provided to the crawlworker_routes group.
+func NewRouter(p NewRouterParams) (*Router, error) {
+	h := p.Handler
+	if h == nil {
+		h = missingHandler{}
+	}
+
+	r, err := NewRouterWith(append([]Route{CrawlRequestedRoute(h)}, p.Routes...)...)
+	if err != nil {
+		return nil, err
+	}
+	p.Logger.Infow("crawlworker_routes_registered", "routes", r.Names())
+	return r, nil
+}
+
+// NewRouterWith builds a Router from routes; registering the same event_name and
+// version twice is an error.
+func NewRouterWith(routes ...Route) (*Router, error) {
+	r := &Router{routes: map[routeKey]Route{}, validate: validator.New()}
+	for _, route := range routes {
+		name := strings.TrimSpace(route.EventName)
+		if name == "" || route.Version <= 0 || route.handle == nil {
+			return nil, fmt.Errorf("crawlworker route %q v%d: incomplete route", route.EventName, route.Version)
+		}
+		key := routeKey{name: name, version: route.Version}
+		if _, ok := r.routes[key]; ok {
+			return nil, fmt.Errorf("crawlworker route %s v%d registered twice", name, route.Version)
+		}
+		r.routes[key] = route
+	}
+	return r, nil
+}
+
+// Dispatch decodes env for its route and runs it.
+func (r *Router) Dispatch(ctx context.Context, env Envelope) error {
+	route, ok := r.routes[routeKey{name: strings.TrimSpace(env.EventName), version: env.version()}]
+	if !ok {
+		return fmt.Errorf("%w: %q v%d", ErrUnknownEvent, env.EventName, env.version())
+	}
+	return route.handle(ctx, r.validate, env)
+}
+
+// Abandon tells env's route that the consumer gave up on env after cause.
+// Routes without an abandon hook ignore it.
+func (r *Router) Abandon(ctx context.Context, env Envelope, cause error) {
+	route, ok := r.routes[routeKey{name: strings.TrimSpace(env.EventName), version: env.version()}]
+	if !ok || route.abandon == nil {
+		return
+	}
+	route.abandon(ctx, env, cause)
+}
+
+// Names lists the registered routes as "<event_name> v<version>", sorted.
+func (r *Router) Names() []string {
+	out := make([]string, 0, len(r.routes))
+	for key := range r.routes {
+		out = append(out, fmt.Sprintf("%s v%d", key.name, key.version))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CrawlRequestedRoute adapts a Handler to crawler/url.requested v1.
+// If h is an Abandoner, the route also forwards Router.Abandon to it.
+func CrawlRequestedRoute(h Handler) Route {
+	toMsg := func(env Envelope, data CrawlRequestedEventData) CrawlRequestedEnvelope {
+		return CrawlRequestedEnvelope{
+			EventName:     env.EventName,
+			EventID:       env.EventID,
+			SchemaVersion: env.version(),
+			TS:            env.TS,
+			Data:          data,
+		}
+	}
+
+	route := NewRoute(CrawlRequestedEventName, 1, func(ctx context.Context, env Envelope, data CrawlRequestedEventData) error {
+		return h.Handle(ctx, toMsg(env, data))
+	})
+	if a, ok := h.(Abandoner); ok {
+		route.abandon = func(ctx context.Context, env Envelope, cause error) {
+			var data CrawlRequestedEventData
+			if err := json.Unmarshal(env.Data, &data); err != nil {
+				return
+			}
+			a.Abandon(ctx, toMsg(env, data), cause)
+		}
+	}
+	return route
+}
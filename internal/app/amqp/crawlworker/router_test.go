@@ -0,0 +1,138 @@
+package crawlworker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+type recrawlData struct {
+	DraftID string `json:"draft_id" validate:"required"`
+}
+
+func TestRouter_DispatchesByNameAndVersion(t *testing.T) {
+	t.Parallel()
+
+	var got []string
+	r, err := NewRouterWith(
+		NewRoute("crawler/url.recrawl", 1, func(_ context.Context, _ Envelope, d recrawlData) error {
+			got = append(got, "v1:"+d.DraftID)
+			return nil
+		}),
+		NewRoute("crawler/url.recrawl", 2, func(_ context.Context, _ Envelope, d recrawlData) error {
+			got = append(got, "v2:"+d.DraftID)
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"crawler/url.recrawl v1", "crawler/url.recrawl v2"}, r.Names())
+
+	data := json.RawMessage(`{"draft_id":"d-1"}`)
+	require.NoError(t, r.Dispatch(context.Background(), Envelope{EventName: "crawler/url.recrawl", Data: data}))
+	require.NoError(t, r.Dispatch(context.Background(), Envelope{EventName: "crawler/url.recrawl", SchemaVersion: 2, Data: data}))
+	require.Equal(t, []string{"v1:d-1", "v2:d-1"}, got, "a missing schema_version is v1")
+
+	err = r.Dispatch(context.Background(), Envelope{EventName: "crawler/url.recrawl", SchemaVersion: 3, Data: data})
+	require.ErrorIs(t, err, ErrUnknownEvent)
+	err = r.Dispatch(context.Background(), Envelope{EventName: "crawler/url.cancel", Data: data})
+	require.ErrorIs(t, err, ErrUnknownEvent)
+
+	err = r.Dispatch(context.Background(), Envelope{EventName: "crawler/url.recrawl", Data: json.RawMessage(`{}`)})
+	require.ErrorIs(t, err, ErrInvalidPayload, "validate tags are checked")
+	err = r.Dispatch(context.Background(), Envelope{EventName: "crawler/url.recrawl", Data: json.RawMessage(`[1]`)})
+	require.ErrorIs(t, err, ErrInvalidPayload)
+}
+
+func TestNewRouterWith_RejectsDuplicateRoutes(t *testing.T) {
+	t.Parallel()
+
+	h := func(context.Context, Envelope, recrawlData) error { return nil }
+	_, err := NewRouterWith(NewRoute("crawler/url.recrawl", 1, h), NewRoute("crawler/url.recrawl", 1, h))
+	require.Error(t, err)
+	_, err = NewRouterWith(Route{EventName: "crawler/url.recrawl", Version: 1})
+	require.Error(t, err, "a route without a handler is incomplete")
+}
+
+func TestHandleDelivery_DeadLettersUnknownEvents(t *testing.T) {
+	t.Parallel()
+
+	c := newRetryConsumer(2)
+	r, err := NewRouterWith(CrawlRequestedRoute(missingHandler{}))
+	require.NoError(t, err)
+	c.router = r
+
+	ack := &fakeAcknowledger{}
+	pub := &fakePublisher{}
+	body := []byte(`{"event_name":"crawler/url.cancel","event_id":"evt-1","data":{}}`)
+	c.handleDelivery(context.Background(), pub, amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, MessageId: "evt-1", Body: body})
+
+	acked, rejected, nacked := ack.counts()
+	require.Equal(t, 1, acked)
+	require.Zero(t, rejected)
+	require.Zero(t, nacked)
+	require.Len(t, pub.published, 1)
+	require.Equal(t, "crawl.dlq", pub.published[0].key)
+	require.Equal(t, body, pub.published[0].msg.Body)
+	require.Contains(t, pub.published[0].msg.Headers[rejectReasonHeader], "unknown event")
+
+	// Without the DLQ publish the delivery still dead-letters, just without the reason.
+	ack = &fakeAcknowledger{}
+	c.handleDelivery(context.Background(), &fakePublisher{err: errors.New("channel closed")}, amqp.Delivery{Acknowledger: ack, DeliveryTag: 2, Body: body})
+	acked, rejected, _ = ack.counts()
+	require.Zero(t, acked)
+	require.Equal(t, 1, rejected)
+
+	// A copy the broker did not confirm may be lost, so the delivery is requeued.
+	ack = &fakeAcknowledger{}
+	c.handleDelivery(context.Background(), &fakePublisher{err: fmt.Errorf("%w: crawl.dlq", ErrPublishNacked)}, amqp.Delivery{Acknowledger: ack, DeliveryTag: 3, Body: body})
+	acked, rejected, nacked = ack.counts()
+	require.Zero(t, acked)
+	require.Zero(t, rejected)
+	require.Equal(t, 1, nacked)
+}
+
+// abandoningHandler fails every message and records what it is told to abandon.
+type abandoningHandler struct {
+	err       error
+	abandoned []string
+}
+
+func (h *abandoningHandler) Handle(context.Context, CrawlRequestedEnvelope) error { return h.err }
+
+func (h *abandoningHandler) Abandon(_ context.Context, msg CrawlRequestedEnvelope, _ error) {
+	h.abandoned = append(h.abandoned, msg.EventID)
+}
+
+func TestHandleDelivery_AbandonsWhenGivingUp(t *testing.T) {
+	t.Parallel()
+
+	h := &abandoningHandler{err: Retryable(errors.New("devtools down"))}
+	c := newRetryConsumer(1)
+	r, err := NewRouterWith(CrawlRequestedRoute(h))
+	require.NoError(t, err)
+	c.router = r
+
+	body := []byte(`{"event_name":"crawler/url.requested","event_id":"evt-1","data":{"url":"https://shopee.tw/i.1.2"}}`)
+	c.handleDelivery(context.Background(), &fakePublisher{}, amqp.Delivery{Acknowledger: &fakeAcknowledger{}, DeliveryTag: 1, Body: body})
+	require.Empty(t, h.abandoned, "a scheduled retry is not giving up")
+
+	headers := amqp.Table{retryCountHeader: int32(1)}
+	c.handleDelivery(context.Background(), &fakePublisher{}, amqp.Delivery{Acknowledger: &fakeAcknowledger{}, DeliveryTag: 2, Headers: headers, Body: body})
+	require.Equal(t, []string{"evt-1"}, h.abandoned, "retries ran out")
+
+	h.err = Permanent(errors.New("unsupported source"))
+	c.handleDelivery(context.Background(), &fakePublisher{}, amqp.Delivery{Acknowledger: &fakeAcknowledger{}, DeliveryTag: 3, Body: body})
+	require.Equal(t, []string{"evt-1", "evt-1"}, h.abandoned, "permanent failures are rejected at once")
+
+	h.err = fmt.Errorf("%w: tool %q is not allowed", ErrInvalidPayload, "claude")
+	c.handleDelivery(context.Background(), &fakePublisher{}, amqp.Delivery{Acknowledger: &fakeAcknowledger{}, DeliveryTag: 4, Body: body})
+	require.Len(t, h.abandoned, 3, "invalid payloads are dead-lettered and abandoned")
+
+	c.handleDelivery(context.Background(), &fakePublisher{err: ErrPublishNacked}, amqp.Delivery{Acknowledger: &fakeAcknowledger{}, DeliveryTag: 5, Body: body})
+	require.Len(t, h.abandoned, 3, "a requeued delivery is not given up on")
+}
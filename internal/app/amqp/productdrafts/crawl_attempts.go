@@ -0,0 +1,233 @@
+package productdrafts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/db"
+	sqlcdb "peasydeal-product-miner/db/sqlc"
+	"peasydeal-product-miner/internal/product"
+)
+
+// CrawlAttempt is one crawl run (one runner.RunOnce call) of a draft.
+type CrawlAttempt struct {
+	ID             int64
+	DraftID        string
+	EventID        string
+	RunID          string
+	Tool           string
+	Model          string
+	SkillName      string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	Status         string
+	Error          string
+	ArtifactPath   string
+	WorkerHostname string
+}
+
+// Duration is how long the attempt ran, or zero when it never finished.
+func (a CrawlAttempt) Duration() time.Duration {
+	if a.FinishedAt.IsZero() {
+		return 0
+	}
+	return a.FinishedAt.Sub(a.StartedAt)
+}
+
+type RecordCrawlAttemptInput struct {
+	DraftID   string `validate:"required"`
+	EventID   string
+	RunID     string `validate:"required"`
+	Tool      string `validate:"required"`
+	Model     string
+	SkillName string
+	StartedAt time.Time `validate:"required"`
+	// FinishedAt is zero for a run that never returned.
+	FinishedAt     time.Time
+	Status         string `validate:"required,oneof=ok needs_manual error"`
+	Error          string
+	ArtifactPath   string
+	WorkerHostname string
+}
+
+// RecordCrawlAttempt appends one row to the attempt history of in.DraftID. With
+// SQLite disabled nothing is written and 0 is returned.
+func (s *ProductDraftStore) RecordCrawlAttempt(ctx context.Context, in RecordCrawlAttemptInput) (int64, error) {
+	if err := s.validator.Struct(in); err != nil {
+		return 0, fmt.Errorf("validate crawl attempt input: %w", err)
+	}
+	if !in.FinishedAt.IsZero() && in.FinishedAt.Before(in.StartedAt) {
+		return 0, fmt.Errorf("validate crawl attempt input: finished_at %s before started_at %s", in.FinishedAt, in.StartedAt)
+	}
+
+	var finishedAtMs *int64
+	if !in.FinishedAt.IsZero() {
+		ms := in.FinishedAt.UnixMilli()
+		finishedAtMs = &ms
+	}
+
+	id, err := s.queries().CreateCrawlAttempt(ctx, sqlcdb.CreateCrawlAttemptParams{
+		DraftID:        in.DraftID,
+		EventID:        optionalString(in.EventID),
+		RunID:          in.RunID,
+		Tool:           in.Tool,
+		Model:          optionalString(in.Model),
+		SkillName:      optionalString(in.SkillName),
+		StartedAtMs:    in.StartedAt.UnixMilli(),
+		FinishedAtMs:   finishedAtMs,
+		Status:         in.Status,
+		Error:          optionalString(in.Error),
+		ArtifactPath:   optionalString(in.ArtifactPath),
+		WorkerHostname: optionalString(in.WorkerHostname),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrSQLiteDisabled) {
+			s.logger.Infow("turso_sqlite_disabled_skip_persist", "reason", err.Error())
+			return 0, nil
+		}
+		return 0, fmt.Errorf("insert crawl_attempts for draft %s: %w", in.DraftID, err)
+	}
+
+	s.logger.Infow("crawl_attempt_recorded",
+		"id", id,
+		"draft_id", in.DraftID,
+		"run_id", in.RunID,
+		"tool", in.Tool,
+		"status", in.Status,
+	)
+	return id, nil
+}
+
+// ListCrawlAttempts returns the attempt history of draftID, newest first. It is
+// empty when there is none or SQLite is disabled.
+func (s *ProductDraftStore) ListCrawlAttempts(ctx context.Context, draftID string) ([]CrawlAttempt, error) {
+	rows, err := s.queries().ListCrawlAttemptsByDraft(ctx, strings.TrimSpace(draftID))
+	if err != nil {
+		if errors.Is(err, db.ErrSQLiteDisabled) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list crawl_attempts for draft %s: %w", draftID, err)
+	}
+	return crawlAttemptsFromRows(rows), nil
+}
+
+// ListCrawlAttemptsByURL returns up to limit attempts across every draft of url,
+// newest first.
+func (s *ProductDraftStore) ListCrawlAttemptsByURL(ctx context.Context, url string, limit int) ([]CrawlAttempt, error) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := s.queries().ListCrawlAttemptsByURL(ctx, sqlcdb.ListCrawlAttemptsByURLParams{Url: &url, Limit: int64(limit)})
+	if err != nil {
+		if errors.Is(err, db.ErrSQLiteDisabled) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list crawl_attempts by url: %w", err)
+	}
+	return crawlAttemptsFromRows(rows), nil
+}
+
+// CountCrawlAttempts returns how many times draftID was crawled.
+func (s *ProductDraftStore) CountCrawlAttempts(ctx context.Context, draftID string) (int64, error) {
+	n, err := s.queries().CountCrawlAttemptsByDraft(ctx, strings.TrimSpace(draftID))
+	if err != nil {
+		if errors.Is(err, db.ErrSQLiteDisabled) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("count crawl_attempts for draft %s: %w", draftID, err)
+	}
+	return n, nil
+}
+
+// CrawlAttemptStatus is the crawl_attempts.status of a crawl result; a run that
+// returned no result is an error.
+func CrawlAttemptStatus(p *product.Product) string {
+	if p == nil {
+		return product.StatusError
+	}
+	switch p.Status {
+	case product.StatusOK, product.StatusNeedsManual:
+		return p.Status
+	default:
+		return product.StatusError
+	}
+}
+
+func crawlAttemptsFromRows(rows []sqlcdb.CrawlAttempt) []CrawlAttempt {
+	out := make([]CrawlAttempt, 0, len(rows))
+	for _, r := range rows {
+		a := CrawlAttempt{
+			ID:             r.ID,
+			DraftID:        r.DraftID,
+			EventID:        derefString(r.EventID),
+			RunID:          r.RunID,
+			Tool:           r.Tool,
+			Model:          derefString(r.Model),
+			SkillName:      derefString(r.SkillName),
+			StartedAt:      time.UnixMilli(r.StartedAtMs).UTC(),
+			Status:         r.Status,
+			Error:          derefString(r.Error),
+			ArtifactPath:   derefString(r.ArtifactPath),
+			WorkerHostname: derefString(r.WorkerHostname),
+		}
+		if r.FinishedAtMs != nil {
+			a.FinishedAt = time.UnixMilli(*r.FinishedAtMs).UTC()
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func (s *ProductDraftStore) queries() *sqlcdb.Queries {
+	return newQueries(s.conn)
+}
+
+func newQueries(conn db.Conn) *sqlcdb.Queries {
+	return sqlcdb.New(connDBTX{conn: conn})
+}
+
+// connDBTX runs sqlc queries on a db.Conn. Like the rest of the store it does
+// not pass ctx down, since db.Conn has no context-aware methods.
+type connDBTX struct {
+	conn db.Conn
+}
+
+func (c connDBTX) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.Exec(c.conn.Rebind(query), args...)
+}
+
+func (c connDBTX) PrepareContext(_ context.Context, query string) (*sql.Stmt, error) {
+	return c.conn.Prepare(c.conn.Rebind(query))
+}
+
+func (c connDBTX) QueryContext(_ context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.Query(c.conn.Rebind(query), args...)
+}
+
+func (c connDBTX) QueryRowContext(_ context.Context, query string, args ...interface{}) *sql.Row {
+	return c.conn.QueryRow(c.conn.Rebind(query), args...)
+}
+
+func optionalString(s string) *string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,126 @@
+package productdrafts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductDraftStore_CrawlAttemptHistory(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+
+	draftID, err := store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+
+	started := time.UnixMilli(1_760_000_000_000).UTC()
+	_, err = store.RecordCrawlAttempt(ctx, RecordCrawlAttemptInput{
+		DraftID:        draftID,
+		EventID:        "evt-1",
+		RunID:          "evt-1/attempt-2-gemini",
+		Tool:           "gemini",
+		StartedAt:      started,
+		FinishedAt:     started.Add(90 * time.Second),
+		Status:         product.StatusError,
+		Error:          "tool exited 1",
+		ArtifactPath:   "/out/evt-1.json",
+		WorkerHostname: "worker-1",
+	})
+	require.NoError(t, err)
+
+	second := started.Add(time.Hour)
+	_, err = store.RecordCrawlAttempt(ctx, RecordCrawlAttemptInput{
+		DraftID:   draftID,
+		EventID:   "evt-1",
+		RunID:     "evt-1",
+		Tool:      "codex",
+		Model:     "gpt-5",
+		SkillName: "shopee-orchestrator-pipeline",
+		StartedAt: second,
+		// Interrupted runs have no end time.
+		Status: product.StatusError,
+		Error:  "crawl interrupted: context canceled",
+	})
+	require.NoError(t, err)
+
+	got, err := store.ListCrawlAttempts(ctx, draftID)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	require.Equal(t, "codex", got[0].Tool, "newest first")
+	require.Equal(t, "gpt-5", got[0].Model)
+	require.Equal(t, second, got[0].StartedAt)
+	require.True(t, got[0].FinishedAt.IsZero())
+	require.Zero(t, got[0].Duration())
+
+	require.Equal(t, CrawlAttempt{
+		ID:             got[1].ID,
+		DraftID:        draftID,
+		EventID:        "evt-1",
+		RunID:          "evt-1/attempt-2-gemini",
+		Tool:           "gemini",
+		StartedAt:      started,
+		FinishedAt:     started.Add(90 * time.Second),
+		Status:         product.StatusError,
+		Error:          "tool exited 1",
+		ArtifactPath:   "/out/evt-1.json",
+		WorkerHostname: "worker-1",
+	}, got[1])
+	require.Equal(t, 90*time.Second, got[1].Duration())
+
+	n, err := store.CountCrawlAttempts(ctx, draftID)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+
+	byURL, err := store.ListCrawlAttemptsByURL(ctx, url, 1)
+	require.NoError(t, err)
+	require.Len(t, byURL, 1)
+	require.Equal(t, got[0].ID, byURL[0].ID)
+
+	// The history survives the draft being overwritten by a later result.
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", URL: url, Product: &product.Product{URL: url, Status: product.StatusOK, Title: "t"}})
+	require.NoError(t, err)
+	got, err = store.ListCrawlAttempts(ctx, draftID)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+}
+
+func TestProductDraftStore_RecordCrawlAttemptValidates(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+
+	draftID, err := store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: "https://shopee.tw/i.1.2"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	valid := RecordCrawlAttemptInput{DraftID: draftID, RunID: "evt-1", Tool: "codex", StartedAt: now, FinishedAt: now, Status: product.StatusOK}
+	_, err = store.RecordCrawlAttempt(ctx, valid)
+	require.NoError(t, err)
+
+	bad := valid
+	bad.Status = "READY_FOR_REVIEW"
+	_, err = store.RecordCrawlAttempt(ctx, bad)
+	require.Error(t, err)
+
+	bad = valid
+	bad.FinishedAt = now.Add(-time.Second)
+	_, err = store.RecordCrawlAttempt(ctx, bad)
+	require.Error(t, err)
+}
+
+func TestCrawlAttemptStatus(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, product.StatusError, CrawlAttemptStatus(nil))
+	require.Equal(t, product.StatusOK, CrawlAttemptStatus(&product.Product{Status: product.StatusOK}))
+	require.Equal(t, product.StatusNeedsManual, CrawlAttemptStatus(&product.Product{Status: product.StatusNeedsManual}))
+	require.Equal(t, product.StatusError, CrawlAttemptStatus(&product.Product{Status: "weird"}))
+}
@@ -0,0 +1,133 @@
+package productdrafts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/db"
+	sqlcdb "peasydeal-product-miner/db/sqlc"
+)
+
+// product_draft_events.action values.
+const (
+	draftEventCreated = "created"
+	draftEventUpdated = "updated"
+)
+
+// draftEvent is one product_draft_events row about to be written.
+type draftEvent struct {
+	draftID       string
+	eventID       string
+	actor         string
+	action        string
+	from          Status
+	to            Status
+	payloadBefore string
+	payloadAfter  string
+	reason        string
+}
+
+// recordDraftEvent appends e on tx, the transaction of the change it describes.
+func recordDraftEvent(ctx context.Context, tx db.Conn, e draftEvent) error {
+	var from *string
+	if e.from != "" {
+		s := string(e.from)
+		from = &s
+	}
+	_, err := newQueries(tx).CreateProductDraftEvent(ctx, sqlcdb.CreateProductDraftEventParams{
+		DraftID:       e.draftID,
+		EventID:       optionalString(e.eventID),
+		Actor:         e.actor,
+		Action:        e.action,
+		FromStatus:    from,
+		ToStatus:      string(e.to),
+		PayloadBefore: optionalString(e.payloadBefore),
+		PayloadAfter:  optionalString(e.payloadAfter),
+		Reason:        optionalString(e.reason),
+	})
+	if err != nil {
+		return fmt.Errorf("insert product_draft_events for draft %s: %w", e.draftID, err)
+	}
+	return nil
+}
+
+// DraftEvent is one audited change of a draft. PayloadBefore and PayloadAfter
+// are set only when the change replaced draft_payload (PayloadAfter alone on
+// creation).
+type DraftEvent struct {
+	ID            int64
+	DraftID       string
+	EventID       string
+	Actor         string
+	Action        string
+	From          Status
+	To            Status
+	PayloadBefore json.RawMessage
+	PayloadAfter  json.RawMessage
+	Reason        string
+	CreatedAt     time.Time
+}
+
+// PayloadChanged reports whether the change wrote a new draft_payload.
+func (e DraftEvent) PayloadChanged() bool {
+	return len(e.PayloadAfter) > 0
+}
+
+// Timeline is the audit log of one draft, oldest change first.
+type Timeline []DraftEvent
+
+// At replays the timeline up to and including t and returns the draft's status
+// and payload at that moment. ok is false when the draft did not exist yet.
+func (tl Timeline) At(t time.Time) (status Status, payload json.RawMessage, ok bool) {
+	for _, e := range tl {
+		if e.CreatedAt.After(t) {
+			break
+		}
+		status, ok = e.To, true
+		if e.PayloadChanged() {
+			payload = e.PayloadAfter
+		}
+	}
+	return status, payload, ok
+}
+
+// DraftTimeline returns every audited change of draftID, oldest first. It is
+// empty when there is none or SQLite is disabled.
+func (s *ProductDraftStore) DraftTimeline(ctx context.Context, draftID string) (Timeline, error) {
+	rows, err := s.queries().ListProductDraftEventsByDraft(ctx, strings.TrimSpace(draftID))
+	if err != nil {
+		if errors.Is(err, db.ErrSQLiteDisabled) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list product_draft_events for draft %s: %w", draftID, err)
+	}
+
+	tl := make(Timeline, 0, len(rows))
+	for _, r := range rows {
+		tl = append(tl, DraftEvent{
+			ID:            r.ID,
+			DraftID:       r.DraftID,
+			EventID:       derefString(r.EventID),
+			Actor:         r.Actor,
+			Action:        r.Action,
+			From:          Status(derefString(r.FromStatus)),
+			To:            Status(r.ToStatus),
+			PayloadBefore: rawJSON(r.PayloadBefore),
+			PayloadAfter:  rawJSON(r.PayloadAfter),
+			Reason:        derefString(r.Reason),
+			CreatedAt:     time.UnixMilli(r.CreatedAtMs).UTC(),
+		})
+	}
+	return tl, nil
+}
+
+func rawJSON(s *string) json.RawMessage {
+	if s == nil {
+		return nil
+	}
+	return json.RawMessage(*s)
+}
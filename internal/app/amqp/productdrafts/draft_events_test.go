@@ -0,0 +1,120 @@
+package productdrafts
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductDraftStore_DraftTimeline(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+
+	draftID, err := store.UpsertQueuedForDraft(ctx, UpsertQueuedForDraftInput{EventID: "evt-1", URL: url, Source: "shopee"})
+	require.NoError(t, err)
+	_, err = store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+	first := product.Product{URL: url, Status: product.StatusOK, Title: "first"}
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", CreatedBy: "rabbitmq", URL: url, Product: &first})
+	require.NoError(t, err)
+
+	// A re-crawl replaces the payload; the audit log keeps the old one.
+	_, err = store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+	second := product.Product{URL: url, Status: product.StatusOK, Title: "second"}
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", CreatedBy: "rabbitmq", URL: url, Product: &second})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Transition(ctx, TransitionInput{
+		DraftID: draftID,
+		From:    StatusReadyForReview,
+		To:      StatusRejected,
+		Actor:   "reviewer@peasydeal.com",
+		Reason:  "counterfeit listing",
+	}))
+
+	tl, err := store.DraftTimeline(ctx, draftID)
+	require.NoError(t, err)
+	require.Len(t, tl, 6)
+
+	type step struct {
+		actor, action string
+		from, to      Status
+		payload       bool
+	}
+	var got []step
+	for _, e := range tl {
+		require.Equal(t, draftID, e.DraftID)
+		got = append(got, step{e.Actor, e.Action, e.From, e.To, e.PayloadChanged()})
+	}
+	require.Equal(t, []step{
+		{"enqueue", "created", "", StatusQueuedForDraft, true},
+		{"rabbitmq", "updated", StatusQueuedForDraft, StatusCrawling, false},
+		{"rabbitmq", "updated", StatusCrawling, StatusReadyForReview, true},
+		{"rabbitmq", "updated", StatusReadyForReview, StatusCrawling, false},
+		{"rabbitmq", "updated", StatusCrawling, StatusReadyForReview, true},
+		{"reviewer@peasydeal.com", "updated", StatusReadyForReview, StatusRejected, false},
+	}, got)
+	require.Equal(t, "evt-1", tl[0].EventID)
+	require.Empty(t, tl[0].PayloadBefore)
+	require.Equal(t, "counterfeit listing", tl[5].Reason)
+
+	var before, after product.Product
+	require.NoError(t, json.Unmarshal(tl[4].PayloadBefore, &before))
+	require.NoError(t, json.Unmarshal(tl[4].PayloadAfter, &after))
+	require.Equal(t, "first", before.Title)
+	require.Equal(t, "second", after.Title)
+
+	status, payload, ok := tl.At(time.Now().Add(time.Hour))
+	require.True(t, ok)
+	require.Equal(t, StatusRejected, status)
+	require.NoError(t, json.Unmarshal(payload, &after))
+	require.Equal(t, "second", after.Title)
+
+	_, _, ok = tl.At(tl[0].CreatedAt.Add(-time.Second))
+	require.False(t, ok, "the draft did not exist yet")
+}
+
+func TestProductDraftStore_DraftEventsShareTheChangeTransaction(t *testing.T) {
+	t.Parallel()
+
+	store, conn := newMigratedStore(t)
+	ctx := context.Background()
+
+	draftID, err := store.UpsertQueuedForDraft(ctx, UpsertQueuedForDraftInput{EventID: "evt-1", URL: "https://shopee.tw/i.1.2"})
+	require.NoError(t, err)
+
+	// Rejected transitions and lost compare-and-sets leave no trace.
+	require.ErrorIs(t, store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusQueuedForDraft, To: StatusPublished}), ErrIllegalTransition)
+	require.ErrorIs(t, store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusCrawling, To: StatusReadyForReview}), ErrStatusConflict)
+
+	// A failed audit insert rolls the status change back.
+	_, err = conn.Exec(`CREATE TRIGGER fail_events BEFORE INSERT ON product_draft_events BEGIN SELECT RAISE(ABORT, 'boom'); END`)
+	require.NoError(t, err)
+	err = store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusQueuedForDraft, To: StatusCrawling})
+	require.ErrorContains(t, err, "boom")
+	_, err = conn.Exec(`DROP TRIGGER fail_events`)
+	require.NoError(t, err)
+
+	got, err := store.FindByEventID(ctx, "evt-1")
+	require.NoError(t, err)
+	require.Equal(t, StatusQueuedForDraft, got.Status)
+
+	tl, err := store.DraftTimeline(ctx, draftID)
+	require.NoError(t, err)
+	require.Len(t, tl, 1)
+
+	// The log is append-only.
+	_, err = conn.Exec(`UPDATE product_draft_events SET actor = 'someone-else'`)
+	require.ErrorContains(t, err, "append-only")
+	_, err = conn.Exec(`DELETE FROM product_draft_events`)
+	require.ErrorContains(t, err, "append-only")
+}
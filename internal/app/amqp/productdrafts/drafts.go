@@ -0,0 +1,280 @@
+package productdrafts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sqlcdb "peasydeal-product-miner/db/sqlc"
+)
+
+// ErrInvalidCursor means a ListDrafts cursor was not one ListDrafts returned.
+var ErrInvalidCursor = errors.New("invalid product drafts cursor")
+
+// DraftRecord is the full stored state of a product draft.
+type DraftRecord struct {
+	ID                 string
+	EventID            string
+	Status             Status
+	URL                string
+	Source             string
+	Payload            json.RawMessage
+	Error              string
+	CreatedBy          string
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	PublishedAt        time.Time
+	PublishedProductID string
+	// Review is nil until a reviewer approved or rejected the current payload.
+	Review *DraftReview
+}
+
+// DraftReview is a reviewer's decision on a draft.
+type DraftReview struct {
+	Decision   ReviewDecision
+	Reviewer   string
+	Notes      string
+	ReviewedAt time.Time
+}
+
+// Approved reports whether the draft's current payload was approved for
+// publishing.
+func (d DraftRecord) Approved() bool {
+	return d.Review != nil && d.Review.Decision == ReviewApproved
+}
+
+// draftColumns are the product_drafts columns GetProductDraft selects.
+const draftColumns = `
+  id,
+  event_id,
+  status,
+  draft_payload,
+  url,
+  source,
+  error,
+  created_by,
+  created_at_ms,
+  updated_at_ms,
+  published_at_ms,
+  published_product_id,
+  review_decision,
+  reviewed_by,
+  reviewed_at_ms,
+  review_notes`
+
+// draftRow is sqlcdb.GetProductDraftRow with db tags, so ListDrafts scans its
+// hand-built query by column name rather than by position.
+type draftRow struct {
+	ID                 string      `db:"id"`
+	EventID            *string     `db:"event_id"`
+	Status             string      `db:"status"`
+	DraftPayload       string      `db:"draft_payload"`
+	Url                *string     `db:"url"`
+	Source             *string     `db:"source"`
+	Error              interface{} `db:"error"`
+	CreatedBy          interface{} `db:"created_by"`
+	CreatedAtMs        int64       `db:"created_at_ms"`
+	UpdatedAtMs        int64       `db:"updated_at_ms"`
+	PublishedAtMs      interface{} `db:"published_at_ms"`
+	PublishedProductID interface{} `db:"published_product_id"`
+	ReviewDecision     *string     `db:"review_decision"`
+	ReviewedBy         *string     `db:"reviewed_by"`
+	ReviewedAtMs       *int64      `db:"reviewed_at_ms"`
+	ReviewNotes        *string     `db:"review_notes"`
+}
+
+// GetDraft returns draft id. Unlike the Find* lookups it reports a disabled
+// SQLite as an error (db.ErrSQLiteDisabled), and a missing draft as
+// ErrDraftNotFound.
+func (s *ProductDraftStore) GetDraft(ctx context.Context, id string) (*DraftRecord, error) {
+	id = strings.TrimSpace(id)
+	row, err := s.queries().GetProductDraft(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get product_drafts %s: %w", id, err)
+	}
+	d := draftRecordFromRow(row)
+	return &d, nil
+}
+
+type ListDraftsInput struct {
+	Status    Status
+	Source    string
+	CreatedBy string
+	// Limit defaults to 50 and is capped at 200.
+	Limit int `validate:"min=0"`
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// DraftPage is one page of ListDrafts. NextCursor is empty on the last page.
+type DraftPage struct {
+	Items      []DraftRecord
+	NextCursor string
+}
+
+// ListDrafts returns drafts matching in, newest first, one page at a time.
+// Pages are keyed on (created_at_ms, id), so drafts that change status between
+// requests are neither skipped nor repeated.
+func (s *ProductDraftStore) ListDrafts(ctx context.Context, in ListDraftsInput) (*DraftPage, error) {
+	_ = ctx
+
+	if err := s.validator.Struct(in); err != nil {
+		return nil, fmt.Errorf("validate list input: %w", err)
+	}
+	if in.Status != "" && !in.Status.Valid() {
+		return nil, fmt.Errorf("validate list input: %w: unknown status %q", ErrInvalidInput, in.Status)
+	}
+	limit := in.Limit
+	switch {
+	case limit == 0:
+		limit = 50
+	case limit > 200:
+		limit = 200
+	}
+
+	var (
+		where []string
+		args  []any
+	)
+	if in.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, string(in.Status))
+	}
+	if v := strings.TrimSpace(in.Source); v != "" {
+		where = append(where, "source = ?")
+		args = append(args, v)
+	}
+	if v := strings.TrimSpace(in.CreatedBy); v != "" {
+		where = append(where, "created_by = ?")
+		args = append(args, v)
+	}
+	if in.Cursor != "" {
+		createdAtMs, id, err := decodeCursor(in.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "(created_at_ms < ? OR (created_at_ms = ? AND id < ?))")
+		args = append(args, createdAtMs, createdAtMs, id)
+	}
+
+	q := "SELECT" + draftColumns + "\nFROM product_drafts\n"
+	if len(where) > 0 {
+		q += "WHERE " + strings.Join(where, " AND ") + "\n"
+	}
+	q += "ORDER BY created_at_ms DESC, id DESC\nLIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := s.conn.Queryx(s.conn.Rebind(q), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list product_drafts: %w", err)
+	}
+	defer rows.Close()
+
+	page := &DraftPage{Items: []DraftRecord{}}
+	for rows.Next() {
+		var r draftRow
+		if err := rows.StructScan(&r); err != nil {
+			return nil, fmt.Errorf("scan product_drafts: %w", err)
+		}
+		page.Items = append(page.Items, draftRecordFromRow(sqlcdb.GetProductDraftRow(r)))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list product_drafts: %w", err)
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt.UnixMilli(), last.ID)
+	}
+	return page, nil
+}
+
+func encodeCursor(createdAtMs int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAtMs, 10) + ":" + id))
+}
+
+func decodeCursor(cursor string) (createdAtMs int64, id string, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	ms, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	createdAtMs, err = strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	return createdAtMs, id, nil
+}
+
+func draftRecordFromRow(r sqlcdb.GetProductDraftRow) DraftRecord {
+	d := DraftRecord{
+		ID:                 r.ID,
+		EventID:            derefString(r.EventID),
+		Status:             Status(r.Status),
+		URL:                derefString(r.Url),
+		Source:             derefString(r.Source),
+		Payload:            json.RawMessage(r.DraftPayload),
+		Error:              anyString(r.Error),
+		CreatedBy:          anyString(r.CreatedBy),
+		CreatedAt:          time.UnixMilli(r.CreatedAtMs).UTC(),
+		UpdatedAt:          time.UnixMilli(r.UpdatedAtMs).UTC(),
+		PublishedProductID: anyString(r.PublishedProductID),
+	}
+	if ms, ok := anyInt64(r.PublishedAtMs); ok {
+		d.PublishedAt = time.UnixMilli(ms).UTC()
+	}
+	if r.ReviewDecision != nil {
+		d.Review = &DraftReview{
+			Decision: ReviewDecision(*r.ReviewDecision),
+			Reviewer: derefString(r.ReviewedBy),
+			Notes:    derefString(r.ReviewNotes),
+		}
+		if r.ReviewedAtMs != nil {
+			d.Review.ReviewedAt = time.UnixMilli(*r.ReviewedAtMs).UTC()
+		}
+	}
+	return d
+}
+
+// anyString and anyInt64 read the interface{} fields sqlc generates for columns
+// declared NULL; drivers hand back TEXT as string or []byte.
+func anyString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+func anyInt64(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
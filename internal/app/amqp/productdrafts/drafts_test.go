@@ -0,0 +1,47 @@
+package productdrafts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductDraftStore_ListDraftsMatchesGetDraft(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+
+	var ids []string
+	for _, evt := range []string{"evt-1", "evt-2", "evt-3"} {
+		id, err := store.UpsertQueuedForDraft(ctx, UpsertQueuedForDraftInput{
+			EventID:   evt,
+			URL:       "https://shopee.tw/i.1." + evt,
+			Source:    "shopee",
+			CreatedBy: "alice",
+		})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	var listed []DraftRecord
+	in := ListDraftsInput{CreatedBy: "alice", Limit: 2}
+	for {
+		page, err := store.ListDrafts(ctx, in)
+		require.NoError(t, err)
+		listed = append(listed, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		in.Cursor = page.NextCursor
+	}
+	require.Len(t, listed, len(ids))
+
+	// Every listed field comes from the column of the same name.
+	for _, got := range listed {
+		want, err := store.GetDraft(ctx, got.ID)
+		require.NoError(t, err)
+		require.Equal(t, *want, got)
+	}
+}
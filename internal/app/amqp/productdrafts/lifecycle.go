@@ -0,0 +1,99 @@
+package productdrafts
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Status is a product_drafts.status value.
+type Status string
+
+const (
+	StatusFound          Status = "FOUND"
+	StatusQueuedForDraft Status = "QUEUED_FOR_DRAFT"
+	StatusCrawling       Status = "CRAWLING"
+	StatusDrafting       Status = "DRAFTING"
+	StatusReadyForReview Status = "READY_FOR_REVIEW"
+	StatusPublished      Status = "PUBLISHED"
+	StatusFailed         Status = "FAILED"
+	StatusRejected       Status = "REJECTED"
+)
+
+var (
+	// ErrIllegalTransition means the lifecycle does not allow the requested move.
+	ErrIllegalTransition = errors.New("illegal product draft status transition")
+	// ErrStatusConflict means the draft left the expected status before the write
+	// landed; re-read it and decide again.
+	ErrStatusConflict = errors.New("product draft status changed concurrently")
+	// ErrDraftNotFound means there is no draft with the given id.
+	ErrDraftNotFound = errors.New("product draft not found")
+	// ErrInvalidInput means a store call was rejected before touching the draft.
+	ErrInvalidInput = errors.New("invalid product draft input")
+)
+
+// transitions lists the statuses each status may move to. A crawl result lands
+// from CRAWLING (or DRAFTING); READY_FOR_REVIEW and FAILED also accept offline
+// re-extractions and re-crawls, while PUBLISHED is final.
+var transitions = map[Status][]Status{
+	StatusFound:          {StatusQueuedForDraft, StatusRejected},
+	StatusQueuedForDraft: {StatusQueuedForDraft, StatusCrawling, StatusFailed, StatusRejected},
+	// CRAWLING -> CRAWLING is a redelivery after the worker died mid-crawl.
+	StatusCrawling:       {StatusCrawling, StatusDrafting, StatusReadyForReview, StatusFailed, StatusQueuedForDraft},
+	StatusDrafting:       {StatusReadyForReview, StatusFailed, StatusQueuedForDraft},
+	StatusReadyForReview: {StatusReadyForReview, StatusQueuedForDraft, StatusCrawling, StatusPublished, StatusRejected},
+	StatusFailed:         {StatusQueuedForDraft, StatusCrawling, StatusReadyForReview, StatusRejected},
+	StatusPublished:      {},
+	StatusRejected:       {StatusQueuedForDraft},
+}
+
+// Valid reports whether s is a status the product_drafts CHECK accepts.
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether the lifecycle allows moving from s to to.
+func (s Status) CanTransitionTo(to Status) bool {
+	for _, next := range transitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Completed reports whether s holds a successful crawl that a redelivered
+// request should not redo.
+func (s Status) Completed() bool {
+	return s == StatusReadyForReview || s == StatusPublished
+}
+
+// TransitionError is returned when a draft cannot move From -> To. Err is
+// ErrIllegalTransition or ErrStatusConflict; for a conflict, Actual is the
+// status found instead of From.
+type TransitionError struct {
+	DraftID string
+	From    Status
+	To      Status
+	Actual  Status
+	Err     error
+}
+
+func (e *TransitionError) Error() string {
+	if e.Actual != "" {
+		return fmt.Sprintf("product draft %s: %s -> %s: %v (now %s)", e.DraftID, e.From, e.To, e.Err, e.Actual)
+	}
+	return fmt.Sprintf("product draft %s: %s -> %s: %v", e.DraftID, e.From, e.To, e.Err)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return e.Err
+}
+
+// checkTransition returns a *TransitionError unless from -> to is legal.
+func checkTransition(draftID string, from, to Status) error {
+	if from.CanTransitionTo(to) {
+		return nil
+	}
+	return &TransitionError{DraftID: draftID, From: from, To: to, Err: ErrIllegalTransition}
+}
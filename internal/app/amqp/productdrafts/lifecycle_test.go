@@ -0,0 +1,136 @@
+package productdrafts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus_CanTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		from, to Status
+		want     bool
+	}{
+		{StatusFound, StatusQueuedForDraft, true},
+		{StatusQueuedForDraft, StatusCrawling, true},
+		{StatusCrawling, StatusCrawling, true},
+		{StatusCrawling, StatusReadyForReview, true},
+		{StatusCrawling, StatusFailed, true},
+		{StatusDrafting, StatusReadyForReview, true},
+		{StatusReadyForReview, StatusPublished, true},
+		{StatusReadyForReview, StatusCrawling, true},
+		{StatusFailed, StatusCrawling, true},
+		{StatusRejected, StatusQueuedForDraft, true},
+
+		{StatusQueuedForDraft, StatusReadyForReview, false},
+		{StatusReadyForReview, StatusFailed, false},
+		{StatusFailed, StatusPublished, false},
+		{StatusPublished, StatusCrawling, false},
+		{StatusPublished, StatusFailed, false},
+		{StatusPublished, StatusQueuedForDraft, false},
+		{Status("BOGUS"), StatusCrawling, false},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.want, tc.from.CanTransitionTo(tc.to), "%s -> %s", tc.from, tc.to)
+	}
+
+	for s := range transitions {
+		require.True(t, s.Valid())
+		for _, next := range transitions[s] {
+			require.True(t, next.Valid(), "%s -> %s", s, next)
+		}
+	}
+}
+
+func TestProductDraftStore_LifecycleThroughCrawl(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+
+	queuedID, err := store.UpsertQueuedForDraft(ctx, UpsertQueuedForDraftInput{EventID: "evt-1", URL: url, Source: "shopee"})
+	require.NoError(t, err)
+
+	crawlingID, err := store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+	require.Equal(t, queuedID, crawlingID)
+
+	ok := product.Product{URL: url, Status: product.StatusOK, Title: "t"}
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", URL: url, Product: &ok})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Transition(ctx, TransitionInput{DraftID: queuedID, From: StatusReadyForReview, To: StatusPublished}))
+
+	got, err := store.FindByEventID(ctx, "evt-1")
+	require.NoError(t, err)
+	require.Equal(t, StatusPublished, got.Status)
+
+	// Neither a forced re-crawl nor its failed result may touch a published draft.
+	_, err = store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.ErrorIs(t, err, ErrIllegalTransition)
+
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", URL: url})
+	var terr *TransitionError
+	require.True(t, errors.As(err, &terr))
+	require.Equal(t, TransitionError{DraftID: queuedID, From: StatusPublished, To: StatusFailed, Err: ErrIllegalTransition}, *terr)
+
+	got, err = store.FindByEventID(ctx, "evt-1")
+	require.NoError(t, err)
+	require.Equal(t, StatusPublished, got.Status)
+}
+
+func TestProductDraftStore_MarkCrawlingCreatesMissingDraft(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+
+	draftID, err := store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: "https://shopee.tw/i.1.2", Source: "shopee"})
+	require.NoError(t, err)
+
+	got, err := store.FindByEventID(ctx, "evt-1")
+	require.NoError(t, err)
+	require.Equal(t, Draft{ID: draftID, EventID: "evt-1", Status: StatusCrawling}, *got)
+
+	// A redelivery after a worker crash finds the draft still CRAWLING.
+	again, err := store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: "https://shopee.tw/i.1.2"})
+	require.NoError(t, err)
+	require.Equal(t, draftID, again)
+}
+
+func TestProductDraftStore_TransitionCompareAndSet(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+
+	draftID, err := store.UpsertQueuedForDraft(ctx, UpsertQueuedForDraftInput{EventID: "evt-1", URL: "https://shopee.tw/i.1.2"})
+	require.NoError(t, err)
+
+	require.NoError(t, store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusQueuedForDraft, To: StatusCrawling}))
+
+	// A second writer that still believes the draft is queued loses.
+	err = store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusQueuedForDraft, To: StatusCrawling})
+	require.ErrorIs(t, err, ErrStatusConflict)
+	var terr *TransitionError
+	require.True(t, errors.As(err, &terr))
+	require.Equal(t, StatusCrawling, terr.Actual)
+
+	err = store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusCrawling, To: StatusPublished})
+	require.ErrorIs(t, err, ErrIllegalTransition)
+
+	err = store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusCrawling, To: StatusFailed})
+	require.Error(t, err, "FAILED needs an error text")
+
+	require.NoError(t, store.Transition(ctx, TransitionInput{DraftID: draftID, From: StatusCrawling, To: StatusFailed, Error: "tool crashed"}))
+
+	err = store.Transition(ctx, TransitionInput{DraftID: "missing", From: StatusFailed, To: StatusCrawling})
+	require.ErrorIs(t, err, ErrDraftNotFound)
+}
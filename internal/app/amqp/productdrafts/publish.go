@@ -0,0 +1,101 @@
+package productdrafts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/db"
+)
+
+// ErrNotApproved means a draft cannot be published because its current payload
+// has no approving review.
+var ErrNotApproved = errors.New("product draft is not approved")
+
+type MarkPublishedInput struct {
+	DraftID   string `validate:"required"`
+	ProductID string `validate:"required"`
+	Actor     string `validate:"max=128"`
+}
+
+// MarkPublished moves an approved READY_FOR_REVIEW draft to PUBLISHED and links
+// it to its catalog product. Marking a draft that is already PUBLISHED under the
+// same product is a no-op, so a publish that is retried after the catalog write
+// succeeds.
+func (s *ProductDraftStore) MarkPublished(ctx context.Context, in MarkPublishedInput) error {
+	if err := s.validator.Struct(in); err != nil {
+		return fmt.Errorf("validate publish input: %w", err)
+	}
+	actor := strings.TrimSpace(in.Actor)
+	if actor == "" {
+		actor = "system"
+	}
+
+	var already bool
+	err := s.inTx(func(tx db.Conn) error {
+		var (
+			from      Status
+			decision  sql.NullString
+			productID sql.NullString
+		)
+		err := tx.QueryRow(
+			tx.Rebind("SELECT status, review_decision, published_product_id FROM product_drafts WHERE id = ?"),
+			in.DraftID,
+		).Scan(&from, &decision, &productID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrDraftNotFound, in.DraftID)
+		}
+		if err != nil {
+			return fmt.Errorf("read product_drafts %s status: %w", in.DraftID, err)
+		}
+		if from == StatusPublished && productID.String == in.ProductID {
+			already = true
+			return nil
+		}
+		if err := checkTransition(in.DraftID, from, StatusPublished); err != nil {
+			return err
+		}
+		if decision.String != string(ReviewApproved) {
+			return fmt.Errorf("%w: %s", ErrNotApproved, in.DraftID)
+		}
+
+		q := tx.Rebind(`
+UPDATE product_drafts
+SET
+  status = ?,
+  published_at_ms = ?,
+  published_product_id = ?
+WHERE id = ? AND status = ? AND review_decision = ?
+`)
+		res, err := tx.Exec(q, string(StatusPublished), time.Now().UnixMilli(), in.ProductID, in.DraftID, string(from), string(ReviewApproved))
+		if err != nil {
+			return fmt.Errorf("publish product_drafts %s: %w", in.DraftID, err)
+		}
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			return &TransitionError{DraftID: in.DraftID, From: from, To: StatusPublished, Err: ErrStatusConflict}
+		}
+		return recordDraftEvent(ctx, tx, draftEvent{
+			draftID: in.DraftID,
+			actor:   actor,
+			action:  draftEventUpdated,
+			from:    from,
+			to:      StatusPublished,
+			reason:  "published as product " + in.ProductID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if !already {
+		s.logger.Infow("product_draft_published",
+			"id", in.DraftID,
+			"product_id", in.ProductID,
+			"actor", actor,
+		)
+	}
+	return nil
+}
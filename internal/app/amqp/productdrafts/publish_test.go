@@ -0,0 +1,56 @@
+package productdrafts
+
+import (
+	"context"
+	"testing"
+
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductDraftStore_MarkPublished(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+
+	_, err := store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+	draftID, err := store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{
+		EventID: "evt-1",
+		URL:     url,
+		Product: &product.Product{URL: url, Status: product.StatusOK, Title: "t"},
+	})
+	require.NoError(t, err)
+
+	in := MarkPublishedInput{DraftID: draftID, ProductID: "prod-1", Actor: "alice"}
+	err = store.MarkPublished(ctx, in)
+	require.ErrorIs(t, err, ErrNotApproved, "an unreviewed draft is not published")
+
+	require.NoError(t, store.Review(ctx, ReviewInput{DraftID: draftID, Decision: ReviewApproved, Reviewer: "bob"}))
+	require.NoError(t, store.MarkPublished(ctx, in))
+
+	got, err := store.GetDraft(ctx, draftID)
+	require.NoError(t, err)
+	require.Equal(t, StatusPublished, got.Status)
+	require.Equal(t, "prod-1", got.PublishedProductID)
+	require.False(t, got.PublishedAt.IsZero())
+
+	// Publishing again under the same product changes nothing.
+	require.NoError(t, store.MarkPublished(ctx, in))
+	tl, err := store.DraftTimeline(ctx, draftID)
+	require.NoError(t, err)
+	last := tl[len(tl)-1]
+	require.Equal(t, StatusPublished, last.To)
+	require.Equal(t, "alice", last.Actor)
+	require.Equal(t, "published as product prod-1", last.Reason)
+	require.NotEqual(t, StatusPublished, tl[len(tl)-2].To)
+
+	err = store.MarkPublished(ctx, MarkPublishedInput{DraftID: draftID, ProductID: "prod-2"})
+	require.ErrorIs(t, err, ErrIllegalTransition, "PUBLISHED is final")
+
+	err = store.MarkPublished(ctx, MarkPublishedInput{DraftID: "missing", ProductID: "prod-1"})
+	require.ErrorIs(t, err, ErrDraftNotFound)
+}
@@ -0,0 +1,105 @@
+package productdrafts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/db"
+)
+
+// ReviewDecision is a product_drafts.review_decision value.
+type ReviewDecision string
+
+const (
+	ReviewApproved ReviewDecision = "approved"
+	ReviewRejected ReviewDecision = "rejected"
+)
+
+type ReviewInput struct {
+	DraftID  string         `validate:"required"`
+	Decision ReviewDecision `validate:"required,oneof=approved rejected"`
+	Reviewer string         `validate:"required,max=128"`
+	// Notes are required to reject a draft.
+	Notes string `validate:"max=2000"`
+}
+
+// Review records a reviewer's decision. Only a READY_FOR_REVIEW draft can be
+// approved; it keeps that status until it is published. A rejection moves the
+// draft to REJECTED wherever the lifecycle allows it. The decision is cleared
+// when a later crawl replaces the payload.
+func (s *ProductDraftStore) Review(ctx context.Context, in ReviewInput) error {
+	if err := s.validator.Struct(in); err != nil {
+		return fmt.Errorf("validate review input: %w", err)
+	}
+	notes := strings.TrimSpace(in.Notes)
+	if in.Decision == ReviewRejected && notes == "" {
+		return fmt.Errorf("validate review input: %w: rejecting a draft requires notes", ErrInvalidInput)
+	}
+
+	to := StatusReadyForReview
+	if in.Decision == ReviewRejected {
+		to = StatusRejected
+	}
+	reason := string(in.Decision)
+	if notes != "" {
+		reason += ": " + notes
+	}
+	reviewer := strings.TrimSpace(in.Reviewer)
+
+	err := s.inTx(func(tx db.Conn) error {
+		var from Status
+		err := tx.QueryRow(tx.Rebind("SELECT status FROM product_drafts WHERE id = ?"), in.DraftID).Scan(&from)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrDraftNotFound, in.DraftID)
+		}
+		if err != nil {
+			return fmt.Errorf("read product_drafts %s status: %w", in.DraftID, err)
+		}
+		if in.Decision == ReviewApproved && from != StatusReadyForReview {
+			return &TransitionError{DraftID: in.DraftID, From: from, To: to, Err: ErrIllegalTransition}
+		}
+		if err := checkTransition(in.DraftID, from, to); err != nil {
+			return err
+		}
+
+		q := tx.Rebind(`
+UPDATE product_drafts
+SET
+  status = ?,
+  review_decision = ?,
+  reviewed_by = ?,
+  reviewed_at_ms = ?,
+  review_notes = ?
+WHERE id = ? AND status = ?
+`)
+		res, err := tx.Exec(q, string(to), string(in.Decision), reviewer, time.Now().UnixMilli(), nullString(notes), in.DraftID, string(from))
+		if err != nil {
+			return fmt.Errorf("review product_drafts %s: %w", in.DraftID, err)
+		}
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			return &TransitionError{DraftID: in.DraftID, From: from, To: to, Err: ErrStatusConflict}
+		}
+		return recordDraftEvent(ctx, tx, draftEvent{
+			draftID: in.DraftID,
+			actor:   reviewer,
+			action:  draftEventUpdated,
+			from:    from,
+			to:      to,
+			reason:  reason,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("product_draft_reviewed",
+		"id", in.DraftID,
+		"decision", in.Decision,
+		"reviewer", reviewer,
+	)
+	return nil
+}
@@ -0,0 +1,18 @@
+package productdrafts
+
+import (
+	"testing"
+
+	"peasydeal-product-miner/db"
+	"peasydeal-product-miner/db/dbtest"
+
+	"go.uber.org/zap"
+)
+
+// newMigratedStore returns a store on a fresh, fully migrated local SQLite file.
+func newMigratedStore(t *testing.T) (*ProductDraftStore, db.Conn) {
+	t.Helper()
+
+	conn := dbtest.NewSQLite(t)
+	return NewProductDraftStore(NewProductDraftStoreParams{Conn: conn, Logger: zap.NewNop().Sugar()}), conn
+}
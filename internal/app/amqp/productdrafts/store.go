@@ -9,10 +9,11 @@ import (
 	"strings"
 
 	"peasydeal-product-miner/db"
-	"peasydeal-product-miner/internal/runner"
+	"peasydeal-product-miner/internal/product"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -42,7 +43,8 @@ type UpsertFromCrawlResultInput struct {
 	EventID   string
 	CreatedBy string
 	URL       string `validate:"required"`
-	Result    runner.Result
+	// Product is the crawl result. Nil is persisted as a failed draft.
+	Product *product.Product
 }
 
 type UpsertQueuedForDraftInput struct {
@@ -53,8 +55,6 @@ type UpsertQueuedForDraftInput struct {
 }
 
 func (s *ProductDraftStore) UpsertQueuedForDraft(ctx context.Context, in UpsertQueuedForDraftInput) (draftID string, err error) {
-	_ = ctx
-
 	if err := s.validator.Struct(in); err != nil {
 		return "", fmt.Errorf("validate upsert input: %w", err)
 	}
@@ -67,68 +67,239 @@ func (s *ProductDraftStore) UpsertQueuedForDraft(ctx context.Context, in UpsertQ
 
 	source := strings.TrimSpace(in.Source)
 
-	draftID = uuid.NewString()
+	payloadBytes, err := json.Marshal(queuedPayload(in.URL, source))
+	if err != nil {
+		return "", fmt.Errorf("marshal queued payload: %w", err)
+	}
 
-	payload := runner.Result{"url": in.URL}
-	if source == "shopee" || source == "taobao" {
-		payload["source"] = source
+	draftID, err = s.save(ctx, draftWrite{
+		eventID:   eventID,
+		status:    StatusQueuedForDraft,
+		payload:   string(payloadBytes),
+		createdBy: createdBy,
+	})
+	if err != nil {
+		return "", fmt.Errorf("upsert queued product_drafts: %w", err)
 	}
 
-	payloadBytes, err := json.Marshal(payload)
+	s.logger.Infow("product_draft_queued_for_draft",
+		"id", draftID,
+		"event_id", eventID,
+		"source", source,
+	)
+
+	return draftID, nil
+}
+
+// QueueUnlessActive writes a QUEUED_FOR_DRAFT draft like UpsertQueuedForDraft,
+// unless in.URL already has a queued or in-progress draft; that draft is then
+// returned with existing set and nothing is written. The check and the insert
+// are one statement, so concurrent callers for the same URL queue one draft.
+// A draft already stored for in.EventID is moved as UpsertQueuedForDraft would.
+func (s *ProductDraftStore) QueueUnlessActive(ctx context.Context, in UpsertQueuedForDraftInput) (d *Draft, existing bool, err error) {
+	if err := s.validator.Struct(in); err != nil {
+		return nil, false, fmt.Errorf("validate queue input: %w", err)
+	}
+
+	url := strings.TrimSpace(in.URL)
+	eventID := strings.TrimSpace(in.EventID)
+	createdBy := strings.TrimSpace(in.CreatedBy)
+	if createdBy == "" {
+		createdBy = "enqueue"
+	}
+	source := strings.TrimSpace(in.Source)
+
+	payloadBytes, err := json.Marshal(queuedPayload(url, source))
 	if err != nil {
-		return "", fmt.Errorf("marshal queued payload: %w", err)
+		return nil, false, fmt.Errorf("marshal queued payload: %w", err)
+	}
+	w := draftWrite{
+		eventID:   eventID,
+		status:    StatusQueuedForDraft,
+		payload:   string(payloadBytes),
+		createdBy: createdBy,
 	}
 
-	createdByCol := sql.NullString{String: createdBy, Valid: true}
+	d = &Draft{EventID: eventID, Status: StatusQueuedForDraft}
+	err = s.inTx(func(tx db.Conn) error {
+		if eventID != "" {
+			prev, err := findByEventID(tx, eventID)
+			if err != nil {
+				return err
+			}
+			if prev != nil {
+				d.ID = prev.ID
+				return s.compareAndSet(ctx, tx, prev.ID, prev.Status, w)
+			}
+		}
 
-	q := s.conn.Rebind(`
+		d.ID = uuid.NewString()
+		q := tx.Rebind(`
 INSERT INTO product_drafts (
   id,
   event_id,
   status,
   draft_payload,
   created_by
-) VALUES (
-  ?,
-  ?,
-  ?,
-  ?,
-  ?
 )
-ON CONFLICT(event_id) DO UPDATE SET
-  status = excluded.status,
-  draft_payload = excluded.draft_payload,
-  created_by = excluded.created_by
+SELECT ?, ?, ?, ?, ?
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM product_drafts
+  WHERE url = ? AND status IN ('QUEUED_FOR_DRAFT', 'CRAWLING', 'DRAFTING')
+)
+ON CONFLICT(event_id) DO NOTHING
 `)
+		res, err := tx.Exec(q, d.ID, nullString(eventID), string(w.status), w.payload, nullString(createdBy), url)
+		if err != nil {
+			if errors.Is(err, db.ErrSQLiteDisabled) {
+				s.logger.Infow("turso_sqlite_disabled_skip_persist", "reason", err.Error())
+				return nil
+			}
+			return fmt.Errorf("insert product_drafts: %w", err)
+		}
+		if rows, _ := res.RowsAffected(); rows > 0 {
+			return recordDraftEvent(ctx, tx, w.event(d.ID, "", ""))
+		}
 
-	if _, err := s.conn.Exec(q, draftID, sql.NullString{String: eventID, Valid: eventID != ""}, "QUEUED_FOR_DRAFT", string(payloadBytes), createdByCol); err != nil {
-		if errors.Is(err, db.ErrSQLiteDisabled) {
-			s.logger.Infow("turso_sqlite_disabled_skip_persist", "reason", err.Error())
-			return draftID, nil
+		active, err := findActiveByURL(tx, url)
+		if err != nil {
+			return err
 		}
-		return "", fmt.Errorf("upsert queued product_drafts: %w", err)
+		if active == nil {
+			// Another writer created the event's draft since findByEventID.
+			return &TransitionError{DraftID: "event " + eventID, To: w.status, Err: ErrStatusConflict}
+		}
+		d, existing = active, true
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("queue product_drafts: %w", err)
 	}
 
-	// If we used event_id for dedupe, return the stable draft id stored in the row.
-	if eventID != "" {
-		var stableID string
-		if err := s.conn.QueryRow(s.conn.Rebind("SELECT id FROM product_drafts WHERE event_id = ?"), eventID).Scan(&stableID); err == nil && stableID != "" {
-			draftID = stableID
-		}
+	if existing {
+		s.logger.Infow("product_draft_already_active",
+			"id", d.ID,
+			"status", d.Status,
+			"url", url,
+		)
+	} else {
+		s.logger.Infow("product_draft_queued_for_draft",
+			"id", d.ID,
+			"event_id", eventID,
+			"source", source,
+		)
 	}
+	return d, existing, nil
+}
 
-	s.logger.Infow("product_draft_queued_for_draft",
+type MarkCrawlingInput struct {
+	EventID   string `validate:"required"`
+	CreatedBy string
+	URL       string `validate:"required"`
+	Source    string
+}
+
+// MarkCrawling moves the event's draft to CRAWLING, creating it when the request
+// was published without one. A completed or published draft only moves when the
+// lifecycle allows it; otherwise a *TransitionError is returned.
+func (s *ProductDraftStore) MarkCrawling(ctx context.Context, in MarkCrawlingInput) (draftID string, err error) {
+	if err := s.validator.Struct(in); err != nil {
+		return "", fmt.Errorf("validate mark crawling input: %w", err)
+	}
+
+	createdBy := strings.TrimSpace(in.CreatedBy)
+	if createdBy == "" {
+		createdBy = "rabbitmq"
+	}
+	payloadBytes, err := json.Marshal(queuedPayload(in.URL, strings.TrimSpace(in.Source)))
+	if err != nil {
+		return "", fmt.Errorf("marshal crawling payload: %w", err)
+	}
+
+	eventID := strings.TrimSpace(in.EventID)
+	draftID, err = s.save(ctx, draftWrite{
+		eventID:     eventID,
+		status:      StatusCrawling,
+		payload:     string(payloadBytes),
+		keepPayload: true,
+		createdBy:   createdBy,
+	})
+	if err != nil {
+		return "", fmt.Errorf("mark product_drafts crawling: %w", err)
+	}
+
+	s.logger.Infow("product_draft_crawling",
 		"id", draftID,
 		"event_id", eventID,
-		"source", source,
 	)
-
 	return draftID, nil
 }
 
-func (s *ProductDraftStore) UpsertFromCrawlResult(ctx context.Context, in UpsertFromCrawlResultInput) (draftID string, err error) {
-	_ = ctx
+type TransitionInput struct {
+	DraftID string `validate:"required"`
+	From    Status `validate:"required"`
+	To      Status `validate:"required"`
+	// Error is required when To is FAILED and cleared otherwise.
+	Error string
+	// Actor is recorded in the draft's audit log; it defaults to "system".
+	Actor string
+	// Reason is recorded in the draft's audit log (e.g. reviewer notes); it
+	// defaults to Error.
+	Reason string
+}
+
+// Transition moves a draft From -> To if it is still in From. It returns a
+// *TransitionError wrapping ErrIllegalTransition when the lifecycle forbids the
+// move and ErrStatusConflict when the draft is no longer in From.
+func (s *ProductDraftStore) Transition(ctx context.Context, in TransitionInput) error {
+	if err := s.validator.Struct(in); err != nil {
+		return fmt.Errorf("validate transition input: %w", err)
+	}
+	if in.To == StatusFailed && strings.TrimSpace(in.Error) == "" {
+		return fmt.Errorf("validate transition input: %w: %s requires an error", ErrInvalidInput, StatusFailed)
+	}
+
+	w := draftWrite{
+		status:      in.To,
+		keepPayload: true,
+		errorText:   strings.TrimSpace(in.Error),
+		actor:       strings.TrimSpace(in.Actor),
+		reason:      strings.TrimSpace(in.Reason),
+	}
+	err := s.inTx(func(tx db.Conn) error {
+		return s.compareAndSet(ctx, tx, in.DraftID, in.From, w)
+	})
+	if errors.Is(err, db.ErrSQLiteDisabled) {
+		s.logger.Infow("turso_sqlite_disabled_skip_persist", "reason", err.Error())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
+	s.logger.Infow("product_draft_transitioned",
+		"id", in.DraftID,
+		"from", in.From,
+		"to", in.To,
+	)
+	return nil
+}
+
+// FailUnpublished fails a QUEUED_FOR_DRAFT draft whose crawl request could not
+// be published. No worker will ever see the request, so a retry of the enqueue
+// should queue a fresh draft instead of finding this one.
+func (s *ProductDraftStore) FailUnpublished(ctx context.Context, draftID, actor string, cause error) error {
+	return s.Transition(ctx, TransitionInput{
+		DraftID: draftID,
+		From:    StatusQueuedForDraft,
+		To:      StatusFailed,
+		Error:   fmt.Sprintf("publish crawl request: %v", cause),
+		Actor:   actor,
+	})
+}
+
+func (s *ProductDraftStore) UpsertFromCrawlResult(ctx context.Context, in UpsertFromCrawlResultInput) (draftID string, err error) {
 	if err := s.validator.Struct(in); err != nil {
 		return "", fmt.Errorf("validate upsert input: %w", err)
 	}
@@ -139,72 +310,146 @@ func (s *ProductDraftStore) UpsertFromCrawlResult(ctx context.Context, in Upsert
 		createdBy = "inngest"
 	}
 
-	draftID = uuid.NewString()
-
-	if in.Result == nil {
-		in.Result = runner.Result{
-			"url":    in.URL,
-			"status": "error",
-			"error":  "missing runner result",
+	var p product.Product
+	if in.Product == nil {
+		p = product.Product{
+			URL:    in.URL,
+			Status: product.StatusError,
+			Error:  "missing runner result",
 		}
+	} else {
+		p = *in.Product
 	}
 
-	if rawURL, _ := in.Result["url"].(string); rawURL == "" {
-		in.Result["url"] = in.URL
+	if p.URL == "" {
+		p.URL = in.URL
 	}
 
-	payloadBytes, err := json.Marshal(in.Result)
+	payloadBytes, err := json.Marshal(p)
 	if err != nil {
-		payloadBytes, _ = json.Marshal(runner.Result{
-			"url":    in.URL,
-			"status": "error",
-			"error":  fmt.Sprintf("marshal runner result: %v", err),
+		payloadBytes, _ = json.Marshal(product.Product{
+			URL:    in.URL,
+			Status: product.StatusError,
+			Error:  fmt.Sprintf("marshal runner result: %v", err),
 		})
 	}
 
-	status, errorText := draftStatusAndError(in.Result)
+	status, errorText := draftStatusAndError(p)
 
-	createdByCol := sql.NullString{String: createdBy, Valid: true}
-	errorCol := sql.NullString{}
-	if errorText != "" {
-		errorCol = sql.NullString{String: errorText, Valid: true}
+	draftID, err = s.save(ctx, draftWrite{
+		eventID:   eventID,
+		status:    status,
+		payload:   string(payloadBytes),
+		errorText: errorText,
+		createdBy: createdBy,
+	})
+	if err != nil {
+		return "", fmt.Errorf("upsert product_drafts: %w", err)
 	}
 
-	// Backward-compatible path: if older deployments used event_id as the primary key,
-	// update that row in-place and backfill event_id for future dedupe.
-	if eventID != "" {
-		qLegacy := s.conn.Rebind(`
-UPDATE product_drafts
-SET
-  event_id = ?,
-  status = ?,
-  draft_payload = ?,
-  error = ?,
-  created_by = ?
-WHERE id = ?
-`)
+	s.logger.Infow("product_draft_upserted_from_crawl",
+		"id", draftID,
+		"event_id", eventID,
+		"status", status,
+	)
 
-		res, err := s.conn.Exec(qLegacy, eventID, status, string(payloadBytes), errorCol, createdByCol, eventID)
-		if err != nil {
-			if errors.Is(err, db.ErrSQLiteDisabled) {
-				s.logger.Infow("turso_sqlite_disabled_skip_persist", "reason", err.Error())
-				return draftID, nil
-			}
-			return "", fmt.Errorf("update legacy product_drafts by id: %w", err)
-		}
+	return draftID, nil
+}
 
-		if rows, _ := res.RowsAffected(); rows > 0 {
-			s.logger.Infow(
-				"product_draft_upserted_from_crawl",
-				"id", eventID,
-				"event_id", eventID,
-				"status", status,
-			)
-			return eventID, nil
-		}
+// draftWrite is the new state of a draft row.
+type draftWrite struct {
+	eventID string
+	status  Status
+	payload string
+	// keepPayload leaves an existing row's payload alone; payload is then only
+	// used when a row has to be inserted.
+	keepPayload bool
+	errorText   string
+	createdBy   string
+	// actor and reason go to the audit log; they default to createdBy (or
+	// "system") and errorText.
+	actor  string
+	reason string
+}
+
+func (w draftWrite) event(draftID string, from Status, payloadBefore string) draftEvent {
+	actor := w.actor
+	if actor == "" {
+		actor = w.createdBy
+	}
+	if actor == "" {
+		actor = "system"
 	}
+	reason := w.reason
+	if reason == "" {
+		reason = w.errorText
+	}
+	e := draftEvent{
+		draftID: draftID,
+		eventID: w.eventID,
+		actor:   actor,
+		action:  draftEventUpdated,
+		from:    from,
+		to:      w.status,
+		reason:  reason,
+	}
+	switch {
+	case from == "":
+		e.action = draftEventCreated
+		e.payloadAfter = w.payload
+	case !w.keepPayload && payloadBefore != w.payload:
+		e.payloadBefore = payloadBefore
+		e.payloadAfter = w.payload
+	}
+	return e
+}
+
+// txBeginner is implemented by *sqlx.DB; the disabled SQLite conn is not one.
+type txBeginner interface {
+	Beginx() (*sqlx.Tx, error)
+}
 
-	q := s.conn.Rebind(`
+// inTx runs fn in one transaction so a draft change and its audit event land
+// together. Without transaction support fn runs on the plain conn.
+func (s *ProductDraftStore) inTx(fn func(tx db.Conn) error) error {
+	b, ok := s.conn.(txBeginner)
+	if !ok {
+		return fn(s.conn)
+	}
+	tx, err := b.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin product_drafts tx: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit product_drafts tx: %w", err)
+	}
+	return nil
+}
+
+// save writes w to the draft of w.eventID. An existing draft (including a legacy
+// row keyed by id = event_id, whose event_id is backfilled) is moved with
+// compareAndSet from the status just read; otherwise a new row is inserted. The
+// write and its audit event share a transaction. With SQLite disabled nothing is
+// written and a fresh id is returned.
+func (s *ProductDraftStore) save(ctx context.Context, w draftWrite) (draftID string, err error) {
+	err = s.inTx(func(tx db.Conn) error {
+		if w.eventID != "" {
+			existing, err := findByEventID(tx, w.eventID)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				draftID = existing.ID
+				return s.compareAndSet(ctx, tx, existing.ID, existing.Status, w)
+			}
+		}
+
+		draftID = uuid.NewString()
+		q := tx.Rebind(`
 INSERT INTO product_drafts (
   id,
   event_id,
@@ -220,63 +465,206 @@ INSERT INTO product_drafts (
   ?,
   ?
 )
-ON CONFLICT(event_id) DO UPDATE SET
-  status = excluded.status,
-  draft_payload = excluded.draft_payload,
-  error = excluded.error,
-  created_by = excluded.created_by
+ON CONFLICT(event_id) DO NOTHING
 `)
+		res, err := tx.Exec(q, draftID, nullString(w.eventID), string(w.status), w.payload, nullString(w.errorText), nullString(w.createdBy))
+		if err != nil {
+			if errors.Is(err, db.ErrSQLiteDisabled) {
+				s.logger.Infow("turso_sqlite_disabled_skip_persist", "reason", err.Error())
+				return nil
+			}
+			return fmt.Errorf("insert product_drafts: %w", err)
+		}
+		if rows, _ := res.RowsAffected(); rows == 0 {
+			// Another writer created the event's draft since FindByEventID.
+			return &TransitionError{DraftID: "event " + w.eventID, To: w.status, Err: ErrStatusConflict}
+		}
+		return recordDraftEvent(ctx, tx, w.event(draftID, "", ""))
+	})
+	if err != nil {
+		return "", err
+	}
+	return draftID, nil
+}
 
-	if _, err := s.conn.Exec(q, draftID, sql.NullString{String: eventID, Valid: eventID != ""}, status, string(payloadBytes), errorCol, createdByCol); err != nil {
-		if errors.Is(err, db.ErrSQLiteDisabled) {
-			s.logger.Infow("turso_sqlite_disabled_skip_persist", "reason", err.Error())
-			return draftID, nil
+// compareAndSet applies w to draft id only if its status is still from and the
+// lifecycle allows from -> w.status.
+func (s *ProductDraftStore) compareAndSet(ctx context.Context, tx db.Conn, id string, from Status, w draftWrite) error {
+	if err := checkTransition(id, from, w.status); err != nil {
+		return err
+	}
+
+	var payloadBefore string
+	if !w.keepPayload {
+		err := tx.QueryRow(tx.Rebind("SELECT draft_payload FROM product_drafts WHERE id = ?"), id).Scan(&payloadBefore)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+		}
+		if err != nil {
+			if errors.Is(err, db.ErrSQLiteDisabled) {
+				return err
+			}
+			return fmt.Errorf("read product_drafts %s payload: %w", id, err)
 		}
-		return "", fmt.Errorf("upsert product_drafts: %w", err)
 	}
 
-	// If we used event_id for dedupe, return the stable draft id stored in the row.
-	if eventID != "" {
-		var stableID string
-		if err := s.conn.QueryRow(s.conn.Rebind("SELECT id FROM product_drafts WHERE event_id = ?"), eventID).Scan(&stableID); err == nil && stableID != "" {
-			draftID = stableID
+	payload := sql.NullString{String: w.payload, Valid: !w.keepPayload}
+	q := tx.Rebind(`
+UPDATE product_drafts
+SET
+  event_id = COALESCE(event_id, ?),
+  status = ?,
+  draft_payload = COALESCE(?, draft_payload),
+  error = ?,
+  created_by = COALESCE(?, created_by),
+  -- A review applies to the payload it saw; a new payload needs a new one.
+  review_decision = CASE WHEN ? IS NULL THEN review_decision END,
+  reviewed_by = CASE WHEN ? IS NULL THEN reviewed_by END,
+  reviewed_at_ms = CASE WHEN ? IS NULL THEN reviewed_at_ms END,
+  review_notes = CASE WHEN ? IS NULL THEN review_notes END
+WHERE id = ? AND status = ?
+`)
+	res, err := tx.Exec(q, nullString(w.eventID), string(w.status), payload, nullString(w.errorText), nullString(w.createdBy), payload, payload, payload, payload, id, string(from))
+	if err != nil {
+		if errors.Is(err, db.ErrSQLiteDisabled) {
+			return err
 		}
+		return fmt.Errorf("update product_drafts %s: %w", id, err)
+	}
+	if rows, _ := res.RowsAffected(); rows > 0 {
+		return recordDraftEvent(ctx, tx, w.event(id, from, payloadBefore))
 	}
 
-	s.logger.Infow("product_draft_upserted_from_crawl",
-		"id", draftID,
-		"event_id", eventID,
-		"status", status,
+	var actual Status
+	err = tx.QueryRow(tx.Rebind("SELECT status FROM product_drafts WHERE id = ?"), id).Scan(&actual)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrDraftNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("read product_drafts %s status: %w", id, err)
+	}
+	return &TransitionError{DraftID: id, From: from, To: w.status, Actual: actual, Err: ErrStatusConflict}
+}
+
+// queuedPayload is the payload of a draft that has not been crawled yet.
+func queuedPayload(url, source string) product.Product {
+	payload := product.Product{URL: url}
+	if source == "shopee" || source == "taobao" {
+		payload.Source = source
+	}
+	return payload
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Draft is the stored state of a product draft.
+type Draft struct {
+	ID      string
+	EventID string
+	Status  Status
+}
+
+// Completed reports whether the draft already holds a successful crawl that a
+// redelivered request should not redo.
+func (d Draft) Completed() bool {
+	return d.Status.Completed()
+}
+
+// FindByEventID returns the draft written for eventID, or nil when there is none or
+// SQLite is disabled. Legacy rows keyed by id = event_id are found too.
+func (s *ProductDraftStore) FindByEventID(ctx context.Context, eventID string) (*Draft, error) {
+	_ = ctx
+	return findByEventID(s.conn, eventID)
+}
+
+func findByEventID(conn db.Conn, eventID string) (*Draft, error) {
+	eventID = strings.TrimSpace(eventID)
+	if eventID == "" {
+		return nil, nil
+	}
+
+	var (
+		d     Draft
+		evtID sql.NullString
 	)
+	q := conn.Rebind(`
+SELECT id, event_id, status
+FROM product_drafts
+WHERE event_id = ? OR id = ?
+ORDER BY CASE WHEN event_id = ? THEN 0 ELSE 1 END
+LIMIT 1
+`)
+	err := conn.QueryRow(q, eventID, eventID, eventID).Scan(&d.ID, &evtID, &d.Status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, db.ErrSQLiteDisabled):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("find product_drafts by event_id: %w", err)
+	}
+	d.EventID = evtID.String
+	return &d, nil
+}
 
-	return draftID, nil
+// FindQueuedByURL returns the most recent draft for url that is still waiting
+// for or going through a crawl, or nil when there is none or SQLite is disabled.
+func (s *ProductDraftStore) FindQueuedByURL(ctx context.Context, url string) (*Draft, error) {
+	_ = ctx
+	return findActiveByURL(s.conn, url)
 }
 
-func draftStatusAndError(result runner.Result) (status string, errorText string) {
-	raw, _ := result["status"].(string)
+func findActiveByURL(conn db.Conn, url string) (*Draft, error) {
+	url = strings.TrimSpace(url)
+	if url == "" {
+		return nil, nil
+	}
+
+	var (
+		d     Draft
+		evtID sql.NullString
+	)
+	q := conn.Rebind(`
+SELECT id, event_id, status
+FROM product_drafts
+WHERE url = ? AND status IN ('QUEUED_FOR_DRAFT', 'CRAWLING', 'DRAFTING')
+ORDER BY updated_at_ms DESC
+LIMIT 1
+`)
+	err := conn.QueryRow(q, url).Scan(&d.ID, &evtID, &d.Status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, db.ErrSQLiteDisabled):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("find queued product_drafts by url: %w", err)
+	}
+	d.EventID = evtID.String
+	return &d, nil
+}
 
-	switch raw {
-	case "ok":
-		return "READY_FOR_REVIEW", ""
-	case "needs_manual":
-		return "FAILED", errorFromNeedsManual(result)
-	case "error":
-		return "FAILED", errorFromResult(result)
+func draftStatusAndError(p product.Product) (status Status, errorText string) {
+	switch p.Status {
+	case product.StatusOK:
+		return StatusReadyForReview, ""
+	case product.StatusNeedsManual:
+		return StatusFailed, errorFromNeedsManual(p)
+	case product.StatusError:
+		return StatusFailed, errorFromResult(p)
 	default:
-		return "FAILED", errorFromResult(result)
+		return StatusFailed, errorFromResult(p)
 	}
 }
 
-func errorFromNeedsManual(result runner.Result) string {
-	if s, ok := result["notes"].(string); ok && s != "" {
-		return s
+func errorFromNeedsManual(p product.Product) string {
+	if p.Notes != "" {
+		return p.Notes
 	}
 	return "crawler returned status=needs_manual"
 }
 
-func errorFromResult(result runner.Result) string {
-	if s, ok := result["error"].(string); ok && s != "" {
-		return s
+func errorFromResult(p product.Product) string {
+	if p.Error != "" {
+		return p.Error
 	}
 	return "crawler returned status=error"
 }
@@ -3,7 +3,6 @@ package productdrafts
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"log"
 	"testing"
@@ -12,7 +11,7 @@ import (
 	"peasydeal-product-miner/db"
 	dbfx "peasydeal-product-miner/db/fx"
 	appfx "peasydeal-product-miner/internal/app/fx"
-	"peasydeal-product-miner/internal/runner"
+	"peasydeal-product-miner/internal/product"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -94,11 +93,10 @@ func TestProductDraftStore_UpsertFromCrawlResult_E2E_TursoSQLite(t *testing.T) {
   "url": "https://shopee.tw/Pink-Rose%E2%99%A5(%E7%8F%BE%E8%B2%A8)%E8%A4%B2%E8%A5%AA-%E6%80%A7%E6%84%9F%E7%B5%B2%E8%A5%AA-%E8%BC%95%E8%96%84-%E9%80%8F%E8%86%9A%E7%B5%B2%E8%A5%AA-%E5%85%8D%E8%84%AB%E8%A4%B2%E8%A5%AA-%E9%96%8B%E6%AA%94%E7%B5%B2%E8%A5%AA-0151-%E5%8D%81%E8%89%B2%E4%BB%BB%E9%81%B8-%E6%83%85%E8%B6%A3%E7%B6%B2%E8%A5%AA-%E8%A7%92%E8%89%B2%E6%89%AE%E6%BC%94-i.1622185.2279887046?extraParams=%7B%22display_model_id%22%3A4404014428%2C%22model_selection_logic%22%3A3%7D\u0026sp_atk=99eb87ef-bdd1-4a1d-bdf1-d55e2a7f850c\u0026xptdk=99eb87ef-bdd1-4a1d-bdf1-d55e2a7f850c"
 }`
 
-	var m map[string]any
-	require.NoError(t, json.Unmarshal([]byte(raw), &m))
-	res := runner.Result(m)
+	p, err := product.Parse([]byte(raw))
+	require.NoError(t, err)
 
-	url, _ := m["url"].(string)
+	url := p.URL
 	require.NotEmpty(t, url)
 
 	eventID := uuid.NewString()
@@ -106,7 +104,7 @@ func TestProductDraftStore_UpsertFromCrawlResult_E2E_TursoSQLite(t *testing.T) {
 		EventID:   eventID,
 		CreatedBy: "test",
 		URL:       url,
-		Result:    res,
+		Product:   &p,
 	})
 	require.NoError(t, err)
 	require.NotEmpty(t, draftID)
@@ -143,3 +141,115 @@ func TestProductDraftStore_UpsertFromCrawlResult_E2E_TursoSQLite(t *testing.T) {
 	// require.Equal(t, "2026-01-21T04:24:31.695Z", got["captured_at"])
 	// require.Equal(t, "Pink Rose♥(現貨)褲襪 性感絲襪 輕薄 透膚絲襪 免脫褲襪 開檔絲襪 0151-十色任選 情趣網襪 角色扮演 | 蝦皮購物", got["title"])
 }
+
+func TestProductDraftStore_FindByEventID(t *testing.T) {
+	t.Parallel()
+
+	store, conn := newMigratedStore(t)
+	ctx := context.Background()
+
+	got, err := store.FindByEventID(ctx, "evt-missing")
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	ok := product.Product{URL: "https://shopee.tw/i.1.2", Status: product.StatusOK, Title: "t"}
+	draftID, err := store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", URL: ok.URL, Product: &ok})
+	require.NoError(t, err)
+
+	got, err = store.FindByEventID(ctx, "evt-1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, Draft{ID: draftID, EventID: "evt-1", Status: "READY_FOR_REVIEW"}, *got)
+	require.True(t, got.Completed())
+
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-2", URL: ok.URL})
+	require.NoError(t, err)
+	got, err = store.FindByEventID(ctx, "evt-2")
+	require.NoError(t, err)
+	require.Equal(t, StatusFailed, got.Status)
+	require.False(t, got.Completed())
+
+	// Legacy rows used the event id as primary key and have no event_id.
+	_, err = conn.Exec(conn.Rebind(`INSERT INTO product_drafts (id, status, draft_payload) VALUES (?, 'PUBLISHED', ?)`),
+		"evt-legacy", `{"url":"https://shopee.tw/i.1.3"}`)
+	require.NoError(t, err)
+	got, err = store.FindByEventID(ctx, "evt-legacy")
+	require.NoError(t, err)
+	require.Equal(t, Draft{ID: "evt-legacy", Status: "PUBLISHED"}, *got)
+	require.True(t, got.Completed())
+}
+
+func TestProductDraftStore_FindQueuedByURL(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+
+	got, err := store.FindQueuedByURL(ctx, url)
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	draftID, err := store.UpsertQueuedForDraft(ctx, UpsertQueuedForDraftInput{EventID: "evt-1", URL: url, Source: "shopee"})
+	require.NoError(t, err)
+	got, err = store.FindQueuedByURL(ctx, url)
+	require.NoError(t, err)
+	require.Equal(t, Draft{ID: draftID, EventID: "evt-1", Status: "QUEUED_FOR_DRAFT"}, *got)
+
+	// A crawl in progress still counts; once crawled, the URL is no longer queued.
+	_, err = store.MarkCrawling(ctx, MarkCrawlingInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+	got, err = store.FindQueuedByURL(ctx, url)
+	require.NoError(t, err)
+	require.Equal(t, StatusCrawling, got.Status)
+
+	ok := product.Product{URL: url, Status: product.StatusOK, Title: "t"}
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", URL: url, Product: &ok})
+	require.NoError(t, err)
+	got, err = store.FindQueuedByURL(ctx, url)
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
+
+func TestProductDraftStore_QueueUnlessActive(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newMigratedStore(t)
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1.2"
+
+	d, existing, err := store.QueueUnlessActive(ctx, UpsertQueuedForDraftInput{EventID: "evt-1", URL: url, Source: "shopee"})
+	require.NoError(t, err)
+	require.False(t, existing)
+	require.Equal(t, StatusQueuedForDraft, d.Status)
+	tl, err := store.DraftTimeline(ctx, d.ID)
+	require.NoError(t, err)
+	require.Len(t, tl, 1)
+	require.Equal(t, StatusQueuedForDraft, tl[0].To)
+
+	// The URL is queued, so a second request gets that draft back.
+	again, existing, err := store.QueueUnlessActive(ctx, UpsertQueuedForDraftInput{EventID: "evt-2", URL: url, Source: "shopee"})
+	require.NoError(t, err)
+	require.True(t, existing)
+	require.Equal(t, Draft{ID: d.ID, EventID: "evt-1", Status: StatusQueuedForDraft}, *again)
+	got, err := store.FindByEventID(ctx, "evt-2")
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	// A finished draft does not block a new one.
+	_, err = store.UpsertFromCrawlResult(ctx, UpsertFromCrawlResultInput{EventID: "evt-1", URL: url})
+	require.NoError(t, err)
+	next, existing, err := store.QueueUnlessActive(ctx, UpsertQueuedForDraftInput{EventID: "evt-2", URL: url, Source: "shopee"})
+	require.NoError(t, err)
+	require.False(t, existing)
+	require.NotEqual(t, d.ID, next.ID)
+	got, err = store.FindQueuedByURL(ctx, url)
+	require.NoError(t, err)
+	require.Equal(t, Draft{ID: next.ID, EventID: "evt-2", Status: StatusQueuedForDraft}, *got)
+
+	// A reused event id re-queues its own draft.
+	re, existing, err := store.QueueUnlessActive(ctx, UpsertQueuedForDraftInput{EventID: "evt-1", URL: url, Source: "shopee"})
+	require.NoError(t, err)
+	require.False(t, existing)
+	require.Equal(t, d.ID, re.ID)
+}
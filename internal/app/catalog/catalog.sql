@@ -0,0 +1,50 @@
+-- The part of the storefront catalog (Supabase/Postgres) that catalog.Publisher
+-- writes. This file lists the columns and keys the publisher relies on, and sets
+-- up the Postgres integration test.
+--
+-- UNVERIFIED: the storefront's schema is not in this repo, and these tables
+-- were not checked against it. In particular products.draft_id,
+-- source_item_id, shop_name and the uuid ids derived from the draft id are
+-- assumptions. Compare them with the live schema and adjust writeRows before
+-- publishing to a real catalog.
+
+CREATE TABLE IF NOT EXISTS products (
+  id uuid PRIMARY KEY,
+  -- product_drafts.id in Turso; the id is derived from it.
+  draft_id text NOT NULL UNIQUE,
+  title text NOT NULL,
+  description text NOT NULL DEFAULT '',
+  currency text NULL,
+  price numeric(12, 2) NULL,
+  source text NULL,
+  source_url text NOT NULL,
+  source_item_id text NULL,
+  shop_name text NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS product_variants (
+  id uuid PRIMARY KEY,
+  product_id uuid NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  title text NOT NULL,
+  position integer NOT NULL,
+  currency text NULL,
+  price numeric(12, 2) NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants (product_id);
+
+CREATE TABLE IF NOT EXISTS product_images (
+  id uuid PRIMARY KEY,
+  product_id uuid NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+  -- NULL for product images; set for images of one variant.
+  variant_id uuid NULL REFERENCES product_variants (id) ON DELETE CASCADE,
+  url text NOT NULL,
+  position integer NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images (product_id);
@@ -0,0 +1,12 @@
+package fx
+
+import (
+	"peasydeal-product-miner/internal/app/catalog"
+
+	"go.uber.org/fx"
+)
+
+var Module = fx.Module(
+	"catalog",
+	fx.Provide(catalog.NewPublisher),
+)
@@ -0,0 +1,269 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"peasydeal-product-miner/db"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Publisher turns approved product drafts into catalog products in Postgres.
+type Publisher struct {
+	db        *sqlx.DB
+	drafts    *productdrafts.ProductDraftStore
+	logger    *zap.SugaredLogger
+	validator *validator.Validate
+}
+
+type NewPublisherParams struct {
+	fx.In
+
+	// DB is nil when Postgres is disabled; only dry runs work then.
+	DB     *sqlx.DB `optional:"true"`
+	Drafts *productdrafts.ProductDraftStore
+	Logger *zap.SugaredLogger
+}
+
+func NewPublisher(p NewPublisherParams) *Publisher {
+	return &Publisher{
+		db:        p.DB,
+		drafts:    p.Drafts,
+		logger:    p.Logger,
+		validator: validator.New(),
+	}
+}
+
+type PublishInput struct {
+	DraftID string `validate:"required"`
+	Actor   string `validate:"max=128"`
+	// DryRun renders the rows without writing them. It skips the approval check,
+	// so reviewers can preview a draft before approving it.
+	DryRun bool
+}
+
+type PublishResult struct {
+	DraftID   string
+	ProductID string
+	Status    productdrafts.Status
+	DryRun    bool
+	// AlreadyPublished is set when the draft was PUBLISHED before this call and
+	// nothing was written.
+	AlreadyPublished bool
+	// Rows are the rendered rows; nil for an already published draft.
+	Rows *Rows
+}
+
+// Publish writes the catalog rows of an approved READY_FOR_REVIEW draft in one
+// Postgres transaction, then marks the draft PUBLISHED with its product id.
+//
+// Catalog ids are derived from the draft id and rows are upserted, so
+// publishing again converges on the same product. This covers a retry after
+// the catalog commit succeeded but marking the draft failed.
+//
+// The draft and the catalog live in different databases, so the approval is
+// checked again when the draft is marked. If the draft lost its approval or
+// moved on in between, e.g. because a re-crawl cleared its review, the rows
+// just written are deleted again.
+func (p *Publisher) Publish(ctx context.Context, in PublishInput) (*PublishResult, error) {
+	if err := p.validator.Struct(in); err != nil {
+		return nil, fmt.Errorf("validate publish input: %w", err)
+	}
+	actor := strings.TrimSpace(in.Actor)
+	if actor == "" {
+		actor = "publisher"
+	}
+
+	d, err := p.drafts.GetDraft(ctx, in.DraftID)
+	if err != nil {
+		return nil, err
+	}
+	res := &PublishResult{DraftID: d.ID, Status: d.Status, DryRun: in.DryRun}
+
+	if d.Status == productdrafts.StatusPublished && !in.DryRun {
+		res.ProductID = d.PublishedProductID
+		res.AlreadyPublished = true
+		return res, nil
+	}
+	if !in.DryRun {
+		if d.Status != productdrafts.StatusReadyForReview {
+			return nil, &productdrafts.TransitionError{DraftID: d.ID, From: d.Status, To: productdrafts.StatusPublished, Err: productdrafts.ErrIllegalTransition}
+		}
+		if !d.Approved() {
+			return nil, fmt.Errorf("%w: %s", productdrafts.ErrNotApproved, d.ID)
+		}
+	}
+
+	rows, err := RenderRows(*d)
+	if err != nil {
+		return nil, fmt.Errorf("render draft %s: %w", d.ID, err)
+	}
+	res.ProductID = rows.Product.ID
+	res.Rows = rows
+	if in.DryRun {
+		return res, nil
+	}
+
+	if p.db == nil {
+		return nil, fmt.Errorf("publish draft %s: %w", d.ID, db.ErrDBDisabled)
+	}
+	if _, err := db.Tx(p.db, func(tx *sqlx.Tx) (any, error) {
+		return nil, writeRows(ctx, tx, rows)
+	}); err != nil {
+		return nil, fmt.Errorf("write catalog product for draft %s: %w", d.ID, err)
+	}
+
+	if err := p.drafts.MarkPublished(ctx, productdrafts.MarkPublishedInput{
+		DraftID:   d.ID,
+		ProductID: rows.Product.ID,
+		Actor:     actor,
+	}); err != nil {
+		if errors.Is(err, productdrafts.ErrNotApproved) ||
+			errors.Is(err, productdrafts.ErrIllegalTransition) ||
+			errors.Is(err, productdrafts.ErrStatusConflict) {
+			if derr := p.unpublish(context.WithoutCancel(ctx), rows.Product.ID); derr != nil {
+				p.logger.Errorw("catalog_unpublish_failed",
+					"draft_id", d.ID,
+					"product_id", rows.Product.ID,
+					"err", derr,
+				)
+				return nil, errors.Join(err, derr)
+			}
+			p.logger.Warnw("catalog_product_unpublished",
+				"draft_id", d.ID,
+				"product_id", rows.Product.ID,
+				"reason", err.Error(),
+			)
+		}
+		return nil, err
+	}
+	res.Status = productdrafts.StatusPublished
+
+	p.logger.Infow("catalog_product_published",
+		"draft_id", d.ID,
+		"product_id", rows.Product.ID,
+		"variants", len(rows.Variants),
+		"images", len(rows.Images),
+		"actor", actor,
+	)
+	return res, nil
+}
+
+// writeRows upserts rows by id and deletes the product's variants and images
+// that the current payload no longer has.
+func writeRows(ctx context.Context, tx *sqlx.Tx, rows *Rows) error {
+	pr := rows.Product
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO products (
+  id,
+  draft_id,
+  title,
+  description,
+  currency,
+  price,
+  source,
+  source_url,
+  source_item_id,
+  shop_name
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (id) DO UPDATE SET
+  title = EXCLUDED.title,
+  description = EXCLUDED.description,
+  currency = EXCLUDED.currency,
+  price = EXCLUDED.price,
+  source = EXCLUDED.source,
+  source_url = EXCLUDED.source_url,
+  source_item_id = EXCLUDED.source_item_id,
+  shop_name = EXCLUDED.shop_name,
+  updated_at = now()
+`, pr.ID, pr.DraftID, pr.Title, pr.Description, nullString(pr.Currency), pr.Price, nullString(pr.Source), pr.SourceURL, nullString(pr.SourceItemID), nullString(pr.ShopName))
+	if err != nil {
+		return fmt.Errorf("upsert products %s: %w", pr.ID, err)
+	}
+
+	imageIDs := make([]string, 0, len(rows.Images))
+	for _, img := range rows.Images {
+		imageIDs = append(imageIDs, img.ID)
+	}
+	variantIDs := make([]string, 0, len(rows.Variants))
+	for _, v := range rows.Variants {
+		variantIDs = append(variantIDs, v.ID)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_images WHERE product_id = $1 AND NOT (id::text = ANY($2))`, pr.ID, imageIDs); err != nil {
+		return fmt.Errorf("delete stale product_images of %s: %w", pr.ID, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_variants WHERE product_id = $1 AND NOT (id::text = ANY($2))`, pr.ID, variantIDs); err != nil {
+		return fmt.Errorf("delete stale product_variants of %s: %w", pr.ID, err)
+	}
+
+	for _, v := range rows.Variants {
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO product_variants (
+  id,
+  product_id,
+  title,
+  position,
+  currency,
+  price
+) VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (id) DO UPDATE SET
+  title = EXCLUDED.title,
+  position = EXCLUDED.position,
+  currency = EXCLUDED.currency,
+  price = EXCLUDED.price,
+  updated_at = now()
+`, v.ID, v.ProductID, v.Title, v.Position, nullString(v.Currency), v.Price)
+		if err != nil {
+			return fmt.Errorf("upsert product_variants %s: %w", v.ID, err)
+		}
+	}
+
+	for _, img := range rows.Images {
+		_, err := tx.ExecContext(ctx, `
+INSERT INTO product_images (
+  id,
+  product_id,
+  variant_id,
+  url,
+  position
+) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (id) DO UPDATE SET
+  variant_id = EXCLUDED.variant_id,
+  url = EXCLUDED.url,
+  position = EXCLUDED.position
+`, img.ID, img.ProductID, img.VariantID, img.URL, img.Position)
+		if err != nil {
+			return fmt.Errorf("upsert product_images %s: %w", img.ID, err)
+		}
+	}
+	return nil
+}
+
+// unpublish deletes the catalog rows of productID.
+func (p *Publisher) unpublish(ctx context.Context, productID string) error {
+	_, err := db.Tx(p.db, func(tx *sqlx.Tx) (any, error) {
+		for _, q := range []string{
+			`DELETE FROM product_images WHERE product_id = $1`,
+			`DELETE FROM product_variants WHERE product_id = $1`,
+			`DELETE FROM products WHERE id = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, q, productID); err != nil {
+				return nil, fmt.Errorf("delete catalog product %s: %w", productID, err)
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,193 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/db"
+	"peasydeal-product-miner/db/dbtest"
+	dbfx "peasydeal-product-miner/db/fx"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	appfx "peasydeal-product-miner/internal/app/fx"
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+func newTestPublisher(t *testing.T, pg *sqlx.DB) (*Publisher, *productdrafts.ProductDraftStore) {
+	t.Helper()
+
+	logger := zap.NewNop().Sugar()
+	store := productdrafts.NewProductDraftStore(productdrafts.NewProductDraftStoreParams{Conn: dbtest.NewSQLite(t), Logger: logger})
+	return NewPublisher(NewPublisherParams{DB: pg, Drafts: store, Logger: logger}), store
+}
+
+// readyDraft stores a crawled draft waiting for review.
+func readyDraft(t *testing.T, store *productdrafts.ProductDraftStore, eventID string) string {
+	t.Helper()
+
+	ctx := context.Background()
+	url := "https://shopee.tw/i.1." + eventID
+	_, err := store.MarkCrawling(ctx, productdrafts.MarkCrawlingInput{EventID: eventID, URL: url})
+	require.NoError(t, err)
+	id, err := store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
+		EventID: eventID,
+		URL:     url,
+		Product: &product.Product{
+			URL:        url,
+			Status:     product.StatusOK,
+			Title:      "Cat Tree",
+			Currency:   "TWD",
+			Price:      product.NewMoney(1299),
+			Images:     []product.Image{{URL: "https://img/1.jpg"}},
+			Variations: []product.Variation{{Title: "Grey", Images: []string{"https://img/grey.jpg"}}, {Title: "Beige"}},
+		},
+	})
+	require.NoError(t, err)
+	return id
+}
+
+func TestPublisher_DryRun(t *testing.T) {
+	t.Parallel()
+
+	pub, store := newTestPublisher(t, nil)
+	ctx := context.Background()
+	id := readyDraft(t, store, "evt-1")
+
+	// A dry run needs neither an approval nor Postgres.
+	res, err := pub.Publish(ctx, PublishInput{DraftID: id, DryRun: true})
+	require.NoError(t, err)
+	require.True(t, res.DryRun)
+	require.Equal(t, productdrafts.StatusReadyForReview, res.Status)
+	require.Equal(t, res.Rows.Product.ID, res.ProductID)
+	require.Len(t, res.Rows.Variants, 2)
+	require.Len(t, res.Rows.Images, 2)
+
+	got, err := store.GetDraft(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, productdrafts.StatusReadyForReview, got.Status)
+	require.Empty(t, got.PublishedProductID)
+}
+
+func TestPublisher_RequiresApprovalAndPostgres(t *testing.T) {
+	t.Parallel()
+
+	pub, store := newTestPublisher(t, nil)
+	ctx := context.Background()
+	id := readyDraft(t, store, "evt-1")
+
+	_, err := pub.Publish(ctx, PublishInput{DraftID: id})
+	require.ErrorIs(t, err, productdrafts.ErrNotApproved)
+
+	require.NoError(t, store.Review(ctx, productdrafts.ReviewInput{DraftID: id, Decision: productdrafts.ReviewApproved, Reviewer: "alice"}))
+	_, err = pub.Publish(ctx, PublishInput{DraftID: id})
+	require.ErrorIs(t, err, db.ErrDBDisabled)
+
+	_, err = pub.Publish(ctx, PublishInput{DraftID: "missing"})
+	require.ErrorIs(t, err, productdrafts.ErrDraftNotFound)
+
+	require.NoError(t, store.Review(ctx, productdrafts.ReviewInput{DraftID: id, Decision: productdrafts.ReviewRejected, Reviewer: "bob", Notes: "counterfeit"}))
+	_, err = pub.Publish(ctx, PublishInput{DraftID: id})
+	require.ErrorIs(t, err, productdrafts.ErrIllegalTransition)
+}
+
+// TestPublisher_Postgres publishes into the Postgres of the DB_* env vars, for
+// example a local container, inside a scratch schema it drops afterwards.
+func TestPublisher_Postgres(t *testing.T) {
+	var pg *sqlx.DB
+	app := fx.New(
+		appfx.CoreAppOptions,
+		dbfx.Module,
+		fx.Invoke(func(p struct {
+			fx.In
+
+			DB *sqlx.DB
+		}) {
+			pg = p.DB
+		}),
+	)
+	startCtx, cancelStart := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancelStart)
+	require.NoError(t, app.Start(startCtx))
+	t.Cleanup(func() {
+		stopCtx, cancelStop := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelStop()
+		_ = app.Stop(stopCtx)
+	})
+	if pg == nil {
+		t.Skip("postgres is disabled; set DB_HOST/DB_PORT/DB_USER/DB_PASSWORD/DB_NAME")
+	}
+
+	// One connection keeps the search_path for the whole test.
+	pg.SetMaxOpenConns(1)
+	schema := fmt.Sprintf("catalog_test_%d", time.Now().UnixNano())
+	_, err := pg.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = pg.Exec("DROP SCHEMA " + schema + " CASCADE") })
+	_, err = pg.Exec("SET search_path TO " + schema)
+	require.NoError(t, err)
+	ddl, err := os.ReadFile("catalog.sql")
+	require.NoError(t, err)
+	_, err = pg.Exec(string(ddl))
+	require.NoError(t, err)
+
+	pub, store := newTestPublisher(t, pg)
+	ctx := context.Background()
+	id := readyDraft(t, store, "evt-1")
+	require.NoError(t, store.Review(ctx, productdrafts.ReviewInput{DraftID: id, Decision: productdrafts.ReviewApproved, Reviewer: "alice"}))
+
+	count := func(table string) int {
+		var n int
+		require.NoError(t, pg.Get(&n, "SELECT count(*) FROM "+table))
+		return n
+	}
+
+	// Write the catalog rows as a publish would, but leave the draft unmarked,
+	// like a publish that failed after the commit.
+	rows, err := RenderRows(*mustGetDraft(t, store, id))
+	require.NoError(t, err)
+	_, err = db.Tx(pg, func(tx *sqlx.Tx) (any, error) { return nil, writeRows(ctx, tx, rows) })
+	require.NoError(t, err)
+
+	res, err := pub.Publish(ctx, PublishInput{DraftID: id, Actor: "alice"})
+	require.NoError(t, err)
+	require.Equal(t, productdrafts.StatusPublished, res.Status)
+	require.Equal(t, rows.Product.ID, res.ProductID)
+	require.Equal(t, 1, count("products"))
+	require.Equal(t, 2, count("product_variants"))
+	require.Equal(t, 2, count("product_images"))
+
+	var title, price string
+	require.NoError(t, pg.QueryRow("SELECT title, price::text FROM products WHERE id = $1", res.ProductID).Scan(&title, &price))
+	require.Equal(t, "Cat Tree", title)
+	require.Equal(t, "1299.00", price)
+
+	got := mustGetDraft(t, store, id)
+	require.Equal(t, productdrafts.StatusPublished, got.Status)
+	require.Equal(t, res.ProductID, got.PublishedProductID)
+
+	again, err := pub.Publish(ctx, PublishInput{DraftID: id})
+	require.NoError(t, err)
+	require.True(t, again.AlreadyPublished)
+	require.Equal(t, res.ProductID, again.ProductID)
+	require.Equal(t, 1, count("products"))
+
+	// What Publish does when the draft lost its approval after the catalog write.
+	require.NoError(t, pub.unpublish(ctx, res.ProductID))
+	require.Zero(t, count("products"))
+	require.Zero(t, count("product_variants"))
+	require.Zero(t, count("product_images"))
+}
+
+func mustGetDraft(t *testing.T, store *productdrafts.ProductDraftStore, id string) *productdrafts.DraftRecord {
+	t.Helper()
+	d, err := store.GetDraft(context.Background(), id)
+	require.NoError(t, err)
+	return d
+}
@@ -0,0 +1,186 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPayload means a draft's payload lacks what a catalog product needs.
+var ErrInvalidPayload = errors.New("product draft payload cannot be published")
+
+// idNamespace seeds the catalog ids derived from draft ids. Changing it would
+// make republished drafts create new products instead of updating their own.
+var idNamespace = uuid.MustParse("6f0c1a52-8d3e-4b8f-9a51-2f7c6b0d4e19")
+
+// ProductRow is a products row.
+type ProductRow struct {
+	ID           string  `json:"id"`
+	DraftID      string  `json:"draft_id"`
+	Title        string  `json:"title"`
+	Description  string  `json:"description"`
+	Currency     string  `json:"currency,omitempty"`
+	Price        *string `json:"price"`
+	Source       string  `json:"source,omitempty"`
+	SourceURL    string  `json:"source_url"`
+	SourceItemID string  `json:"source_item_id,omitempty"`
+	ShopName     string  `json:"shop_name,omitempty"`
+}
+
+// VariantRow is a product_variants row.
+type VariantRow struct {
+	ID        string  `json:"id"`
+	ProductID string  `json:"product_id"`
+	Title     string  `json:"title"`
+	Position  int     `json:"position"`
+	Currency  string  `json:"currency,omitempty"`
+	Price     *string `json:"price"`
+}
+
+// ImageRow is a product_images row. VariantID is set for variant images.
+type ImageRow struct {
+	ID        string  `json:"id"`
+	ProductID string  `json:"product_id"`
+	VariantID *string `json:"variant_id"`
+	URL       string  `json:"url"`
+	Position  int     `json:"position"`
+}
+
+// Rows are the catalog rows of one draft.
+type Rows struct {
+	Product  ProductRow   `json:"product"`
+	Variants []VariantRow `json:"variants"`
+	Images   []ImageRow   `json:"images"`
+}
+
+// RenderRows converts the draft's payload into catalog rows. A payload without
+// variations gets a single "Default" variant, so every product is purchasable.
+//
+// The product id is derived from the draft id, and variant and image ids from
+// the product id and their position, so rendering a draft again yields the
+// same ids.
+func RenderRows(d productdrafts.DraftRecord) (*Rows, error) {
+	p, err := product.Parse(d.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	title := strings.TrimSpace(p.Title)
+	if title == "" {
+		return nil, fmt.Errorf("%w: title is empty", ErrInvalidPayload)
+	}
+	sourceURL := strings.TrimSpace(d.URL)
+	if sourceURL == "" {
+		sourceURL = strings.TrimSpace(p.URL)
+	}
+	if sourceURL == "" {
+		return nil, fmt.Errorf("%w: source url is empty", ErrInvalidPayload)
+	}
+
+	currency := strings.TrimSpace(p.Currency)
+	price := money(p.Price)
+	if p.Pricing != nil {
+		if currency == "" {
+			currency = strings.TrimSpace(p.Pricing.Currency)
+		}
+		if price == nil {
+			price = money(p.Pricing.SalePriceMin)
+		}
+	}
+
+	productID := uuid.NewSHA1(idNamespace, []byte(d.ID))
+	rows := &Rows{
+		Product: ProductRow{
+			ID:          productID.String(),
+			DraftID:     d.ID,
+			Title:       title,
+			Description: strings.TrimSpace(p.Description),
+			Currency:    currency,
+			Price:       price,
+			Source:      d.Source,
+			SourceURL:   sourceURL,
+		},
+		Variants: []VariantRow{},
+		Images:   []ImageRow{},
+	}
+	if rows.Product.Source == "" {
+		rows.Product.Source = strings.TrimSpace(p.Source)
+	}
+	if p.SourceIDs != nil {
+		rows.Product.SourceItemID = strings.TrimSpace(p.SourceIDs.ItemID)
+	}
+	if p.Shop != nil {
+		rows.Product.ShopName = strings.TrimSpace(p.Shop.Name)
+	}
+
+	for i, url := range dedupe(p.ImageURLs()) {
+		rows.Images = append(rows.Images, ImageRow{
+			ID:        uuid.NewSHA1(productID, []byte("image/"+strconv.Itoa(i+1))).String(),
+			ProductID: productID.String(),
+			URL:       url,
+			Position:  i + 1,
+		})
+	}
+
+	variations := p.Variations
+	if len(variations) == 0 {
+		variations = []product.Variation{{Title: "Default"}}
+	}
+	for i, v := range variations {
+		variantID := uuid.NewSHA1(productID, []byte("variant/"+strconv.Itoa(i+1)))
+		vtitle := strings.TrimSpace(v.Title)
+		if vtitle == "" {
+			vtitle = "Option " + strconv.Itoa(i+1)
+		}
+		rows.Variants = append(rows.Variants, VariantRow{
+			ID:        variantID.String(),
+			ProductID: productID.String(),
+			Title:     vtitle,
+			Position:  i + 1,
+			Currency:  currency,
+			Price:     price,
+		})
+
+		vid := variantID.String()
+		for j, url := range v.ImageURLs() {
+			rows.Images = append(rows.Images, ImageRow{
+				ID:        uuid.NewSHA1(variantID, []byte("image/"+strconv.Itoa(j+1))).String(),
+				ProductID: productID.String(),
+				VariantID: &vid,
+				URL:       url,
+				Position:  j + 1,
+			})
+		}
+	}
+	return rows, nil
+}
+
+// money returns m as a decimal literal, or nil when it is missing or not a
+// number.
+func money(m product.Money) *string {
+	v, err := m.Float64()
+	if err != nil {
+		return nil
+	}
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	return &s
+}
+
+func dedupe(urls []string) []string {
+	out := make([]string, 0, len(urls))
+	seen := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		u = strings.TrimSpace(u)
+		if u == "" || seen[u] {
+			continue
+		}
+		seen[u] = true
+		out = append(out, u)
+	}
+	return out
+}
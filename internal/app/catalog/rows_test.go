@@ -0,0 +1,100 @@
+package catalog
+
+import (
+	"encoding/json"
+	"testing"
+
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderRows(t *testing.T) {
+	t.Parallel()
+
+	d := productdrafts.DraftRecord{
+		ID:     "draft-1",
+		URL:    "https://shopee.tw/i.1.2",
+		Source: "shopee",
+		Payload: json.RawMessage(`{
+  "title": " Cat Tree ",
+  "description": "Tall",
+  "currency": "TWD",
+  "price": "1299",
+  "images": ["https://img/1.jpg", {"url": "https://img/2.jpg"}, "https://img/1.jpg"],
+  "variations": [
+    {"title": "Grey", "images": ["https://img/grey.jpg"]},
+    {"title": "", "image": "https://img/beige.jpg"}
+  ],
+  "source_ids": {"platform": "shopee", "shop_id": "1", "item_id": "2"},
+  "shop": {"name": "Pet Shop"}
+}`),
+	}
+
+	rows, err := RenderRows(d)
+	require.NoError(t, err)
+
+	pr := rows.Product
+	require.Equal(t, "draft-1", pr.DraftID)
+	require.Equal(t, "Cat Tree", pr.Title)
+	require.Equal(t, "TWD", pr.Currency)
+	require.Equal(t, "1299", *pr.Price)
+	require.Equal(t, "2", pr.SourceItemID)
+	require.Equal(t, "Pet Shop", pr.ShopName)
+
+	require.Len(t, rows.Variants, 2)
+	require.Equal(t, "Grey", rows.Variants[0].Title)
+	require.Equal(t, "Option 2", rows.Variants[1].Title)
+	require.Equal(t, "1299", *rows.Variants[1].Price)
+
+	var urls []string
+	ids := map[string]bool{}
+	for _, img := range rows.Images {
+		require.Equal(t, pr.ID, img.ProductID)
+		urls = append(urls, img.URL)
+		ids[img.ID] = true
+	}
+	require.Equal(t, []string{"https://img/1.jpg", "https://img/2.jpg", "https://img/grey.jpg", "https://img/beige.jpg"}, urls)
+	require.Len(t, ids, 4, "image ids are unique")
+	require.Nil(t, rows.Images[0].VariantID)
+	require.Equal(t, rows.Variants[1].ID, *rows.Images[3].VariantID)
+
+	// Ids depend only on the draft id and positions.
+	again, err := RenderRows(d)
+	require.NoError(t, err)
+	require.Equal(t, rows, again)
+
+	d.ID = "draft-2"
+	other, err := RenderRows(d)
+	require.NoError(t, err)
+	require.NotEqual(t, pr.ID, other.Product.ID)
+}
+
+func TestRenderRows_DefaultVariant(t *testing.T) {
+	t.Parallel()
+
+	rows, err := RenderRows(productdrafts.DraftRecord{
+		ID:      "draft-1",
+		Payload: json.RawMessage(`{"url": "https://item.taobao.com/item.htm?id=4", "title": "t", "pricing": {"currency": "CNY", "sale_price_min": 12.5}}`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "https://item.taobao.com/item.htm?id=4", rows.Product.SourceURL)
+	require.Equal(t, "CNY", rows.Product.Currency)
+	require.Equal(t, "12.5", *rows.Product.Price)
+	require.Len(t, rows.Variants, 1)
+	require.Equal(t, "Default", rows.Variants[0].Title)
+	require.Empty(t, rows.Images)
+}
+
+func TestRenderRows_InvalidPayload(t *testing.T) {
+	t.Parallel()
+
+	for _, payload := range []string{
+		`not json`,
+		`{"url": "https://shopee.tw/i.1.2"}`,
+		`{"title": "no url"}`,
+	} {
+		_, err := RenderRows(productdrafts.DraftRecord{ID: "draft-1", Payload: json.RawMessage(payload)})
+		require.ErrorIs(t, err, ErrInvalidPayload, payload)
+	}
+}
@@ -0,0 +1,384 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/app/amqp/crawlworker"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	"peasydeal-product-miner/internal/app/catalog"
+	"peasydeal-product-miner/internal/source"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// draftsPath is the base path the Refine admin calls (see
+// docs/peasydeal-ai-product-drafts-api-proposal-turso.md).
+const draftsPath = "/admin/ai/product-drafts"
+
+// requestPublisher is the part of crawlworker.EventPublisher the API needs.
+type requestPublisher interface {
+	PublishRequest(ctx context.Context, env crawlworker.CrawlRequestedEnvelope) error
+}
+
+// draftPublisher is the part of catalog.Publisher the API needs.
+type draftPublisher interface {
+	Publish(ctx context.Context, in catalog.PublishInput) (*catalog.PublishResult, error)
+}
+
+// DraftsHandler serves the product draft endpoints.
+type DraftsHandler struct {
+	store     *productdrafts.ProductDraftStore
+	requests  requestPublisher
+	publisher draftPublisher
+	overrides crawlworker.OverrideAllowlist
+	logger    *zap.SugaredLogger
+	validator *validator.Validate
+}
+
+type NewDraftsHandlerParams struct {
+	fx.In
+
+	Config    *config.Config
+	Store     *productdrafts.ProductDraftStore
+	Events    *crawlworker.EventPublisher `optional:"true"`
+	Publisher *catalog.Publisher          `optional:"true"`
+	Logger    *zap.SugaredLogger
+}
+
+func NewDraftsHandler(p NewDraftsHandlerParams) *DraftsHandler {
+	h := &DraftsHandler{
+		store:     p.Store,
+		overrides: crawlworker.NewOverrideAllowlist(p.Config),
+		logger:    p.Logger,
+		validator: validator.New(),
+	}
+	// Events is nil when RabbitMQ is not configured; keep the interface nil too.
+	if p.Events != nil {
+		h.requests = p.Events
+	}
+	if p.Publisher != nil {
+		h.publisher = p.Publisher
+	}
+	return h
+}
+
+// Register adds the draft routes to mux.
+func (h *DraftsHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST "+draftsPath, h.create)
+	mux.HandleFunc("GET "+draftsPath, h.list)
+	mux.HandleFunc("GET "+draftsPath+"/{id}", h.get)
+	mux.HandleFunc("POST "+draftsPath+"/{id}/approve", h.review(productdrafts.ReviewApproved))
+	mux.HandleFunc("POST "+draftsPath+"/{id}/reject", h.review(productdrafts.ReviewRejected))
+	mux.HandleFunc("POST "+draftsPath+"/{id}/publish", h.publish)
+}
+
+type createDraftRequest struct {
+	SourceURL   string                 `json:"source_url" validate:"required,url"`
+	Hints       crawlworker.CrawlHints `json:"hints"`
+	Tool        string                 `json:"tool"`
+	Model       string                 `json:"model"`
+	SkillName   string                 `json:"skill_name"`
+	Priority    int                    `json:"priority" validate:"min=0,max=9"`
+	RequestedBy string                 `json:"requested_by" validate:"max=128"`
+	// Force re-crawls a URL that already has a queued or in-progress draft.
+	Force bool `json:"force"`
+}
+
+type createDraftResponse struct {
+	DraftID string               `json:"draft_id"`
+	EventID string               `json:"event_id"`
+	Status  productdrafts.Status `json:"status"`
+	// Existing is set when the URL already had a queued or in-progress draft,
+	// which is returned instead of queueing another crawl.
+	Existing bool `json:"existing,omitempty"`
+}
+
+// create queues a draft for the URL and publishes its crawl request, the same
+// way devtool enqueue does.
+func (h *DraftsHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req createDraftRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	req.SourceURL = strings.TrimSpace(req.SourceURL)
+	if err := h.validator.Struct(req); err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	src, err := source.Detect(req.SourceURL)
+	if err != nil {
+		writeError(w, r, h.logger, badRequest("%v", err))
+		return
+	}
+	if h.requests == nil {
+		writeError(w, r, h.logger, unavailable("RabbitMQ is not configured; drafts cannot be queued"))
+		return
+	}
+
+	requestedBy := strings.TrimSpace(req.RequestedBy)
+	if requestedBy == "" {
+		requestedBy = "api"
+	}
+	env := crawlworker.NewCrawlRequest(crawlworker.CrawlRequestedEventData{
+		URL:         req.SourceURL,
+		Tool:        strings.TrimSpace(req.Tool),
+		Model:       strings.TrimSpace(req.Model),
+		SkillName:   strings.TrimSpace(req.SkillName),
+		Priority:    req.Priority,
+		Hints:       req.Hints,
+		RequestedBy: requestedBy,
+		Force:       req.Force,
+	})
+	if err := h.validator.Struct(env.Data); err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	// A request the worker would dead-letter must not leave a queued draft.
+	if err := h.overrides.Check(env.Data); err != nil {
+		writeError(w, r, h.logger, badRequest("%v", err))
+		return
+	}
+
+	ctx := r.Context()
+	in := productdrafts.UpsertQueuedForDraftInput{
+		EventID:   env.EventID,
+		CreatedBy: requestedBy,
+		URL:       req.SourceURL,
+		Source:    string(src),
+	}
+	var draftID string
+	if req.Force {
+		id, err := h.store.UpsertQueuedForDraft(ctx, in)
+		if err != nil {
+			writeError(w, r, h.logger, err)
+			return
+		}
+		draftID = id
+	} else {
+		// One store call checks for an active draft and queues, so two
+		// concurrent requests for a URL cannot both queue a crawl.
+		d, existing, err := h.store.QueueUnlessActive(ctx, in)
+		if err != nil {
+			writeError(w, r, h.logger, err)
+			return
+		}
+		if existing {
+			writeJSON(w, http.StatusOK, createDraftResponse{DraftID: d.ID, EventID: d.EventID, Status: d.Status, Existing: true})
+			return
+		}
+		draftID = d.ID
+	}
+
+	if err := h.requests.PublishRequest(ctx, env); err != nil {
+		h.logger.Errorw("httpapi_publish_request_failed",
+			"event_id", env.EventID,
+			"draft_id", draftID,
+			"err", err,
+		)
+		if terr := h.store.FailUnpublished(context.WithoutCancel(ctx), draftID, "api", err); terr != nil {
+			h.logger.Errorw("httpapi_fail_unpublished_draft_failed", "draft_id", draftID, "err", terr)
+		}
+		writeError(w, r, h.logger, unavailable("publish crawl request: %v", err))
+		return
+	}
+
+	h.logger.Infow("httpapi_draft_queued",
+		"draft_id", draftID,
+		"event_id", env.EventID,
+		"url", req.SourceURL,
+		"requested_by", requestedBy,
+	)
+	writeJSON(w, http.StatusAccepted, createDraftResponse{DraftID: draftID, EventID: env.EventID, Status: productdrafts.StatusQueuedForDraft})
+}
+
+func (h *DraftsHandler) get(w http.ResponseWriter, r *http.Request) {
+	d, err := h.store.GetDraft(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newDraftResponse(*d, true))
+}
+
+type listDraftsResponse struct {
+	Items      []draftResponse `json:"items"`
+	NextCursor *string         `json:"next_cursor"`
+}
+
+func (h *DraftsHandler) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	in := productdrafts.ListDraftsInput{
+		Status:    productdrafts.Status(strings.ToUpper(strings.TrimSpace(q.Get("status")))),
+		Source:    q.Get("source"),
+		CreatedBy: q.Get("created_by"),
+		Cursor:    q.Get("cursor"),
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, r, h.logger, badRequest("limit must be a positive integer"))
+			return
+		}
+		in.Limit = n
+	}
+	if in.Status != "" && !in.Status.Valid() {
+		writeError(w, r, h.logger, badRequest("unknown status %q", in.Status))
+		return
+	}
+
+	page, err := h.store.ListDrafts(r.Context(), in)
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	resp := listDraftsResponse{Items: make([]draftResponse, 0, len(page.Items))}
+	for _, d := range page.Items {
+		resp.Items = append(resp.Items, newDraftResponse(d, false))
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type reviewRequest struct {
+	Reviewer string `json:"reviewer"`
+	Notes    string `json:"notes"`
+}
+
+func (h *DraftsHandler) review(decision productdrafts.ReviewDecision) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req reviewRequest
+		if err := decodeJSON(w, r, &req); err != nil {
+			writeError(w, r, h.logger, err)
+			return
+		}
+		id := r.PathValue("id")
+		err := h.store.Review(r.Context(), productdrafts.ReviewInput{
+			DraftID:  id,
+			Decision: decision,
+			Reviewer: strings.TrimSpace(req.Reviewer),
+			Notes:    req.Notes,
+		})
+		if err != nil {
+			writeError(w, r, h.logger, err)
+			return
+		}
+		d, err := h.store.GetDraft(r.Context(), id)
+		if err != nil {
+			writeError(w, r, h.logger, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newDraftResponse(*d, true))
+	}
+}
+
+type publishRequest struct {
+	Actor  string `json:"actor"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type publishResponse struct {
+	DraftID          string               `json:"draft_id"`
+	ProductID        string               `json:"product_id"`
+	Status           productdrafts.Status `json:"status"`
+	DryRun           bool                 `json:"dry_run"`
+	AlreadyPublished bool                 `json:"already_published"`
+	Rows             *catalog.Rows        `json:"rows,omitempty"`
+}
+
+// publish writes an approved draft to the catalog, or with dry_run returns the
+// rows it would write.
+func (h *DraftsHandler) publish(w http.ResponseWriter, r *http.Request) {
+	var req publishRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	if h.publisher == nil {
+		writeError(w, r, h.logger, unavailable("the catalog publisher is not configured"))
+		return
+	}
+	res, err := h.publisher.Publish(r.Context(), catalog.PublishInput{
+		DraftID: r.PathValue("id"),
+		Actor:   strings.TrimSpace(req.Actor),
+		DryRun:  req.DryRun,
+	})
+	if err != nil {
+		writeError(w, r, h.logger, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, publishResponse{
+		DraftID:          res.DraftID,
+		ProductID:        res.ProductID,
+		Status:           res.Status,
+		DryRun:           res.DryRun,
+		AlreadyPublished: res.AlreadyPublished,
+		Rows:             res.Rows,
+	})
+}
+
+type draftResponse struct {
+	DraftID            string               `json:"draft_id"`
+	EventID            string               `json:"event_id,omitempty"`
+	Status             productdrafts.Status `json:"status"`
+	SourceURL          string               `json:"source_url"`
+	Source             string               `json:"source,omitempty"`
+	DraftPayload       json.RawMessage      `json:"draft_payload,omitempty"`
+	ErrorMessage       *string              `json:"error_message"`
+	CreatedBy          string               `json:"created_by,omitempty"`
+	CreatedAt          time.Time            `json:"created_at"`
+	UpdatedAt          time.Time            `json:"updated_at"`
+	PublishedAt        *time.Time           `json:"published_at,omitempty"`
+	PublishedProductID string               `json:"published_product_id,omitempty"`
+	Review             *reviewResponse      `json:"review,omitempty"`
+}
+
+type reviewResponse struct {
+	Decision   productdrafts.ReviewDecision `json:"decision"`
+	Reviewer   string                       `json:"reviewer"`
+	Notes      string                       `json:"notes,omitempty"`
+	ReviewedAt time.Time                    `json:"reviewed_at"`
+}
+
+// newDraftResponse renders d; list items leave the payload out.
+func newDraftResponse(d productdrafts.DraftRecord, withPayload bool) draftResponse {
+	resp := draftResponse{
+		DraftID:            d.ID,
+		EventID:            d.EventID,
+		Status:             d.Status,
+		SourceURL:          d.URL,
+		Source:             d.Source,
+		CreatedBy:          d.CreatedBy,
+		CreatedAt:          d.CreatedAt,
+		UpdatedAt:          d.UpdatedAt,
+		PublishedProductID: d.PublishedProductID,
+	}
+	if withPayload {
+		resp.DraftPayload = d.Payload
+	}
+	if d.Error != "" {
+		resp.ErrorMessage = &d.Error
+	}
+	if !d.PublishedAt.IsZero() {
+		resp.PublishedAt = &d.PublishedAt
+	}
+	if d.Review != nil {
+		resp.Review = &reviewResponse{
+			Decision:   d.Review.Decision,
+			Reviewer:   d.Review.Reviewer,
+			Notes:      d.Review.Notes,
+			ReviewedAt: d.Review.ReviewedAt,
+		}
+	}
+	return resp
+}
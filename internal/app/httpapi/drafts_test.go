@@ -0,0 +1,323 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/db/dbtest"
+	"peasydeal-product-miner/internal/app/amqp/crawlworker"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	"peasydeal-product-miner/internal/app/catalog"
+	"peasydeal-product-miner/internal/product"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakePublisher struct {
+	mu   sync.Mutex
+	err  error
+	sent []crawlworker.CrawlRequestedEnvelope
+}
+
+func (p *fakePublisher) PublishRequest(_ context.Context, env crawlworker.CrawlRequestedEnvelope) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.sent = append(p.sent, env)
+	return nil
+}
+
+// newTestAPI serves the API over a fresh, migrated SQLite file. A nil pub
+// behaves like RabbitMQ being disabled; Postgres is always disabled.
+func newTestAPI(t *testing.T, pub *fakePublisher) (http.Handler, *productdrafts.ProductDraftStore) {
+	t.Helper()
+
+	logger := zap.NewNop().Sugar()
+	store := productdrafts.NewProductDraftStore(productdrafts.NewProductDraftStoreParams{Conn: dbtest.NewSQLite(t), Logger: logger})
+	cfg := &config.Config{CrawlOverrideTools: "codex"}
+	h := &DraftsHandler{
+		store:     store,
+		overrides: crawlworker.NewOverrideAllowlist(cfg),
+		publisher: catalog.NewPublisher(catalog.NewPublisherParams{Drafts: store, Logger: logger}),
+		logger:    logger,
+		validator: validator.New(),
+	}
+	if pub != nil {
+		h.requests = pub
+	}
+	return NewRouter(h, nil, logger), store
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string) (int, map[string]any) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out), rec.Body.String())
+	return rec.Code, out
+}
+
+func errorCode(t *testing.T, body map[string]any) string {
+	t.Helper()
+	e, ok := body["error"].(map[string]any)
+	require.True(t, ok, "error body: %v", body)
+	require.NotEmpty(t, e["message"])
+	return e["code"].(string)
+}
+
+// readyDraft stores a crawled draft waiting for review.
+func readyDraft(t *testing.T, store *productdrafts.ProductDraftStore, eventID, url string) string {
+	t.Helper()
+
+	ctx := context.Background()
+	_, err := store.MarkCrawling(ctx, productdrafts.MarkCrawlingInput{EventID: eventID, URL: url})
+	require.NoError(t, err)
+	id, err := store.UpsertFromCrawlResult(ctx, productdrafts.UpsertFromCrawlResultInput{
+		EventID: eventID,
+		URL:     url,
+		Product: &product.Product{URL: url, Status: product.StatusOK, Title: "t", Currency: "TWD"},
+	})
+	require.NoError(t, err)
+	return id
+}
+
+func TestDraftsAPI_Create(t *testing.T) {
+	t.Parallel()
+
+	pub := &fakePublisher{}
+	h, store := newTestAPI(t, pub)
+	url := "https://shopee.tw/i.1.2"
+
+	code, body := do(t, h, http.MethodPost, draftsPath, `{"source_url": "`+url+`", "hints": {"category": "pets"}, "requested_by": "admin-1"}`)
+	require.Equal(t, http.StatusAccepted, code)
+	require.Equal(t, "QUEUED_FOR_DRAFT", body["status"])
+
+	require.Len(t, pub.sent, 1)
+	env := pub.sent[0]
+	require.Equal(t, body["event_id"], env.EventID)
+	require.Equal(t, url, env.Data.URL)
+	require.Equal(t, "pets", env.Data.Hints.Category)
+	require.Equal(t, "admin-1", env.Data.RequestedBy)
+
+	got, err := store.GetDraft(context.Background(), body["draft_id"].(string))
+	require.NoError(t, err)
+	require.Equal(t, productdrafts.StatusQueuedForDraft, got.Status)
+	require.Equal(t, "admin-1", got.CreatedBy)
+
+	// The URL is already queued, so its draft is returned instead of a new crawl.
+	code, again := do(t, h, http.MethodPost, draftsPath, `{"source_url": "`+url+`"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, body["draft_id"], again["draft_id"])
+	require.Equal(t, true, again["existing"])
+	require.Len(t, pub.sent, 1)
+
+	for _, bad := range []string{
+		`{"source_url": "https://example.com/p/1"}`,
+		`{"source_url": "not a url"}`,
+		`{"source_url": "` + url + `", "priority": 12}`,
+		`{"source_url": "` + url + `", "unknown": true}`,
+		`{"source_url": "` + url + `"} {}`,
+	} {
+		code, body := do(t, h, http.MethodPost, draftsPath, bad)
+		require.Equal(t, http.StatusBadRequest, code, bad)
+		require.Equal(t, "invalid_request", errorCode(t, body), bad)
+	}
+
+	// Overrides the worker would reject are refused before a draft is queued.
+	other := "https://shopee.tw/i.1.3"
+	code, body = do(t, h, http.MethodPost, draftsPath, `{"source_url": "`+other+`", "tool": "claude"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "invalid_request", errorCode(t, body))
+	queued, err := store.FindQueuedByURL(context.Background(), other)
+	require.NoError(t, err)
+	require.Nil(t, queued)
+	require.Len(t, pub.sent, 1)
+}
+
+func TestDraftsAPI_CreateWhenPublishingFails(t *testing.T) {
+	t.Parallel()
+
+	pub := &fakePublisher{err: errors.New("channel closed")}
+	h, store := newTestAPI(t, pub)
+	url := "https://shopee.tw/i.1.2"
+
+	code, body := do(t, h, http.MethodPost, draftsPath, `{"source_url": "`+url+`"}`)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "unavailable", errorCode(t, body))
+
+	// The unpublished draft is failed, so a retry queues a new one.
+	queued, err := store.FindQueuedByURL(context.Background(), url)
+	require.NoError(t, err)
+	require.Nil(t, queued)
+
+	pub.err = nil
+	code, body = do(t, h, http.MethodPost, draftsPath, `{"source_url": "`+url+`"}`)
+	require.Equal(t, http.StatusAccepted, code)
+	require.Nil(t, body["existing"])
+
+	h, _ = newTestAPI(t, nil)
+	code, body = do(t, h, http.MethodPost, draftsPath, `{"source_url": "`+url+`"}`)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "unavailable", errorCode(t, body))
+}
+
+func TestDraftsAPI_Get(t *testing.T) {
+	t.Parallel()
+
+	h, store := newTestAPI(t, &fakePublisher{})
+	id := readyDraft(t, store, "evt-1", "https://shopee.tw/i.1.2")
+
+	code, body := do(t, h, http.MethodGet, draftsPath+"/"+id, "")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, id, body["draft_id"])
+	require.Equal(t, "evt-1", body["event_id"])
+	require.Equal(t, "READY_FOR_REVIEW", body["status"])
+	require.Equal(t, "https://shopee.tw/i.1.2", body["source_url"])
+	require.Nil(t, body["error_message"])
+	require.Equal(t, "t", body["draft_payload"].(map[string]any)["title"])
+
+	code, body = do(t, h, http.MethodGet, draftsPath+"/missing", "")
+	require.Equal(t, http.StatusNotFound, code)
+	require.Equal(t, "not_found", errorCode(t, body))
+
+	code, body = do(t, h, http.MethodDelete, draftsPath+"/"+id, "")
+	require.Equal(t, http.StatusMethodNotAllowed, code)
+	require.Equal(t, "method_not_allowed", errorCode(t, body))
+
+	code, body = do(t, h, http.MethodGet, "/nope", "")
+	require.Equal(t, http.StatusNotFound, code)
+	require.Equal(t, "not_found", errorCode(t, body))
+}
+
+func TestDraftsAPI_ListFiltersAndPaginates(t *testing.T) {
+	t.Parallel()
+
+	h, store := newTestAPI(t, &fakePublisher{})
+	ctx := context.Background()
+
+	ready := map[string]bool{}
+	for _, evt := range []string{"evt-1", "evt-2", "evt-3"} {
+		ready[readyDraft(t, store, evt, "https://shopee.tw/i.1."+evt)] = true
+	}
+	_, err := store.UpsertQueuedForDraft(ctx, productdrafts.UpsertQueuedForDraftInput{EventID: "evt-4", CreatedBy: "admin-1", URL: "https://item.taobao.com/item.htm?id=4", Source: "taobao"})
+	require.NoError(t, err)
+
+	seen := map[string]bool{}
+	path := draftsPath + "?status=ready_for_review&limit=2"
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 3)
+		code, body := do(t, h, http.MethodGet, path, "")
+		require.Equal(t, http.StatusOK, code)
+		for _, it := range body["items"].([]any) {
+			item := it.(map[string]any)
+			require.Equal(t, "READY_FOR_REVIEW", item["status"])
+			require.Nil(t, item["draft_payload"], "list items leave the payload out")
+			require.False(t, seen[item["draft_id"].(string)], "no draft is repeated")
+			seen[item["draft_id"].(string)] = true
+		}
+		next, ok := body["next_cursor"].(string)
+		if !ok {
+			break
+		}
+		path = draftsPath + "?status=READY_FOR_REVIEW&limit=2&cursor=" + next
+	}
+	require.Equal(t, ready, seen)
+
+	code, body := do(t, h, http.MethodGet, draftsPath+"?source=taobao&created_by=admin-1", "")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, body["items"], 1)
+	require.Nil(t, body["next_cursor"])
+
+	for _, bad := range []string{"?status=BOGUS", "?limit=0", "?cursor=%21%21"} {
+		code, body := do(t, h, http.MethodGet, draftsPath+bad, "")
+		require.Equal(t, http.StatusBadRequest, code, bad)
+		require.Equal(t, "invalid_request", errorCode(t, body), bad)
+	}
+}
+
+func TestDraftsAPI_Review(t *testing.T) {
+	t.Parallel()
+
+	h, store := newTestAPI(t, &fakePublisher{})
+	ctx := context.Background()
+	id := readyDraft(t, store, "evt-1", "https://shopee.tw/i.1.2")
+
+	code, body := do(t, h, http.MethodPost, draftsPath+"/"+id+"/approve", `{"reviewer": "alice", "notes": "looks good"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "READY_FOR_REVIEW", body["status"])
+	review := body["review"].(map[string]any)
+	require.Equal(t, "approved", review["decision"])
+	require.Equal(t, "alice", review["reviewer"])
+
+	got, err := store.GetDraft(ctx, id)
+	require.NoError(t, err)
+	require.True(t, got.Approved())
+
+	code, body = do(t, h, http.MethodPost, draftsPath+"/"+id+"/reject", `{"reviewer": "bob"}`)
+	require.Equal(t, http.StatusBadRequest, code, "rejecting needs notes")
+	require.Equal(t, "invalid_request", errorCode(t, body))
+
+	code, body = do(t, h, http.MethodPost, draftsPath+"/"+id+"/reject", `{"reviewer": "bob", "notes": "counterfeit"}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "REJECTED", body["status"])
+	require.Equal(t, "rejected", body["review"].(map[string]any)["decision"])
+
+	code, body = do(t, h, http.MethodPost, draftsPath+"/"+id+"/approve", `{"reviewer": "alice"}`)
+	require.Equal(t, http.StatusConflict, code, "only READY_FOR_REVIEW drafts can be approved")
+	require.Equal(t, "illegal_transition", errorCode(t, body))
+
+	code, body = do(t, h, http.MethodPost, draftsPath+"/missing/approve", `{"reviewer": "alice"}`)
+	require.Equal(t, http.StatusNotFound, code)
+	require.Equal(t, "not_found", errorCode(t, body))
+
+	code, body = do(t, h, http.MethodPost, draftsPath+"/"+id+"/approve", `{}`)
+	require.Equal(t, http.StatusBadRequest, code, "reviewer is required")
+	require.Equal(t, "invalid_request", errorCode(t, body))
+
+	tl, err := store.DraftTimeline(ctx, id)
+	require.NoError(t, err)
+	last := tl[len(tl)-1]
+	require.Equal(t, "bob", last.Actor)
+	require.Equal(t, "rejected: counterfeit", last.Reason)
+}
+
+func TestDraftsAPI_Publish(t *testing.T) {
+	t.Parallel()
+
+	h, store := newTestAPI(t, &fakePublisher{})
+	id := readyDraft(t, store, "evt-1", "https://shopee.tw/i.1.2")
+	path := draftsPath + "/" + id + "/publish"
+
+	code, body := do(t, h, http.MethodPost, path, `{"dry_run": true}`)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, true, body["dry_run"])
+	require.Equal(t, "READY_FOR_REVIEW", body["status"])
+	rows := body["rows"].(map[string]any)
+	require.Equal(t, body["product_id"], rows["product"].(map[string]any)["id"])
+	require.Len(t, rows["variants"], 1)
+
+	code, body = do(t, h, http.MethodPost, path, `{"actor": "alice"}`)
+	require.Equal(t, http.StatusConflict, code)
+	require.Equal(t, "not_approved", errorCode(t, body))
+
+	code, _ = do(t, h, http.MethodPost, draftsPath+"/"+id+"/approve", `{"reviewer": "alice"}`)
+	require.Equal(t, http.StatusOK, code)
+	code, body = do(t, h, http.MethodPost, path, `{"actor": "alice"}`)
+	require.Equal(t, http.StatusServiceUnavailable, code, "postgres is disabled")
+	require.Equal(t, "unavailable", errorCode(t, body))
+}
@@ -0,0 +1,19 @@
+package fx
+
+import (
+	"net/http"
+
+	"peasydeal-product-miner/internal/app/httpapi"
+
+	"go.uber.org/fx"
+)
+
+var Module = fx.Module(
+	"httpapi",
+	fx.Provide(
+		httpapi.NewDraftsHandler,
+		httpapi.NewServer,
+	),
+	// Nothing else depends on the server; invoking it registers its lifecycle.
+	fx.Invoke(func(*http.Server) {}),
+)
@@ -0,0 +1,113 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"peasydeal-product-miner/db"
+	productdrafts "peasydeal-product-miner/internal/app/amqp/productdrafts"
+	"peasydeal-product-miner/internal/app/catalog"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// maxBodyBytes caps request bodies; drafts are created from a URL and hints.
+const maxBodyBytes = 1 << 20
+
+// errorBody is the JSON shape of every error response.
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// apiError is an error with the status and code it is reported under.
+type apiError struct {
+	status int
+	code   string
+	err    error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+func (e *apiError) Unwrap() error { return e.err }
+
+func badRequest(format string, args ...any) error {
+	return &apiError{status: http.StatusBadRequest, code: "invalid_request", err: fmt.Errorf(format, args...)}
+}
+
+func unavailable(format string, args ...any) error {
+	return &apiError{status: http.StatusServiceUnavailable, code: "unavailable", err: fmt.Errorf(format, args...)}
+}
+
+// classify maps an error from the handlers or the draft store to a response.
+func classify(err error) (status int, code string) {
+	var (
+		aerr *apiError
+		verr validator.ValidationErrors
+	)
+	switch {
+	case errors.As(err, &aerr):
+		return aerr.status, aerr.code
+	case errors.As(err, &verr),
+		errors.Is(err, productdrafts.ErrInvalidInput),
+		errors.Is(err, productdrafts.ErrInvalidCursor):
+		return http.StatusBadRequest, "invalid_request"
+	case errors.Is(err, productdrafts.ErrDraftNotFound):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, productdrafts.ErrIllegalTransition):
+		return http.StatusConflict, "illegal_transition"
+	case errors.Is(err, productdrafts.ErrStatusConflict):
+		return http.StatusConflict, "status_conflict"
+	case errors.Is(err, productdrafts.ErrNotApproved):
+		return http.StatusConflict, "not_approved"
+	case errors.Is(err, catalog.ErrInvalidPayload):
+		return http.StatusUnprocessableEntity, "invalid_payload"
+	case errors.Is(err, db.ErrSQLiteDisabled), errors.Is(err, db.ErrDBDisabled):
+		return http.StatusServiceUnavailable, "unavailable"
+	default:
+		return http.StatusInternalServerError, "internal"
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError reports err as JSON. Internal errors are logged and their detail
+// is kept out of the response.
+func writeError(w http.ResponseWriter, r *http.Request, logger *zap.SugaredLogger, err error) {
+	status, code := classify(err)
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		logger.Errorw("http_request_failed",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"err", err,
+		)
+		msg = http.StatusText(status)
+	}
+	writeJSON(w, status, errorBody{Error: errorDetail{Code: code, Message: msg}})
+}
+
+// decodeJSON reads one JSON object from the request body into v, rejecting
+// unknown fields and trailing data.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("decode request body: %v", err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return badRequest("decode request body: unexpected data after the JSON object")
+	}
+	return nil
+}
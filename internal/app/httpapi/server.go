@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"peasydeal-product-miner/config"
+	"peasydeal-product-miner/internal/pkg/amqpclient"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// NewRouter returns the API's routes wrapped in request logging. rabbit may be
+// nil when RabbitMQ is not part of the app.
+func NewRouter(drafts *DraftsHandler, rabbit *amqpclient.Manager, logger *zap.SugaredLogger) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", healthz(rabbit))
+	drafts.Register(mux)
+	return logRequests(jsonMuxErrors(mux), logger)
+}
+
+type healthResponse struct {
+	Status   string            `json:"status"`
+	RabbitMQ amqpclient.Status `json:"rabbitmq"`
+}
+
+// healthz reports 503 while RabbitMQ is configured but not connected, since
+// drafts cannot be queued then.
+func healthz(rabbit *amqpclient.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		st := rabbit.Status()
+		if rabbit.Enabled() && !st.Healthy() {
+			writeJSON(w, http.StatusServiceUnavailable, healthResponse{Status: "unavailable", RabbitMQ: st})
+			return
+		}
+		writeJSON(w, http.StatusOK, healthResponse{Status: "ok", RabbitMQ: st})
+	}
+}
+
+// jsonMuxErrors answers requests no route matches (404, or 405 for a known
+// path) with a JSON error instead of the mux's plain-text reply.
+func jsonMuxErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		probe := &probeWriter{header: http.Header{}, status: http.StatusNotFound}
+		h.ServeHTTP(probe, r)
+		code := "not_found"
+		if probe.status == http.StatusMethodNotAllowed {
+			code = "method_not_allowed"
+			w.Header().Set("Allow", probe.header.Get("Allow"))
+		}
+		writeJSON(w, probe.status, errorBody{Error: errorDetail{Code: code, Message: "no route for " + r.Method + " " + r.URL.Path}})
+	})
+}
+
+// probeWriter records the status and headers of the mux's error reply and
+// drops its body.
+type probeWriter struct {
+	header http.Header
+	status int
+}
+
+func (p *probeWriter) Header() http.Header         { return p.header }
+func (p *probeWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (p *probeWriter) WriteHeader(status int)      { p.status = status }
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler, logger *zap.SugaredLogger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logger.Infow("http_request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	})
+}
+
+type NewServerParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Config    *config.Config
+	Drafts    *DraftsHandler
+	AMQP      *amqpclient.Manager `optional:"true"`
+	Logger    *zap.SugaredLogger
+}
+
+// NewServer listens on APP_ADDR:APP_PORT for the app's lifetime.
+func NewServer(p NewServerParams) *http.Server {
+	srv := &http.Server{
+		Addr:              net.JoinHostPort(p.Config.App.Addr, p.Config.App.Port),
+		Handler:           NewRouter(p.Drafts, p.AMQP, p.Logger),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			p.Logger.Infow("http_server_listening", "addr", ln.Addr().String())
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					p.Logger.Errorw("http_server_failed", "err", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			return srv.Shutdown(ctx)
+		},
+	})
+	return srv
+}
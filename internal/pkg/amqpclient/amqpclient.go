@@ -2,8 +2,10 @@ package amqpclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"peasydeal-product-miner/config"
@@ -13,49 +15,91 @@ import (
 	"go.uber.org/zap"
 )
 
-type NewAMQPParams struct {
-	fx.In
+var (
+	// ErrDisabled is returned for channel requests when RABBITMQ_URL is not set.
+	ErrDisabled = errors.New("rabbitmq disabled: missing RABBITMQ_URL")
+	// ErrClosed is returned for channel requests after Close.
+	ErrClosed = errors.New("rabbitmq connection manager closed")
+)
 
-	Lifecycle fx.Lifecycle
-	Config    *config.Config
-	Logger    *zap.SugaredLogger
+// State is the manager's connection state.
+type State string
+
+const (
+	StateDisabled   State = "disabled"
+	StateIdle       State = "idle"
+	StateConnecting State = "connecting"
+	StateConnected  State = "connected"
+	StateClosed     State = "closed"
+)
+
+// Status is a snapshot of the connection for health checks.
+type Status struct {
+	State      State     `json:"state"`
+	Since      time.Time `json:"since"`
+	LastError  string    `json:"last_error,omitempty"`
+	Reconnects int       `json:"reconnects"`
 }
 
-type AMQPOut struct {
-	fx.Out
+// Healthy reports whether the connection is up.
+func (s Status) Healthy() bool {
+	return s.State == StateConnected
+}
 
-	Conn    *amqp.Connection
-	Channel *amqp.Channel
+// connection is the part of *amqp.Connection the manager uses.
+type connection interface {
+	Channel() (*amqp.Channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	IsClosed() bool
+	Close() error
 }
 
-func NewAMQP(p NewAMQPParams) (AMQPOut, error) {
-	url := ""
-	if p.Config != nil {
-		url = strings.TrimSpace(p.Config.RabbitMQ.URL)
-	}
-	if url == "" {
-		p.Logger.Infow("rabbitmq_disabled", "reason", "missing RABBITMQ_URL")
-		return AMQPOut{Conn: nil, Channel: nil}, nil
-	}
+// Manager owns the process's single RabbitMQ connection. It dials in the
+// background, redials with backoff whenever the connection drops, and hands out
+// fresh channels on the current connection. Channels die with the connection, so
+// holders should request a new one after a reconnect.
+type Manager struct {
+	url    string
+	logger *zap.SugaredLogger
+	dial   func(url string) (connection, error)
 
-	conn, err := amqp.DialConfig(url, amqp.Config{
-		Heartbeat: 10 * time.Second,
-	})
-	if err != nil {
-		return AMQPOut{}, fmt.Errorf("rabbitmq dial: %w", err)
-	}
+	minBackoff time.Duration
+	maxBackoff time.Duration
 
-	ch, err := conn.Channel()
-	if err != nil {
-		_ = conn.Close()
-		return AMQPOut{}, fmt.Errorf("rabbitmq channel: %w", err)
+	mu         sync.Mutex
+	conn       connection
+	ready      chan struct{} // closed while conn is usable
+	status     Status
+	started    bool
+	closing    chan struct{}
+	done       chan struct{}
+	everOnline bool
+}
+
+type NewManagerParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Config    *config.Config
+	Logger    *zap.SugaredLogger
+}
+
+// NewManager provides the shared Manager. It connects on start (or on the first
+// channel request, whichever comes first) and closes the connection on stop.
+func NewManager(p NewManagerParams) *Manager {
+	m := New(p.Config, p.Logger)
+	if !m.Enabled() {
+		p.Logger.Infow("rabbitmq_disabled", "reason", "missing RABBITMQ_URL")
+		return m
 	}
 
 	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			m.Start()
+			return nil
+		},
 		OnStop: func(ctx context.Context) error {
-			_ = ctx
-			_ = ch.Close()
-			_ = conn.Close()
+			m.Close()
 			return nil
 		},
 	})
@@ -69,9 +113,261 @@ func NewAMQP(p NewAMQPParams) (AMQPOut, error) {
 			"prefetch", p.Config.RabbitMQ.Prefetch,
 			"declare_topology", p.Config.RabbitMQ.DeclareTopology,
 		)
+	}
+	return m
+}
+
+// New returns a Manager for cfg's RABBITMQ_URL without lifecycle hooks; callers
+// must Close it.
+func New(cfg *config.Config, logger *zap.SugaredLogger) *Manager {
+	url := ""
+	if cfg != nil {
+		url = strings.TrimSpace(cfg.RabbitMQ.URL)
+	}
+	state := StateIdle
+	if url == "" {
+		state = StateDisabled
+	}
+	return &Manager{
+		url:    url,
+		logger: logger,
+		dial: func(url string) (connection, error) {
+			return amqp.DialConfig(url, amqp.Config{Heartbeat: 10 * time.Second})
+		},
+		minBackoff: time.Second,
+		maxBackoff: 30 * time.Second,
+		ready:      make(chan struct{}),
+		status:     Status{State: state, Since: time.Now()},
+		closing:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Enabled reports whether RABBITMQ_URL is set.
+func (m *Manager) Enabled() bool {
+	return m != nil && m.url != ""
+}
+
+// Status returns the current connection state.
+func (m *Manager) Status() Status {
+	if m == nil {
+		return Status{State: StateDisabled}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Start begins connecting in the background. It is a no-op when disabled,
+// closed or already started.
+func (m *Manager) Start() {
+	if !m.Enabled() {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.startLocked()
+}
+
+func (m *Manager) startLocked() {
+	if m.started || m.status.State == StateClosed {
+		return
+	}
+	m.started = true
+	go m.run()
+}
+
+// Channel opens a channel on the current connection, waiting for ctx while the
+// manager is (re)connecting.
+func (m *Manager) Channel(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		conn, err := m.connection(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ch, err := conn.Channel()
+		if err == nil {
+			return ch, nil
+		}
+		if !conn.IsClosed() {
+			return nil, fmt.Errorf("rabbitmq channel: %w", err)
+		}
+		// The connection dropped under us; wait for the redial.
+	}
+}
+
+// ConfirmChannel opens a channel in publisher-confirm mode.
+func (m *Manager) ConfirmChannel(ctx context.Context) (*amqp.Channel, error) {
+	ch, err := m.Channel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		_ = ch.Close()
+		return nil, fmt.Errorf("rabbitmq confirm mode: %w", err)
+	}
+	return ch, nil
+}
+
+// connection waits until a usable connection exists.
+func (m *Manager) connection(ctx context.Context) (connection, error) {
+	if !m.Enabled() {
+		return nil, ErrDisabled
+	}
+	for {
+		m.mu.Lock()
+		if m.status.State == StateClosed {
+			m.mu.Unlock()
+			return nil, ErrClosed
+		}
+		m.startLocked()
+		if m.conn != nil {
+			if !m.conn.IsClosed() {
+				conn := m.conn
+				m.mu.Unlock()
+				return conn, nil
+			}
+			// Closed but run has not seen it yet.
+			m.dropLocked()
+		}
+		ready := m.ready
+		m.mu.Unlock()
+
+		select {
+		case <-ready:
+		case <-m.closing:
+			return nil, ErrClosed
+		case <-ctx.Done():
+			return nil, fmt.Errorf("rabbitmq connect: %w", ctx.Err())
+		}
+	}
+}
+
+// Close stops reconnecting and closes the connection, which closes every
+// channel handed out.
+func (m *Manager) Close() {
+	if !m.Enabled() {
+		return
+	}
+	m.mu.Lock()
+	if m.status.State == StateClosed {
+		m.mu.Unlock()
+		return
+	}
+	started := m.started
+	m.setStateLocked(StateClosed, nil)
+	close(m.closing)
+	conn := m.conn
+	m.conn = nil
+	m.mu.Unlock()
+
+	if conn != nil {
+		_ = conn.Close()
+	}
+	if started {
+		<-m.done
+	}
+}
+
+func (m *Manager) run() {
+	defer close(m.done)
+
+	backoff := m.minBackoff
+	for {
+		m.mu.Lock()
+		m.setStateLocked(StateConnecting, nil)
+		m.mu.Unlock()
+
+		conn, err := m.dial(m.url)
+		if err != nil {
+			m.mu.Lock()
+			m.setStateLocked(StateConnecting, err)
+			m.mu.Unlock()
+			m.logger.Warnw("rabbitmq_dial_failed", "err", err, "backoff", backoff.String())
+			if !m.sleep(backoff) {
+				return
+			}
+			backoff = min(backoff*2, m.maxBackoff)
+			continue
+		}
+		backoff = m.minBackoff
+
+		closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+		if !m.online(conn) {
+			_ = conn.Close()
+			return
+		}
+
+		select {
+		case <-m.closing:
+			return
+		case err := <-closed:
+			m.mu.Lock()
+			if m.conn == conn {
+				m.dropLocked()
+			}
+			var cause error
+			if err != nil {
+				cause = err
+			}
+			m.setStateLocked(StateConnecting, cause)
+			m.mu.Unlock()
+			m.logger.Warnw("rabbitmq_connection_lost", "err", cause)
+		}
+	}
+}
+
+// online publishes conn as the current connection and wakes waiters. It returns
+// false if the manager closed meanwhile.
+func (m *Manager) online(conn connection) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status.State == StateClosed {
+		return false
+	}
+	m.conn = conn
+	close(m.ready)
+	reconnect := m.everOnline
+	m.everOnline = true
+	m.setStateLocked(StateConnected, nil)
+
+	if reconnect {
+		m.status.Reconnects++
+		m.logger.Infow("rabbitmq_reconnected", "reconnects", m.status.Reconnects)
 	} else {
-		p.Logger.Infow("rabbitmq_enabled")
+		m.logger.Infow("rabbitmq_connected")
+	}
+	return true
+}
+
+// dropLocked forgets the current connection so waiters block until the next one.
+func (m *Manager) dropLocked() {
+	m.conn = nil
+	m.ready = make(chan struct{})
+}
+
+func (m *Manager) setStateLocked(state State, err error) {
+	if m.status.State == StateClosed {
+		return
+	}
+	if m.status.State != state {
+		m.status.State = state
+		m.status.Since = time.Now()
+	}
+	if err != nil {
+		m.status.LastError = err.Error()
 	}
+}
 
-	return AMQPOut{Conn: conn, Channel: ch}, nil
+// sleep waits d, returning false if the manager closed first.
+func (m *Manager) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-m.closing:
+		return false
+	case <-timer.C:
+		return true
+	}
 }
@@ -0,0 +1,13 @@
+package fx
+
+import (
+	"peasydeal-product-miner/internal/pkg/amqpclient"
+
+	"go.uber.org/fx"
+)
+
+// Module provides the shared *amqpclient.Manager.
+var Module = fx.Module(
+	"amqpclient",
+	fx.Provide(amqpclient.NewManager),
+)
@@ -0,0 +1,195 @@
+package chromedevtools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/coder/websocket"
+)
+
+// maxMessageBytes bounds a single CDP message. Full-page outerHTML of a
+// marketplace product page routinely exceeds the websocket default of 32KiB.
+const maxMessageBytes = 64 << 20
+
+// ErrConnClosed is returned by Call after the websocket has been closed.
+var ErrConnClosed = errors.New("cdp connection closed")
+
+// Error is an error object returned by the DevTools protocol.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("cdp error %d: %s (%s)", e.Code, e.Message, e.Data)
+	}
+	return fmt.Sprintf("cdp error %d: %s", e.Code, e.Message)
+}
+
+type cdpRequest struct {
+	ID     int64  `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params,omitempty"`
+}
+
+type cdpMessage struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Conn is a DevTools protocol session on a single target websocket.
+// Events are read and discarded; callers poll state via Call/Evaluate.
+type Conn struct {
+	ws     *websocket.Conn
+	nextID atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]chan cdpMessage
+	readErr error
+
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+// Dial connects to a target's webSocketDebuggerUrl.
+func Dial(ctx context.Context, wsURL string) (*Conn, error) {
+	ws, _, err := websocket.Dial(ctx, wsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dial devtools websocket: %w", err)
+	}
+	ws.SetReadLimit(maxMessageBytes)
+
+	readCtx, cancel := context.WithCancel(context.Background())
+	c := &Conn{
+		ws:      ws,
+		pending: make(map[int64]chan cdpMessage),
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+	go c.readLoop(readCtx)
+	return c, nil
+}
+
+func (c *Conn) readLoop(ctx context.Context) {
+	defer close(c.done)
+	for {
+		_, data, err := c.ws.Read(ctx)
+		if err != nil {
+			c.mu.Lock()
+			c.readErr = err
+			c.mu.Unlock()
+			return
+		}
+
+		var msg cdpMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.ID == 0 {
+			continue
+		}
+
+		c.mu.Lock()
+		ch := c.pending[msg.ID]
+		delete(c.pending, msg.ID)
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+	}
+}
+
+// Call sends a protocol command and decodes its result into result (if non-nil).
+func (c *Conn) Call(ctx context.Context, method string, params any, result any) error {
+	id := c.nextID.Add(1)
+	payload, err := json.Marshal(cdpRequest{ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", method, err)
+	}
+
+	ch := make(chan cdpMessage, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.ws.Write(ctx, websocket.MessageText, payload); err != nil {
+		return fmt.Errorf("send %s: %w", method, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	case <-c.done:
+		c.mu.Lock()
+		readErr := c.readErr
+		c.mu.Unlock()
+		return fmt.Errorf("%s: %w: %v", method, ErrConnClosed, readErr)
+	case msg := <-ch:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %w", method, msg.Error)
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	}
+}
+
+// Evaluate runs a JavaScript expression in the page (awaiting promises) and
+// decodes its by-value result into out (if non-nil).
+func (c *Conn) Evaluate(ctx context.Context, expression string, out any) error {
+	var res struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text      string `json:"text"`
+			Exception *struct {
+				Description string `json:"description"`
+			} `json:"exception"`
+		} `json:"exceptionDetails"`
+	}
+
+	params := map[string]any{
+		"expression":    expression,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}
+	if err := c.Call(ctx, "Runtime.evaluate", params, &res); err != nil {
+		return err
+	}
+	if d := res.ExceptionDetails; d != nil {
+		msg := d.Text
+		if d.Exception != nil && d.Exception.Description != "" {
+			msg = d.Exception.Description
+		}
+		return fmt.Errorf("runtime exception: %s", msg)
+	}
+	if out == nil || len(res.Result.Value) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(res.Result.Value, out); err != nil {
+		return fmt.Errorf("decode evaluate result: %w", err)
+	}
+	return nil
+}
+
+// Close closes the websocket and stops the read loop.
+func (c *Conn) Close() error {
+	err := c.ws.Close(websocket.StatusNormalClosure, "")
+	c.cancel()
+	<-c.done
+	return err
+}
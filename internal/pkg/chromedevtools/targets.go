@@ -0,0 +1,88 @@
+package chromedevtools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	neturl "net/url"
+)
+
+// Target is an entry from the DevTools HTTP endpoint (/json/list, /json/new).
+type Target struct {
+	ID                   string `json:"id"`
+	Type                 string `json:"type"`
+	Title                string `json:"title"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// BaseURL returns the DevTools HTTP base URL (http://host:port) using the same
+// defaults as VersionURL.
+func BaseURL(host, port string) string {
+	return strings.TrimSuffix(VersionURL(host, port), "/json/version")
+}
+
+const targetHTTPTimeout = 10 * time.Second
+
+// NewTarget opens a new page tab at pageURL.
+func NewTarget(ctx context.Context, baseURL, pageURL string) (Target, error) {
+	endpoint := strings.TrimRight(baseURL, "/") + "/json/new?" + neturl.QueryEscape(pageURL)
+
+	// Chrome >= 111 rejects GET for /json/new.
+	var t Target
+	if err := targetRequest(ctx, http.MethodPut, endpoint, &t); err != nil {
+		return Target{}, err
+	}
+	if t.WebSocketDebuggerURL == "" {
+		return Target{}, fmt.Errorf("new target %q has no webSocketDebuggerUrl", t.ID)
+	}
+	return t, nil
+}
+
+// CloseTarget closes the tab with the given target id.
+func CloseTarget(ctx context.Context, baseURL, targetID string) error {
+	endpoint := strings.TrimRight(baseURL, "/") + "/json/close/" + neturl.PathEscape(targetID)
+	return targetRequest(ctx, http.MethodGet, endpoint, nil)
+}
+
+// ListTargets returns all targets known to the browser.
+func ListTargets(ctx context.Context, baseURL string) ([]Target, error) {
+	var out []Target
+	if err := targetRequest(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/json/list", &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func targetRequest(ctx context.Context, method, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := newHTTPClient(targetHTTPTimeout).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %s from %s: %s", resp.Status, endpoint, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("decode response from %s: %w", endpoint, err)
+	}
+	return nil
+}
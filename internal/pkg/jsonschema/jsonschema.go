@@ -0,0 +1,555 @@
+// Package jsonschema implements the subset of JSON Schema draft 2020-12 used by the
+// crawler output contract (config/schema.product.json).
+//
+// Supported keywords: $ref (local JSON pointers only), $defs, type, enum, const,
+// required, properties, patternProperties, additionalProperties, minProperties,
+// maxProperties, items, minItems, maxItems, minLength, maxLength, pattern,
+// format (date-time, uri), minimum, maximum, exclusiveMinimum, exclusiveMaximum,
+// allOf, anyOf, oneOf, not, if/then/else. Annotation keywords are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError describes one failed keyword at an instance location.
+type ValidationError struct {
+	// Path is the JSON pointer (RFC 6901) of the failing instance value; "" is the root.
+	Path    string `json:"path"`
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// Schema is a parsed JSON Schema document.
+type Schema struct {
+	root     any
+	patterns map[string]*regexp.Regexp // compiled once in Parse; read-only afterwards
+}
+
+// Load reads and parses a schema file.
+func Load(path string) (*Schema, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read schema: %w", err)
+	}
+	s, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Parse parses a schema document and pre-compiles its regular expressions.
+func Parse(b []byte) (*Schema, error) {
+	root, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	switch root.(type) {
+	case map[string]any, bool:
+	default:
+		return nil, fmt.Errorf("schema must be an object or boolean")
+	}
+
+	s := &Schema{root: root, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ValidateJSON validates a raw JSON document.
+func (s *Schema) ValidateJSON(b []byte) ([]ValidationError, error) {
+	inst, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	return s.Validate(inst), nil
+}
+
+// Validate validates a decoded JSON value (as produced by encoding/json into any, with
+// or without UseNumber). Errors are sorted by path for stable output.
+func (s *Schema) Validate(instance any) []ValidationError {
+	var errs []ValidationError
+	s.validate(s.root, instance, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+func decode(b []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (s *Schema) compilePatterns(node any) error {
+	switch n := node.(type) {
+	case map[string]any:
+		if p, ok := n["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+			s.patterns[p] = re
+		}
+		if pp, ok := n["patternProperties"].(map[string]any); ok {
+			for p := range pp {
+				re, err := regexp.Compile(p)
+				if err != nil {
+					return fmt.Errorf("invalid patternProperties key %q: %w", p, err)
+				}
+				s.patterns[p] = re
+			}
+		}
+		for _, v := range n {
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, v := range n {
+			if err := s.compilePatterns(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) pattern(p string) *regexp.Regexp {
+	return s.patterns[p]
+}
+
+func (s *Schema) validate(node any, inst any, ptr string, errs *[]ValidationError) {
+	switch n := node.(type) {
+	case bool:
+		if !n {
+			addErr(errs, ptr, "false", "value is not allowed")
+		}
+		return
+	case map[string]any:
+		s.validateObjectSchema(n, inst, ptr, errs)
+	}
+}
+
+func (s *Schema) validateObjectSchema(n map[string]any, inst any, ptr string, errs *[]ValidationError) {
+	if ref, ok := n["$ref"].(string); ok {
+		target, err := s.resolveRef(ref)
+		if err != nil {
+			addErr(errs, ptr, "$ref", err.Error())
+		} else {
+			s.validate(target, inst, ptr, errs)
+		}
+	}
+
+	if t, ok := n["type"]; ok && !typeMatches(t, inst) {
+		addErr(errs, ptr, "type", fmt.Sprintf("expected %s, got %s", describeType(t), jsonType(inst)))
+		// Type-specific keywords below would only add noise.
+		return
+	}
+
+	if enum, ok := n["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, inst) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addErr(errs, ptr, "enum", fmt.Sprintf("value must be one of %s", compactJSON(enum)))
+		}
+	}
+	if c, ok := n["const"]; ok && !jsonEqual(c, inst) {
+		addErr(errs, ptr, "const", fmt.Sprintf("value must be %s", compactJSON(c)))
+	}
+
+	switch v := inst.(type) {
+	case map[string]any:
+		s.validateObject(n, v, ptr, errs)
+	case []any:
+		s.validateArray(n, v, ptr, errs)
+	case string:
+		s.validateString(n, v, ptr, errs)
+	default:
+		if f, ok := toFloat(inst); ok {
+			validateNumber(n, f, ptr, errs)
+		}
+	}
+
+	s.validateApplicators(n, inst, ptr, errs)
+}
+
+func (s *Schema) validateObject(n map[string]any, obj map[string]any, ptr string, errs *[]ValidationError) {
+	if req, ok := n["required"].([]any); ok {
+		for _, r := range req {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				addErr(errs, ptr, "required", fmt.Sprintf("missing required property %q", name))
+			}
+		}
+	}
+	if min, ok := toInt(n["minProperties"]); ok && len(obj) < min {
+		addErr(errs, ptr, "minProperties", fmt.Sprintf("must have at least %d properties", min))
+	}
+	if max, ok := toInt(n["maxProperties"]); ok && len(obj) > max {
+		addErr(errs, ptr, "maxProperties", fmt.Sprintf("must have at most %d properties", max))
+	}
+
+	props, _ := n["properties"].(map[string]any)
+	patternProps, _ := n["patternProperties"].(map[string]any)
+	additional, hasAdditional := n["additionalProperties"]
+
+	for _, key := range sortedKeys(obj) {
+		val := obj[key]
+		childPtr := ptr + "/" + escapePointer(key)
+		matched := false
+
+		if sub, ok := props[key]; ok {
+			matched = true
+			s.validate(sub, val, childPtr, errs)
+		}
+		for p, sub := range patternProps {
+			if re := s.pattern(p); re != nil && re.MatchString(key) {
+				matched = true
+				s.validate(sub, val, childPtr, errs)
+			}
+		}
+		if matched || !hasAdditional {
+			continue
+		}
+		if b, ok := additional.(bool); ok && !b {
+			addErr(errs, childPtr, "additionalProperties", fmt.Sprintf("property %q is not allowed", key))
+			continue
+		}
+		s.validate(additional, val, childPtr, errs)
+	}
+}
+
+func (s *Schema) validateArray(n map[string]any, arr []any, ptr string, errs *[]ValidationError) {
+	if min, ok := toInt(n["minItems"]); ok && len(arr) < min {
+		addErr(errs, ptr, "minItems", fmt.Sprintf("must have at least %d items", min))
+	}
+	if max, ok := toInt(n["maxItems"]); ok && len(arr) > max {
+		addErr(errs, ptr, "maxItems", fmt.Sprintf("must have at most %d items", max))
+	}
+	if items, ok := n["items"]; ok {
+		for i, it := range arr {
+			s.validate(items, it, fmt.Sprintf("%s/%d", ptr, i), errs)
+		}
+	}
+}
+
+func (s *Schema) validateString(n map[string]any, str string, ptr string, errs *[]ValidationError) {
+	length := utf8.RuneCountInString(str)
+	if min, ok := toInt(n["minLength"]); ok && length < min {
+		addErr(errs, ptr, "minLength", fmt.Sprintf("must be at least %d characters", min))
+	}
+	if max, ok := toInt(n["maxLength"]); ok && length > max {
+		addErr(errs, ptr, "maxLength", fmt.Sprintf("must be at most %d characters", max))
+	}
+	if p, ok := n["pattern"].(string); ok {
+		if re := s.pattern(p); re != nil && !re.MatchString(str) {
+			addErr(errs, ptr, "pattern", fmt.Sprintf("does not match pattern %q", p))
+		}
+	}
+	if f, ok := n["format"].(string); ok && !formatMatches(f, str) {
+		addErr(errs, ptr, "format", fmt.Sprintf("is not a valid %s", f))
+	}
+}
+
+func validateNumber(n map[string]any, f float64, ptr string, errs *[]ValidationError) {
+	if min, ok := toFloat(n["minimum"]); ok && f < min {
+		addErr(errs, ptr, "minimum", fmt.Sprintf("must be >= %v", min))
+	}
+	if max, ok := toFloat(n["maximum"]); ok && f > max {
+		addErr(errs, ptr, "maximum", fmt.Sprintf("must be <= %v", max))
+	}
+	if min, ok := toFloat(n["exclusiveMinimum"]); ok && f <= min {
+		addErr(errs, ptr, "exclusiveMinimum", fmt.Sprintf("must be > %v", min))
+	}
+	if max, ok := toFloat(n["exclusiveMaximum"]); ok && f >= max {
+		addErr(errs, ptr, "exclusiveMaximum", fmt.Sprintf("must be < %v", max))
+	}
+}
+
+func (s *Schema) validateApplicators(n map[string]any, inst any, ptr string, errs *[]ValidationError) {
+	if all, ok := n["allOf"].([]any); ok {
+		for _, sub := range all {
+			s.validate(sub, inst, ptr, errs)
+		}
+	}
+	if anyOf, ok := n["anyOf"].([]any); ok {
+		passed := false
+		for _, sub := range anyOf {
+			if s.valid(sub, inst, ptr) {
+				passed = true
+				break
+			}
+		}
+		if !passed {
+			addErr(errs, ptr, "anyOf", "value does not match any allowed schema")
+		}
+	}
+	if oneOf, ok := n["oneOf"].([]any); ok {
+		count := 0
+		for _, sub := range oneOf {
+			if s.valid(sub, inst, ptr) {
+				count++
+			}
+		}
+		if count != 1 {
+			addErr(errs, ptr, "oneOf", fmt.Sprintf("value must match exactly one schema (matched %d)", count))
+		}
+	}
+	if not, ok := n["not"]; ok && s.valid(not, inst, ptr) {
+		addErr(errs, ptr, "not", "value must not match schema")
+	}
+	if cond, ok := n["if"]; ok {
+		if s.valid(cond, inst, ptr) {
+			if then, ok := n["then"]; ok {
+				s.validate(then, inst, ptr, errs)
+			}
+		} else if els, ok := n["else"]; ok {
+			s.validate(els, inst, ptr, errs)
+		}
+	}
+}
+
+func (s *Schema) valid(node any, inst any, ptr string) bool {
+	var tmp []ValidationError
+	s.validate(node, inst, ptr, &tmp)
+	return len(tmp) == 0
+}
+
+func (s *Schema) resolveRef(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported non-local $ref %q", ref)
+	}
+	frag := strings.TrimPrefix(ref, "#")
+	if frag == "" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(frag, "/") {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+
+	cur := s.root
+	for _, tok := range strings.Split(frag[1:], "/") {
+		tok = unescapePointer(tok)
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		cur, ok = m[tok]
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+	return cur, nil
+}
+
+func typeMatches(t any, inst any) bool {
+	switch tt := t.(type) {
+	case string:
+		return typeIs(tt, inst)
+	case []any:
+		for _, x := range tt {
+			if s, ok := x.(string); ok && typeIs(s, inst) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func typeIs(t string, inst any) bool {
+	switch t {
+	case "null":
+		return inst == nil
+	case "boolean":
+		_, ok := inst.(bool)
+		return ok
+	case "object":
+		_, ok := inst.(map[string]any)
+		return ok
+	case "array":
+		_, ok := inst.([]any)
+		return ok
+	case "string":
+		_, ok := inst.(string)
+		return ok
+	case "number":
+		_, ok := toFloat(inst)
+		return ok
+	case "integer":
+		f, ok := toFloat(inst)
+		return ok && f == math.Trunc(f)
+	default:
+		return false
+	}
+}
+
+func jsonType(inst any) string {
+	switch inst.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	}
+	if _, ok := toFloat(inst); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", inst)
+}
+
+func describeType(t any) string {
+	switch tt := t.(type) {
+	case string:
+		return tt
+	case []any:
+		parts := make([]string, 0, len(tt))
+		for _, x := range tt {
+			parts = append(parts, fmt.Sprint(x))
+		}
+		return strings.Join(parts, " or ")
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func formatMatches(format string, s string) bool {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return true
+		}
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	default:
+		// Unknown formats are annotations only.
+		return true
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func toInt(v any) (int, bool) {
+	f, ok := toFloat(v)
+	if !ok {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func jsonEqual(a any, b any) bool {
+	return reflect.DeepEqual(normalizeNumbers(a), normalizeNumbers(b))
+}
+
+func normalizeNumbers(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, x := range t {
+			out[k] = normalizeNumbers(x)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, x := range t {
+			out[i] = normalizeNumbers(x)
+		}
+		return out
+	}
+	if f, ok := toFloat(v); ok {
+		return f
+	}
+	return v
+}
+
+func compactJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func escapePointer(tok string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(tok, "~", "~0"), "/", "~1")
+}
+
+func unescapePointer(tok string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+}
+
+func addErr(errs *[]ValidationError, ptr string, keyword string, msg string) {
+	*errs = append(*errs, ValidationError{Path: ptr, Keyword: keyword, Message: msg})
+}
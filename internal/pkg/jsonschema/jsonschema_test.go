@@ -0,0 +1,124 @@
+package jsonschema
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadProductSchema(t *testing.T) *Schema {
+	t.Helper()
+
+	s, err := Load(filepath.Join("..", "..", "..", "config", "schema.product.json"))
+	require.NoError(t, err)
+	return s
+}
+
+func paths(errs []ValidationError) []string {
+	out := make([]string, 0, len(errs))
+	for _, e := range errs {
+		out = append(out, e.Path+" "+e.Keyword)
+	}
+	return out
+}
+
+func TestProductSchema_ValidOK(t *testing.T) {
+	t.Parallel()
+
+	s := loadProductSchema(t)
+	errs, err := s.ValidateJSON([]byte(`{
+  "url": "https://shopee.tw/x-i.1.2",
+  "status": "ok",
+  "captured_at": "2026-01-01T00:00:00Z",
+  "source": "shopee",
+  "source_ids": {"platform": "shopee", "shop_id": "1", "item_id": "2"},
+  "product": {"title": "t", "description": "d"},
+  "images": [{"url": "https://img/1.jpg", "position": 0}],
+  "pricing": {"currency": "TWD", "sale_price_min": 10, "sale_price_max": "12.50"},
+  "models": [{"name": "red", "available_stock": 3}]
+}`))
+	require.NoError(t, err)
+	require.Empty(t, errs)
+}
+
+func TestProductSchema_OKRequiresConditionalFields(t *testing.T) {
+	t.Parallel()
+
+	s := loadProductSchema(t)
+	errs, err := s.ValidateJSON([]byte(`{
+  "url": "https://shopee.tw/x-i.1.2",
+  "status": "ok",
+  "captured_at": "2026-01-01T00:00:00Z",
+  "source": "shopee",
+  "images": [],
+  "models": []
+}`))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		" required", // source_ids
+		" required", // product
+		" required", // pricing
+		"/images minItems",
+		"/models minItems",
+	}, paths(errs))
+}
+
+func TestProductSchema_ReportsNestedPointers(t *testing.T) {
+	t.Parallel()
+
+	s := loadProductSchema(t)
+	errs, err := s.ValidateJSON([]byte(`{
+  "url": "https://shopee.tw/x-i.1.2",
+  "status": "needs_manual",
+  "notes": "captcha",
+  "captured_at": "yesterday",
+  "source_ids": {"platform": "shopee", "shop_id": "abc", "item_id": "2"},
+  "pricing": {"currency": "TWD", "sale_price_min": -1, "sale_price_max": "1.234"},
+  "models": [{"name": "", "bogus": true}],
+  "a/b": 1
+}`))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		"/a~1b additionalProperties",
+		"/captured_at format",
+		"/models/0/bogus additionalProperties",
+		"/models/0/name minLength",
+		"/pricing/sale_price_max pattern",
+		"/pricing/sale_price_min minimum",
+		"/source_ids/shop_id pattern",
+	}, paths(errs))
+}
+
+func TestSchema_TypeMismatchSkipsTypeSpecificKeywords(t *testing.T) {
+	t.Parallel()
+
+	s, err := Parse([]byte(`{"type":"object","properties":{"n":{"type":"integer","minimum":1}}}`))
+	require.NoError(t, err)
+
+	errs := s.Validate(map[string]any{"n": "x"})
+	require.Len(t, errs, 1)
+	require.Equal(t, "/n", errs[0].Path)
+	require.Equal(t, "type", errs[0].Keyword)
+
+	errs = s.Validate(map[string]any{"n": 1.5})
+	require.Equal(t, []string{"/n type"}, paths(errs))
+
+	require.Empty(t, s.Validate(map[string]any{"n": 2}))
+}
+
+func TestSchema_Combinators(t *testing.T) {
+	t.Parallel()
+
+	s, err := Parse([]byte(`{
+  "anyOf": [{"type":"string"},{"type":"number"}],
+  "not": {"const": "forbidden"},
+  "oneOf": [{"type":"string","minLength":3},{"type":"number"}]
+}`))
+	require.NoError(t, err)
+
+	require.Empty(t, s.Validate("abcd"))
+	require.Equal(t, []string{" oneOf"}, paths(s.Validate("ab")))
+	require.ElementsMatch(t, []string{" anyOf", " oneOf"}, paths(s.Validate(true)))
+	require.Equal(t, []string{" not"}, paths(s.Validate("forbidden")))
+}
@@ -0,0 +1,151 @@
+package product
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// field binds a JSON key to a pointer into the owning struct.
+type field struct {
+	key string
+	ptr any
+}
+
+// fieldSet records which known keys were present when an object was decoded.
+type fieldSet map[string]bool
+
+var jsonNull = []byte("null")
+
+// decodeObject fills the known fields from data and moves everything else into
+// extras. A null for a non-nullable field, or a value of the wrong shape (e.g. a
+// string position), is also kept in extras verbatim so it survives re-encoding;
+// the typed field is left at its zero value.
+func decodeObject(data []byte, fields []field, extras *map[string]json.RawMessage, present *fieldSet) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*extras = nil
+	*present = make(fieldSet, len(fields))
+	for _, f := range fields {
+		v, ok := raw[f.key]
+		if !ok {
+			continue
+		}
+		if bytes.Equal(bytes.TrimSpace(v), jsonNull) && !nullable(f.ptr) {
+			continue
+		}
+		if err := json.Unmarshal(v, f.ptr); err != nil {
+			target := reflect.ValueOf(f.ptr).Elem()
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		delete(raw, f.key)
+		(*present)[f.key] = true
+	}
+	if len(raw) > 0 {
+		*extras = raw
+	}
+	return nil
+}
+
+// encodeObject writes the fields that were present on decode or are now set,
+// plus extras. Known fields win over extras with the same key.
+func encodeObject(fields []field, extras map[string]json.RawMessage, present fieldSet) ([]byte, error) {
+	out := make(map[string]json.RawMessage, len(fields)+len(extras))
+	for k, v := range extras {
+		out[k] = v
+	}
+	for _, f := range fields {
+		if !present[f.key] && reflect.ValueOf(f.ptr).Elem().IsZero() {
+			continue
+		}
+		b, err := json.Marshal(f.ptr)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.key, err)
+		}
+		out[f.key] = b
+	}
+	return json.Marshal(out)
+}
+
+func nullable(ptr any) bool {
+	switch reflect.ValueOf(ptr).Elem().Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map:
+		return true
+	}
+	_, ok := ptr.(*Money)
+	return ok
+}
+
+// Money is a price as emitted by the tools: a JSON number or a numeric string.
+// The original literal is preserved so re-encoding does not change its form.
+type Money struct {
+	raw json.RawMessage
+}
+
+// NewMoney returns a Money encoded as a JSON number.
+func NewMoney(v float64) Money {
+	return Money{raw: json.RawMessage(strconv.FormatFloat(v, 'f', -1, 64))}
+}
+
+// MoneyString returns a Money encoded as a JSON string, e.g. "199.00".
+func MoneyString(s string) Money {
+	b, _ := json.Marshal(s)
+	return Money{raw: b}
+}
+
+// IsZero reports whether the value is absent.
+func (m Money) IsZero() bool { return len(m.raw) == 0 }
+
+// IsNull reports whether the value was an explicit JSON null.
+func (m Money) IsNull() bool { return bytes.Equal(m.raw, jsonNull) }
+
+// String returns the value as text without JSON quoting; empty when absent or null.
+func (m Money) String() string {
+	if m.IsZero() || m.IsNull() {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(m.raw, &s); err == nil {
+		return s
+	}
+	return string(m.raw)
+}
+
+// Float64 parses the value as a number, accepting both number and string forms.
+func (m Money) Float64() (float64, error) {
+	s := m.String()
+	if s == "" {
+		return 0, fmt.Errorf("money is empty")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.IsZero() {
+		return jsonNull, nil
+	}
+	return m.raw, nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("empty money value")
+	}
+	switch data[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+	default:
+		return fmt.Errorf("money must be a number or string, got %s", data)
+	}
+	if !json.Valid(data) {
+		return fmt.Errorf("invalid money value %s", data)
+	}
+	m.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
@@ -0,0 +1,285 @@
+// Package product is the typed domain model for a crawled product.
+//
+// The crawl contract (see config/schema.product.json) is produced by external
+// tools and evolves faster than this package, so every struct keeps unknown keys
+// in an Extras bag and remembers which known keys were present on decode. That
+// makes Parse -> Marshal lossless for any payload the tools emit today.
+package product
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Crawl statuses emitted by the tools.
+const (
+	StatusOK          = "ok"
+	StatusNeedsManual = "needs_manual"
+	StatusError       = "error"
+)
+
+// Product is a single crawl result.
+type Product struct {
+	URL         string
+	Status      string
+	Source      string
+	CapturedAt  string
+	Title       string
+	Description string
+	Currency    string
+	Price       Money
+	Notes       string
+	Error       string
+
+	Images     []Image
+	Variations []Variation
+	Pricing    *Pricing
+	SourceIDs  *SourceIDs
+	Shop       *Shop
+
+	// Extras holds keys this package does not model, verbatim.
+	Extras map[string]json.RawMessage
+
+	present fieldSet
+}
+
+func (p *Product) fields() []field {
+	return []field{
+		{"url", &p.URL},
+		{"status", &p.Status},
+		{"source", &p.Source},
+		{"captured_at", &p.CapturedAt},
+		{"title", &p.Title},
+		{"description", &p.Description},
+		{"currency", &p.Currency},
+		{"price", &p.Price},
+		{"notes", &p.Notes},
+		{"error", &p.Error},
+		{"images", &p.Images},
+		{"variations", &p.Variations},
+		{"pricing", &p.Pricing},
+		{"source_ids", &p.SourceIDs},
+		{"shop", &p.Shop},
+	}
+}
+
+func (p Product) MarshalJSON() ([]byte, error) {
+	return encodeObject(p.fields(), p.Extras, p.present)
+}
+
+func (p *Product) UnmarshalJSON(data []byte) error {
+	return decodeObject(data, p.fields(), &p.Extras, &p.present)
+}
+
+// ImageURLs returns the product-level image URLs in order.
+func (p Product) ImageURLs() []string {
+	out := make([]string, 0, len(p.Images))
+	for _, img := range p.Images {
+		if img.URL != "" {
+			out = append(out, img.URL)
+		}
+	}
+	return out
+}
+
+// Image is a product image. The contract accepts either a bare URL string or an
+// object with url/position; the original form is kept for round-trips.
+type Image struct {
+	URL      string
+	Position *int
+
+	Extras map[string]json.RawMessage
+
+	object  bool
+	present fieldSet
+}
+
+func (img *Image) fields() []field {
+	return []field{
+		{"url", &img.URL},
+		{"position", &img.Position},
+	}
+}
+
+func (img Image) MarshalJSON() ([]byte, error) {
+	if !img.object && img.Position == nil && len(img.Extras) == 0 {
+		return json.Marshal(img.URL)
+	}
+	return encodeObject(img.fields(), img.Extras, img.present)
+}
+
+func (img *Image) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		*img = Image{}
+		return json.Unmarshal(data, &img.URL)
+	}
+	img.object = true
+	return decodeObject(data, img.fields(), &img.Extras, &img.present)
+}
+
+// Variation is one selectable option (colour, size, ...) of a product.
+type Variation struct {
+	Title    string
+	Position int
+	Images   []string
+	// Image is the legacy single-image field; prefer ImageURLs.
+	Image string
+
+	Extras map[string]json.RawMessage
+
+	present fieldSet
+}
+
+func (v *Variation) fields() []field {
+	return []field{
+		{"title", &v.Title},
+		{"position", &v.Position},
+		{"images", &v.Images},
+		{"image", &v.Image},
+	}
+}
+
+func (v Variation) MarshalJSON() ([]byte, error) {
+	return encodeObject(v.fields(), v.Extras, v.present)
+}
+
+func (v *Variation) UnmarshalJSON(data []byte) error {
+	return decodeObject(data, v.fields(), &v.Extras, &v.present)
+}
+
+// ImageURLs returns Images followed by the legacy Image, trimmed and de-duplicated.
+func (v Variation) ImageURLs() []string {
+	out := make([]string, 0, len(v.Images)+1)
+	seen := make(map[string]bool, len(v.Images)+1)
+	for _, s := range append(append([]string(nil), v.Images...), v.Image) {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// Pricing carries price ranges when a tool reports more than a single price.
+type Pricing struct {
+	Currency       string
+	SalePriceMin   Money
+	SalePriceMax   Money
+	RetailPriceMin Money
+	RetailPriceMax Money
+	ShippingFeeMin Money
+	ShippingFeeMax Money
+
+	Extras map[string]json.RawMessage
+
+	present fieldSet
+}
+
+func (pr *Pricing) fields() []field {
+	return []field{
+		{"currency", &pr.Currency},
+		{"sale_price_min", &pr.SalePriceMin},
+		{"sale_price_max", &pr.SalePriceMax},
+		{"retail_price_min", &pr.RetailPriceMin},
+		{"retail_price_max", &pr.RetailPriceMax},
+		{"shipping_fee_min", &pr.ShippingFeeMin},
+		{"shipping_fee_max", &pr.ShippingFeeMax},
+	}
+}
+
+func (pr Pricing) MarshalJSON() ([]byte, error) {
+	return encodeObject(pr.fields(), pr.Extras, pr.present)
+}
+
+func (pr *Pricing) UnmarshalJSON(data []byte) error {
+	return decodeObject(data, pr.fields(), &pr.Extras, &pr.present)
+}
+
+// SourceIDs are the marketplace identifiers of the product.
+type SourceIDs struct {
+	Platform string
+	ShopID   string
+	ItemID   string
+
+	Extras map[string]json.RawMessage
+
+	present fieldSet
+}
+
+func (s *SourceIDs) fields() []field {
+	return []field{
+		{"platform", &s.Platform},
+		{"shop_id", &s.ShopID},
+		{"item_id", &s.ItemID},
+	}
+}
+
+func (s SourceIDs) MarshalJSON() ([]byte, error) {
+	return encodeObject(s.fields(), s.Extras, s.present)
+}
+
+func (s *SourceIDs) UnmarshalJSON(data []byte) error {
+	return decodeObject(data, s.fields(), &s.Extras, &s.present)
+}
+
+// Shop is the seller of the product.
+type Shop struct {
+	Name string
+
+	Extras map[string]json.RawMessage
+
+	present fieldSet
+}
+
+func (s *Shop) fields() []field {
+	return []field{
+		{"name", &s.Name},
+	}
+}
+
+func (s Shop) MarshalJSON() ([]byte, error) {
+	return encodeObject(s.fields(), s.Extras, s.present)
+}
+
+func (s *Shop) UnmarshalJSON(data []byte) error {
+	return decodeObject(data, s.fields(), &s.Extras, &s.present)
+}
+
+// Parse decodes a crawl result JSON object.
+func Parse(data []byte) (Product, error) {
+	var p Product
+	if err := json.Unmarshal(data, &p); err != nil {
+		return Product{}, fmt.Errorf("decode product: %w", err)
+	}
+	return p, nil
+}
+
+// FromMap converts a generic result map (e.g. runner.Result) into a Product.
+func FromMap(m map[string]any) (Product, error) {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return Product{}, fmt.Errorf("encode product map: %w", err)
+	}
+	return Parse(b)
+}
+
+// Map converts p back into a generic map. Numbers decode as json.Number so the
+// original literals survive another round-trip.
+func (p Product) Map() (map[string]any, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("encode product: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode product map: %w", err)
+	}
+	return m, nil
+}
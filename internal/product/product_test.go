@@ -0,0 +1,109 @@
+package product
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_RoundTripIsLossless(t *testing.T) {
+	t.Parallel()
+
+	const raw = `{
+  "url": "https://shopee.tw/product/1/2",
+  "status": "ok",
+  "source": "shopee",
+  "captured_at": "2026-01-21T04:24:31.695Z",
+  "title": "Socks",
+  "description": "",
+  "currency": "TWD",
+  "price": "199.00",
+  "images": ["https://img/1", {"url": "https://img/2", "position": 1}],
+  "variations": [
+    {"title": "red", "position": 0, "images": ["https://img/r"], "image": null, "sku": "R-1"},
+    {"title": "blue", "position": "1"}
+  ],
+  "pricing": {"currency": "TWD", "sale_price_min": 199, "sale_price_max": "249.5", "retail_price_min": null},
+  "source_ids": {"platform": "shopee", "shop_id": "1", "item_id": "2"},
+  "shop": {"name": "Sock Shop", "rating": 4.9},
+  "attempts": [{"tool": "codex", "status": "ok"}]
+}`
+
+	p, err := Parse([]byte(raw))
+	require.NoError(t, err)
+
+	require.Equal(t, StatusOK, p.Status)
+	require.Equal(t, "199.00", p.Price.String())
+	require.Equal(t, []string{"https://img/1", "https://img/2"}, p.ImageURLs())
+	require.Len(t, p.Variations, 2)
+	require.Equal(t, "red", p.Variations[0].Title)
+	require.Equal(t, 0, p.Variations[1].Position, "mis-typed position stays in extras")
+	require.Contains(t, p.Variations[1].Extras, "position")
+	require.NotNil(t, p.Pricing)
+	got, err := p.Pricing.SalePriceMax.Float64()
+	require.NoError(t, err)
+	require.Equal(t, 249.5, got)
+	require.True(t, p.Pricing.RetailPriceMin.IsNull())
+	require.Equal(t, "2", p.SourceIDs.ItemID)
+	require.Equal(t, "Sock Shop", p.Shop.Name)
+	require.Contains(t, p.Extras, "attempts")
+
+	out, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, raw, string(out))
+}
+
+func TestProduct_MarshalOmitsUnsetFields(t *testing.T) {
+	t.Parallel()
+
+	p := Product{
+		URL:    "https://item.taobao.com/item.htm?id=1",
+		Status: StatusError,
+		Error:  "boom",
+		Price:  NewMoney(12.5),
+		Images: []Image{{URL: "https://img/1"}},
+	}
+
+	out, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+  "url": "https://item.taobao.com/item.htm?id=1",
+  "status": "error",
+  "error": "boom",
+  "price": 12.5,
+  "images": ["https://img/1"]
+}`, string(out))
+}
+
+func TestFromMap_MapRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	in := map[string]any{
+		"url":    "https://shopee.tw/product/1/2",
+		"status": "needs_manual",
+		"notes":  "captcha",
+		"price":  25,
+		"extra":  map[string]any{"k": "v"},
+	}
+
+	p, err := FromMap(in)
+	require.NoError(t, err)
+	require.Equal(t, StatusNeedsManual, p.Status)
+	require.Equal(t, "captcha", p.Notes)
+	require.Equal(t, "25", p.Price.String())
+
+	p.Currency = "TWD"
+	m, err := p.Map()
+	require.NoError(t, err)
+	require.Equal(t, json.Number("25"), m["price"])
+	require.Equal(t, "TWD", m["currency"])
+	require.Equal(t, map[string]any{"k": "v"}, m["extra"])
+}
+
+func TestVariation_ImageURLsMergesLegacyImage(t *testing.T) {
+	t.Parallel()
+
+	v := Variation{Images: []string{" https://img/a ", "https://img/b"}, Image: "https://img/a"}
+	require.Equal(t, []string{"https://img/a", "https://img/b"}, v.ImageURLs())
+}
@@ -26,7 +26,6 @@ type CodexRunner struct {
 	workDir          string
 	logger           *zap.SugaredLogger
 
-	execCommand        func(name string, args ...string) *exec.Cmd
 	execCommandContext func(ctx context.Context, name string, args ...string) *exec.Cmd
 }
 
@@ -41,14 +40,13 @@ func NewCodexRunner(cfg CodexRunnerConfig) *CodexRunner {
 		skipGitRepoCheck:   cfg.SkipGitRepoCheck,
 		workDir:            resolveRunnerWorkDir(cfg.WorkDir),
 		logger:             logger,
-		execCommand:        exec.Command,
 		execCommandContext: exec.CommandContext,
 	}
 }
 
 func (r *CodexRunner) Name() string { return "codex" }
 
-func (r *CodexRunner) CheckAuth() error {
+func (r *CodexRunner) CheckAuth(ctx context.Context) error {
 	path, pathErr := resolveHomePath(".codex/auth.json")
 	if pathErr != "" {
 		return fmt.Errorf("codex auth file path error: %s", pathErr)
@@ -62,38 +60,39 @@ func (r *CodexRunner) CheckAuth() error {
 		return fmt.Errorf("codex auth file not found")
 	}
 
-	ok, probeErr := r.runAuthProbe()
+	ok, probeErr := r.runAuthProbe(ctx)
 	if !ok {
 		return fmt.Errorf("codex auth probe failed: %s", probeErr)
 	}
 	return nil
 }
 
-func (r *CodexRunner) Run(url string, prompt string) (string, error) {
-	modelText, err := r.runModelText(url, prompt)
+func (r *CodexRunner) Run(ctx context.Context, url string, prompt string) (string, error) {
+	modelText, err := r.runModelText(ctx, url, prompt)
 	if err != nil {
 		return "", err
 	}
 
 	if _, err := extractJSONObjectWithStatus(modelText); err != nil {
-		return "", fmt.Errorf("codex returned non-JSON output: %w", err)
+		return "", fmt.Errorf("codex returned %w: %w", ErrNonJSONOutput, err)
 	}
 	r.logCodexOutput(url, modelText)
 	return modelText, nil
 }
 
-func (r *CodexRunner) runModelText(url string, prompt string) (string, error) {
+func (r *CodexRunner) runModelText(ctx context.Context, url string, prompt string) (string, error) {
 	// Codex CLI expects exec-scoped flags after the subcommand:
 	//   codex exec --skip-git-repo-check --model <model> "<prompt>"
 	args := []string{"exec"}
 	if r.skipGitRepoCheck {
 		args = append(args, "--skip-git-repo-check")
 	}
-	if r.model != "" {
-		args = append(args, "--model", r.model)
+	model := modelFor(ctx, r.model)
+	if model != "" {
+		args = append(args, "--model", model)
 	}
 
-	r.logger.Infof("🏃🏻 running on model: %v", r.model)
+	r.logger.Infof("🏃🏻 running on model: %v", model)
 
 	args = append(args, prompt)
 	start := time.Now()
@@ -101,10 +100,10 @@ func (r *CodexRunner) runModelText(url string, prompt string) (string, error) {
 		"crawl_started",
 		"tool", "codex",
 		"url", url,
-		"model", r.model,
+		"model", model,
 	)
 
-	cmd := r.execCommand(r.cmd, args...)
+	cmd := newToolCommand(ctx, r.execCommandContext, r.cmd, args...)
 	if r.workDir != "" {
 		cmd.Dir = r.workDir
 	}
@@ -119,6 +118,9 @@ func (r *CodexRunner) runModelText(url string, prompt string) (string, error) {
 			"duration", time.Since(start).Round(time.Millisecond).String(),
 			"err", err.Error(),
 		)
+		if cerr := runContextErr(ctx); cerr != nil {
+			return "", fmt.Errorf("codex exec failed: %w", cerr)
+		}
 		return "", fmt.Errorf("codex exec failed: %s", err.Error())
 	}
 
@@ -132,24 +134,25 @@ func (r *CodexRunner) runModelText(url string, prompt string) (string, error) {
 	return stdout.String(), nil
 }
 
-func (r *CodexRunner) runAuthProbe() (bool, string) {
+func (r *CodexRunner) runAuthProbe(ctx context.Context) (bool, string) {
 	if strings.TrimSpace(r.cmd) == "" {
 		return false, "missing codex command"
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
 	defer cancel()
 
 	args := []string{"exec"}
 	if r.skipGitRepoCheck {
 		args = append(args, "--skip-git-repo-check")
 	}
-	if r.model != "" {
-		args = append(args, "--model", r.model)
+	model := modelFor(ctx, r.model)
+	if model != "" {
+		args = append(args, "--model", model)
 	}
 	args = append(args, "Return exactly: OK")
 
-	cmd := r.execCommandContext(ctx, r.cmd, args...)
+	cmd := newToolCommand(ctx, r.execCommandContext, r.cmd, args...)
 	if r.workDir != "" {
 		cmd.Dir = r.workDir
 	}
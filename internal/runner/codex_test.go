@@ -1,12 +1,15 @@
 package runner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap"
 )
@@ -26,10 +29,10 @@ func TestCodexRunner_Run_ReturnsExtractedJSON_NoRepair(t *testing.T) {
 	})
 
 	callIdx := 0
-	r.execCommand = func(_ string, args ...string) *exec.Cmd {
+	r.execCommandContext = func(ctx context.Context, _ string, args ...string) *exec.Cmd {
 		calls = append(calls, append([]string(nil), args...))
 
-		cmd := exec.Command(os.Args[0], "-test.run=TestCodexRunnerHelperProcess", "--")
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=TestCodexRunnerHelperProcess", "--")
 		cmd.Env = append(os.Environ(),
 			"GO_WANT_HELPER_PROCESS=1",
 			fmt.Sprintf("HELPER_STDOUT=%s", outputs[callIdx]),
@@ -40,7 +43,7 @@ func TestCodexRunner_Run_ReturnsExtractedJSON_NoRepair(t *testing.T) {
 		return cmd
 	}
 
-	got, err := r.Run("https://example.com", `{"prompt":"x"}`)
+	got, err := r.Run(context.Background(), "https://example.com", `{"prompt":"x"}`)
 	if err != nil {
 		t.Fatalf("Run error: %v", err)
 	}
@@ -72,10 +75,10 @@ func TestCodexRunner_Run_NoRetryWhenInvalidJSON(t *testing.T) {
 	})
 
 	callIdx := 0
-	r.execCommand = func(_ string, args ...string) *exec.Cmd {
+	r.execCommandContext = func(ctx context.Context, _ string, args ...string) *exec.Cmd {
 		calls = append(calls, append([]string(nil), args...))
 
-		cmd := exec.Command(os.Args[0], "-test.run=TestCodexRunnerHelperProcess", "--")
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=TestCodexRunnerHelperProcess", "--")
 		cmd.Env = append(os.Environ(),
 			"GO_WANT_HELPER_PROCESS=1",
 			fmt.Sprintf("HELPER_STDOUT=%s", outputs[callIdx]),
@@ -86,7 +89,7 @@ func TestCodexRunner_Run_NoRetryWhenInvalidJSON(t *testing.T) {
 		return cmd
 	}
 
-	_, err := r.Run("https://example.com/p/1", "original prompt")
+	_, err := r.Run(context.Background(), "https://example.com/p/1", "original prompt")
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -112,8 +115,8 @@ func TestCodexRunner_Run_RunModelError(t *testing.T) {
 	})
 
 	callIdx := 0
-	r.execCommand = func(_ string, args ...string) *exec.Cmd {
-		cmd := exec.Command(os.Args[0], "-test.run=TestCodexRunnerHelperProcess", "--")
+	r.execCommandContext = func(ctx context.Context, _ string, args ...string) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=TestCodexRunnerHelperProcess", "--")
 		cmd.Env = append(os.Environ(),
 			"GO_WANT_HELPER_PROCESS=1",
 			fmt.Sprintf("HELPER_STDOUT=%s", outputs[callIdx]),
@@ -124,7 +127,7 @@ func TestCodexRunner_Run_RunModelError(t *testing.T) {
 		return cmd
 	}
 
-	_, err := r.Run("https://example.com", "prompt")
+	_, err := r.Run(context.Background(), "https://example.com", "prompt")
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -136,11 +139,49 @@ func TestCodexRunner_Run_RunModelError(t *testing.T) {
 	}
 }
 
+func TestCodexRunner_Run_TimeoutKillsProcess(t *testing.T) {
+	t.Parallel()
+
+	r := NewCodexRunner(CodexRunnerConfig{
+		Cmd:    "codex",
+		Logger: zap.NewNop().Sugar(),
+	})
+
+	r.execCommandContext = func(ctx context.Context, _ string, _ ...string) *exec.Cmd {
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=TestCodexRunnerHelperProcess", "--")
+		cmd.Env = append(os.Environ(),
+			"GO_WANT_HELPER_PROCESS=1",
+			"HELPER_SLEEP=30s",
+			"HELPER_EXIT=0",
+		)
+		return cmd
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := r.Run(ctx, "https://example.com", "prompt")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !errors.Is(err, ErrToolTimeout) {
+		t.Fatalf("expected ErrToolTimeout, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("run was not interrupted promptly: %s", elapsed)
+	}
+}
+
 func TestCodexRunnerHelperProcess(t *testing.T) {
 	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
 		return
 	}
 
+	if d, err := time.ParseDuration(os.Getenv("HELPER_SLEEP")); err == nil {
+		time.Sleep(d)
+	}
+
 	_, _ = os.Stdout.WriteString(os.Getenv("HELPER_STDOUT"))
 	_, _ = os.Stderr.WriteString(os.Getenv("HELPER_STDERR"))
 
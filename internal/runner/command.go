@@ -0,0 +1,288 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Output formats understood by CommandRunner.
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
+// CommandOutputSpec declares how to turn a tool's stdout into model text.
+//
+//   - format "text" (default): stdout is the model text.
+//   - format "json": stdout is a JSON wrapper object and Field is a dot-separated path
+//     to the model text inside it (e.g. "response" for `gemini -o json`).
+type CommandOutputSpec struct {
+	Format string `json:"format"`
+	Field  string `json:"field,omitempty"`
+}
+
+// CommandRunnerSpec describes an agent CLI invoked from an argv template.
+//
+// Args elements may contain the placeholders {prompt}, {model}, {workdir} and {url}.
+// An element prefixed with "?" is optional: it is dropped when any placeholder in it
+// expands to an empty string (e.g. "?--model={model}" when no model is configured).
+type CommandRunnerSpec struct {
+	Name     string            `json:"name"`
+	Cmd      string            `json:"cmd"`
+	Args     []string          `json:"args"`
+	AuthArgs []string          `json:"auth_args,omitempty"`
+	Model    string            `json:"model,omitempty"`
+	WorkDir  string            `json:"workdir,omitempty"`
+	Env      map[string]string `json:"env,omitempty"`
+	Output   CommandOutputSpec `json:"output"`
+}
+
+type commandToolsFile struct {
+	Tools []CommandRunnerSpec `json:"tools"`
+}
+
+// LoadCommandRunnerSpecs reads a JSON file of the form {"tools": [CommandRunnerSpec, ...]}.
+func LoadCommandRunnerSpecs(path string) ([]CommandRunnerSpec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read command tools file: %w", err)
+	}
+
+	var f commandToolsFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("parse command tools file %s: %w", path, err)
+	}
+
+	for i, spec := range f.Tools {
+		if err := spec.validate(); err != nil {
+			return nil, fmt.Errorf("command tool #%d: %w", i, err)
+		}
+	}
+	return f.Tools, nil
+}
+
+func (s CommandRunnerSpec) validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return fmt.Errorf("missing name")
+	}
+	if strings.TrimSpace(s.Cmd) == "" {
+		return fmt.Errorf("tool %q: missing cmd", s.Name)
+	}
+	if len(s.Args) == 0 {
+		return fmt.Errorf("tool %q: missing args template", s.Name)
+	}
+	if !argsHavePlaceholder(s.Args, "{prompt}") {
+		return fmt.Errorf("tool %q: args template must contain {prompt}", s.Name)
+	}
+	switch strings.TrimSpace(s.Output.Format) {
+	case "", OutputFormatText:
+	case OutputFormatJSON:
+		if strings.TrimSpace(s.Output.Field) == "" {
+			return fmt.Errorf("tool %q: output format json requires a field path", s.Name)
+		}
+	default:
+		return fmt.Errorf("tool %q: unknown output format %q", s.Name, s.Output.Format)
+	}
+	return nil
+}
+
+type CommandRunnerConfig struct {
+	Spec   CommandRunnerSpec
+	Logger *zap.SugaredLogger
+}
+
+// CommandRunner is a ToolRunner for any agent CLI described by a CommandRunnerSpec.
+type CommandRunner struct {
+	spec    CommandRunnerSpec
+	workDir string
+	logger  *zap.SugaredLogger
+
+	execCommandContext func(ctx context.Context, name string, args ...string) *exec.Cmd
+}
+
+func NewCommandRunner(cfg CommandRunnerConfig) *CommandRunner {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop().Sugar()
+	}
+	return &CommandRunner{
+		spec:               cfg.Spec,
+		workDir:            resolveRunnerWorkDir(cfg.Spec.WorkDir),
+		logger:             logger,
+		execCommandContext: exec.CommandContext,
+	}
+}
+
+func (r *CommandRunner) Name() string { return r.spec.Name }
+
+func (r *CommandRunner) CheckAuth(ctx context.Context) error {
+	if len(r.spec.AuthArgs) == 0 {
+		if _, err := exec.LookPath(r.spec.Cmd); err != nil {
+			return fmt.Errorf("%s command not found: %w", r.spec.Name, err)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	args := expandArgsTemplate(r.spec.AuthArgs, r.placeholders("", "Return exactly: OK", modelFor(ctx, r.spec.Model)))
+	if _, err := r.exec(ctx, args); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s auth probe failed: timeout", r.spec.Name)
+		}
+		return fmt.Errorf("%s auth probe failed: %s", r.spec.Name, err.Error())
+	}
+	return nil
+}
+
+func (r *CommandRunner) Run(ctx context.Context, url string, prompt string) (string, error) {
+	model := modelFor(ctx, r.spec.Model)
+	args := expandArgsTemplate(r.spec.Args, r.placeholders(url, prompt, model))
+
+	start := time.Now()
+	r.logger.Infow(
+		"crawl_started",
+		"tool", r.spec.Name,
+		"url", url,
+		"model", model,
+	)
+
+	raw, err := r.exec(ctx, args)
+	if err != nil {
+		r.logger.Infow(
+			"crawl_failed",
+			"tool", r.spec.Name,
+			"url", url,
+			"duration", time.Since(start).Round(time.Millisecond).String(),
+			"err", err.Error(),
+		)
+		if cerr := runContextErr(ctx); cerr != nil {
+			return "", fmt.Errorf("%s failed: %w", r.spec.Name, cerr)
+		}
+		return "", fmt.Errorf("%s failed: %s", r.spec.Name, err.Error())
+	}
+
+	r.logger.Infow(
+		"crawl_finished",
+		"tool", r.spec.Name,
+		"url", url,
+		"duration", time.Since(start).Round(time.Millisecond).String(),
+	)
+
+	modelText, err := unwrapCommandOutput(r.spec.Output, raw)
+	if err != nil {
+		return "", fmt.Errorf("%s output unwrap failed: %w", r.spec.Name, err)
+	}
+	r.logger.Debugw(
+		"llm_output",
+		"tool", r.spec.Name,
+		"url", url,
+		"truncated", len(modelText) > 8000,
+		"output", previewText(modelText, 8000),
+	)
+
+	if _, err := extractJSONObjectWithStatus(modelText); err != nil {
+		return "", fmt.Errorf("%s returned %w: %w", r.spec.Name, ErrNonJSONOutput, err)
+	}
+	return modelText, nil
+}
+
+func (r *CommandRunner) exec(ctx context.Context, args []string) (string, error) {
+	cmd := newToolCommand(ctx, r.execCommandContext, r.spec.Cmd, args...)
+	if r.workDir != "" {
+		cmd.Dir = r.workDir
+	}
+	if len(r.spec.Env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range r.spec.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, previewText(msg, 500))
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func (r *CommandRunner) placeholders(url string, prompt string, model string) map[string]string {
+	return map[string]string{
+		"{prompt}":  prompt,
+		"{model}":   strings.TrimSpace(model),
+		"{workdir}": r.workDir,
+		"{url}":     url,
+	}
+}
+
+// expandArgsTemplate substitutes placeholders in each argv element in a single pass
+// (so placeholder-like text inside the prompt is left alone). Elements prefixed with
+// "?" are dropped when any placeholder they reference is empty.
+func expandArgsTemplate(tmpl []string, values map[string]string) []string {
+	pairs := make([]string, 0, len(values)*2)
+	for k, v := range values {
+		pairs = append(pairs, k, v)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	out := make([]string, 0, len(tmpl))
+	for _, arg := range tmpl {
+		if strings.HasPrefix(arg, "?") {
+			arg = strings.TrimPrefix(arg, "?")
+			if referencesEmptyPlaceholder(arg, values) {
+				continue
+			}
+		}
+		out = append(out, replacer.Replace(arg))
+	}
+	return out
+}
+
+func referencesEmptyPlaceholder(arg string, values map[string]string) bool {
+	for k, v := range values {
+		if v == "" && strings.Contains(arg, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func argsHavePlaceholder(args []string, key string) bool {
+	for _, a := range args {
+		if strings.Contains(a, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func unwrapCommandOutput(spec CommandOutputSpec, raw string) (string, error) {
+	switch strings.TrimSpace(spec.Format) {
+	case "", OutputFormatText:
+		return raw, nil
+	case OutputFormatJSON:
+		if s, ok := unwrapJSONField(raw, spec.Field); ok {
+			return s, nil
+		}
+		return "", fmt.Errorf("field %q not found in JSON output", spec.Field)
+	default:
+		return "", fmt.Errorf("unknown output format %q", spec.Format)
+	}
+}
+
+var _ ToolRunner = (*CommandRunner)(nil)
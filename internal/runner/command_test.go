@@ -0,0 +1,213 @@
+package runner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// writeStandInTool writes an executable shell script that echoes its argv to argsFile
+// and prints stdout.
+func writeStandInTool(t *testing.T, dir string, stdout string, exitCode int) (bin string, argsFile string) {
+	t.Helper()
+
+	bin = filepath.Join(dir, "fake-agent")
+	argsFile = filepath.Join(dir, "args.txt")
+	outFile := filepath.Join(dir, "stdout.txt")
+	if err := os.WriteFile(outFile, []byte(stdout), 0o644); err != nil {
+		t.Fatalf("write stdout fixture: %v", err)
+	}
+
+	script := "#!/bin/sh\n" +
+		"for a in \"$@\"; do printf '%s\\n' \"$a\"; done > '" + argsFile + "'\n" +
+		"cat '" + outFile + "'\n" +
+		"exit " + strconv.Itoa(exitCode) + "\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatalf("write stand-in tool: %v", err)
+	}
+	return bin, argsFile
+}
+
+func readArgs(t *testing.T, path string) []string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read args: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+const commandContractJSON = `{"status":"ok","url":"https://example.com","captured_at":"2026-01-01T00:00:00Z","title":"t"}`
+
+func TestCommandRunner_Run_TextOutput(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	bin, argsFile := writeStandInTool(t, dir, "thinking...\n"+commandContractJSON+"\n", 0)
+
+	r := NewCommandRunner(CommandRunnerConfig{
+		Spec: CommandRunnerSpec{
+			Name:    "fake",
+			Cmd:     bin,
+			Args:    []string{"run", "?--model={model}", "--cwd", "{workdir}", "{prompt}"},
+			WorkDir: dir,
+		},
+		Logger: zap.NewNop().Sugar(),
+	})
+
+	got, err := r.Run(context.Background(), "https://example.com", "crawl {url} please")
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if _, err := extractJSONObjectWithStatus(got); err != nil {
+		t.Fatalf("expected contract JSON in output: %v", err)
+	}
+
+	want := []string{"run", "--cwd", dir, "crawl {url} please"}
+	if args := readArgs(t, argsFile); !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+func TestCommandRunner_Run_ModelOverride(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	bin, argsFile := writeStandInTool(t, dir, commandContractJSON+"\n", 0)
+
+	r := NewCommandRunner(CommandRunnerConfig{
+		Spec: CommandRunnerSpec{
+			Name:  "fake",
+			Cmd:   bin,
+			Model: "configured",
+			Args:  []string{"run", "?--model={model}", "{prompt}"},
+		},
+		Logger: zap.NewNop().Sugar(),
+	})
+
+	if _, err := r.Run(withModelOverride(context.Background(), "override"), "https://example.com", "p"); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	want := []string{"run", "--model=override", "p"}
+	if args := readArgs(t, argsFile); !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+func TestCommandRunner_Run_JSONWrapperOutput(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	wrapper := `{"type":"result","result":{"text":` + strconv.Quote(commandContractJSON) + `}}`
+	bin, argsFile := writeStandInTool(t, dir, wrapper, 0)
+
+	r := NewCommandRunner(CommandRunnerConfig{
+		Spec: CommandRunnerSpec{
+			Name:    "fake",
+			Cmd:     bin,
+			Args:    []string{"-p", "{prompt}", "?--model={model}"},
+			Model:   "m-1",
+			WorkDir: dir,
+			Output:  CommandOutputSpec{Format: OutputFormatJSON, Field: "result.text"},
+		},
+		Logger: zap.NewNop().Sugar(),
+	})
+
+	got, err := r.Run(context.Background(), "https://example.com", "prompt")
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if got != commandContractJSON {
+		t.Fatalf("unexpected output: %s", got)
+	}
+
+	want := []string{"-p", "prompt", "--model=m-1"}
+	if args := readArgs(t, argsFile); !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+func TestCommandRunner_Run_NonZeroExit(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	bin, _ := writeStandInTool(t, dir, "", 3)
+
+	r := NewCommandRunner(CommandRunnerConfig{
+		Spec: CommandRunnerSpec{
+			Name:    "fake",
+			Cmd:     bin,
+			Args:    []string{"{prompt}"},
+			WorkDir: dir,
+		},
+	})
+
+	_, err := r.Run(context.Background(), "https://example.com", "prompt")
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "fake failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommandRunner_Run_MissingWrapperField(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	bin, _ := writeStandInTool(t, dir, `{"other":"x"}`, 0)
+
+	r := NewCommandRunner(CommandRunnerConfig{
+		Spec: CommandRunnerSpec{
+			Name:    "fake",
+			Cmd:     bin,
+			Args:    []string{"{prompt}"},
+			WorkDir: dir,
+			Output:  CommandOutputSpec{Format: OutputFormatJSON, Field: "response"},
+		},
+	})
+
+	_, err := r.Run(context.Background(), "https://example.com", "prompt")
+	if err == nil || !strings.Contains(err.Error(), "output unwrap failed") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLoadCommandRunnerSpecs(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tools.json")
+	body := `{"tools":[{"name":"claude","cmd":"claude","args":["-p","{prompt}"],"output":{"format":"json","field":"result"}}]}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	specs, err := LoadCommandRunnerSpecs(path)
+	if err != nil {
+		t.Fatalf("LoadCommandRunnerSpecs error: %v", err)
+	}
+	if len(specs) != 1 || specs[0].Name != "claude" || specs[0].Output.Field != "result" {
+		t.Fatalf("unexpected specs: %#v", specs)
+	}
+}
+
+func TestLoadCommandRunnerSpecs_RejectsTemplateWithoutPrompt(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tools.json")
+	body := `{"tools":[{"name":"x","cmd":"x","args":["run"]}]}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := LoadCommandRunnerSpecs(path); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,119 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Change is one differing leaf between two final.json documents. Old or New is
+// nil when the value is absent on that side.
+type Change struct {
+	Path string
+	Old  json.RawMessage
+	New  json.RawMessage
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, rawOrNone(c.Old), rawOrNone(c.New))
+}
+
+func rawOrNone(r json.RawMessage) string {
+	if r == nil {
+		return "(none)"
+	}
+	return string(r)
+}
+
+// Diff compares two final.json documents field by field, descending into
+// objects and arrays (e.g. "variations[1].price"). An empty old document
+// reports every field of new as added.
+func Diff(oldJSON, newJSON []byte) ([]Change, error) {
+	var oldV, newV any
+	if len(bytes.TrimSpace(oldJSON)) > 0 {
+		if err := decodeJSON(oldJSON, &oldV); err != nil {
+			return nil, fmt.Errorf("decode old final: %w", err)
+		}
+	} else {
+		oldV = map[string]any{}
+	}
+	if err := decodeJSON(newJSON, &newV); err != nil {
+		return nil, fmt.Errorf("decode new final: %w", err)
+	}
+
+	var out []Change
+	diffValue("", oldV, newV, true, true, &out)
+	return out, nil
+}
+
+func decodeJSON(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func diffValue(path string, oldV, newV any, hasOld, hasNew bool, out *[]Change) {
+	if hasOld && hasNew {
+		oldObj, oldIsObj := oldV.(map[string]any)
+		newObj, newIsObj := newV.(map[string]any)
+		if oldIsObj && newIsObj {
+			keys := make([]string, 0, len(oldObj)+len(newObj))
+			for k := range oldObj {
+				keys = append(keys, k)
+			}
+			for k := range newObj {
+				if _, ok := oldObj[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				o, okOld := oldObj[k]
+				n, okNew := newObj[k]
+				diffValue(joinPath(path, k), o, n, okOld, okNew, out)
+			}
+			return
+		}
+
+		oldArr, oldIsArr := oldV.([]any)
+		newArr, newIsArr := newV.([]any)
+		if oldIsArr && newIsArr {
+			for i := 0; i < max(len(oldArr), len(newArr)); i++ {
+				var o, n any
+				if i < len(oldArr) {
+					o = oldArr[i]
+				}
+				if i < len(newArr) {
+					n = newArr[i]
+				}
+				diffValue(path+"["+strconv.Itoa(i)+"]", o, n, i < len(oldArr), i < len(newArr), out)
+			}
+			return
+		}
+	}
+
+	oldRaw, newRaw := rawOf(oldV, hasOld), rawOf(newV, hasNew)
+	if !bytes.Equal(oldRaw, newRaw) {
+		*out = append(*out, Change{Path: path, Old: oldRaw, New: newRaw})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func rawOf(v any, ok bool) json.RawMessage {
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage(strconv.Quote(fmt.Sprint(v)))
+	}
+	return b
+}
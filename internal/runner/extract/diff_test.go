@@ -0,0 +1,45 @@
+package extract
+
+import (
+	"testing"
+)
+
+func TestDiff_ReportsLeafChanges(t *testing.T) {
+	oldJSON := []byte(`{"status":"ok","price":"1290","images":["a"],"variations":[{"title":"黑色","price":"1290"}],"notes":""}`)
+	newJSON := []byte(`{"status":"ok","price":"1190","images":["a","b"],"variations":[{"title":"黑色","price":"1190"}],"notes":"","run_id":"r1"}`)
+
+	changes, err := Diff(oldJSON, newJSON)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+
+	got := make([]string, 0, len(changes))
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		`images[1]: (none) -> "b"`,
+		`price: "1290" -> "1190"`,
+		`run_id: (none) -> "r1"`,
+		`variations[0].price: "1290" -> "1190"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("changes[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiff_IdenticalAndMissingOld(t *testing.T) {
+	doc := []byte(`{"status":"ok","price":"1"}`)
+	if changes, err := Diff(doc, doc); err != nil || len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v (err=%v)", changes, err)
+	}
+	changes, err := Diff(nil, doc)
+	if err != nil || len(changes) != 2 {
+		t.Fatalf("expected every field added, got %v (err=%v)", changes, err)
+	}
+}
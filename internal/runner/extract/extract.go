@@ -0,0 +1,158 @@
+// Package extract implements the offline stages of the snapshot-first pipeline
+// (core_extract, images_extract, variations_extract, variation_image_map_extract)
+// natively in Go. Each stage reads the s0-*.html(.gz) artifacts of one run and
+// produces the same stage JSON the skill scripts under codex/.codex/skills write.
+package extract
+
+import (
+	"fmt"
+	"strings"
+
+	"peasydeal-product-miner/internal/source"
+)
+
+// Stage names as used in _pipeline-state.json.
+const (
+	StageSnapshotCapture   = "snapshot_capture"
+	StageCore              = "core_extract"
+	StageImages            = "images_extract"
+	StageVariations        = "variations_extract"
+	StageVariationImageMap = "variation_image_map_extract"
+	StageFinalMerge        = "final_merge"
+)
+
+// Stages lists the pipeline stages in execution order.
+var Stages = []string{
+	StageSnapshotCapture,
+	StageCore,
+	StageImages,
+	StageVariations,
+	StageVariationImageMap,
+}
+
+// Artifact file names under out/artifacts/<run_id>/.
+const (
+	PointerFile           = "s0-snapshot-pointer.json"
+	ManifestFile          = "s0-manifest.json"
+	CoreFile              = "core_extract.json"
+	ImagesFile            = "images_extract.json"
+	VariationsFile        = "variations_extract.json"
+	VariationImageMapFile = "variation_image_map_extract.json"
+	PipelineStateFile     = "_pipeline-state.json"
+	MetaFile              = "meta.json"
+	FinalFile             = "final.json"
+)
+
+// Stage statuses reported by extractors.
+const (
+	StatusOK          = "ok"
+	StatusNeedsManual = "needs_manual"
+	StatusError       = "error"
+)
+
+const (
+	maxImages     = 20
+	maxVariations = 20
+	descMaxChars  = 1500
+	titleMaxChars = 300
+)
+
+// CoreResult is core_extract.json.
+type CoreResult struct {
+	Status      string `json:"status"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Currency    string `json:"currency"`
+	Price       string `json:"price"`
+	Notes       string `json:"notes"`
+	Error       string `json:"error"`
+}
+
+// ImagesResult is images_extract.json.
+type ImagesResult struct {
+	Status string   `json:"status"`
+	Images []string `json:"images"`
+	Error  string   `json:"error"`
+}
+
+// VariationPrice is one row of variations_extract.json.
+type VariationPrice struct {
+	Title    string `json:"title"`
+	Position int    `json:"position"`
+	Price    string `json:"price"`
+}
+
+// VariationsResult is variations_extract.json.
+type VariationsResult struct {
+	Status     string           `json:"status"`
+	Variations []VariationPrice `json:"variations"`
+	Error      string           `json:"error"`
+}
+
+// VariationImages is one row of variation_image_map_extract.json.
+type VariationImages struct {
+	Title    string   `json:"title"`
+	Position int      `json:"position"`
+	Images   []string `json:"images"`
+}
+
+// VariationImageMapResult is variation_image_map_extract.json.
+type VariationImageMapResult struct {
+	Status     string            `json:"status"`
+	Variations []VariationImages `json:"variations"`
+	Error      string            `json:"error"`
+}
+
+// Extractor runs the offline stages for one marketplace against an artifact
+// directory. Stage failures are reported in the result's Status/Error; the
+// variation image map may read variations_extract.json written before it.
+type Extractor interface {
+	Core(artifactDir string) CoreResult
+	Images(artifactDir string) ImagesResult
+	Variations(artifactDir string) VariationsResult
+	VariationImageMap(artifactDir string) VariationImageMapResult
+}
+
+// For returns the extractor for src.
+func For(src source.Source) (Extractor, error) {
+	switch src {
+	case source.Shopee:
+		return shopee{}, nil
+	case source.Taobao:
+		return taobao{}, nil
+	default:
+		return nil, fmt.Errorf("no offline extractor for source %q", src)
+	}
+}
+
+func coreError(err error) CoreResult {
+	return CoreResult{Status: StatusError, Error: err.Error()}
+}
+
+func imagesError(err error) ImagesResult {
+	return ImagesResult{Status: StatusError, Images: []string{}, Error: err.Error()}
+}
+
+func variationsError(err error) VariationsResult {
+	return VariationsResult{Status: StatusError, Variations: []VariationPrice{}, Error: err.Error()}
+}
+
+func variationImageMapError(err error) VariationImageMapResult {
+	return VariationImageMapResult{Status: StatusError, Variations: []VariationImages{}, Error: err.Error()}
+}
+
+// coreFromFields applies the shared status rules of both core extractors.
+func coreFromFields(title, description, currency, price string) CoreResult {
+	res := CoreResult{
+		Status:      StatusOK,
+		Title:       clean(title, titleMaxChars),
+		Description: clean(description, descMaxChars),
+		Currency:    strings.ToUpper(clean(currency, 8)),
+		Price:       clean(price, 64),
+	}
+	if res.Title == "" || res.Description == "" || res.Currency == "" || res.Price == "" {
+		res.Status = StatusError
+		res.Error = "incomplete core fields from html artifact"
+	}
+	return res
+}
@@ -0,0 +1,335 @@
+package extract
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newArtifactDir gzips the HTML fixtures under testdata/<fixture> into a fresh
+// out/artifacts/<run_id> directory, copies any _variation<n>_capture.json and
+// writes the S0 pointer and manifest.
+func newArtifactDir(t *testing.T, fixture string, pointer map[string]any) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "artifacts", "run-1")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatalf("read fixtures: %v", err)
+	}
+	for _, e := range entries {
+		src := filepath.Join("testdata", fixture, e.Name())
+		switch {
+		case strings.HasPrefix(e.Name(), "s0-"):
+			writeGzipFixture(t, src, filepath.Join(dir, e.Name()+".gz"))
+		case strings.HasPrefix(e.Name(), "_variation"):
+			b, err := os.ReadFile(src)
+			if err != nil {
+				t.Fatalf("read %s: %v", src, err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, e.Name()), b, 0o644); err != nil {
+				t.Fatalf("write %s: %v", e.Name(), err)
+			}
+		}
+	}
+	writeJSONFile(t, filepath.Join(dir, PointerFile), pointer)
+	writeJSONFile(t, filepath.Join(dir, ManifestFile), pointer)
+	return dir
+}
+
+func writeGzipFixture(t *testing.T, src, dst string) {
+	t.Helper()
+	b, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatalf("read %s: %v", src, err)
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		t.Fatalf("create %s: %v", dst, err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatalf("gzip %s: %v", dst, err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip close %s: %v", dst, err)
+	}
+}
+
+func writeJSONFile(t *testing.T, path string, v any) {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func okPointer(url string) map[string]any {
+	return map[string]any{"status": "ok", "url": url, "captured_at": "2026-01-02T03:04:05Z"}
+}
+
+func TestShopee_Stages(t *testing.T) {
+	dir := newArtifactDir(t, "shopee", okPointer("https://shopee.tw/product/1/2"))
+	ex := shopee{}
+
+	core := ex.Core(dir)
+	want := CoreResult{
+		Status:      StatusOK,
+		Title:       "不鏽鋼保溫杯 500ml",
+		Description: "316 不鏽鋼內膽，雙層真空設計，保冷保溫 12 小時，附提把與防漏杯蓋。",
+		Currency:    "TWD",
+		Price:       "1290",
+	}
+	if core != want {
+		t.Fatalf("unexpected core:\n got %+v\nwant %+v", core, want)
+	}
+
+	images := ex.Images(dir)
+	wantImages := []string{
+		"https://down-tw.img.susercontent.com/file/tw-11134207-aaa111@resize_w450_nl.webp",
+		"https://down-tw.img.susercontent.com/file/tw-11134207-bbb222@resize_w450_nl.webp",
+		"https://cdn.example.com/assets/banner.png",
+	}
+	if images.Status != StatusOK || !reflect.DeepEqual(images.Images, wantImages) {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+
+	variations := ex.Variations(dir)
+	wantVariations := []VariationPrice{
+		{Title: "黑色", Position: 0, Price: "1290"},
+		{Title: "白色 XL", Position: 1, Price: "1390"},
+	}
+	if variations.Status != StatusOK || !reflect.DeepEqual(variations.Variations, wantVariations) {
+		t.Fatalf("unexpected variations: %+v", variations)
+	}
+	writeJSONFile(t, filepath.Join(dir, VariationsFile), variations)
+
+	// Snapshot 1 has no parsable selected label, so its title comes from
+	// variations_extract.json.
+	vmap := ex.VariationImageMap(dir)
+	wantMap := []VariationImages{
+		{Title: "黑色", Position: 0, Images: []string{"https://down-tw.img.susercontent.com/file/tw-black@resize_w450_nl.webp"}},
+		{Title: "白色 XL", Position: 1, Images: []string{"https://down-tw.img.susercontent.com/file/tw-white@resize_w900_nl.webp"}},
+	}
+	if vmap.Status != StatusOK || !reflect.DeepEqual(vmap.Variations, wantMap) {
+		t.Fatalf("unexpected variation image map: %+v", vmap)
+	}
+}
+
+func TestShopee_CoreWallNeedsManual(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run-wall")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeGzipFixture(t, filepath.Join("testdata", "shopee", "wall.html"), filepath.Join(dir, "s0-initial.html.gz"))
+
+	core := shopee{}.Core(dir)
+	if core.Status != StatusNeedsManual || core.Notes == "" {
+		t.Fatalf("expected needs_manual, got %+v", core)
+	}
+}
+
+func TestTaobao_Stages(t *testing.T) {
+	dir := newArtifactDir(t, "taobao", okPointer("https://item.taobao.com/item.htm?id=700000000001"))
+	ex := taobao{}
+
+	core := ex.Core(dir)
+	want := CoreResult{
+		Status:      StatusOK,
+		Title:       "北欧风陶瓷马克杯 大容量",
+		Description: "材质: 陶瓷; 容量: 350ml/500ml; 风格: 北欧",
+		Currency:    "CNY",
+		Price:       "39.90",
+	}
+	if core != want {
+		t.Fatalf("unexpected core:\n got %+v\nwant %+v", core, want)
+	}
+
+	images := ex.Images(dir)
+	wantImages := []string{
+		"https://img.alicdn.com/imgextra/i1/100/O1CN01main1.jpg",
+		"https://img.alicdn.com/imgextra/i2/100/O1CN01main2.jpg",
+	}
+	if images.Status != StatusOK || !reflect.DeepEqual(images.Images, wantImages) {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+
+	// The snapshot skill recorded which SKU it opened for option 0; that SKU's
+	// price wins over the first matching SKU.
+	writeGzipFixture(t, filepath.Join("testdata", "taobao", "s0-initial.html"), filepath.Join(dir, "s0-variation-0.html.gz"))
+	writeJSONFile(t, filepath.Join(dir, "_variation0_capture.json"), map[string]any{
+		"target_url": "https://item.taobao.com/item.htm?id=700000000001&skuId=5002",
+	})
+	variations := ex.Variations(dir)
+	wantVariations := []VariationPrice{
+		{Title: "奶白", Position: 0, Price: "45"},
+		{Title: "墨黑", Position: 1, Price: "42.90"},
+	}
+	if variations.Status != StatusOK || !reflect.DeepEqual(variations.Variations, wantVariations) {
+		t.Fatalf("unexpected variations: %+v", variations)
+	}
+
+	vmap := ex.VariationImageMap(dir)
+	wantMap := []VariationImages{
+		{Title: "奶白", Position: 0, Images: []string{"https://img.alicdn.com/imgextra/i3/100/O1CN01white.jpg"}},
+		{Title: "墨黑", Position: 1, Images: []string{"https://img.alicdn.com/imgextra/i4/100/O1CN01black.jpg"}},
+	}
+	if vmap.Status != StatusOK || !reflect.DeepEqual(vmap.Variations, wantMap) {
+		t.Fatalf("unexpected variation image map: %+v", vmap)
+	}
+}
+
+func TestTaobao_CoreBlockedPointer(t *testing.T) {
+	dir := newArtifactDir(t, "taobao", map[string]any{"status": "needs_manual", "notes": "slider captcha"})
+
+	core := taobao{}.Core(dir)
+	if core.Status != StatusNeedsManual || core.Notes != "slider captcha" {
+		t.Fatalf("expected needs_manual with pointer notes, got %+v", core)
+	}
+}
+
+// TestShopee_Capture runs the stages over the trimmed product page under
+// testdata/captures/shopee (see testdata/captures/README.md).
+func TestShopee_Capture(t *testing.T) {
+	dir := newArtifactDir(t, "captures/shopee", okPointer("https://shopee.tw/product/38104872/21956738410"))
+	ex := shopee{}
+
+	// The JSON-LD offer is an AggregateOffer without a price, so the price is
+	// the low end of the rendered range.
+	core := ex.Core(dir)
+	want := CoreResult{
+		Status:      StatusOK,
+		Title:       "【台灣現貨】北歐風棉麻抱枕套 45x45cm 多色可選 沙發靠枕 腰枕",
+		Description: "✨ 棉麻混紡布料，觸感柔軟透氣 ✨ 隱形拉鍊設計，可拆洗 ✨ 尺寸：45x45cm（不含枕芯） 【注意事項】 手工測量會有 1-2cm 誤差，顏色依螢幕顯示略有不同，介意者請勿下單。 台灣現貨，下單後 1-2 個工作天出貨。",
+		Currency:    "TWD",
+		Price:       "290",
+	}
+	if core != want {
+		t.Fatalf("unexpected core:\n got %+v\nwant %+v", core, want)
+	}
+
+	// Every susercontent URL is kept in page order, so the gallery thumbnails
+	// and the option swatches follow the og:image and hero sizes.
+	const file = "https://down-tw.img.susercontent.com/file/"
+	images := ex.Images(dir)
+	wantImages := []string{
+		file + "tw-11134207-7r98o-lx2k9f3mh1qa5b",
+		file + "tw-11134207-7r98o-lx2k9f3mh1qa5b@resize_w450_nl.webp",
+		file + "tw-11134207-7r98o-lx2k9f3mh1qa5b@resize_w900_nl.webp",
+		file + "tw-11134207-7r98o-lx2k9f3mh1qa5b_tn.webp",
+		file + "tw-11134207-7r98v-lx2k9f3mkumq3c_tn.webp",
+		file + "tw-11134207-7r98q-lx2k9f3mm96ac2_tn.webp",
+		file + "tw-11134207-7r98y-lx2k9f3mnnqqd7_tn.webp",
+		file + "tw-11134207-7r98t-lx2k9f3mp2b6ee_tn.webp",
+		file + "tw-11134207-7r98u-lx2ka0b1c2d3e4_tn",
+		file + "tw-11134207-7r98w-lx2ka0b1f5g6h7_tn",
+		file + "tw-11134207-7r98s-lx2ka0b1i8j9k0_tn",
+	}
+	if images.Status != StatusOK || !reflect.DeepEqual(images.Images, wantImages) {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+
+	variations := ex.Variations(dir)
+	wantVariations := []VariationPrice{
+		{Title: "燕麥米", Position: 0, Price: "290"},
+		{Title: "霧霾藍", Position: 1, Price: "290"},
+		{Title: "墨綠（限量）", Position: 2, Price: "320"},
+	}
+	if variations.Status != StatusOK || !reflect.DeepEqual(variations.Variations, wantVariations) {
+		t.Fatalf("unexpected variations: %+v", variations)
+	}
+	writeJSONFile(t, filepath.Join(dir, VariationsFile), variations)
+
+	vmap := ex.VariationImageMap(dir)
+	wantMap := []VariationImages{
+		{Title: "燕麥米", Position: 0, Images: []string{file + "tw-11134207-7r98u-lx2ka0b1c2d3e4@resize_w450_nl.webp"}},
+		{Title: "霧霾藍", Position: 1, Images: []string{file + "tw-11134207-7r98w-lx2ka0b1f5g6h7@resize_w450_nl.webp"}},
+		{Title: "墨綠（限量）", Position: 2, Images: []string{file + "tw-11134207-7r98s-lx2ka0b1i8j9k0@resize_w450_nl.webp"}},
+	}
+	if vmap.Status != StatusOK || !reflect.DeepEqual(vmap.Variations, wantMap) {
+		t.Fatalf("unexpected variation image map: %+v", vmap)
+	}
+}
+
+// TestTaobao_Capture runs the stages over the trimmed item page and SKU
+// snapshots under testdata/captures/taobao (see testdata/captures/README.md).
+func TestTaobao_Capture(t *testing.T) {
+	dir := newArtifactDir(t, "captures/taobao", okPointer("https://item.taobao.com/item.htm?id=812345678901"))
+	ex := taobao{}
+
+	core := ex.Core(dir)
+	want := CoreResult{
+		Status:      StatusOK,
+		Title:       "日式复古陶瓷马克杯 大容量带盖勺 办公室咖啡杯早餐牛奶杯",
+		Description: "品牌: 拾光陶舍; 材质: 陶瓷; 容量: 401mL(含)-500mL(含); 风格: 日式; 是否带盖: 带盖; 产地: 中国大陆; 省份: 江西省",
+		Currency:    "CNY",
+		Price:       "39.9",
+	}
+	if core != want {
+		t.Fatalf("unexpected core:\n got %+v\nwant %+v", core, want)
+	}
+
+	const cdn = "https://img.alicdn.com/imgextra/"
+	images := ex.Images(dir)
+	wantImages := []string{
+		cdn + "i1/2206713586121/O1CN01Qh8mZP1vZ7xN4mKpB_!!2206713586121.jpg",
+		cdn + "i4/2206713586121/O1CN01bW3tYx1vZ7xKqQ9Ud_!!2206713586121.jpg",
+		cdn + "i2/2206713586121/O1CN01ZfR6kS1vZ7xMwE2Hc_!!2206713586121.jpg",
+		cdn + "i3/2206713586121/O1CN01p7JdVn1vZ7xLsT0xG_!!2206713586121.jpg",
+		cdn + "i1/2206713586121/O1CN01yC4aHq1vZ7xQnRrVz_!!2206713586121.jpg",
+	}
+	if images.Status != StatusOK || !reflect.DeepEqual(images.Images, wantImages) {
+		t.Fatalf("unexpected images: %+v", images)
+	}
+
+	// Option 0 was captured on its 杯+盖勺 SKU, so it carries that SKU's
+	// coupon price instead of the cheaper 单杯 one.
+	variations := ex.Variations(dir)
+	wantVariations := []VariationPrice{
+		{Title: "奶油白", Position: 0, Price: "49.9"},
+		{Title: "雾霾蓝", Position: 1, Price: "39.9"},
+		{Title: "复古绿", Position: 2, Price: "42.9"},
+	}
+	if variations.Status != StatusOK || !reflect.DeepEqual(variations.Variations, wantVariations) {
+		t.Fatalf("unexpected variations: %+v", variations)
+	}
+	writeJSONFile(t, filepath.Join(dir, VariationsFile), variations)
+
+	vmap := ex.VariationImageMap(dir)
+	wantMap := []VariationImages{
+		{Title: "奶油白", Position: 0, Images: []string{cdn + "i3/2206713586121/O1CN01cream9a1vZ7xRt3bWp_!!2206713586121.jpg"}},
+		{Title: "雾霾蓝", Position: 1, Images: []string{cdn + "i2/2206713586121/O1CN01haze4Kd1vZ7xSyq2Lm_!!2206713586121.jpg"}},
+		{Title: "复古绿", Position: 2, Images: []string{cdn + "i4/2206713586121/O1CN01green7Tb1vZ7xTzw8Qe_!!2206713586121.jpg"}},
+	}
+	if vmap.Status != StatusOK || !reflect.DeepEqual(vmap.Variations, wantMap) {
+		t.Fatalf("unexpected variation image map: %+v", vmap)
+	}
+}
+
+func TestFindChain_RespectsWindow(t *testing.T) {
+	src := `"priceVO":{` + strings.Repeat(" ", 50) + `"extraPrice":{"priceUnit":"￥","priceText":"9.90"}`
+	steps := []step{
+		{re: extraPriceRE, window: 6000},
+		{re: priceUnitRE, window: 1200},
+		{re: priceTextRE, window: 1200},
+	}
+	if g := findChain(src, priceVORE, steps...); !reflect.DeepEqual(g, []string{"￥", "9.90"}) {
+		t.Fatalf("unexpected groups: %q", g)
+	}
+
+	steps[0].window = 10
+	if g := findChain(src, priceVORE, steps...); g != nil {
+		t.Fatalf("expected no match outside the window, got %q", g)
+	}
+}
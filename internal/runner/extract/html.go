@@ -0,0 +1,373 @@
+package extract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// htmlCandidates lists snapshot files in preference order for the primary page
+// state. The .html variants and s0-page.* are accepted for older runs.
+var htmlCandidates = []string{
+	"s0-initial.html.gz",
+	"s0-initial.html",
+	"s0-page.html.gz",
+	"s0-page.html",
+}
+
+// overlayCandidates prefers the gallery overlay state for image extraction.
+var overlayCandidates = append([]string{
+	"s0-overlay.html.gz",
+	"s0-overlay.html",
+}, htmlCandidates...)
+
+var errNoHTML = errors.New("no input html artifact found")
+
+// pickFile returns the first candidate that exists under dir.
+func pickFile(dir string, candidates []string) (string, error) {
+	for _, name := range candidates {
+		p := filepath.Join(dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", errNoHTML
+}
+
+// variationHTMLPath returns the snapshot for the option at position, or "".
+func variationHTMLPath(dir string, position int) string {
+	p, err := pickFile(dir, []string{
+		fmt.Sprintf("s0-variation-%d.html.gz", position),
+		fmt.Sprintf("s0-variation-%d.html", position),
+	})
+	if err != nil {
+		return ""
+	}
+	return p
+}
+
+// readHTML reads a snapshot, transparently gunzipping *.gz files. Invalid UTF-8
+// is replaced rather than rejected, as the Python extractors did.
+func readHTML(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return "", fmt.Errorf("gunzip %s: %w", filepath.Base(path), err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToValidUTF8(string(b), "�"), nil
+}
+
+// readJSONObject decodes path as a JSON object; a missing file yields nil.
+func readJSONObject(path string) (map[string]any, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", filepath.Base(path), err)
+	}
+	return obj, nil
+}
+
+// clean unescapes HTML entities, collapses whitespace and truncates to maxLen
+// runes (0 = unlimited).
+func clean(s string, maxLen int) string {
+	s = strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+	if maxLen > 0 && utf8.RuneCountInString(s) > maxLen {
+		s = string([]rune(s)[:maxLen])
+	}
+	return s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+var (
+	commentRE = regexp.MustCompile(`(?s)<!--.*?-->`)
+	tagRE     = regexp.MustCompile(`<[^>]*>`)
+)
+
+// plainText approximates the text content of a document (including script
+// bodies, like Python's HTMLParser data callback).
+func plainText(src string) string {
+	s := commentRE.ReplaceAllString(src, " ")
+	s = tagRE.ReplaceAllString(s, " ")
+	return clean(s, 0)
+}
+
+func stripTags(s string) string {
+	return tagRE.ReplaceAllString(s, " ")
+}
+
+// str renders a decoded JSON value like Python's str() for the scalar cases the
+// extractors care about.
+func str(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// intValue reports v as an int when it is a JSON integer.
+func intValue(v any) (int, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	if err != nil {
+		return 0, false
+	}
+	return int(i), true
+}
+
+// dig walks nested objects by key; a missing or non-object hop yields nil.
+func dig(v any, keys ...string) any {
+	for _, k := range keys {
+		v = obj(v)[k]
+	}
+	return v
+}
+
+func obj(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func list(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+// step is one hop of a windowed search: re must match starting within window
+// bytes of the previous hop's end. It stands in for Python's `.{0,N}?` gaps,
+// which exceed RE2's repeat limit.
+type step struct {
+	re     *regexp.Regexp
+	window int
+}
+
+// tailSlack bounds how far past the window a hop's own match may extend.
+const tailSlack = 64 << 10
+
+// findChain tries every match of first in order and returns the submatches of
+// the first chain whose hops all land within their windows. The returned slice
+// holds the capture groups of every regexp, in order.
+func findChain(src string, first *regexp.Regexp, steps ...step) []string {
+	for _, loc := range first.FindAllStringSubmatchIndex(src, -1) {
+		if groups, _ := chainAt(src, loc, steps); groups != nil {
+			return groups
+		}
+	}
+	return nil
+}
+
+// findAllChains is findChain for every non-overlapping chain in src.
+func findAllChains(src string, first *regexp.Regexp, steps ...step) [][]string {
+	var out [][]string
+	end := 0
+	for _, loc := range first.FindAllStringSubmatchIndex(src, -1) {
+		if loc[0] < end {
+			continue
+		}
+		if groups, e := chainAt(src, loc, steps); groups != nil {
+			out = append(out, groups)
+			end = e
+		}
+	}
+	return out
+}
+
+// chainAt follows steps from the first-hop match at loc, returning the
+// collected groups and the end offset of the last hop, or nil.
+func chainAt(src string, loc []int, steps []step) ([]string, int) {
+	groups := submatches(src, loc)
+	pos := loc[1]
+	for _, st := range steps {
+		end := min(len(src), pos+st.window+tailSlack)
+		sub := st.re.FindStringSubmatchIndex(src[pos:end])
+		if sub == nil || sub[0] > st.window {
+			return nil, 0
+		}
+		for i := range sub {
+			if sub[i] >= 0 {
+				sub[i] += pos
+			}
+		}
+		groups = append(groups, submatches(src, sub)...)
+		pos = sub[1]
+	}
+	return groups, pos
+}
+
+func submatches(src string, loc []int) []string {
+	out := make([]string, 0, len(loc)/2-1)
+	for i := 2; i+1 < len(loc); i += 2 {
+		if loc[i] < 0 {
+			out = append(out, "")
+			continue
+		}
+		out = append(out, src[loc[i]:loc[i+1]])
+	}
+	return out
+}
+
+// jsObjectAfter decodes the first balanced {...} literal following marker.
+func jsObjectAfter(src, marker string) map[string]any {
+	idx := strings.Index(src, marker)
+	if idx < 0 {
+		return nil
+	}
+
+	start, end, depth := -1, -1, 0
+	inStr, esc := false, false
+	for pos := idx + len(marker); pos < len(src); pos++ {
+		ch := src[pos]
+		if start < 0 {
+			if ch == '{' {
+				start, depth = pos, 1
+			}
+			continue
+		}
+		if inStr {
+			switch {
+			case esc:
+				esc = false
+			case ch == '\\':
+				esc = true
+			case ch == '"':
+				inStr = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inStr = true
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 {
+			end = pos + 1
+			break
+		}
+	}
+	if start < 0 || end < 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(src[start:end]))
+	dec.UseNumber()
+	var out map[string]any
+	if err := dec.Decode(&out); err != nil {
+		return nil
+	}
+	return out
+}
+
+var priceNumberRE = regexp.MustCompile(`(\d+(?:\.\d{1,2})?)`)
+
+// normalizePrice keeps the first decimal number in s ("￥119.00起" -> "119.00").
+func normalizePrice(s string) string {
+	t := strings.ReplaceAll(clean(s, 0), ",", "")
+	m := priceNumberRE.FindStringSubmatch(t)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+func isHTTPURL(u string) bool {
+	l := strings.ToLower(u)
+	return strings.HasPrefix(l, "http://") || strings.HasPrefix(l, "https://")
+}
+
+// urlSet collects unique http(s) URLs in insertion order up to max.
+type urlSet struct {
+	max  int
+	seen map[string]bool
+	urls []string
+}
+
+func newURLSet(max int) *urlSet {
+	return &urlSet{max: max, seen: make(map[string]bool)}
+}
+
+// add returns true once the set is full.
+func (s *urlSet) add(u string) bool {
+	if s.full() {
+		return true
+	}
+	if isHTTPURL(u) && !s.seen[u] {
+		s.seen[u] = true
+		s.urls = append(s.urls, u)
+	}
+	return s.full()
+}
+
+func (s *urlSet) full() bool { return s.max > 0 && len(s.urls) >= s.max }
+
+func (s *urlSet) list() []string {
+	if s.urls == nil {
+		return []string{}
+	}
+	return s.urls
+}
+
+// dedupeFold removes case-insensitive duplicates, keeping order.
+func dedupeFold(items []string) []string {
+	out := make([]string, 0, len(items))
+	seen := make(map[string]bool, len(items))
+	for _, it := range items {
+		c := clean(it, 0)
+		if c == "" || seen[strings.ToLower(c)] {
+			continue
+		}
+		seen[strings.ToLower(c)] = true
+		out = append(out, c)
+	}
+	return out
+}
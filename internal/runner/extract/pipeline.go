@@ -0,0 +1,475 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/internal/source"
+)
+
+// Options configures Run.
+type Options struct {
+	// ArtifactDir is out/artifacts/<run_id>; it must already hold the S0 snapshot.
+	ArtifactDir string
+	Source      source.Source
+
+	// URL and RunID default to the snapshot pointer's url and the artifact
+	// directory name.
+	URL   string
+	RunID string
+
+	SkipImages            bool
+	SkipVariations        bool
+	SkipVariationImageMap bool
+}
+
+// Final is final.json, the merged result of all stages.
+type Final struct {
+	URL         string           `json:"url"`
+	Status      string           `json:"status"`
+	CapturedAt  string           `json:"captured_at"`
+	Notes       string           `json:"notes"`
+	Error       string           `json:"error"`
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	Currency    string           `json:"currency"`
+	Price       string           `json:"price"`
+	Images      []string         `json:"images"`
+	Variations  []FinalVariation `json:"variations"`
+	ArtifactDir string           `json:"artifact_dir"`
+	RunID       string           `json:"run_id"`
+
+	// ResultSource and Stages are set only when Salvage assembled the result.
+	ResultSource string         `json:"result_source,omitempty"`
+	Stages       []StageSummary `json:"stages,omitempty"`
+}
+
+// FinalVariation is one merged variation of final.json.
+type FinalVariation struct {
+	Title    string   `json:"title"`
+	Position int      `json:"position"`
+	Price    string   `json:"price"`
+	Images   []string `json:"images"`
+}
+
+// StageState is one stage entry of _pipeline-state.json.
+type StageState struct {
+	Status    string `json:"status"`
+	StartedAt string `json:"started_at"`
+	EndedAt   string `json:"ended_at"`
+	Error     string `json:"error"`
+	Stage     string `json:"stage"`
+}
+
+// State is _pipeline-state.json.
+type State struct {
+	RunID        string                 `json:"run_id"`
+	URL          string                 `json:"url"`
+	StartedAt    string                 `json:"started_at"`
+	UpdatedAt    string                 `json:"updated_at"`
+	CurrentStage string                 `json:"current_stage"`
+	Status       string                 `json:"status"`
+	Stages       map[string]*StageState `json:"stages"`
+	Flags        map[string]bool        `json:"flags"`
+}
+
+// StageError is one entry of meta.json's stage_errors.
+type StageError struct {
+	Stage string `json:"stage"`
+	Error string `json:"error"`
+}
+
+// Meta is meta.json.
+type Meta struct {
+	RunID             string           `json:"run_id"`
+	OrchestratorSkill string           `json:"orchestrator_skill"`
+	StageDurationMS   map[string]int64 `json:"stage_duration_ms"`
+	StageErrors       []StageError     `json:"stage_errors"`
+	Limits            map[string]int   `json:"limits"`
+	Fallbacks         []string         `json:"fallbacks"`
+}
+
+var nowFunc = time.Now
+
+// Run executes the offline stages against an existing snapshot and writes the
+// stage JSON files, _pipeline-state.json, meta.json and final.json, exactly as
+// the <source>-orchestrator-pipeline skill does. Extraction failures are
+// reported through Final.Status; the error is non-nil only when the artifacts
+// cannot be written or the source has no extractor.
+func Run(opts Options) (*Final, error) {
+	ex, err := For(opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	dir := opts.ArtifactDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	p := &pipeline{opts: opts, dir: dir, ex: ex}
+	p.init()
+	err = p.run()
+	return p.final, err
+}
+
+type pipeline struct {
+	opts    Options
+	dir     string
+	ex      Extractor
+	pointer map[string]any
+	state   *State
+	meta    *Meta
+	final   *Final
+}
+
+func (p *pipeline) init() {
+	runID := strings.TrimSpace(p.opts.RunID)
+	if runID == "" {
+		runID = filepath.Base(filepath.Clean(p.dir))
+	}
+	url := strings.TrimSpace(p.opts.URL)
+	if url == "" {
+		if pointer, err := readJSONObject(filepath.Join(p.dir, PointerFile)); err == nil {
+			url = strings.TrimSpace(str(pointer["url"]))
+		}
+	}
+
+	now := utcNow()
+	stages := make(map[string]*StageState, len(Stages))
+	durations := make(map[string]int64, len(Stages))
+	for _, s := range Stages {
+		stages[s] = &StageState{Status: "pending", Stage: s}
+		durations[s] = 0
+	}
+	p.state = &State{
+		RunID:        runID,
+		URL:          url,
+		StartedAt:    now,
+		UpdatedAt:    now,
+		CurrentStage: StageSnapshotCapture,
+		Status:       "running",
+		Stages:       stages,
+		Flags: map[string]bool{
+			"images_enabled":              !p.opts.SkipImages,
+			"variations_enabled":          !p.opts.SkipVariations,
+			"variation_image_map_enabled": !p.opts.SkipVariationImageMap,
+		},
+	}
+	p.meta = &Meta{
+		RunID:             runID,
+		OrchestratorSkill: string(p.opts.Source) + "-orchestrator-pipeline",
+		StageDurationMS:   durations,
+		StageErrors:       []StageError{},
+		Limits: map[string]int{
+			"description_max_chars":   descMaxChars,
+			"images_max":              maxImages,
+			"variations_max":          maxVariations,
+			"variation_image_map_max": maxVariations,
+		},
+		Fallbacks: []string{},
+	}
+}
+
+func (p *pipeline) run() error {
+	if err := p.saveState(); err != nil {
+		return err
+	}
+
+	if done, err := p.snapshotStage(); done || err != nil {
+		return err
+	}
+
+	core, err := p.coreStage()
+	if err != nil || core == nil {
+		return err
+	}
+
+	start, err := p.startStage(StageImages)
+	if err != nil {
+		return err
+	}
+	images := ImagesResult{Status: StatusOK, Images: []string{}}
+	if !p.opts.SkipImages {
+		if images = p.ex.Images(p.dir); images.Status != StatusOK {
+			images = imagesError(errors.New(firstNonEmpty(images.Error, "images stage failed")))
+		}
+	}
+	if err := p.endOptionalStage(StageImages, ImagesFile, p.opts.SkipImages, start, images.Status, images.Error, images); err != nil {
+		return err
+	}
+
+	if start, err = p.startStage(StageVariations); err != nil {
+		return err
+	}
+	variations := VariationsResult{Status: StatusOK, Variations: []VariationPrice{}}
+	if !p.opts.SkipVariations {
+		if variations = p.ex.Variations(p.dir); variations.Status != StatusOK {
+			variations = variationsError(errors.New(firstNonEmpty(variations.Error, "variations stage failed")))
+		}
+	}
+	if err := p.endOptionalStage(StageVariations, VariationsFile, p.opts.SkipVariations, start, variations.Status, variations.Error, variations); err != nil {
+		return err
+	}
+
+	if start, err = p.startStage(StageVariationImageMap); err != nil {
+		return err
+	}
+	vmap := VariationImageMapResult{Status: StatusOK, Variations: []VariationImages{}}
+	if !p.opts.SkipVariationImageMap {
+		if vmap = p.ex.VariationImageMap(p.dir); vmap.Status != StatusOK {
+			vmap = variationImageMapError(errors.New(firstNonEmpty(vmap.Error, "variation image map stage failed")))
+		}
+	}
+	if err := p.endOptionalStage(StageVariationImageMap, VariationImageMapFile, p.opts.SkipVariationImageMap, start, vmap.Status, vmap.Error, vmap); err != nil {
+		return err
+	}
+
+	return p.finalize(p.merge(*core, images, variations, vmap))
+}
+
+// snapshotStage checks the S0 artifacts. done is true when the pipeline
+// already finished (snapshot missing, blocked or failed).
+func (p *pipeline) snapshotStage() (done bool, err error) {
+	start, err := p.startStage(StageSnapshotCapture)
+	if err != nil {
+		return true, err
+	}
+
+	fail := func(cause error) (bool, error) {
+		msg := cause.Error()
+		p.endStage(StageSnapshotCapture, StatusError, msg, start)
+		p.meta.StageErrors = append(p.meta.StageErrors, StageError{Stage: StageSnapshotCapture, Error: msg})
+		return true, p.finalize(p.terminal(StatusError, "", msg))
+	}
+
+	pointer, err := readJSONObject(filepath.Join(p.dir, PointerFile))
+	if err == nil && pointer == nil {
+		err = fmt.Errorf("missing required snapshot file: %s", PointerFile)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	p.pointer = pointer
+	if manifest, err := readJSONObject(filepath.Join(p.dir, ManifestFile)); err != nil || manifest == nil {
+		if err == nil {
+			err = fmt.Errorf("missing required snapshot file: %s", ManifestFile)
+		}
+		return fail(err)
+	}
+	if _, err := os.Stat(filepath.Join(p.dir, htmlCandidates[0])); err != nil {
+		return fail(fmt.Errorf("missing required snapshot html: %s", htmlCandidates[0]))
+	}
+
+	switch strings.ToLower(strings.TrimSpace(str(pointer["status"]))) {
+	case StatusNeedsManual:
+		p.endStage(StageSnapshotCapture, StatusNeedsManual, "", start)
+		notes := firstNonEmpty(str(pointer["notes"]), "blocked or verification wall detected from snapshot stage")
+		return true, p.finalize(p.terminal(StatusNeedsManual, notes, ""))
+	case StatusError:
+		return fail(errors.New(firstNonEmpty(str(pointer["error"]), "snapshot stage returned error")))
+	}
+
+	p.endStage(StageSnapshotCapture, "completed", "", start)
+	return false, p.saveState()
+}
+
+// coreStage gates the pipeline: a nil result means it already finished.
+func (p *pipeline) coreStage() (*CoreResult, error) {
+	start, err := p.startStage(StageCore)
+	if err != nil {
+		return nil, err
+	}
+
+	core := p.ex.Core(p.dir)
+	if err := writeJSON(filepath.Join(p.dir, CoreFile), core); err != nil {
+		return nil, err
+	}
+
+	switch core.Status {
+	case StatusOK:
+		p.endStage(StageCore, "completed", "", start)
+		return &core, p.saveState()
+	case StatusNeedsManual:
+		p.endStage(StageCore, StatusNeedsManual, "", start)
+		notes := firstNonEmpty(core.Notes, "blocked or verification wall detected from core stage")
+		return nil, p.finalize(p.terminal(StatusNeedsManual, notes, ""))
+	default:
+		msg := firstNonEmpty(core.Error, "core stage failed")
+		p.endStage(StageCore, StatusError, msg, start)
+		p.meta.StageErrors = append(p.meta.StageErrors, StageError{Stage: StageCore, Error: msg})
+		return nil, p.finalize(p.terminal(StatusError, "", msg))
+	}
+}
+
+// endOptionalStage records the outcome of a stage that degrades instead of
+// failing the pipeline, writing its stage JSON.
+func (p *pipeline) endOptionalStage(stage, file string, skipped bool, start time.Time, status, errMsg string, payload any) error {
+	if err := writeJSON(filepath.Join(p.dir, file), payload); err != nil {
+		return err
+	}
+	switch {
+	case skipped:
+		p.endStage(stage, "skipped", "", start)
+	case status == StatusOK:
+		p.endStage(stage, "completed", "", start)
+	default:
+		p.endStage(stage, StatusError, errMsg, start)
+		p.meta.StageErrors = append(p.meta.StageErrors, StageError{Stage: stage, Error: errMsg})
+		p.meta.Fallbacks = append(p.meta.Fallbacks, stage+"_degraded")
+	}
+	return p.saveState()
+}
+
+func (p *pipeline) merge(core CoreResult, images ImagesResult, variations VariationsResult, vmap VariationImageMapResult) *Final {
+	final := p.terminal(StatusOK, "", "")
+	final.Title = core.Title
+	final.Description = clean(core.Description, descMaxChars)
+	final.Currency = core.Currency
+	final.Price = core.Price
+	final.Images = uniqueHTTPURLs(images.Images, maxImages)
+
+	imagesByKey := make(map[string][]string)
+	for i, row := range vmap.Variations {
+		if i >= maxVariations {
+			break
+		}
+		if title := strings.TrimSpace(row.Title); title != "" {
+			imagesByKey[variationKey(title, row.Position)] = uniqueHTTPURLs(row.Images, maxImages)
+		}
+	}
+	for i, row := range variations.Variations {
+		if i >= maxVariations {
+			break
+		}
+		title := strings.TrimSpace(row.Title)
+		if title == "" {
+			continue
+		}
+		images := imagesByKey[variationKey(title, row.Position)]
+		if images == nil {
+			images = []string{}
+		}
+		final.Variations = append(final.Variations, FinalVariation{
+			Title:    title,
+			Position: row.Position,
+			Price:    row.Price,
+			Images:   images,
+		})
+	}
+
+	var notes []string
+	if n := strings.TrimSpace(str(p.pointer["notes"])); n != "" {
+		notes = append(notes, n)
+	}
+	for _, f := range p.meta.Fallbacks {
+		notes = append(notes, "degraded: "+f)
+	}
+	final.Notes = strings.Join(notes, "; ")
+	return final
+}
+
+func variationKey(title string, position int) string {
+	return fmt.Sprintf("%s#%d", strings.ToLower(title), position)
+}
+
+// uniqueHTTPURLs normalizes protocol-relative URLs and keeps unique http(s)
+// ones in order, up to limit.
+func uniqueHTTPURLs(items []string, limit int) []string {
+	set := newURLSet(limit)
+	for _, raw := range items {
+		u := strings.TrimSpace(raw)
+		if strings.HasPrefix(u, "//") {
+			u = "https:" + u
+		}
+		if set.add(u) {
+			break
+		}
+	}
+	return set.list()
+}
+
+// terminal builds a final.json skeleton carrying the pointer's url and capture
+// time when available.
+func (p *pipeline) terminal(status, notes, errMsg string) *Final {
+	return &Final{
+		URL:         firstNonEmpty(str(p.pointer["url"]), p.state.URL),
+		Status:      status,
+		CapturedAt:  firstNonEmpty(str(p.pointer["captured_at"]), utcNow()),
+		Notes:       notes,
+		Error:       errMsg,
+		Images:      []string{},
+		Variations:  []FinalVariation{},
+		ArtifactDir: relPath(p.dir),
+		RunID:       p.state.RunID,
+	}
+}
+
+func (p *pipeline) finalize(final *Final) error {
+	p.final = final
+	p.state.CurrentStage = StageFinalMerge
+	switch final.Status {
+	case StatusOK:
+		p.state.Status = "completed"
+	case StatusNeedsManual:
+		p.state.Status = StatusNeedsManual
+	default:
+		p.state.Status = StatusError
+	}
+	if err := p.saveState(); err != nil {
+		return err
+	}
+	if err := writeJSON(filepath.Join(p.dir, MetaFile), p.meta); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(p.dir, FinalFile), final)
+}
+
+func (p *pipeline) startStage(stage string) (time.Time, error) {
+	p.state.CurrentStage = stage
+	p.state.Stages[stage] = &StageState{Status: "running", StartedAt: utcNow(), Stage: stage}
+	return nowFunc(), p.saveState()
+}
+
+func (p *pipeline) endStage(stage, status, errMsg string, start time.Time) {
+	s := p.state.Stages[stage]
+	s.Status = status
+	s.EndedAt = utcNow()
+	s.Error = errMsg
+	p.meta.StageDurationMS[stage] = nowFunc().Sub(start).Milliseconds()
+}
+
+func (p *pipeline) saveState() error {
+	p.state.UpdatedAt = utcNow()
+	return writeJSON(filepath.Join(p.dir, PipelineStateFile), p.state)
+}
+
+// relPath reports dir relative to the working directory when it is below it.
+func relPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(dir)
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(dir)
+}
+
+func utcNow() string {
+	return nowFunc().UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+func writeJSON(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
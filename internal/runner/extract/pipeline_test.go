@@ -0,0 +1,116 @@
+package extract
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"peasydeal-product-miner/internal/source"
+)
+
+func readState(t *testing.T, dir string) State {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, PipelineStateFile))
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	var st State
+	if err := json.Unmarshal(b, &st); err != nil {
+		t.Fatalf("decode state: %v", err)
+	}
+	return st
+}
+
+func TestRun_MergesStagesIntoFinal(t *testing.T) {
+	dir := newArtifactDir(t, "shopee", okPointer("https://shopee.tw/product/1/2"))
+
+	final, err := Run(Options{ArtifactDir: dir, Source: source.Shopee})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if final.Status != StatusOK || final.RunID != "run-1" || final.URL != "https://shopee.tw/product/1/2" {
+		t.Fatalf("unexpected final: %+v", final)
+	}
+	if final.CapturedAt != "2026-01-02T03:04:05Z" || final.Price != "1290" || len(final.Images) != 3 {
+		t.Fatalf("unexpected final fields: %+v", final)
+	}
+	if len(final.Variations) != 2 || final.Variations[1].Price != "1390" || len(final.Variations[1].Images) != 1 {
+		t.Fatalf("unexpected merged variations: %+v", final.Variations)
+	}
+
+	for _, name := range []string{CoreFile, ImagesFile, VariationsFile, VariationImageMapFile, MetaFile, FinalFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("missing %s: %v", name, err)
+		}
+	}
+	st := readState(t, dir)
+	if st.Status != "completed" || st.CurrentStage != StageFinalMerge {
+		t.Fatalf("unexpected state: %+v", st)
+	}
+	for _, s := range Stages {
+		if st.Stages[s].Status != "completed" {
+			t.Fatalf("stage %s: %+v", s, st.Stages[s])
+		}
+	}
+}
+
+func TestRun_DegradedStageAddsFallbackNote(t *testing.T) {
+	dir := newArtifactDir(t, "taobao", okPointer("https://item.taobao.com/item.htm?id=700000000001"))
+	// A corrupt capture file fails the variations stage, which must degrade
+	// rather than fail the run.
+	writeGzipFixture(t, filepath.Join("testdata", "taobao", "s0-initial.html"), filepath.Join(dir, "s0-variation-0.html.gz"))
+	if err := os.WriteFile(filepath.Join(dir, "_variation0_capture.json"), []byte("{"), 0o644); err != nil {
+		t.Fatalf("write capture: %v", err)
+	}
+
+	final, err := Run(Options{ArtifactDir: dir, Source: source.Taobao, SkipImages: true})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if final.Status != StatusOK || final.Notes != "degraded: variations_extract_degraded" {
+		t.Fatalf("unexpected final: %+v", final)
+	}
+	if len(final.Images) != 0 || len(final.Variations) != 0 {
+		t.Fatalf("expected no images (skipped) and no variations (degraded): %+v", final)
+	}
+
+	st := readState(t, dir)
+	if st.Stages[StageImages].Status != "skipped" || st.Stages[StageVariations].Status != StatusError {
+		t.Fatalf("unexpected stage states: %+v %+v", st.Stages[StageImages], st.Stages[StageVariations])
+	}
+}
+
+func TestRun_SnapshotNeedsManualShortCircuits(t *testing.T) {
+	dir := newArtifactDir(t, "shopee", map[string]any{
+		"status": "needs_manual",
+		"url":    "https://shopee.tw/product/1/2",
+		"notes":  "captcha wall",
+	})
+
+	final, err := Run(Options{ArtifactDir: dir, Source: source.Shopee})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if final.Status != StatusNeedsManual || final.Notes != "captcha wall" {
+		t.Fatalf("unexpected final: %+v", final)
+	}
+	if _, err := os.Stat(filepath.Join(dir, CoreFile)); !os.IsNotExist(err) {
+		t.Fatalf("core stage should not run, stat err=%v", err)
+	}
+}
+
+func TestRun_MissingSnapshotIsError(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run-empty")
+
+	final, err := Run(Options{ArtifactDir: dir, Source: source.Taobao})
+	if err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if final.Status != StatusError || final.Error == "" {
+		t.Fatalf("expected error final, got %+v", final)
+	}
+	if st := readState(t, dir); st.Stages[StageSnapshotCapture].Status != StatusError {
+		t.Fatalf("unexpected snapshot stage: %+v", st.Stages[StageSnapshotCapture])
+	}
+}
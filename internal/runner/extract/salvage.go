@@ -0,0 +1,172 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Stage outcomes recorded in StageSummary.Salvage.
+const (
+	OutcomeSalvaged = "salvaged"
+	OutcomeSkipped  = "skipped"
+	OutcomeFailed   = "failed"
+	OutcomeMissing  = "missing"
+)
+
+// ResultSourceSalvaged prefixes Final.ResultSource of a salvaged result. The
+// salvaged stages follow it, e.g. "final_merge_salvage:core_extract,images_extract".
+const ResultSourceSalvaged = "final_merge_salvage"
+
+// ErrNothingToSalvage is returned by Salvage when no extraction stage left a
+// usable artifact.
+var ErrNothingToSalvage = errors.New("no usable stage artifacts to salvage")
+
+// Salvage is the Go final_merge for a run whose pipeline stopped before writing
+// final.json. It reads _pipeline-state.json and whichever stage artifacts exist
+// and writes the best final.json they allow: status ok when core_extract
+// produced title, currency and price, needs_manual otherwise. Missing or failed
+// optional stages degrade the result the same way Run does.
+func Salvage(opts Options) (*Final, error) {
+	p := &pipeline{opts: opts, dir: opts.ArtifactDir}
+	p.init()
+	if err := p.loadState(); err != nil {
+		return nil, err
+	}
+
+	summary := make(map[string]string, len(Stages))
+	pointer, err := readJSONObject(filepath.Join(p.dir, PointerFile))
+	switch {
+	case err != nil:
+		summary[StageSnapshotCapture] = OutcomeFailed
+	case pointer == nil:
+		summary[StageSnapshotCapture] = OutcomeMissing
+	default:
+		summary[StageSnapshotCapture] = OutcomeSalvaged
+		p.pointer = pointer
+	}
+
+	var (
+		core       CoreResult
+		images     ImagesResult
+		variations VariationsResult
+		vmap       VariationImageMapResult
+	)
+	summary[StageCore] = p.salvageStage(StageCore, CoreFile, &core, &core.Status)
+	summary[StageImages] = p.salvageStage(StageImages, ImagesFile, &images, &images.Status)
+	summary[StageVariations] = p.salvageStage(StageVariations, VariationsFile, &variations, &variations.Status)
+	summary[StageVariationImageMap] = p.salvageStage(StageVariationImageMap, VariationImageMapFile, &vmap, &vmap.Status)
+
+	var salvaged []string
+	for _, stage := range Stages[1:] {
+		switch summary[stage] {
+		case OutcomeSalvaged:
+			salvaged = append(salvaged, stage)
+		case OutcomeFailed:
+			p.meta.Fallbacks = append(p.meta.Fallbacks, stage+"_degraded")
+		case OutcomeMissing:
+			p.meta.Fallbacks = append(p.meta.Fallbacks, stage+"_missing")
+		}
+	}
+	if len(salvaged) == 0 {
+		return nil, ErrNothingToSalvage
+	}
+	p.meta.Fallbacks = append(p.meta.Fallbacks, StageFinalMerge+"_salvaged")
+
+	final := p.merge(core, images, variations, vmap)
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"title", core.Title},
+		{"currency", core.Currency},
+		{"price", core.Price},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		final.Status = StatusNeedsManual
+		note := "salvaged result is missing required fields: " + strings.Join(missing, ", ")
+		final.Notes = strings.Join(append([]string{note}, nonEmpty(final.Notes)...), "; ")
+	}
+	final.ResultSource = ResultSourceSalvaged + ":" + strings.Join(salvaged, ",")
+	final.Stages = summarize(p.state, p.meta.StageDurationMS)
+	for i := range final.Stages {
+		final.Stages[i].Salvage = summary[final.Stages[i].Stage]
+	}
+
+	if err := p.finalize(final); err != nil {
+		return nil, err
+	}
+	return final, nil
+}
+
+// loadState replaces the fresh state from init with _pipeline-state.json when
+// the interrupted pipeline left one behind.
+func (p *pipeline) loadState() error {
+	st, err := ReadState(p.dir)
+	if err != nil || st == nil {
+		return err
+	}
+	st.RunID = firstNonEmpty(st.RunID, p.state.RunID)
+	st.URL = firstNonEmpty(st.URL, p.state.URL)
+	if st.Stages == nil {
+		st.Stages = make(map[string]*StageState, len(Stages))
+	}
+	for name, s := range p.state.Stages {
+		if st.Stages[name] == nil {
+			st.Stages[name] = s
+		}
+	}
+	p.state = st
+	p.meta.RunID = st.RunID
+	return nil
+}
+
+// salvageStage decodes a stage artifact into v and reports its outcome. v is
+// reset unless the artifact is usable, so merge never sees a failed stage.
+func (p *pipeline) salvageStage(stage, file string, v any, status *string) string {
+	if s := p.state.Stages[stage]; s != nil && s.Status == "skipped" {
+		return OutcomeSkipped
+	}
+
+	b, err := os.ReadFile(filepath.Join(p.dir, file))
+	if errors.Is(err, os.ErrNotExist) {
+		return OutcomeMissing
+	}
+	if err == nil {
+		err = json.Unmarshal(b, v)
+	}
+	if err == nil && *status != StatusOK {
+		err = fmt.Errorf("stage status %q", *status)
+	}
+	if err != nil {
+		resetStage(v)
+		p.meta.StageErrors = append(p.meta.StageErrors, StageError{Stage: stage, Error: err.Error()})
+		return OutcomeFailed
+	}
+	return OutcomeSalvaged
+}
+
+func resetStage(v any) {
+	switch t := v.(type) {
+	case *CoreResult:
+		*t = CoreResult{}
+	case *ImagesResult:
+		*t = ImagesResult{}
+	case *VariationsResult:
+		*t = VariationsResult{}
+	case *VariationImageMapResult:
+		*t = VariationImageMapResult{}
+	}
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
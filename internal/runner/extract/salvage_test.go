@@ -0,0 +1,74 @@
+package extract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"peasydeal-product-miner/internal/source"
+)
+
+func TestSalvage_PartialCoreIsNeedsManual(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run-1")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeJSONFile(t, filepath.Join(dir, PointerFile), okPointer("https://item.taobao.com/item.htm?id=1"))
+	writeJSONFile(t, filepath.Join(dir, PipelineStateFile), map[string]any{
+		"run_id":        "run-1",
+		"current_stage": StageImages,
+		"status":        "running",
+		"stages": map[string]any{
+			StageImages:     map[string]any{"status": "running", "stage": StageImages},
+			StageVariations: map[string]any{"status": "skipped", "stage": StageVariations},
+		},
+	})
+	writeJSONFile(t, filepath.Join(dir, CoreFile), map[string]any{"status": "error", "error": "boom"})
+	writeJSONFile(t, filepath.Join(dir, ImagesFile), map[string]any{"status": "ok", "images": []string{"//img.alicdn.com/a.jpg"}})
+
+	final, err := Salvage(Options{ArtifactDir: dir, Source: source.Taobao})
+	if err != nil {
+		t.Fatalf("Salvage error: %v", err)
+	}
+	if final.Status != StatusNeedsManual || !strings.HasPrefix(final.Notes, "salvaged result is missing required fields: title, currency, price") {
+		t.Fatalf("unexpected final: %+v", final)
+	}
+	if final.ResultSource != "final_merge_salvage:images_extract" || len(final.Images) != 1 {
+		t.Fatalf("unexpected final: %+v", final)
+	}
+	want := map[string]string{
+		StageSnapshotCapture:   OutcomeSalvaged,
+		StageCore:              OutcomeFailed,
+		StageImages:            OutcomeSalvaged,
+		StageVariations:        OutcomeSkipped,
+		StageVariationImageMap: OutcomeMissing,
+	}
+	if len(final.Stages) != len(want) {
+		t.Fatalf("unexpected stages: %+v", final.Stages)
+	}
+	for _, s := range final.Stages {
+		if s.Salvage != want[s.Stage] {
+			t.Fatalf("stage %s salvage = %q, want %q", s.Stage, s.Salvage, want[s.Stage])
+		}
+	}
+	if st := readState(t, dir); st.Status != StatusNeedsManual || st.CurrentStage != StageFinalMerge {
+		t.Fatalf("unexpected state: %+v", st)
+	}
+}
+
+func TestSalvage_NothingToSalvage(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run-1")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	writeJSONFile(t, filepath.Join(dir, PointerFile), okPointer("https://shopee.tw/product/1/2"))
+
+	if _, err := Salvage(Options{ArtifactDir: dir, Source: source.Shopee}); !errors.Is(err, ErrNothingToSalvage) {
+		t.Fatalf("expected ErrNothingToSalvage, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, FinalFile)); !os.IsNotExist(err) {
+		t.Fatalf("final.json must not be written, stat err=%v", err)
+	}
+}
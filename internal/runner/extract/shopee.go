@@ -0,0 +1,496 @@
+package extract
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// shopee ports the shopee-product-* skill scripts.
+type shopee struct{}
+
+var (
+	shopeeOGTitleRE   = regexp.MustCompile(`(?i)<meta[^>]+property=["']og:title["'][^>]+content=["']([^"']+)`)
+	shopeeH1RE        = regexp.MustCompile(`(?is)<h1[^>]*>(.*?)</h1>`)
+	titleTagRE        = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	jsonLDRE          = regexp.MustCompile(`(?is)<script[^>]+type=["']application/ld\+json["'][^>]*>(.*?)</script>`)
+	shopeeDescRE      = regexp.MustCompile(`(?s)商品描述\s*(.*?)\s*(商品評價|賣場優惠券|你可能感興趣的商品|專屬推薦商品)`)
+	shopeePriceRE     = regexp.MustCompile(`(?i)(NT\$|TWD|NTD|\$)\s*([0-9][0-9,]*(?:\.[0-9]+)?)`)
+	shopeeBlockedRE   = regexp.MustCompile(`(?i)captcha|驗證|驗證碼|robot|機器人|verification|security check|verify/captcha|verify/traffic`)
+	shopeeNavLabelRE  = regexp.MustCompile(`(?i)(主要內容|賣家中心|通知|追蹤我們|蝦皮購物)`)
+	shopeeOptionishRE = regexp.MustCompile(`(?i)(\d|\*|x|cm|mm|kg|ml|絲|號|入|組|包|款|色|\(|\)|-|/|尺寸|規格|款式|顏色|color|size)`)
+)
+
+func (shopee) Core(dir string) CoreResult {
+	path, err := pickFile(dir, htmlCandidates)
+	if err != nil {
+		return coreError(err)
+	}
+	src, err := readHTML(path)
+	if err != nil {
+		return coreError(err)
+	}
+	manifest, err := readJSONObject(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return coreError(err)
+	}
+
+	plain := plainText(src)
+	products := jsonLDProducts(src)
+	title := shopeeTitle(src)
+	description := shopeeDescription(plain, products)
+	currency, price := shopeeCurrencyPrice(plain, products)
+
+	blocked := manifest["blocked"] == true ||
+		shopeeBlockedRE.MatchString(strings.ToLower(str(manifest["url"])+" "+plain))
+	if blocked && (title == "" || price == "") {
+		return CoreResult{
+			Status: StatusNeedsManual,
+			Notes:  "blocked or verification wall detected from html/manifest",
+		}
+	}
+	return coreFromFields(title, description, currency, price)
+}
+
+func shopeeTitle(src string) string {
+	var og, h1, tag string
+	if m := shopeeOGTitleRE.FindStringSubmatch(src); m != nil {
+		og = clean(m[1], titleMaxChars)
+	}
+	if m := shopeeH1RE.FindStringSubmatch(src); m != nil {
+		h1 = clean(stripTags(m[1]), titleMaxChars)
+	}
+	if m := titleTagRE.FindStringSubmatch(src); m != nil {
+		tag = clean(stripTags(m[1]), titleMaxChars)
+		if before, _, ok := strings.Cut(tag, "|"); ok {
+			tag = clean(before, titleMaxChars)
+		}
+	}
+	return firstNonEmpty(h1, og, tag)
+}
+
+// jsonLDProducts returns every schema.org Product object in the page's
+// JSON-LD blocks, depth first.
+func jsonLDProducts(src string) []map[string]any {
+	var out []map[string]any
+	var walk func(v any)
+	walk = func(v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			if isProductType(t["@type"]) {
+				out = append(out, t)
+			}
+			for _, child := range t {
+				walk(child)
+			}
+		case []any:
+			for _, child := range t {
+				walk(child)
+			}
+		}
+	}
+	for _, m := range jsonLDRE.FindAllStringSubmatch(src, -1) {
+		block := strings.TrimSpace(m[1])
+		if block == "" {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(block))
+		dec.UseNumber()
+		var data any
+		if err := dec.Decode(&data); err != nil {
+			continue
+		}
+		walk(data)
+	}
+	return out
+}
+
+func isProductType(v any) bool {
+	if v == "Product" {
+		return true
+	}
+	for _, t := range list(v) {
+		if t == "Product" {
+			return true
+		}
+	}
+	return false
+}
+
+func shopeeDescription(plain string, products []map[string]any) string {
+	for _, p := range products {
+		if d := clean(str(p["description"]), descMaxChars); utf8.RuneCountInString(d) >= 20 {
+			return d
+		}
+	}
+	if m := shopeeDescRE.FindStringSubmatch(plain); m != nil {
+		return clean(m[1], descMaxChars)
+	}
+	return ""
+}
+
+func shopeeCurrencyPrice(plain string, products []map[string]any) (string, string) {
+	for _, p := range products {
+		offers := p["offers"]
+		if l := list(offers); len(l) > 0 {
+			offers = l[0]
+		}
+		if o := obj(offers); o != nil {
+			c := strings.ToUpper(clean(str(o["priceCurrency"]), 8))
+			price := strings.ReplaceAll(clean(str(o["price"]), 64), ",", "")
+			if c != "" && price != "" {
+				return c, price
+			}
+		}
+	}
+	if m := shopeePriceRE.FindStringSubmatch(plain); m != nil {
+		// Every alternative of the currency group is a Taiwan dollar marker.
+		return "TWD", strings.ReplaceAll(m[2], ",", "")
+	}
+	return "", ""
+}
+
+var (
+	susercontentREs = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)https?://[^"'\s>]*susercontent\.com/file/[^"'\s>]*`),
+		regexp.MustCompile(`(?i)https?://[^"'\s>]*img\.susercontent\.com/file/[^"'\s>]*`),
+	}
+	genericImageRE = regexp.MustCompile(`(?i)https?://[^"'\s>]+\.(?:jpg|jpeg|png|webp)(?:\?[^"'\s>]*)?`)
+)
+
+// cleanURL undoes JSON slash escaping and strips stray quotes.
+func cleanURL(u string) string {
+	return strings.Trim(strings.TrimSpace(strings.ReplaceAll(u, `\/`, "/")), `"'`)
+}
+
+func (shopee) Images(dir string) ImagesResult {
+	path, err := pickFile(dir, overlayCandidates)
+	if err != nil {
+		return imagesError(err)
+	}
+	src, err := readHTML(path)
+	if err != nil {
+		return imagesError(err)
+	}
+
+	set := newURLSet(maxImages)
+	for _, re := range append(susercontentREs, genericImageRE) {
+		for _, m := range re.FindAllString(src, -1) {
+			if set.add(cleanURL(m)) {
+				return ImagesResult{Status: StatusOK, Images: set.list()}
+			}
+		}
+	}
+	return ImagesResult{Status: StatusOK, Images: set.list()}
+}
+
+var shopeeBlockedLabels = map[string]bool{
+	"跳到主要內容": true,
+	"加入購物車":  true,
+	"直接購買":   true,
+	"購物車":    true,
+	"登入":     true,
+	"註冊":     true,
+}
+
+func shopeeOptionLike(s string) bool {
+	if s == "" || shopeeBlockedLabels[s] {
+		return false
+	}
+	n := utf8.RuneCountInString(s)
+	if n > 80 || shopeeNavLabelRE.MatchString(s) {
+		return false
+	}
+	if shopeeOptionishRE.MatchString(s) {
+		return true
+	}
+	return n <= 24
+}
+
+var (
+	shopeeOptionREs = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)<button[^>]*class="[^"]*selection-box[^"]*"[^>]*aria-label="([^"]+)"`),
+		regexp.MustCompile(`(?i)<button[^>]*aria-label="([^"]+)"[^>]*class="[^"]*selection-box[^"]*"`),
+	}
+	shopeeSelectedREs = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)<button[^>]*class="[^"]*selection-box-selected[^"]*"[^>]*aria-label="([^"]+)"`),
+		regexp.MustCompile(`(?i)<button[^>]*aria-label="([^"]+)"[^>]*class="[^"]*selection-box-selected[^"]*"`),
+		regexp.MustCompile(`(?i)Product image[^"]*?\s([^\s"<]{1,80})"`),
+	}
+	shopeeVariationPriceRE = regexp.MustCompile(`(?is)<div[^>]*class="[^"]*IZPeQz[^"]*B67UQ0[^"]*"[^>]*>\s*([^<]+)\s*</div>`)
+	ariaLivePoliteRE       = regexp.MustCompile(`(?i)aria-live="polite"`)
+	dollarAmountRE         = regexp.MustCompile(`\$([0-9][0-9,]*)`)
+)
+
+// shopeeOptionTitles reads the labels of the variation picker buttons.
+func shopeeOptionTitles(src string) []string {
+	var out []string
+	for _, re := range shopeeOptionREs {
+		for _, m := range re.FindAllStringSubmatch(src, -1) {
+			if t := clean(m[1], 0); shopeeOptionLike(t) {
+				out = append(out, t)
+			}
+		}
+		if len(out) > 0 {
+			break
+		}
+	}
+	return dedupeFold(out)
+}
+
+func shopeeSelectedTitle(src string) string {
+	for _, re := range shopeeSelectedREs {
+		m := re.FindStringSubmatch(src)
+		if m == nil {
+			continue
+		}
+		if t := clean(m[1], 0); shopeeOptionLike(t) {
+			return t
+		}
+	}
+	return ""
+}
+
+func shopeeVariationPrice(src string) string {
+	if m := shopeeVariationPriceRE.FindStringSubmatch(src); m != nil {
+		if p := normalizePrice(m[1]); p != "" {
+			return p
+		}
+	}
+	if g := findChain(src, ariaLivePoliteRE, step{re: dollarAmountRE, window: 1200}); g != nil {
+		return normalizePrice(g[0])
+	}
+	return ""
+}
+
+func (shopee) Variations(dir string) VariationsResult {
+	path, err := pickFile(dir, htmlCandidates)
+	if err != nil {
+		return variationsError(err)
+	}
+	src, err := readHTML(path)
+	if err != nil {
+		return variationsError(err)
+	}
+	titles := shopeeOptionTitles(src)
+
+	priceByTitle := make(map[string]string)
+	priceByPos := make(map[int]string)
+	var snapTitles []string
+	for i := 0; i < maxVariations; i++ {
+		p := variationHTMLPath(dir, i)
+		if p == "" {
+			continue
+		}
+		vsrc, err := readHTML(p)
+		if err != nil {
+			continue
+		}
+		title := shopeeSelectedTitle(vsrc)
+		if title == "" && i < len(titles) {
+			title = titles[i]
+		}
+		if !shopeeOptionLike(title) {
+			continue
+		}
+		snapTitles = append(snapTitles, title)
+		price := shopeeVariationPrice(vsrc)
+		if price == "" {
+			continue
+		}
+		if _, ok := priceByTitle[title]; !ok {
+			priceByTitle[title] = price
+		}
+		if _, ok := priceByPos[i]; !ok {
+			priceByPos[i] = price
+		}
+	}
+	if len(titles) == 0 {
+		titles = dedupeFold(snapTitles)
+	}
+
+	out := make([]VariationPrice, 0, min(len(titles), maxVariations))
+	for i, title := range titles {
+		if i >= maxVariations {
+			break
+		}
+		price, ok := priceByTitle[title]
+		if !ok {
+			price = priceByPos[i]
+		}
+		out = append(out, VariationPrice{Title: title, Position: i, Price: price})
+	}
+	return VariationsResult{Status: StatusOK, Variations: out}
+}
+
+var (
+	variationSectionRE  = regexp.MustCompile(`(?is)<h2[^>]*>\s*Variation\s*</h2>(.*?)</section>`)
+	ariaLabelButtonRE   = regexp.MustCompile(`(?i)<button[^>]+aria-label="([^"]+)"`)
+	selectedAriaLabelRE = regexp.MustCompile(`(?i)<button[^>]*selection-box-selected[^>]*aria-label="([^"]+)"`)
+	selectedSpanRE      = regexp.MustCompile(`(?is)<button[^>]*selection-box-selected[^>]*>.*?<span[^>]*>([^<]+)</span>`)
+	heroImgRE           = regexp.MustCompile(`(?i)<img[^>]*alt="Product image[^"]*"[^>]*>`)
+	imgTagRE            = regexp.MustCompile(`(?i)<img[^>]+>`)
+	imgAttrREs          = map[string]*regexp.Regexp{}
+)
+
+func init() {
+	for _, attr := range []string{"currentSrc", "src", "data-src", "data-lazy", "data-original", "alt"} {
+		imgAttrREs[attr] = regexp.MustCompile(`(?i)` + regexp.QuoteMeta(attr) + `="([^"]+)"`)
+	}
+}
+
+// shopeeTitleFallbacks maps option positions to titles, preferring an earlier
+// variations_extract.json over the initial snapshot's Variation section.
+func shopeeTitleFallbacks(dir string) map[int]string {
+	out := make(map[int]string)
+	if data, err := readJSONObject(filepath.Join(dir, VariationsFile)); err == nil {
+		for _, item := range list(data["variations"]) {
+			row := obj(item)
+			pos, ok := intValue(row["position"])
+			if t := clean(str(row["title"]), 0); ok && t != "" {
+				out[pos] = t
+			}
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+
+	path, err := pickFile(dir, htmlCandidates)
+	if err != nil {
+		return out
+	}
+	src, err := readHTML(path)
+	if err != nil {
+		return out
+	}
+	block := src
+	if m := variationSectionRE.FindStringSubmatch(src); m != nil {
+		block = m[1]
+	}
+	var labels []string
+	for _, m := range ariaLabelButtonRE.FindAllStringSubmatch(block, -1) {
+		labels = append(labels, m[1])
+	}
+	for i, t := range dedupeFold(labels) {
+		if i >= maxVariations {
+			break
+		}
+		out[i] = t
+	}
+	return out
+}
+
+func shopeeMapSelectedTitle(src string) string {
+	if m := selectedAriaLabelRE.FindStringSubmatch(src); m != nil {
+		return clean(m[1], 0)
+	}
+	if m := selectedSpanRE.FindStringSubmatch(src); m != nil {
+		return clean(m[1], 0)
+	}
+	return ""
+}
+
+func imgAttr(tag, attr string) string {
+	m := imgAttrREs[attr].FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return strings.ReplaceAll(clean(m[1], 0), `\/`, "/")
+}
+
+type scoredURL struct {
+	score int
+	url   string
+}
+
+func bestScored(candidates []scoredURL) string {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	for _, c := range candidates {
+		if isHTTPURL(c.url) {
+			return c.url
+		}
+	}
+	return ""
+}
+
+// shopeeOptionImage picks the single best product image of a variation
+// snapshot: the hero gallery image first, any product-like <img> otherwise.
+func shopeeOptionImage(src string) string {
+	const file = "susercontent.com/file/"
+
+	var hero []scoredURL
+	for _, tag := range heroImgRE.FindAllString(src, -1) {
+		for _, attr := range []string{"currentSrc", "src", "data-src", "data-lazy", "data-original"} {
+			u := imgAttr(tag, attr)
+			if !strings.Contains(u, file) {
+				continue
+			}
+			score := 0
+			if strings.Contains(u, "@resize_w450") {
+				score += 10
+			}
+			if strings.Contains(u, "@resize_w900") {
+				score += 9
+			}
+			if strings.Contains(u, "_tn") {
+				score -= 5
+			}
+			hero = append(hero, scoredURL{score, u})
+		}
+	}
+	if u := bestScored(hero); u != "" {
+		return u
+	}
+
+	var scored []scoredURL
+	for _, tag := range imgTagRE.FindAllString(src, -1) {
+		u := imgAttr(tag, "src")
+		if !strings.Contains(u, file) {
+			continue
+		}
+		score := 0
+		if strings.Contains(strings.ToLower(imgAttr(tag, "alt")), "product image") {
+			score += 10
+		}
+		if strings.Contains(u, "@resize_w450") || strings.Contains(u, "@resize_w900") {
+			score += 5
+		}
+		if strings.Contains(u, "_tn") {
+			score -= 3
+		}
+		scored = append(scored, scoredURL{score, u})
+	}
+	return bestScored(scored)
+}
+
+func (shopee) VariationImageMap(dir string) VariationImageMapResult {
+	fallbacks := shopeeTitleFallbacks(dir)
+	out := []VariationImages{}
+	for i := 0; i < maxVariations; i++ {
+		p := variationHTMLPath(dir, i)
+		if p == "" {
+			continue
+		}
+		src, err := readHTML(p)
+		if err != nil {
+			continue
+		}
+		title := shopeeMapSelectedTitle(src)
+		if title == "" {
+			title = fallbacks[i]
+		}
+		if title == "" {
+			continue
+		}
+		images := []string{}
+		if u := shopeeOptionImage(src); u != "" {
+			images = append(images, u)
+		}
+		out = append(out, VariationImages{Title: title, Position: i, Images: images})
+	}
+	return VariationImageMapResult{Status: StatusOK, Variations: out}
+}
@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// StageSummary is one entry of the stages block attached to crawl results: a
+// stage's status, timing and error as recorded in _pipeline-state.json.
+type StageSummary struct {
+	Stage      string `json:"stage"`
+	Status     string `json:"status"`
+	StartedAt  string `json:"started_at,omitempty"`
+	EndedAt    string `json:"ended_at,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	// Salvage is the stage's outcome when Salvage assembled final.json.
+	Salvage string `json:"salvage,omitempty"`
+}
+
+// ReadState reads _pipeline-state.json from dir. It returns nil, nil when the
+// run never wrote one.
+func ReadState(dir string) (*State, error) {
+	b, err := os.ReadFile(filepath.Join(dir, PipelineStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st State
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", PipelineStateFile, err)
+	}
+	return &st, nil
+}
+
+// ReadStages summarizes the pipeline state in dir, preferring meta.json's
+// stage durations over the second-precision state timestamps. It returns nil,
+// nil when there is no pipeline state.
+func ReadStages(dir string) ([]StageSummary, error) {
+	st, err := ReadState(dir)
+	if err != nil || st == nil {
+		return nil, err
+	}
+	var meta Meta
+	if b, err := os.ReadFile(filepath.Join(dir, MetaFile)); err == nil {
+		// meta.json is optional; a corrupt one only loses the precise durations.
+		_ = json.Unmarshal(b, &meta)
+	}
+	return summarize(st, meta.StageDurationMS), nil
+}
+
+// summarize lists the stages of st in pipeline order, followed by any stages
+// the skills added, by name.
+func summarize(st *State, durations map[string]int64) []StageSummary {
+	names := make([]string, 0, len(st.Stages))
+	for name := range st.Stages {
+		if !slices.Contains(Stages, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	names = append(slices.Clone(Stages), names...)
+
+	out := make([]StageSummary, 0, len(names))
+	for _, name := range names {
+		s := st.Stages[name]
+		if s == nil {
+			continue
+		}
+		d := durations[name]
+		if d <= 0 {
+			d = stageDuration(s.StartedAt, s.EndedAt)
+		}
+		out = append(out, StageSummary{
+			Stage:      name,
+			Status:     s.Status,
+			StartedAt:  s.StartedAt,
+			EndedAt:    s.EndedAt,
+			DurationMS: d,
+			Error:      s.Error,
+		})
+	}
+	return out
+}
+
+func stageDuration(startedAt, endedAt string) int64 {
+	start, err := time.Parse(time.RFC3339Nano, startedAt)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(time.RFC3339Nano, endedAt)
+	if err != nil || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Milliseconds()
+}
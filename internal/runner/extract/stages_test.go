@@ -0,0 +1,50 @@
+package extract
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReadStages_OrdersStagesAndPrefersMetaDurations(t *testing.T) {
+	dir := t.TempDir()
+	writeJSONFile(t, filepath.Join(dir, PipelineStateFile), map[string]any{
+		"run_id": "run-1",
+		"stages": map[string]any{
+			"z_custom":  map[string]any{"status": "completed"},
+			StageImages: map[string]any{"status": "error", "error": "no images", "started_at": "2026-01-01T00:00:05Z", "ended_at": "2026-01-01T00:00:07Z"},
+			StageCore:   map[string]any{"status": "completed", "started_at": "2026-01-01T00:00:01Z", "ended_at": "2026-01-01T00:00:05Z"},
+			"a_unknown": map[string]any{"status": "pending"},
+		},
+	})
+	writeJSONFile(t, filepath.Join(dir, MetaFile), map[string]any{
+		"stage_duration_ms": map[string]any{StageCore: 4321},
+	})
+
+	stages, err := ReadStages(dir)
+	if err != nil {
+		t.Fatalf("ReadStages error: %v", err)
+	}
+	var names []string
+	for _, s := range stages {
+		names = append(names, s.Stage)
+	}
+	want := []string{StageCore, StageImages, "a_unknown", "z_custom"}
+	if len(names) != len(want) {
+		t.Fatalf("stages = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("stages = %v, want %v", names, want)
+		}
+	}
+	if stages[0].DurationMS != 4321 || stages[1].DurationMS != 2000 || stages[1].Error != "no images" {
+		t.Fatalf("unexpected stages: %+v", stages)
+	}
+}
+
+func TestReadStages_NoStateFile(t *testing.T) {
+	stages, err := ReadStages(t.TempDir())
+	if err != nil || stages != nil {
+		t.Fatalf("expected nil, nil; got %v, %v", stages, err)
+	}
+}
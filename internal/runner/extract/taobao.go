@@ -0,0 +1,534 @@
+package extract
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// taobao ports the taobao-product-* skill scripts. Most fields come from the
+// SSR payload embedded in the initial snapshot rather than from markup.
+type taobao struct{}
+
+var (
+	taobaoTitleSuffixRE = regexp.MustCompile(`(?i)\s*[-_|]\s*(淘宝网|天猫|taobao).*$`)
+	taobaoBlockedRE     = regexp.MustCompile(`(?i)(captcha|verification|security check|verify|havanaone/login|x5secdata|请登录|登入|登录|验证码|安全验证|人机验证)`)
+
+	taobaoTitleChains = [][]*regexp.Regexp{
+		{regexp.MustCompile(`(?i)"item"\s*:\s*\{`), regexp.MustCompile(`(?i)"title":"([^"]{2,300})"\s*,\s*"itemId"`)},
+		{regexp.MustCompile(`(?i)"titleVO"\s*:\s*\{`), regexp.MustCompile(`(?i)"title"\s*:\s*\{\s*"title":"([^"]{2,300})"`)},
+		{regexp.MustCompile(`(?i)"pcBuyParams"\s*:\s*\{`), regexp.MustCompile(`(?i)"title":"([^"]{2,300})"`)},
+	}
+	taobaoTitleWindows = []int{120000, 12000, 12000}
+
+	// taobaoPropertyREs match the SSR parameter table; nameFirst tells which
+	// capture group holds the property name.
+	taobaoPropertyREs = []struct {
+		re        *regexp.Regexp
+		nameFirst bool
+	}{
+		{regexp.MustCompile(`(?i)\{"valueName":"([^"]{1,600})","propertyName":"([^"]{1,120})"\}`), false},
+		{regexp.MustCompile(`(?i)\{"text":\["([^"]{1,600})"\],"title":"([^"]{1,120})"\}`), false},
+		{regexp.MustCompile(`(?i)\{"propertyName":"([^"]{1,120})","valueName":"([^"]{1,600})"\}`), true},
+		{regexp.MustCompile(`(?i)\{"title":"([^"]{1,120})","text":\["([^"]{1,600})"\]\}`), true},
+	}
+	taobaoShopNameRE  = regexp.MustCompile(`(?i)"shopName":"([^"]{1,200})"`)
+	taobaoSellCountRE = regexp.MustCompile(`(?i)"vagueSellCount":"([^"]{1,80})"`)
+
+	priceVORE       = regexp.MustCompile(`(?i)"priceVO"\s*:\s*\{`)
+	extraPriceRE    = regexp.MustCompile(`(?i)"extraPrice"\s*:\s*\{`)
+	priceUnitRE     = regexp.MustCompile(`(?i)"priceUnit":"([^"]{1,8})"`)
+	priceTextRE     = regexp.MustCompile(`(?i)"priceText":"([^"]{1,32})"`)
+	subPriceRE      = regexp.MustCompile(`(?i)"subPrice"\s*:\s*\{`)
+	yuanPriceRE     = regexp.MustCompile(`[￥¥]\s*([0-9][0-9,]*(?:\.[0-9]+)?)`)
+	priceMoneyRE    = regexp.MustCompile(`(?i)"priceMoney":"([0-9]{2,})"`)
+	threeLetterRE   = regexp.MustCompile(`^[A-Za-z]{3}$`)
+	cnyMarkerRE     = regexp.MustCompile(`(?i)(CNY|RMB|人民币|元)`)
+	twdMarkerRE     = regexp.MustCompile(`(?i)(TWD|NT\$|NTD)`)
+	captureSkuIDRE  = regexp.MustCompile(`(?i)(?:[?&]|%26)skuId(?:=|%3D)([0-9]+)`)
+	protocolRelRE   = regexp.MustCompile(`(?i)"((?:https?:)?//[^"\s]+)"`)
+	itemImagesREs   = []*regexp.Regexp{regexp.MustCompile(`(?i)"item"\s*:\s*\{`), regexp.MustCompile(`(?i)"headImageVO"\s*:\s*\{`)}
+	itemImagesWins  = []int{200000, 20000}
+	imagesArrayRE   = regexp.MustCompile(`(?is)"images"\s*:\s*\[(.*?)\]`)
+	alicdnImageREs  = []*regexp.Regexp{regexp.MustCompile(`(?i)"((?:https?:)?//img\.alicdn\.com/imgextra/[^"\s]+)"`), regexp.MustCompile(`(?i)"((?:https?:)?//gw\.alicdn\.com/bao/uploaded/[^"\s]+)"`)}
+	genericRelImage = regexp.MustCompile(`(?i)((?:https?:)?//[^"'\s>]+\.(?:jpg|jpeg|png|webp)(?:\?[^"'\s>]*)?)`)
+)
+
+func (taobao) Core(dir string) CoreResult {
+	path, err := pickFile(dir, htmlCandidates)
+	if err != nil {
+		return coreError(err)
+	}
+	src, err := readHTML(path)
+	if err != nil {
+		return coreError(err)
+	}
+	manifest, err := readJSONObject(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return coreError(err)
+	}
+	pointer, err := readJSONObject(filepath.Join(dir, PointerFile))
+	if err != nil {
+		return coreError(err)
+	}
+
+	plain := plainText(src)
+	if blocked, note := taobaoBlocked(plain, pointer, manifest); blocked {
+		return CoreResult{Status: StatusNeedsManual, Notes: note}
+	}
+
+	title := clean(taobaoTitle(src), titleMaxChars)
+	description := taobaoDescription(src, title, plain)
+	currency, price := taobaoCurrencyPrice(src, plain)
+	return coreFromFields(title, description, currency, price)
+}
+
+func taobaoBlocked(plain string, pointer, manifest map[string]any) (bool, string) {
+	url := ""
+	for _, m := range []map[string]any{pointer, manifest} {
+		if u := str(m["url"]); u != "" {
+			url = clean(u, 500)
+			break
+		}
+	}
+	for _, m := range []map[string]any{pointer, manifest} {
+		notes := clean(str(m["notes"]), 300)
+		if strings.ToLower(strings.TrimSpace(str(m["status"]))) == StatusNeedsManual {
+			return true, firstNonEmpty(notes, "blocked or verification wall detected from manifest/pointer")
+		}
+		if m["blocked"] == true {
+			return true, firstNonEmpty(notes, "blocked flag detected from manifest/pointer")
+		}
+	}
+	if taobaoBlockedRE.MatchString(strings.ToLower(clean(url+" "+plain, 4000))) {
+		return true, "blocked or verification wall detected from html"
+	}
+	return false, ""
+}
+
+func taobaoTitle(src string) string {
+	var tag string
+	if m := titleTagRE.FindStringSubmatch(src); m != nil {
+		tag = clean(stripTags(m[1]), titleMaxChars)
+		tag = strings.TrimSpace(taobaoTitleSuffixRE.ReplaceAllString(tag, ""))
+	}
+	cands := []string{tag}
+	for i, chain := range taobaoTitleChains {
+		if g := findChain(src, chain[0], step{re: chain[1], window: taobaoTitleWindows[i]}); g != nil {
+			cands = append(cands, clean(g[0], 0))
+		}
+	}
+	return firstNonEmpty(cands...)
+}
+
+type propertyPair struct{ name, value string }
+
+// taobaoProperties collects up to 20 distinct name/value pairs from the
+// product parameter table.
+func taobaoProperties(src string) []propertyPair {
+	var out []propertyPair
+	seen := make(map[propertyPair]bool)
+	for _, p := range taobaoPropertyREs {
+		for _, m := range p.re.FindAllStringSubmatch(src, -1) {
+			name, value := m[2], m[1]
+			if p.nameFirst {
+				name, value = m[1], m[2]
+			}
+			pair := propertyPair{clean(name, 120), clean(value, 500)}
+			key := propertyPair{strings.ToLower(pair.name), strings.ToLower(pair.value)}
+			if pair.name == "" || pair.value == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			out = append(out, pair)
+			if len(out) >= 20 {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+func taobaoDescription(src, title, plain string) string {
+	var parts []string
+	for _, p := range taobaoProperties(src) {
+		if v := clean(p.value, 180); v != "" {
+			parts = append(parts, p.name+": "+v)
+		}
+		if len(parts) >= 8 {
+			break
+		}
+	}
+	if len(parts) > 0 {
+		return clean(strings.Join(parts, "; "), descMaxChars)
+	}
+
+	var fallback []string
+	if m := taobaoShopNameRE.FindStringSubmatch(src); m != nil {
+		if shop := clean(m[1], 120); shop != "" {
+			fallback = append(fallback, "店铺: "+shop)
+		}
+	}
+	if m := taobaoSellCountRE.FindStringSubmatch(src); m != nil {
+		if sales := clean(m[1], 40); sales != "" {
+			fallback = append(fallback, "销量: "+sales)
+		}
+	}
+	if title != "" {
+		fallback = append(fallback, "商品标题: "+title)
+	}
+	if len(fallback) > 0 {
+		return clean(strings.Join(fallback, "; "), descMaxChars)
+	}
+	return clean(plain, 300)
+}
+
+// centsToPrice renders a priceMoney value ("11900") as "119.00".
+func centsToPrice(cents string) string {
+	if len(cents) <= 2 {
+		return ""
+	}
+	return normalizePrice(cents[:len(cents)-2] + "." + cents[len(cents)-2:])
+}
+
+func taobaoCurrencyPrice(src, plain string) (string, string) {
+	var unit, price string
+	if g := findChain(src, priceVORE,
+		step{re: extraPriceRE, window: 6000},
+		step{re: priceUnitRE, window: 1200},
+		step{re: priceTextRE, window: 1200},
+	); g != nil {
+		unit = clean(g[0], 8)
+		price = normalizePrice(g[1])
+	}
+	if price == "" {
+		if g := findChain(src, subPriceRE, step{re: priceTextRE, window: 1200}); g != nil {
+			price = normalizePrice(g[0])
+		}
+	}
+	if unit == "" {
+		if m := priceUnitRE.FindStringSubmatch(src); m != nil {
+			unit = clean(m[1], 8)
+		}
+	}
+	if price == "" {
+		if m := yuanPriceRE.FindStringSubmatch(plain); m != nil {
+			price = normalizePrice(m[1])
+			if unit == "" {
+				unit = "￥"
+			}
+		}
+	}
+	if price == "" {
+		if m := priceMoneyRE.FindStringSubmatch(src); m != nil {
+			price = centsToPrice(m[1])
+		}
+	}
+
+	var currency string
+	switch {
+	case unit == "￥" || unit == "¥":
+		currency = "CNY"
+	case threeLetterRE.MatchString(unit):
+		currency = strings.ToUpper(unit)
+	case cnyMarkerRE.MatchString(unit + " " + plain):
+		currency = "CNY"
+	case twdMarkerRE.MatchString(unit + " " + plain):
+		currency = "TWD"
+	}
+	return currency, price
+}
+
+// normalizeImageURL undoes slash escaping and makes protocol-relative URLs
+// absolute.
+func normalizeImageURL(u string) string {
+	u = cleanURL(u)
+	if strings.HasPrefix(u, "//") {
+		u = "https:" + u
+	}
+	return u
+}
+
+func (taobao) Images(dir string) ImagesResult {
+	path, err := pickFile(dir, overlayCandidates)
+	if err != nil {
+		return imagesError(err)
+	}
+	src, err := readHTML(path)
+	if err != nil {
+		return imagesError(err)
+	}
+
+	// Item-level gallery images from the SSR payload are authoritative.
+	set := newURLSet(maxImages)
+	for i, first := range itemImagesREs {
+		for _, g := range findAllChains(src, first, step{re: imagesArrayRE, window: itemImagesWins[i]}) {
+			for _, m := range protocolRelRE.FindAllStringSubmatch(g[0], -1) {
+				if set.add(normalizeImageURL(m[1])) {
+					return ImagesResult{Status: StatusOK, Images: set.list()}
+				}
+			}
+		}
+	}
+	if len(set.urls) > 0 {
+		return ImagesResult{Status: StatusOK, Images: set.list()}
+	}
+
+	for _, re := range append(alicdnImageREs, genericRelImage) {
+		for _, m := range re.FindAllStringSubmatch(src, -1) {
+			if set.add(normalizeImageURL(m[1])) {
+				return ImagesResult{Status: StatusOK, Images: set.list()}
+			}
+		}
+	}
+	return ImagesResult{Status: StatusOK, Images: set.list()}
+}
+
+// taobaoRes returns loaderData.home.data.res from the page's bootstrap script.
+func taobaoRes(dir string) (map[string]any, error) {
+	path, err := pickFile(dir, htmlCandidates)
+	if err != nil {
+		return nil, err
+	}
+	src, err := readHTML(path)
+	if err != nil {
+		return nil, err
+	}
+	return obj(dig(jsObjectAfter(src, "var b = "), "loaderData", "home", "data", "res")), nil
+}
+
+// taobaoVariationGroup picks the 颜色分类 property, or the first one with values.
+func taobaoVariationGroup(res map[string]any) map[string]any {
+	props := list(dig(res, "skuBase", "props"))
+	for _, p := range props {
+		g := obj(p)
+		if strings.Contains(clean(str(g["name"]), 0), "颜色分类") && len(list(g["values"])) > 0 {
+			return g
+		}
+	}
+	for _, p := range props {
+		if g := obj(p); len(list(g["values"])) > 0 {
+			return g
+		}
+	}
+	return nil
+}
+
+func taobaoSkuPrices(res map[string]any) map[string]string {
+	out := make(map[string]string)
+	for skuID, v := range obj(dig(res, "skuCore", "sku2info")) {
+		info := obj(v)
+		if info == nil {
+			continue
+		}
+		price := normalizePrice(str(dig(info, "subPrice", "priceText")))
+		if price == "" {
+			price = normalizePrice(str(dig(info, "price", "priceText")))
+		}
+		for _, key := range []string{"subPrice", "price"} {
+			if price != "" {
+				break
+			}
+			if money := normalizePrice(str(dig(info, key, "priceMoney"))); isDigits(money) {
+				price = centsToPrice(money)
+			}
+		}
+		out[skuID] = price
+	}
+	return out
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (taobao) Variations(dir string) VariationsResult {
+	res, err := taobaoRes(dir)
+	if err != nil {
+		return variationsError(err)
+	}
+	out := []VariationPrice{}
+	group := taobaoVariationGroup(res)
+	pid := clean(str(group["pid"]), 0)
+	if pid == "" {
+		return VariationsResult{Status: StatusOK, Variations: out}
+	}
+
+	var titles []string
+	vidTitle := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, v := range list(group["values"]) {
+		val := obj(v)
+		title, vid := clean(str(val["name"]), 0), clean(str(val["vid"]), 0)
+		if title == "" || vid == "" || seen[strings.ToLower(title)] {
+			continue
+		}
+		seen[strings.ToLower(title)] = true
+		titles = append(titles, title)
+		vidTitle[vid] = title
+		if len(titles) >= maxVariations {
+			break
+		}
+	}
+	titlePos := make(map[string]int, len(titles))
+	for i, t := range titles {
+		titlePos[t] = i
+	}
+
+	skuPrices := taobaoSkuPrices(res)
+	pathRE := regexp.MustCompile(`(?:^|;)` + regexp.QuoteMeta(pid) + `:([^;]+)`)
+	priceByTitle := make(map[string]string)
+	priceByPos := make(map[int]string)
+	skuTitle := make(map[string]string)
+	for _, s := range list(dig(res, "skuBase", "skus")) {
+		sku := obj(s)
+		m := pathRE.FindStringSubmatch(clean(str(sku["propPath"]), 0))
+		if m == nil {
+			continue
+		}
+		title := vidTitle[m[1]]
+		if title == "" {
+			continue
+		}
+		skuID := clean(str(sku["skuId"]), 0)
+		if _, ok := skuTitle[skuID]; skuID != "" && !ok {
+			skuTitle[skuID] = title
+		}
+		price := skuPrices[skuID]
+		if price == "" {
+			continue
+		}
+		if _, ok := priceByTitle[title]; !ok {
+			priceByTitle[title] = price
+		}
+		if pos := titlePos[title]; priceByPos[pos] == "" {
+			priceByPos[pos] = price
+		}
+	}
+
+	// Prices of the SKUs actually captured in variation snapshots win.
+	for i := 0; i < maxVariations; i++ {
+		if variationHTMLPath(dir, i) == "" {
+			continue
+		}
+		skuID, err := capturedSkuID(dir, i)
+		if err != nil {
+			return variationsError(err)
+		}
+		price := skuPrices[skuID]
+		if skuID == "" || price == "" {
+			continue
+		}
+		if title := skuTitle[skuID]; title != "" {
+			priceByTitle[title] = price
+		} else {
+			priceByPos[i] = price
+		}
+	}
+
+	for i, title := range titles {
+		price := priceByTitle[title]
+		if price == "" {
+			price = priceByPos[i]
+		}
+		out = append(out, VariationPrice{Title: title, Position: i, Price: normalizePrice(price)})
+	}
+	return VariationsResult{Status: StatusOK, Variations: out}
+}
+
+// capturedSkuID reads the skuId the snapshot skill navigated to for the option
+// at position, from _variation<position>_capture.json.
+func capturedSkuID(dir string, position int) (string, error) {
+	capture, err := readJSONObject(filepath.Join(dir, fmt.Sprintf("_variation%d_capture.json", position)))
+	if err != nil {
+		return "", err
+	}
+	if m := captureSkuIDRE.FindStringSubmatch(clean(str(capture["target_url"]), 0)); m != nil {
+		return m[1], nil
+	}
+	return "", nil
+}
+
+func (taobao) VariationImageMap(dir string) VariationImageMapResult {
+	res, err := taobaoRes(dir)
+	if err != nil {
+		return variationImageMapError(err)
+	}
+	out := []VariationImages{}
+	group := taobaoVariationGroup(res)
+	values := list(group["values"])
+
+	titleImages := make(map[string][]string)
+	for _, v := range values {
+		val := obj(v)
+		title := clean(str(val["name"]), 0)
+		if title == "" {
+			continue
+		}
+		set := newURLSet(0)
+		for _, raw := range []any{val["image"], val["imageUrl"], dig(val, "corner", "icon")} {
+			set.add(normalizeImageURL(clean(str(raw), 0)))
+		}
+		if len(set.urls) > 0 {
+			titleImages[title] = set.urls
+		}
+	}
+	if len(titleImages) == 0 {
+		return VariationImageMapResult{Status: StatusOK, Variations: out}
+	}
+
+	order, err := readJSONObject(filepath.Join(dir, VariationsFile))
+	if err != nil {
+		return variationImageMapError(err)
+	}
+	type entry struct {
+		title    string
+		position int
+	}
+	var entries []entry
+	for _, r := range list(order["variations"]) {
+		row := obj(r)
+		title := clean(str(row["title"]), 0)
+		pos, ok := intValue(row["position"])
+		if title == "" || !ok {
+			continue
+		}
+		entries = append(entries, entry{title, pos})
+		if len(entries) >= maxVariations {
+			break
+		}
+	}
+	if len(entries) == 0 {
+		// Without variations_extract.json, fall back to the group value order.
+		for i, v := range values {
+			if i >= maxVariations {
+				break
+			}
+			entries = append(entries, entry{clean(str(obj(v)["name"]), 0), i})
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		key := strings.ToLower(e.title)
+		images := titleImages[e.title]
+		if e.title == "" || seen[key] || len(images) == 0 {
+			continue
+		}
+		seen[key] = true
+		out = append(out, VariationImages{Title: e.title, Position: e.position, Images: images})
+		if len(out) >= maxVariations {
+			break
+		}
+	}
+	return VariationImageMapResult{Status: StatusOK, Variations: out}
+}
@@ -0,0 +1,167 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/internal/source"
+)
+
+// FailureClass categorizes why a tool attempt failed.
+type FailureClass string
+
+const (
+	FailureNone                 FailureClass = ""
+	FailureUnknownTool          FailureClass = "unknown_tool"
+	FailureAuth                 FailureClass = "auth_failed"
+	FailureToolExit             FailureClass = "tool_exit"
+	FailureUnparseableOutput    FailureClass = "unparseable_output"
+	FailureMissingFinalArtifact FailureClass = "missing_final_artifact"
+	FailureTimeout              FailureClass = "timeout"
+	FailureCanceled             FailureClass = "canceled"
+	FailureResultError          FailureClass = "result_error"
+	FailureContractInvalid      FailureClass = "contract_invalid"
+)
+
+// failover reports whether the next tool in the chain should be tried. Failures that
+// describe the page itself (result_error, contract_invalid) or the caller giving up
+// (timeout, canceled) do not fail over.
+func (c FailureClass) failover() bool {
+	switch c {
+	case FailureUnknownTool,
+		FailureAuth,
+		FailureToolExit,
+		FailureUnparseableOutput,
+		FailureMissingFinalArtifact:
+		return true
+	default:
+		return false
+	}
+}
+
+// Attempt records one tool run within RunOnce. The final Result lists every attempt
+// under the "attempts" key.
+type Attempt struct {
+	Tool         string `json:"tool"`
+	RunID        string `json:"run_id,omitempty"`
+	Status       string `json:"status"`
+	DurationMS   int64  `json:"duration_ms"`
+	FailureClass string `json:"failure_class,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func newAttempt(opts Options, res Result, class FailureClass, err error, d time.Duration) Attempt {
+	a := Attempt{
+		Tool:         opts.Tool,
+		RunID:        opts.RunID,
+		DurationMS:   d.Milliseconds(),
+		FailureClass: string(class),
+	}
+	if status, ok := res["status"].(string); ok {
+		a.Status = status
+	}
+	if err != nil {
+		a.Error = err.Error()
+		a.Status = "error"
+	}
+	return a
+}
+
+// toolChain returns the ordered, de-duplicated tool list for a run: opts.Tool followed
+// by the per-source fallback list when present, else the global one.
+func toolChain(opts Options, src source.Source) []string {
+	fallback := opts.Fallback
+	if bySource, ok := opts.FallbackBySource[string(src)]; ok {
+		fallback = bySource
+	}
+
+	chain := make([]string, 0, 1+len(fallback))
+	seen := make(map[string]bool, 1+len(fallback))
+	for _, tool := range append([]string{opts.Tool}, fallback...) {
+		tool = strings.TrimSpace(tool)
+		if tool == "" || seen[tool] {
+			continue
+		}
+		seen[tool] = true
+		chain = append(chain, tool)
+	}
+	return chain
+}
+
+// attemptRunID places each attempt of a multi-tool chain in its own sub-directory of the
+// run's artifact dir: artifacts/<run_id>/attempt-<n>-<tool>/.
+func attemptRunID(runID string, idx int, tool string) string {
+	if strings.TrimSpace(runID) == "" {
+		return ""
+	}
+	return path.Join(runID, fmt.Sprintf("attempt-%d-%s", idx+1, tool))
+}
+
+func classifyRunErr(runErr error, authErr error) FailureClass {
+	switch {
+	case errors.Is(runErr, ErrToolTimeout):
+		return FailureTimeout
+	case errors.Is(runErr, ErrToolCanceled):
+		return FailureCanceled
+	case authErr != nil:
+		return FailureAuth
+	case errors.Is(runErr, ErrNonJSONOutput), errors.Is(runErr, ErrInvalidJSON):
+		return FailureUnparseableOutput
+	default:
+		return FailureToolExit
+	}
+}
+
+func classifyFinalArtifactErr(err error, runErr error, authErr error) FailureClass {
+	switch {
+	case errors.Is(runErr, ErrToolTimeout):
+		return FailureTimeout
+	case errors.Is(runErr, ErrToolCanceled):
+		return FailureCanceled
+	case errors.Is(err, errFinalArtifactStatusError):
+		return FailureResultError
+	case errors.Is(err, fs.ErrNotExist):
+		if authErr != nil {
+			return FailureAuth
+		}
+		return FailureMissingFinalArtifact
+	case errors.Is(err, ErrInvalidJSON):
+		return FailureUnparseableOutput
+	default:
+		return FailureNone
+	}
+}
+
+// ParseToolList parses a comma-separated tool list such as "gemini,claude".
+func ParseToolList(raw string) []string {
+	out := make([]string, 0, 2)
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// ParseToolChainsBySource parses per-source fallback lists such as
+// "shopee=gemini;taobao=gemini,claude".
+func ParseToolChainsBySource(raw string) (map[string][]string, error) {
+	out := make(map[string][]string)
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		src, tools, ok := strings.Cut(entry, "=")
+		src = strings.TrimSpace(src)
+		if !ok || src == "" {
+			return nil, fmt.Errorf("invalid tool fallback entry %q (want <source>=<tool>[,<tool>...])", entry)
+		}
+		out[src] = ParseToolList(tools)
+	}
+	return out, nil
+}
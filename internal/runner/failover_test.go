@@ -0,0 +1,330 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"peasydeal-product-miner/internal/source"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+func writeFinalArtifact(t *testing.T, outDir string, runID string, body string) {
+	t.Helper()
+
+	dir := filepath.Join(outDir, "artifacts", runID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "final.json"), []byte(body), 0o644); err != nil {
+		t.Fatalf("write final.json: %v", err)
+	}
+}
+
+func TestRunOnce_FailoverToNextToolOnMissingFinalArtifact(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	// Only the gemini attempt produces final.json, in its own attempt sub-directory.
+	writeFinalArtifact(t, outDir, "run-1/attempt-2-gemini",
+		`{"url":"https://shopee.tw/i.1.2","status":"ok","captured_at":"2026-01-01T00:00:00Z","title":"t","images":[],"variations":[]}`)
+
+	codex := &stubToolRunner{name: "codex", runErr: fmt.Errorf("codex exec failed: exit status 1")}
+	gemini := &stubToolRunner{name: "gemini", raw: "ignored"}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": codex, "gemini": gemini},
+		validator: validator.New(),
+	}
+
+	outPath, res, err := r.RunOnce(context.Background(), Options{
+		URL:      "https://shopee.tw/i.1.2",
+		OutDir:   outDir,
+		Tool:     "codex",
+		RunID:    "run-1",
+		Fallback: []string{"gemini"},
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if got, _ := res["status"].(string); got != "ok" {
+		t.Fatalf("unexpected status: %#v", res["status"])
+	}
+	if want := filepath.Join(outDir, "artifacts", "run-1", "attempt-2-gemini", "final.json"); outPath != want {
+		t.Fatalf("unexpected out path: %s", outPath)
+	}
+	if codex.runCalls != 1 || gemini.runCalls != 1 {
+		t.Fatalf("unexpected calls codex=%d gemini=%d", codex.runCalls, gemini.runCalls)
+	}
+
+	attempts, ok := res["attempts"].([]Attempt)
+	if !ok || len(attempts) != 2 {
+		t.Fatalf("unexpected attempts: %#v", res["attempts"])
+	}
+	if attempts[0].Tool != "codex" || attempts[0].FailureClass != string(FailureMissingFinalArtifact) || attempts[0].Error == "" {
+		t.Fatalf("unexpected first attempt: %#v", attempts[0])
+	}
+	if attempts[0].RunID != "run-1/attempt-1-codex" {
+		t.Fatalf("unexpected first attempt run id: %s", attempts[0].RunID)
+	}
+	if attempts[1].Tool != "gemini" || attempts[1].Status != "ok" || attempts[1].Error != "" {
+		t.Fatalf("unexpected second attempt: %#v", attempts[1])
+	}
+}
+
+func TestRunOnce_SalvagesOnlyOnLastAttempt(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	// Both attempts leave stage artifacts behind; only gemini writes final.json.
+	for _, runID := range []string{"run-3/attempt-1-codex", "run-3/attempt-2-gemini"} {
+		dir := filepath.Join(outDir, "artifacts", runID)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "core_extract.json"), []byte(`{"status":"ok","title":"partial"}`), 0o644); err != nil {
+			t.Fatalf("write core_extract.json: %v", err)
+		}
+	}
+	writeFinalArtifact(t, outDir, "run-3/attempt-2-gemini",
+		`{"url":"https://shopee.tw/i.1.2","status":"ok","captured_at":"2026-01-01T00:00:00Z","title":"t","images":[],"variations":[]}`)
+
+	codex := &stubToolRunner{name: "codex", runErr: fmt.Errorf("codex exec failed: exit status 1")}
+	gemini := &stubToolRunner{name: "gemini", raw: "ignored"}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": codex, "gemini": gemini},
+		validator: validator.New(),
+	}
+
+	opts := Options{
+		URL:      "https://shopee.tw/i.1.2",
+		OutDir:   outDir,
+		Tool:     "codex",
+		RunID:    "run-3",
+		Fallback: []string{"gemini"},
+	}
+	_, res, err := r.RunOnce(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if gemini.runCalls != 1 || res["title"] != "t" {
+		t.Fatalf("expected the gemini result, gemini calls=%d title=%#v", gemini.runCalls, res["title"])
+	}
+
+	// Without a fallback the failed tool's stage artifacts are salvaged.
+	opts.Fallback = nil
+	opts.RunID = "run-3/attempt-1-codex"
+	_, res, err = r.RunOnce(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if res["status"] != "needs_manual" || res["title"] != "partial" {
+		t.Fatalf("expected a salvaged result: %#v", res)
+	}
+}
+
+func TestRunOnce_TimeoutCoversWholeChain(t *testing.T) {
+	t.Parallel()
+
+	codex := &stubToolRunner{name: "codex", runErr: fmt.Errorf("codex exec failed: exit status 1")}
+	gemini := &stubToolRunner{name: "gemini", runErr: fmt.Errorf("gemini exec failed: exit status 1")}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": codex, "gemini": gemini},
+		validator: validator.New(),
+	}
+
+	_, _, err := r.RunOnce(context.Background(), Options{
+		URL:      "https://shopee.tw/i.1.2",
+		OutDir:   t.TempDir(),
+		Tool:     "codex",
+		RunID:    "run-4",
+		Timeout:  time.Hour,
+		Fallback: []string{"gemini"},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(codex.deadlines) != 1 || len(gemini.deadlines) != 1 || !codex.deadlines[0].Equal(gemini.deadlines[0]) {
+		t.Fatalf("both attempts should share one deadline: codex=%v gemini=%v", codex.deadlines, gemini.deadlines)
+	}
+}
+
+func TestRunOnce_NoFailoverOnFinalArtifactStatusError(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	writeFinalArtifact(t, outDir, "run-2/attempt-1-codex",
+		`{"url":"https://shopee.tw/i.1.2","status":"error","captured_at":"2026-01-01T00:00:00Z","error":"item removed"}`)
+
+	codex := &stubToolRunner{name: "codex", raw: "ignored"}
+	gemini := &stubToolRunner{name: "gemini", raw: "ignored"}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": codex, "gemini": gemini},
+		validator: validator.New(),
+	}
+
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:      "https://shopee.tw/i.1.2",
+		OutDir:   outDir,
+		Tool:     "codex",
+		RunID:    "run-2",
+		Fallback: []string{"gemini"},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if gemini.runCalls != 0 {
+		t.Fatalf("expected no failover, gemini calls=%d", gemini.runCalls)
+	}
+	attempts, _ := res["attempts"].([]Attempt)
+	if len(attempts) != 1 || attempts[0].FailureClass != string(FailureResultError) {
+		t.Fatalf("unexpected attempts: %#v", res["attempts"])
+	}
+}
+
+func TestRunOnce_FailoverChainIsDeduped(t *testing.T) {
+	t.Parallel()
+
+	codex := &stubToolRunner{name: "codex", runErr: fmt.Errorf("codex returned %w: boom", ErrNonJSONOutput)}
+	gemini := &stubToolRunner{name: "gemini", runErr: fmt.Errorf("gemini returned %w: boom", ErrNonJSONOutput)}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": codex, "gemini": gemini},
+		validator: validator.New(),
+	}
+
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:       "https://shopee.tw/i.1.2",
+		OutDir:    t.TempDir(),
+		Tool:      "codex",
+		SkillName: shopeeOrchestratorPipelineSkill,
+		RunID:     "run-3",
+		Fallback:  []string{"gemini", "codex"},
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	attempts, _ := res["attempts"].([]Attempt)
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts (deduped chain), got %#v", res["attempts"])
+	}
+	if codex.runCalls != 1 || gemini.runCalls != 1 {
+		t.Fatalf("unexpected calls codex=%d gemini=%d", codex.runCalls, gemini.runCalls)
+	}
+}
+
+func TestToolChain_PerSourceOverridesGlobal(t *testing.T) {
+	t.Parallel()
+
+	bySource, err := ParseToolChainsBySource("shopee=claude, gemini; taobao=")
+	if err != nil {
+		t.Fatalf("ParseToolChainsBySource error: %v", err)
+	}
+
+	opts := Options{
+		Tool:             "codex",
+		Fallback:         ParseToolList("gemini"),
+		FallbackBySource: bySource,
+	}
+
+	if got := toolChain(opts, source.Shopee); !reflect.DeepEqual(got, []string{"codex", "claude", "gemini"}) {
+		t.Fatalf("unexpected shopee chain: %#v", got)
+	}
+	if got := toolChain(opts, source.Taobao); !reflect.DeepEqual(got, []string{"codex"}) {
+		t.Fatalf("unexpected taobao chain: %#v", got)
+	}
+	if got := toolChain(Options{Tool: "codex", Fallback: []string{"gemini"}}, source.Taobao); !reflect.DeepEqual(got, []string{"codex", "gemini"}) {
+		t.Fatalf("unexpected global chain: %#v", got)
+	}
+}
+
+func TestParseToolChainsBySource_RejectsMalformedEntry(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseToolChainsBySource("shopee"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestRunOnce_ModelOverrideAndHintsReachRequestedToolOnly(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	writeFinalArtifact(t, outDir, "run-1/attempt-2-gemini",
+		`{"url":"https://shopee.tw/i.1.2","status":"ok","captured_at":"2026-01-01T00:00:00Z","title":"t","images":[],"variations":[]}`)
+
+	codex := &stubToolRunner{name: "codex", runErr: fmt.Errorf("codex exec failed: exit status 1")}
+	gemini := &stubToolRunner{name: "gemini", raw: "ignored"}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": codex, "gemini": gemini},
+		validator: validator.New(),
+	}
+
+	_, _, err := r.RunOnce(context.Background(), Options{
+		URL:      "https://shopee.tw/i.1.2",
+		OutDir:   outDir,
+		Tool:     "codex",
+		Model:    "gpt-5.2-mini",
+		RunID:    "run-1",
+		Fallback: []string{"gemini"},
+		Hints:    Hints{Category: "snacks", Locale: "zh-TW", Notes: "prefer the\nbundle price"},
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if !reflect.DeepEqual(codex.models, []string{"gpt-5.2-mini"}) {
+		t.Fatalf("codex should run on the override model, got %#v", codex.models)
+	}
+	if !reflect.DeepEqual(gemini.models, []string{""}) {
+		t.Fatalf("the fallback tool should keep its own model, got %#v", gemini.models)
+	}
+	for _, want := range []string{"Target category: snacks", "Target locale: zh-TW", "Requester notes: prefer the bundle price"} {
+		if !strings.Contains(gemini.prompt, want) {
+			t.Fatalf("expected %q in prompt, got: %s", want, gemini.prompt)
+		}
+	}
+}
+
+func TestRunOnce_ModelOverrideSurvivesTimeout(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	writeFinalArtifact(t, outDir, "run-1/attempt-1-codex",
+		`{"url":"https://shopee.tw/i.1.2","status":"ok","captured_at":"2026-01-01T00:00:00Z","title":"t","images":[],"variations":[]}`)
+
+	codex := &stubToolRunner{name: "codex", raw: "ignored"}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": codex, "gemini": &stubToolRunner{name: "gemini"}},
+		validator: validator.New(),
+	}
+
+	// The worker always sets Timeout (CRAWL_TIMEOUT), so the override must
+	// survive the timeout context.
+	_, _, err := r.RunOnce(context.Background(), Options{
+		URL:      "https://shopee.tw/i.1.2",
+		OutDir:   outDir,
+		Tool:     "codex",
+		Model:    "gpt-5.2-mini",
+		Timeout:  time.Minute,
+		RunID:    "run-1",
+		Fallback: []string{"gemini"},
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if !reflect.DeepEqual(codex.models, []string{"gpt-5.2-mini"}) {
+		t.Fatalf("codex should run on the override model, got %#v", codex.models)
+	}
+}
@@ -1,6 +1,8 @@
 package fx
 
 import (
+	"strings"
+
 	"peasydeal-product-miner/config"
 	runnerPkg "peasydeal-product-miner/internal/runner"
 
@@ -18,6 +20,74 @@ func AsRunner(f any) fx.Option {
 	)
 }
 
+// AsCommandRunners registers a constructor returning several ToolRunners at once.
+func AsCommandRunners(f any) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			f,
+			fx.ResultTags(`group:"tool_runners,flatten"`),
+		),
+	)
+}
+
+type NewCommandRunnersParams struct {
+	fx.In
+
+	Logger *zap.SugaredLogger
+	Cfg    *config.Config
+}
+
+// NewCommandRunners builds one CommandRunner per tool declared in CRAWL_TOOLS_FILE.
+// It returns no runners when the file is not configured.
+func NewCommandRunners(p NewCommandRunnersParams) ([]runnerPkg.ToolRunner, error) {
+	if p.Cfg == nil || strings.TrimSpace(p.Cfg.CrawlToolsFile) == "" {
+		return nil, nil
+	}
+
+	specs, err := runnerPkg.LoadCommandRunnerSpecs(strings.TrimSpace(p.Cfg.CrawlToolsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]runnerPkg.ToolRunner, 0, len(specs))
+	for _, spec := range specs {
+		out = append(out, runnerPkg.NewCommandRunner(runnerPkg.CommandRunnerConfig{
+			Spec:   spec,
+			Logger: p.Logger,
+		}))
+		p.Logger.Infow("command_tool_registered", "tool", spec.Name, "cmd", spec.Cmd)
+	}
+	return out, nil
+}
+
+type NewSchemaValidatorParams struct {
+	fx.In
+
+	Logger *zap.SugaredLogger
+	Cfg    *config.Config
+}
+
+// NewSchemaValidator loads CRAWL_SCHEMA_FILE in CRAWL_SCHEMA_MODE. It returns nil when
+// no schema file is configured.
+func NewSchemaValidator(p NewSchemaValidatorParams) (*runnerPkg.SchemaValidator, error) {
+	if p.Cfg == nil {
+		return nil, nil
+	}
+
+	mode, err := runnerPkg.ParseSchemaMode(p.Cfg.CrawlSchemaMode)
+	if err != nil {
+		return nil, err
+	}
+	v, err := runnerPkg.NewSchemaValidator(p.Cfg.CrawlSchemaFile, mode)
+	if err != nil {
+		return nil, err
+	}
+	if v != nil {
+		p.Logger.Infow("runner_schema_enabled", "path", p.Cfg.CrawlSchemaFile, "mode", string(mode))
+	}
+	return v, nil
+}
+
 // Provide config struct for `runnerPkg.CodexRunnerConfig`
 type NewCodexRunnerConfigParams struct {
 	fx.In
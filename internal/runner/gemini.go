@@ -32,7 +32,6 @@ type GeminiRunner struct {
 	workDir string
 	logger  *zap.SugaredLogger
 
-	execCommand        func(name string, args ...string) *exec.Cmd
 	execCommandContext func(ctx context.Context, name string, args ...string) *exec.Cmd
 }
 
@@ -41,7 +40,6 @@ func NewGeminiRunner(cfg GeminiRunnerConfig) *GeminiRunner {
 		cmd:                cfg.Cmd,
 		model:              cfg.Model,
 		workDir:            resolveRunnerWorkDir(cfg.WorkDir),
-		execCommand:        exec.Command,
 		execCommandContext: exec.CommandContext,
 		logger:             cfg.Logger,
 	}
@@ -49,7 +47,7 @@ func NewGeminiRunner(cfg GeminiRunnerConfig) *GeminiRunner {
 
 func (r *GeminiRunner) Name() string { return "gemini" }
 
-func (r *GeminiRunner) CheckAuth() error {
+func (r *GeminiRunner) CheckAuth(ctx context.Context) error {
 	paths := []string{
 		".gemini/oauth_creds.json",
 		".gemini/google_accounts.json",
@@ -71,34 +69,35 @@ func (r *GeminiRunner) CheckAuth() error {
 		}
 	}
 
-	ok, errText := r.runAuthProbe()
+	ok, errText := r.runAuthProbe(ctx)
 	if !ok {
 		return fmt.Errorf("gemini auth probe failed: %s", errText)
 	}
 	return nil
 }
 
-func (r *GeminiRunner) Run(url string, prompt string) (string, error) {
-	modelText, err := r.runModelText(url, prompt)
+func (r *GeminiRunner) Run(ctx context.Context, url string, prompt string) (string, error) {
+	modelText, err := r.runModelText(ctx, url, prompt)
 	if err != nil {
 		return "", err
 	}
 
 	if _, err := extractJSONObjectWithStatus(modelText); err != nil {
-		return "", fmt.Errorf("gemini returned non-JSON output: %w", err)
+		return "", fmt.Errorf("gemini returned %w: %w", ErrNonJSONOutput, err)
 	}
 	return modelText, nil
 }
 
-func (r *GeminiRunner) runModelText(url string, prompt string) (string, error) {
+func (r *GeminiRunner) runModelText(ctx context.Context, url string, prompt string) (string, error) {
 	// gemini [query..]
 	// We use -o json to ensure we get parsable output.
 	args := []string{"-o", "json"}
-	if r.model != "" {
-		args = append(args, "--model", r.model)
+	model := modelFor(ctx, r.model)
+	if model != "" {
+		args = append(args, "--model", model)
 	}
 
-	r.logger.Infof("🏃🏻 running on model: %v", r.model)
+	r.logger.Infof("🏃🏻 running on model: %v", model)
 
 	// Skills rely on tool calls (MCP DevTools + filesystem writes for artifacts). In CI/worker/headless runs
 	// we must auto-approve tool actions. Allow overriding via env for safer deployments.
@@ -121,7 +120,7 @@ func (r *GeminiRunner) runModelText(url string, prompt string) (string, error) {
 	start := time.Now()
 	r.logger.Infow("crawl_started", "tool", "gemini", "url", url)
 
-	cmd := r.execCommand(r.cmd, args...)
+	cmd := newToolCommand(ctx, r.execCommandContext, r.cmd, args...)
 	if r.workDir != "" {
 		cmd.Dir = r.workDir
 	}
@@ -135,6 +134,9 @@ func (r *GeminiRunner) runModelText(url string, prompt string) (string, error) {
 			time.Since(start).Round(time.Millisecond),
 			fmt.Sprintf("std err %v, command err %v", cmd.Stderr, err.Error()),
 		)
+		if cerr := runContextErr(ctx); cerr != nil {
+			return "", fmt.Errorf("gemini failed: %w", cerr)
+		}
 		return "", fmt.Errorf("gemini failed: %s", err.Error())
 	}
 
@@ -156,19 +158,20 @@ func (r *GeminiRunner) runModelText(url string, prompt string) (string, error) {
 	return raw, nil
 }
 
-func (r *GeminiRunner) runAuthProbe() (bool, string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+func (r *GeminiRunner) runAuthProbe(ctx context.Context) (bool, string) {
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
 	defer cancel()
 
 	args := []string{"-o", "json", "--prompt", "Return exactly: OK"}
-	if r.model != "" {
-		args = append(args, "--model", r.model)
+	model := modelFor(ctx, r.model)
+	if model != "" {
+		args = append(args, "--model", model)
 	}
 	for _, dir := range geminiIncludeDirectories(r.workDir) {
 		args = append(args, "--include-directories", dir)
 	}
 
-	cmd := r.execCommandContext(ctx, r.cmd, args...)
+	cmd := newToolCommand(ctx, r.execCommandContext, r.cmd, args...)
 	if r.workDir != "" {
 		cmd.Dir = r.workDir
 	}
@@ -191,6 +194,12 @@ func (r *GeminiRunner) runAuthProbe() (bool, string) {
 //
 // We want stdout to be the model text only (which should be the JSON contract).
 func unwrapGeminiJSON(raw string) (string, bool) {
+	return unwrapJSONField(raw, "response")
+}
+
+// unwrapJSONField extracts the value at a dot-separated field path from a JSON wrapper
+// object. String values are returned trimmed; object values are re-marshaled.
+func unwrapJSONField(raw string, fieldPath string) (string, bool) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" || !strings.HasPrefix(raw, "{") {
 		return "", false
@@ -201,12 +210,19 @@ func unwrapGeminiJSON(raw string) (string, bool) {
 		return "", false
 	}
 
-	resp, ok := obj["response"]
-	if !ok {
-		return "", false
+	var cur any = obj
+	for _, key := range strings.Split(strings.TrimSpace(fieldPath), ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		cur, ok = m[key]
+		if !ok {
+			return "", false
+		}
 	}
 
-	switch v := resp.(type) {
+	switch v := cur.(type) {
 	case string:
 		s := strings.TrimSpace(v)
 		if s == "" {
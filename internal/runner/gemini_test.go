@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -27,10 +28,10 @@ func TestGeminiRunner_Run_NoRetryWhenInvalidJSON(t *testing.T) {
 		Logger: zap.NewNop().Sugar(),
 	})
 
-	r.execCommand = func(_ string, args ...string) *exec.Cmd {
+	r.execCommandContext = func(ctx context.Context, _ string, args ...string) *exec.Cmd {
 		calls = append(calls, append([]string(nil), args...))
 
-		cmd := exec.Command(os.Args[0], "-test.run=TestGeminiRunnerHelperProcess", "--")
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=TestGeminiRunnerHelperProcess", "--")
 		cmd.Env = append(os.Environ(),
 			"GO_WANT_HELPER_PROCESS=1",
 			fmt.Sprintf("HELPER_STDOUT=%s", outputs[callIdx]),
@@ -41,7 +42,7 @@ func TestGeminiRunner_Run_NoRetryWhenInvalidJSON(t *testing.T) {
 		return cmd
 	}
 
-	_, err := r.Run("https://example.com", "original prompt")
+	_, err := r.Run(context.Background(), "https://example.com", "original prompt")
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -67,10 +68,10 @@ func TestGeminiRunner_Run_ValidJSON(t *testing.T) {
 		Logger: zap.NewNop().Sugar(),
 	})
 
-	r.execCommand = func(_ string, args ...string) *exec.Cmd {
+	r.execCommandContext = func(ctx context.Context, _ string, args ...string) *exec.Cmd {
 		calls = append(calls, append([]string(nil), args...))
 
-		cmd := exec.Command(os.Args[0], "-test.run=TestGeminiRunnerHelperProcess", "--")
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=TestGeminiRunnerHelperProcess", "--")
 		cmd.Env = append(os.Environ(),
 			"GO_WANT_HELPER_PROCESS=1",
 			fmt.Sprintf("HELPER_STDOUT=%s", outputs[callIdx]),
@@ -81,7 +82,7 @@ func TestGeminiRunner_Run_ValidJSON(t *testing.T) {
 		return cmd
 	}
 
-	got, err := r.Run("https://example.com", "original prompt")
+	got, err := r.Run(context.Background(), "https://example.com", "original prompt")
 	if err != nil {
 		t.Fatalf("Run error: %v", err)
 	}
@@ -0,0 +1,26 @@
+package runner
+
+import (
+	"context"
+	"strings"
+)
+
+type modelOverrideKey struct{}
+
+// withModelOverride makes tool runners use model instead of their configured one
+// for runs under ctx. An empty model leaves ctx unchanged.
+func withModelOverride(ctx context.Context, model string) context.Context {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, modelOverrideKey{}, model)
+}
+
+// modelFor returns the per-run model override carried by ctx, or configured.
+func modelFor(ctx context.Context, configured string) string {
+	if model, ok := ctx.Value(modelOverrideKey{}).(string); ok && model != "" {
+		return model
+	}
+	return configured
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"time"
+)
+
+// ErrToolTimeout is returned (wrapped) when a tool run exceeds its deadline.
+var ErrToolTimeout = errors.New("tool run timed out")
+
+// ErrToolCanceled is returned (wrapped) when a tool run is canceled by the caller.
+var ErrToolCanceled = errors.New("tool run canceled")
+
+const (
+	errorReasonTimeout  = "timeout"
+	errorReasonCanceled = "canceled"
+)
+
+// processWaitDelay bounds how long Wait blocks on stdout/stderr pipes after the
+// process group was killed (e.g. a stray grandchild still holding the pipe).
+const processWaitDelay = 5 * time.Second
+
+// newToolCommand builds an *exec.Cmd bound to ctx that runs in its own process group,
+// so cancellation kills the whole tree (Chrome MCP helpers, python snapshot scripts, ...)
+// instead of only the direct child.
+func newToolCommand(
+	ctx context.Context,
+	execCommandContext func(ctx context.Context, name string, args ...string) *exec.Cmd,
+	name string,
+	args ...string,
+) *exec.Cmd {
+	cmd := execCommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = processWaitDelay
+	return cmd
+}
+
+// runContextErr maps a done context to ErrToolTimeout / ErrToolCanceled.
+// It returns nil while ctx is still live.
+func runContextErr(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrToolTimeout
+	default:
+		return ErrToolCanceled
+	}
+}
+
+// errorReason returns a stable machine-readable reason for well-known run errors.
+func errorReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrToolTimeout):
+		return errorReasonTimeout
+	case errors.Is(err, ErrToolCanceled):
+		return errorReasonCanceled
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package runner
+
+import "os/exec"
+
+// setProcessGroup is a no-op on platforms without POSIX process groups; the default
+// exec.CommandContext behavior (kill the direct child) applies.
+func setProcessGroup(cmd *exec.Cmd) {
+	_ = cmd
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestNewToolCommand_CancelKillsProcessGroup(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The backgrounded sleep inherits stdout; without a process-group kill it would keep
+	// the pipe open and Wait would block until processWaitDelay.
+	cmd := newToolCommand(ctx, exec.CommandContext, "sh", "-c", "sleep 30 & sleep 30")
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	start := time.Now()
+	err := cmd.Run()
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if elapsed := time.Since(start); elapsed >= processWaitDelay {
+		t.Fatalf("process group was not killed: waited %s", elapsed)
+	}
+	if got := runContextErr(ctx); got != ErrToolTimeout {
+		t.Fatalf("unexpected context error: %v", got)
+	}
+}
@@ -1,13 +1,18 @@
 package runner
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"peasydeal-product-miner/internal/product"
+	"peasydeal-product-miner/internal/runner/extract"
 	"peasydeal-product-miner/internal/source"
 
 	"github.com/go-playground/validator/v10"
@@ -15,6 +20,11 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrInvalidJSON is wrapped when tool output or an artifact cannot be parsed as a JSON object.
+var ErrInvalidJSON = errors.New("invalid JSON")
+
+var errFinalArtifactStatusError = errors.New("orchestrator final artifact status error")
+
 var allowedStatus = map[string]bool{
 	"ok":           true,
 	"needs_manual": true,
@@ -25,6 +35,7 @@ type Runner struct {
 	logger    *zap.SugaredLogger
 	runners   map[string]ToolRunner
 	validator *validator.Validate
+	schema    *SchemaValidator
 }
 
 type NewRunnerParams struct {
@@ -32,6 +43,7 @@ type NewRunnerParams struct {
 
 	Runners map[string]ToolRunner
 	Logger  *zap.SugaredLogger
+	Schema  *SchemaValidator `optional:"true"`
 }
 
 func NewRunner(p NewRunnerParams) *Runner {
@@ -39,6 +51,7 @@ func NewRunner(p NewRunnerParams) *Runner {
 		runners:   p.Runners,
 		logger:    p.Logger,
 		validator: validator.New(),
+		schema:    p.Schema,
 	}
 }
 
@@ -57,6 +70,21 @@ func (r Result) ensureImagesArray() {
 	}
 }
 
+// Product decodes the result into the typed product model. Runner-owned keys
+// (attempts, error_reason, ...) are kept in Product.Extras.
+func (r Result) Product() (product.Product, error) {
+	return product.FromMap(r)
+}
+
+// ResultFromProduct converts a typed product back into a Result.
+func ResultFromProduct(p product.Product) (Result, error) {
+	m, err := p.Map()
+	if err != nil {
+		return nil, err
+	}
+	return Result(m), nil
+}
+
 type Options struct {
 	URL       string `validate:"required"`
 	OutDir    string `validate:"required"`
@@ -68,8 +96,10 @@ type Options struct {
 	// If empty, CodexCmd is used for backward compatibility.
 	Cmd string
 
-	// Model passes `--model` to tools that support it (Codex CLI and Gemini CLI).
-	// If empty, CodexModel is used for backward compatibility.
+	// Model passes `--model` to tools that support it (Codex CLI, Gemini CLI and
+	// command tools with a {model} placeholder), overriding the tool's configured
+	// model for Tool only; fallback tools keep their own. If empty, CodexModel is
+	// used for backward compatibility.
 	Model string
 
 	// CodexCmd is a deprecated alias for Cmd.
@@ -82,6 +112,31 @@ type Options struct {
 	// SkipGitRepoCheck passes `--skip-git-repo-check` to Codex CLI.
 	// This is useful in containers or non-git directories.
 	SkipGitRepoCheck bool
+
+	// Fallback lists tools to try, in order, after Tool fails with a failover-eligible
+	// FailureClass. FallbackBySource (keyed by source, e.g. "shopee") overrides it.
+	Fallback         []string
+	FallbackBySource map[string][]string
+
+	// Timeout bounds the whole run, including every fallback attempt, so a
+	// fallback tool only gets what the failed ones left. Zero means no deadline
+	// beyond the caller's context. On expiry the tool's process group is killed and
+	// the error result carries error_reason=timeout.
+	Timeout time.Duration
+
+	// Offline skips the agent CLI and runs the Go extraction stages against an
+	// existing S0 snapshot in OutDir/artifacts/<RunID>.
+	Offline bool
+
+	// Hints are requester notes folded into the skill prompt.
+	Hints Hints
+}
+
+// Hints steer the agent for a single crawl. Empty fields are left out of the prompt.
+type Hints struct {
+	Category string // target category the product will be listed under
+	Locale   string // target locale for titles and descriptions, e.g. "zh-TW"
+	Notes    string // free-form notes for the agent
 }
 
 func normalizeOptions(opts Options) Options {
@@ -99,6 +154,10 @@ func normalizeOptions(opts Options) Options {
 	if opts.Tool == "" {
 		opts.Tool = "codex"
 	}
+	opts.Model = strings.TrimSpace(opts.Model)
+	if opts.Model == "" {
+		opts.Model = strings.TrimSpace(opts.CodexModel)
+	}
 	return opts
 }
 
@@ -184,7 +243,7 @@ func collectVariationImages(obj map[string]any) []string {
 	return out
 }
 
-func (r *Runner) RunOnce(opts Options) (string, Result, error) {
+func (r *Runner) RunOnce(ctx context.Context, opts Options) (string, Result, error) {
 	opts = normalizeOptions(opts)
 	if err := r.validator.Struct(opts); err != nil {
 		r.logger.Errorf("❌ Missing required field value %v", err)
@@ -202,22 +261,89 @@ func (r *Runner) RunOnce(opts Options) (string, Result, error) {
 		return "", res, err
 	}
 
-	prompt, err := buildSkillPrompt(src, opts.URL, opts.SkillName, opts.Tool, opts.RunID, opts.OutDir)
+	if opts.Offline {
+		return r.runOffline(opts, src)
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	chain := toolChain(opts, src)
+	attempts := make([]Attempt, 0, len(chain))
+
+	var (
+		outPath string
+		res     Result
+	)
+	for i, tool := range chain {
+		attemptOpts := opts
+		attemptOpts.Tool = tool
+		if i > 0 {
+			// The model override names a model of the requested tool only.
+			attemptOpts.Model = ""
+		}
+		if len(chain) > 1 {
+			attemptOpts.RunID = attemptRunID(opts.RunID, i, tool)
+		}
+
+		start := time.Now()
+		var class FailureClass
+		outPath, res, class, err = r.runAttempt(ctx, attemptOpts, src, i == len(chain)-1)
+		attempts = append(attempts, newAttempt(attemptOpts, res, class, err, time.Since(start)))
+
+		if err == nil || !class.failover() || i == len(chain)-1 || ctx.Err() != nil {
+			break
+		}
+		r.logger.Warnw(
+			"runner_tool_failover",
+			"url", opts.URL,
+			"from", tool,
+			"to", chain[i+1],
+			"failure_class", string(class),
+			"err", err,
+		)
+	}
+
+	if res != nil {
+		res["attempts"] = attempts
+	}
+	return outPath, res, err
+}
+
+// runAttempt runs a single tool for opts.Tool and classifies any failure so RunOnce can
+// decide whether to fail over to the next tool in the chain. last is set for the
+// final tool of the chain.
+func (r *Runner) runAttempt(ctx context.Context, opts Options, src source.Source, last bool) (string, Result, FailureClass, error) {
+	prompt, err := buildSkillPrompt(src, opts.URL, opts.SkillName, opts.Tool, opts.RunID, opts.OutDir, opts.Hints)
 	r.logger.Infof("📨 prompt used: %v", prompt)
 	if err != nil {
 		res := errorResult(opts.URL, err)
-		return "", res, err
+		return "", res, FailureNone, err
 	}
 
 	tr, ok := r.runners[opts.Tool]
 	if !ok {
 		err := fmt.Errorf("❌ Unknown tool: %s", opts.Tool)
 		res := errorResult(opts.URL, err)
-		return "", res, err
+		return "", res, FailureUnknownTool, err
 	}
 
-	authErr := tr.CheckAuth()
-	raw, runErr := tr.Run(opts.URL, prompt)
+	runCtx := withModelOverride(ctx, opts.Model)
+
+	authErr := tr.CheckAuth(runCtx)
+	raw, runErr := tr.Run(runCtx, opts.URL, prompt)
+	if runErr != nil && errorReason(runErr) != "" {
+		r.logger.Warnw(
+			"runner_tool_interrupted",
+			"tool", tr.Name(),
+			"url", opts.URL,
+			"reason", errorReason(runErr),
+			"timeout", opts.Timeout.String(),
+		)
+	}
 	var res Result
 	outPath := ""
 	if isOrchestratorSkillMode(opts, src) {
@@ -229,15 +355,26 @@ func (r *Runner) RunOnce(opts Options) (string, Result, error) {
 			"path", outPath,
 		)
 		res, err = loadOrchestratorFinalResult(opts, src)
-		if err != nil && runErr != nil {
-			err = fmt.Errorf("%w (tool error: %v)", err, runErr)
+		// Salvage only when no other tool gets a turn: a degraded result would
+		// otherwise end the chain before a fallback tool could crawl the page.
+		if errors.Is(err, fs.ErrNotExist) && (last || !classifyFinalArtifactErr(err, runErr, authErr).failover()) {
+			if salvaged, serr := r.salvageFinalResult(opts, src); serr == nil {
+				res, err = salvaged, nil
+			} else if !errors.Is(serr, extract.ErrNothingToSalvage) {
+				r.logger.Warnw("runner_final_merge_salvage_failed", "url", opts.URL, "run_id", opts.RunID, "err", serr)
+			}
 		}
 		if err != nil {
+			class := classifyFinalArtifactErr(err, runErr, authErr)
+			if runErr != nil {
+				err = fmt.Errorf("%w (tool error: %w)", err, runErr)
+			}
 			res = errorResult(opts.URL, err)
 			if authErr != nil {
 				res["auth_check_error"] = authErr.Error()
 			}
-			return outPath, res, err
+			r.attachStages(opts, res)
+			return outPath, res, class, err
 		}
 	} else {
 		if runErr != nil {
@@ -246,7 +383,7 @@ func (r *Runner) RunOnce(opts Options) (string, Result, error) {
 			if authErr != nil {
 				res["auth_check_error"] = authErr.Error()
 			}
-			return outPath, res, err
+			return outPath, res, classifyRunErr(runErr, authErr), err
 		}
 
 		var err error
@@ -256,10 +393,106 @@ func (r *Runner) RunOnce(opts Options) (string, Result, error) {
 			if authErr != nil {
 				res["auth_check_error"] = authErr.Error()
 			}
-			return outPath, res, err
+			return outPath, res, FailureUnparseableOutput, err
 		}
 	}
 
+	res, class, err := r.finishResult(opts, src, res, authErr)
+	if outPath != "" {
+		r.attachStages(opts, res)
+	}
+	return outPath, res, class, err
+}
+
+// runOffline runs the Go extraction stages over an existing snapshot and loads
+// the final.json they write, without starting any tool.
+func (r *Runner) runOffline(opts Options, src source.Source) (string, Result, error) {
+	if opts.RunID == "" {
+		err := errors.New("offline extraction requires a run id with an existing snapshot")
+		return "", errorResult(opts.URL, err), err
+	}
+
+	outPath := orchestratorFinalPath(opts)
+	start := time.Now()
+	final, err := extract.Run(extract.Options{
+		ArtifactDir: filepath.Dir(outPath),
+		Source:      src,
+		URL:         opts.URL,
+		RunID:       opts.RunID,
+	})
+	if err != nil {
+		err = fmt.Errorf("offline extract: %w", err)
+		return outPath, errorResult(opts.URL, err), err
+	}
+	r.logger.Infow(
+		"runner_offline_extract_finished",
+		"url", opts.URL,
+		"run_id", opts.RunID,
+		"status", final.Status,
+		"elapsed", time.Since(start).String(),
+	)
+
+	res, err := readFinalArtifact(outPath)
+	if err != nil {
+		return outPath, errorResult(opts.URL, err), err
+	}
+	res["result_source"] = "offline_extract"
+	res["artifact_final_path"] = outPath
+
+	res, _, err = r.finishResult(opts, src, res, nil)
+	r.attachStages(opts, res)
+	return outPath, res, err
+}
+
+// attachStages adds the per-stage status, duration and error recorded in the
+// run's _pipeline-state.json to res as "stages", unless final.json already
+// carried them.
+func (r *Runner) attachStages(opts Options, res Result) {
+	if _, ok := res["stages"]; ok {
+		return
+	}
+	stages, err := extract.ReadStages(filepath.Dir(orchestratorFinalPath(opts)))
+	if err != nil {
+		r.logger.Warnw("runner_pipeline_state_unreadable", "url", opts.URL, "run_id", opts.RunID, "err", err)
+		return
+	}
+	if len(stages) > 0 {
+		res["stages"] = stages
+	}
+}
+
+// salvageFinalResult runs the Go final_merge over whatever stage artifacts an
+// interrupted orchestrator run left behind and loads the final.json it writes.
+func (r *Runner) salvageFinalResult(opts Options, src source.Source) (Result, error) {
+	outPath := orchestratorFinalPath(opts)
+	final, err := extract.Salvage(extract.Options{
+		ArtifactDir: filepath.Dir(outPath),
+		Source:      src,
+		URL:         opts.URL,
+		RunID:       opts.RunID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Warnw(
+		"runner_final_merge_salvaged",
+		"url", opts.URL,
+		"run_id", opts.RunID,
+		"status", final.Status,
+		"result_source", final.ResultSource,
+	)
+
+	res, err := readFinalArtifact(outPath)
+	if err != nil {
+		return nil, err
+	}
+	res["artifact_final_path"] = outPath
+	return res, nil
+}
+
+// finishResult fills runner defaults into res and validates it against the output
+// contract and the configured schema. On failure it returns an error result instead.
+func (r *Runner) finishResult(opts Options, src source.Source, res Result, authErr error) (Result, FailureClass, error) {
 	res.setdefault("url", opts.URL)
 	res.setdefault("source", string(src))
 	res.setdefault("captured_at", nowISO())
@@ -273,10 +506,48 @@ func (r *Runner) RunOnce(opts Options) (string, Result, error) {
 		if authErr != nil {
 			res["auth_check_error"] = authErr.Error()
 		}
-		return outPath, res, verr
+		return res, FailureContractInvalid, verr
+	}
+	if serr := r.applySchema(opts, res); serr != nil {
+		schemaErrors := res["schema_errors"]
+		res = errorResult(opts.URL, serr)
+		res["schema_errors"] = schemaErrors
+		if authErr != nil {
+			res["auth_check_error"] = authErr.Error()
+		}
+		return res, FailureContractInvalid, serr
+	}
+	return res, FailureNone, nil
+}
+
+// applySchema validates res against the configured JSON Schema. Violations are attached
+// to res as schema_errors; an error is returned only in enforce mode.
+func (r *Runner) applySchema(opts Options, res Result) error {
+	if r.schema == nil {
+		return nil
+	}
+
+	violations, err := r.schema.Validate(res)
+	if err != nil {
+		r.logger.Warnw("runner_schema_validation_skipped", "url", opts.URL, "err", err)
+		return nil
+	}
+	if len(violations) == 0 {
+		return nil
 	}
 
-	return outPath, res, nil
+	res["schema_errors"] = violations
+	r.logger.Warnw(
+		"runner_schema_validation_failed",
+		"url", opts.URL,
+		"mode", string(r.schema.Mode()),
+		"violations", len(violations),
+		"first", violations[0].Error(),
+	)
+	if r.schema.Mode() != SchemaModeEnforce {
+		return nil
+	}
+	return schemaValidationError(violations)
 }
 
 func loadOrchestratorFinalResult(opts Options, src source.Source) (Result, error) {
@@ -297,6 +568,18 @@ func loadOrchestratorFinalResult(opts Options, src source.Source) (Result, error
 	}
 
 	finalPath := orchestratorFinalPath(opts)
+	res, err := readFinalArtifact(finalPath)
+	if err != nil {
+		return nil, err
+	}
+	res["result_source"] = "artifact_final"
+	res["artifact_final_path"] = finalPath
+	return res, nil
+}
+
+// readFinalArtifact parses a pipeline final.json; a final status of "error" is
+// reported as errFinalArtifactStatusError.
+func readFinalArtifact(finalPath string) (Result, error) {
 	b, err := os.ReadFile(finalPath)
 	if err != nil {
 		return nil, fmt.Errorf("read orchestrator final artifact: %w (path=%s)", err, finalPath)
@@ -312,10 +595,8 @@ func loadOrchestratorFinalResult(opts Options, src source.Source) (Result, error
 		if msg == "" {
 			msg = "final artifact status is error"
 		}
-		return nil, fmt.Errorf("orchestrator final artifact status error: %s", msg)
+		return nil, fmt.Errorf("%w: %s", errFinalArtifactStatusError, msg)
 	}
-	res["result_source"] = "artifact_final"
-	res["artifact_final_path"] = finalPath
 	return res, nil
 }
 
@@ -352,7 +633,7 @@ func parseResult(toolName string, raw string) (Result, bool, error) {
 			if strings.TrimSpace(toolName) == "" {
 				toolName = "tool"
 			}
-			return nil, false, fmt.Errorf("invalid JSON from %s: %w", toolName, err)
+			return nil, false, fmt.Errorf("%w from %s: %w", ErrInvalidJSON, toolName, err)
 		}
 		res, derr := parseResultDecoded(toolName, extracted)
 		return res, true, derr
@@ -370,12 +651,12 @@ func parseResultDecoded(toolName string, extracted string) (Result, error) {
 		if strings.TrimSpace(toolName) == "" {
 			toolName = "tool"
 		}
-		return nil, fmt.Errorf("invalid JSON from %s: %w", toolName, err)
+		return nil, fmt.Errorf("%w from %s: %w", ErrInvalidJSON, toolName, err)
 	}
 
 	obj, ok := parsed.(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("output JSON is not an object")
+		return nil, fmt.Errorf("output JSON is not an object: %w", ErrInvalidJSON)
 	}
 	return Result(obj), nil
 }
@@ -402,10 +683,14 @@ func validateContract(r Result) error {
 }
 
 func errorResult(url string, err error) Result {
-	return Result{
+	res := Result{
 		"url":         url,
 		"status":      "error",
 		"captured_at": nowISO(),
 		"error":       err.Error(),
 	}
+	if reason := errorReason(err); reason != "" {
+		res["error_reason"] = reason
+	}
+	return res
 }
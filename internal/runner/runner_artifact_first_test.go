@@ -1,32 +1,50 @@
 package runner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"peasydeal-product-miner/internal/runner/extract"
 
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
 type stubToolRunner struct {
-	name     string
-	raw      string
-	runErr   error
-	authErr  error
-	runCalls int
+	name      string
+	raw       string
+	runErr    error
+	authErr   error
+	runCalls  int
+	block     bool
+	models    []string    // model override seen by each Run
+	deadlines []time.Time // context deadline seen by each Run
+	prompt    string      // prompt of the last Run
 }
 
 func (s *stubToolRunner) Name() string { return s.name }
 
-func (s *stubToolRunner) Run(_ string, _ string) (string, error) {
+func (s *stubToolRunner) Run(ctx context.Context, _ string, prompt string) (string, error) {
 	s.runCalls++
+	s.models = append(s.models, modelFor(ctx, ""))
+	if d, ok := ctx.Deadline(); ok {
+		s.deadlines = append(s.deadlines, d)
+	}
+	s.prompt = prompt
+	if s.block {
+		<-ctx.Done()
+		return "", fmt.Errorf("%s failed: %w", s.name, runContextErr(ctx))
+	}
 	return s.raw, s.runErr
 }
 
-func (s *stubToolRunner) CheckAuth() error { return s.authErr }
+func (s *stubToolRunner) CheckAuth(_ context.Context) error { return s.authErr }
 
 func TestRunOnce_OrchestratorSkill_UsesFinalArtifactEvenWhenToolOutputInvalid(t *testing.T) {
 	t.Parallel()
@@ -55,7 +73,7 @@ func TestRunOnce_OrchestratorSkill_UsesFinalArtifactEvenWhenToolOutputInvalid(t
 		validator: validator.New(),
 	}
 
-	_, res, err := r.RunOnce(Options{
+	_, res, err := r.RunOnce(context.Background(), Options{
 		URL:       "https://shopee.tw/i.1.2",
 		OutDir:    outDir,
 		Tool:      "gemini",
@@ -95,7 +113,7 @@ func TestRunOnce_OrchestratorSkill_FinalArtifactStatusErrorReturnsError(t *testi
 		validator: validator.New(),
 	}
 
-	_, res, err := r.RunOnce(Options{
+	_, res, err := r.RunOnce(context.Background(), Options{
 		URL:       "https://shopee.tw/i.1.2",
 		OutDir:    outDir,
 		Tool:      "gemini",
@@ -112,3 +130,143 @@ func TestRunOnce_OrchestratorSkill_FinalArtifactStatusErrorReturnsError(t *testi
 		t.Fatalf("unexpected result status: %#v", res["status"])
 	}
 }
+
+func TestRunOnce_OrchestratorSkill_AttachesPipelineStages(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	runID := "run-stages"
+	artifactDir := filepath.Join(outDir, "artifacts", runID)
+	if err := os.MkdirAll(artifactDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"final.json": `{"url":"https://shopee.tw/i.1.2","status":"error","captured_at":"2026-01-01T00:00:00Z","error":"core failed"}`,
+		"_pipeline-state.json": `{"run_id":"run-stages","status":"error","stages":{
+			"snapshot_capture":{"status":"completed","started_at":"2026-01-01T00:00:00Z","ended_at":"2026-01-01T00:00:03Z"},
+			"core_extract":{"status":"error","started_at":"2026-01-01T00:00:03Z","ended_at":"2026-01-01T00:00:04Z","error":"title not found"}}}`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(artifactDir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": &stubToolRunner{name: "codex"}},
+		validator: validator.New(),
+	}
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:       "https://shopee.tw/i.1.2",
+		OutDir:    outDir,
+		Tool:      "codex",
+		SkillName: shopeeOrchestratorPipelineSkill,
+		RunID:     runID,
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	stages, ok := res["stages"].([]extract.StageSummary)
+	if !ok || len(stages) != 2 {
+		t.Fatalf("unexpected stages: %#v", res["stages"])
+	}
+	if stages[0].DurationMS != 3000 || stages[1].Status != "error" || stages[1].Error != "title not found" {
+		t.Fatalf("unexpected stages: %+v", stages)
+	}
+
+	p, err := res.Product()
+	if err != nil {
+		t.Fatalf("Product: %v", err)
+	}
+	if _, ok := p.Extras["stages"]; !ok {
+		t.Fatalf("stages should reach the draft payload, extras=%v", p.Extras)
+	}
+}
+
+func TestRunOnce_TimeoutReturnsErrorResultWithReason(t *testing.T) {
+	t.Parallel()
+
+	tool := &stubToolRunner{name: "codex", block: true}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": tool},
+		validator: validator.New(),
+	}
+
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:     "https://shopee.tw/i.1.2",
+		OutDir:  t.TempDir(),
+		Tool:    "codex",
+		RunID:   "run-timeout",
+		Timeout: 50 * time.Millisecond,
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !errors.Is(err, ErrToolTimeout) {
+		t.Fatalf("expected ErrToolTimeout, got: %v", err)
+	}
+	if got, _ := res["status"].(string); got != "error" {
+		t.Fatalf("unexpected result status: %#v", res["status"])
+	}
+	if got, _ := res["error_reason"].(string); got != "timeout" {
+		t.Fatalf("unexpected error_reason: %#v", res["error_reason"])
+	}
+}
+
+func TestRunOnce_OrchestratorSkill_SalvagesStageArtifactsWhenFinalMissing(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	runID := "run-salvage"
+	artifactDir := filepath.Join(outDir, "artifacts", runID)
+	if err := os.MkdirAll(artifactDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"s0-snapshot-pointer.json": `{"status":"ok","url":"https://shopee.tw/i.1.2","captured_at":"2026-01-01T00:00:00Z"}`,
+		"core_extract.json":        `{"status":"ok","title":"t","description":"d","currency":"TWD","price":"199","notes":"","error":""}`,
+		"images_extract.json":      `{"status":"ok","images":["https://cf.shopee.tw/a.jpg"],"error":""}`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(artifactDir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	tool := &stubToolRunner{name: "codex", runErr: fmt.Errorf("codex failed: %w", ErrToolTimeout)}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": tool},
+		validator: validator.New(),
+	}
+
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:       "https://shopee.tw/i.1.2",
+		OutDir:    outDir,
+		Tool:      "codex",
+		SkillName: shopeeOrchestratorPipelineSkill,
+		RunID:     runID,
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if res["status"] != "ok" || res["price"] != "199" {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+	if got := res["result_source"]; got != "final_merge_salvage:core_extract,images_extract" {
+		t.Fatalf("unexpected result_source: %#v", got)
+	}
+	salvage := map[string]string{}
+	for _, s := range res["stages"].([]any) {
+		s := s.(map[string]any)
+		salvage[s["stage"].(string)], _ = s["salvage"].(string)
+	}
+	if salvage["core_extract"] != "salvaged" || salvage["variations_extract"] != "missing" {
+		t.Fatalf("unexpected stages summary: %#v", res["stages"])
+	}
+	if _, err := os.Stat(filepath.Join(artifactDir, "final.json")); err != nil {
+		t.Fatalf("salvage should write final.json: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package runner
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// writeShopeeSnapshot gzips the extract package's Shopee fixtures into an S0
+// artifact directory.
+func writeShopeeSnapshot(t *testing.T, artifactDir, pointer string) {
+	t.Helper()
+
+	if err := os.MkdirAll(artifactDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	fixtures, err := filepath.Glob(filepath.Join("extract", "testdata", "shopee", "s0-*.html"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("glob fixtures: %v (%d found)", err, len(fixtures))
+	}
+	for _, src := range fixtures {
+		b, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatalf("read %s: %v", src, err)
+		}
+		f, err := os.Create(filepath.Join(artifactDir, filepath.Base(src)+".gz"))
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		zw := gzip.NewWriter(f)
+		_, werr := zw.Write(b)
+		cerr := zw.Close()
+		f.Close()
+		if werr != nil || cerr != nil {
+			t.Fatalf("gzip %s: %v %v", src, werr, cerr)
+		}
+	}
+	for _, name := range []string{"s0-snapshot-pointer.json", "s0-manifest.json"} {
+		if err := os.WriteFile(filepath.Join(artifactDir, name), []byte(pointer), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+}
+
+func TestRunOnce_Offline_ExtractsFromSnapshotWithoutTool(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	writeShopeeSnapshot(t, filepath.Join(outDir, "artifacts", "run-1"),
+		`{"status":"ok","url":"https://shopee.tw/product/1/2","captured_at":"2026-01-02T03:04:05Z"}`)
+
+	tool := &stubToolRunner{name: "codex", raw: "not-json"}
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": tool},
+		validator: validator.New(),
+	}
+
+	outPath, res, err := r.RunOnce(context.Background(), Options{
+		URL:     "https://shopee.tw/product/1/2",
+		OutDir:  outDir,
+		RunID:   "run-1",
+		Offline: true,
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if tool.runCalls != 0 {
+		t.Fatalf("offline mode must not run the tool, got %d calls", tool.runCalls)
+	}
+	if res["status"] != "ok" || res["result_source"] != "offline_extract" || res["source"] != "shopee" {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+	if res["title"] != "不鏽鋼保溫杯 500ml" || res["price"] != "1290" {
+		t.Fatalf("unexpected core fields: %#v", res)
+	}
+	if want := filepath.Join(outDir, "artifacts", "run-1", "final.json"); outPath != want {
+		t.Fatalf("outPath = %q, want %q", outPath, want)
+	}
+}
+
+func TestRunOnce_Offline_BlockedSnapshotIsNeedsManual(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	writeShopeeSnapshot(t, filepath.Join(outDir, "artifacts", "run-2"),
+		`{"status":"needs_manual","url":"https://shopee.tw/product/1/2","notes":"captcha"}`)
+
+	r := &Runner{logger: zap.NewNop().Sugar(), validator: validator.New()}
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:     "https://shopee.tw/product/1/2",
+		OutDir:  outDir,
+		RunID:   "run-2",
+		Offline: true,
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if res["status"] != "needs_manual" || res["notes"] != "captcha" {
+		t.Fatalf("unexpected result: %#v", res)
+	}
+}
+
+func TestRunOnce_Offline_RequiresRunID(t *testing.T) {
+	t.Setenv("CRAWL_RUN_ID", "")
+
+	r := &Runner{logger: zap.NewNop().Sugar(), validator: validator.New()}
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:     "https://shopee.tw/product/1/2",
+		OutDir:  t.TempDir(),
+		Offline: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "run id") {
+		t.Fatalf("expected run id error, got %v", err)
+	}
+	if res["status"] != "error" {
+		t.Fatalf("expected error result, got %#v", res)
+	}
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"peasydeal-product-miner/internal/pkg/jsonschema"
+)
+
+// SchemaMode controls what happens when a result fails JSON Schema validation.
+type SchemaMode string
+
+const (
+	// SchemaModeOff skips schema validation entirely.
+	SchemaModeOff SchemaMode = "off"
+	// SchemaModeWarn keeps the result and attaches schema_errors to it.
+	SchemaModeWarn SchemaMode = "warn"
+	// SchemaModeEnforce replaces the result with an error result carrying schema_errors.
+	SchemaModeEnforce SchemaMode = "enforce"
+)
+
+// ErrSchemaValidation is wrapped when a result fails schema validation in enforce mode.
+var ErrSchemaValidation = errors.New("output schema validation failed")
+
+// runnerOwnedKeys are added by the runner itself and are not part of the tool's output
+// contract, so they are stripped before schema validation.
+var runnerOwnedKeys = []string{
+	"result_source",
+	"artifact_final_path",
+	"auth_check_error",
+	"attempts",
+	"error_reason",
+	"schema_errors",
+	"stages",
+}
+
+// SchemaValidator validates runner results against a JSON Schema file (e.g.
+// config/schema.product.json) on top of the built-in validateCrawlOut checks.
+type SchemaValidator struct {
+	schema *jsonschema.Schema
+	mode   SchemaMode
+	path   string
+}
+
+func ParseSchemaMode(raw string) (SchemaMode, error) {
+	switch mode := SchemaMode(strings.ToLower(strings.TrimSpace(raw))); mode {
+	case "", SchemaModeOff:
+		return SchemaModeOff, nil
+	case SchemaModeWarn, SchemaModeEnforce:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown schema mode %q (expected off, warn or enforce)", raw)
+	}
+}
+
+// NewSchemaValidator loads the schema at path. It returns nil (validation disabled)
+// when path is empty or mode is off.
+func NewSchemaValidator(path string, mode SchemaMode) (*SchemaValidator, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || mode == SchemaModeOff {
+		return nil, nil
+	}
+	s, err := jsonschema.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaValidator{schema: s, mode: mode, path: path}, nil
+}
+
+func (v *SchemaValidator) Mode() SchemaMode {
+	if v == nil {
+		return SchemaModeOff
+	}
+	return v.mode
+}
+
+// Validate returns the schema violations for res, ignoring runner-owned keys.
+func (v *SchemaValidator) Validate(res Result) ([]jsonschema.ValidationError, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	contract := make(Result, len(res))
+	for k, val := range res {
+		contract[k] = val
+	}
+	for _, k := range runnerOwnedKeys {
+		delete(contract, k)
+	}
+
+	b, err := json.Marshal(contract)
+	if err != nil {
+		return nil, fmt.Errorf("marshal result for schema validation: %w", err)
+	}
+	return v.schema.ValidateJSON(b)
+}
+
+func schemaValidationError(errs []jsonschema.ValidationError) error {
+	parts := make([]string, 0, len(errs))
+	for _, e := range errs {
+		parts = append(parts, e.Error())
+	}
+	return fmt.Errorf("%w: %s", ErrSchemaValidation, strings.Join(parts, "; "))
+}
@@ -0,0 +1,193 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"peasydeal-product-miner/internal/pkg/jsonschema"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+const testSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["url", "status", "captured_at"],
+  "properties": {
+    "url": {"type": "string"},
+    "status": {"enum": ["ok", "needs_manual", "error"]},
+    "captured_at": {"type": "string", "format": "date-time"},
+    "source": {"type": "string"},
+    "images": {"type": "array"},
+    "pricing": {
+      "type": "object",
+      "required": ["currency"],
+      "properties": {"currency": {"type": "string", "minLength": 1}}
+    }
+  },
+  "if": {"properties": {"status": {"const": "ok"}}},
+  "then": {"required": ["pricing"]}
+}`
+
+func newSchemaTestRunner(t *testing.T, mode SchemaMode) (*Runner, string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "schema.json")
+	if err := os.WriteFile(schemaPath, []byte(testSchema), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+	sv, err := NewSchemaValidator(schemaPath, mode)
+	if err != nil {
+		t.Fatalf("NewSchemaValidator error: %v", err)
+	}
+
+	writeFinalArtifact(t, dir, "run-1",
+		`{"url":"https://shopee.tw/i.1.2","status":"ok","captured_at":"2026-01-01T00:00:00Z","pricing":{"currency":""}}`)
+
+	return &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": &stubToolRunner{name: "codex"}},
+		validator: validator.New(),
+		schema:    sv,
+	}, dir
+}
+
+func TestRunOnce_SchemaWarnModeKeepsResult(t *testing.T) {
+	t.Parallel()
+
+	r, outDir := newSchemaTestRunner(t, SchemaModeWarn)
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:    "https://shopee.tw/i.1.2",
+		OutDir: outDir,
+		RunID:  "run-1",
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v", err)
+	}
+	if got, _ := res["status"].(string); got != "ok" {
+		t.Fatalf("unexpected status: %#v", res["status"])
+	}
+	violations, ok := res["schema_errors"].([]jsonschema.ValidationError)
+	if !ok || len(violations) != 1 || violations[0].Path != "/pricing/currency" {
+		t.Fatalf("unexpected schema_errors: %#v", res["schema_errors"])
+	}
+}
+
+func TestRunOnce_SchemaEnforceModeReturnsErrorResult(t *testing.T) {
+	t.Parallel()
+
+	r, outDir := newSchemaTestRunner(t, SchemaModeEnforce)
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:    "https://shopee.tw/i.1.2",
+		OutDir: outDir,
+		RunID:  "run-1",
+	})
+	if !errors.Is(err, ErrSchemaValidation) {
+		t.Fatalf("expected ErrSchemaValidation, got: %v", err)
+	}
+	if got, _ := res["status"].(string); got != "error" {
+		t.Fatalf("unexpected status: %#v", res["status"])
+	}
+	if _, ok := res["schema_errors"].([]jsonschema.ValidationError); !ok {
+		t.Fatalf("expected schema_errors on error result: %#v", res)
+	}
+}
+
+func TestParseSchemaMode(t *testing.T) {
+	t.Parallel()
+
+	for raw, want := range map[string]SchemaMode{"": SchemaModeOff, "WARN": SchemaModeWarn, " enforce ": SchemaModeEnforce} {
+		got, err := ParseSchemaMode(raw)
+		if err != nil || got != want {
+			t.Fatalf("ParseSchemaMode(%q) = %q, %v", raw, got, err)
+		}
+	}
+	if _, err := ParseSchemaMode("strict"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+// finalSchema closes the orchestrator final.json shape, so any key the runner
+// adds on top of it must be runner-owned.
+const finalSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["url", "status", "captured_at"],
+  "properties": {
+    "url": {"type": "string"},
+    "status": {"enum": ["ok", "needs_manual", "error"]},
+    "captured_at": {"type": "string"},
+    "source": {"type": "string"},
+    "notes": {"type": "string"},
+    "error": {"type": "string"},
+    "title": {"type": "string"},
+    "description": {"type": "string"},
+    "currency": {"type": "string"},
+    "price": {"type": "string"},
+    "images": {"type": "array", "items": {"type": "string"}},
+    "variations": {"type": "array"},
+    "artifact_dir": {"type": "string"},
+    "run_id": {"type": "string"}
+  }
+}`
+
+func TestRunOnce_SchemaEnforceModeAcceptsSalvagedResult(t *testing.T) {
+	t.Parallel()
+
+	outDir := t.TempDir()
+	schemaPath := filepath.Join(outDir, "schema.json")
+	if err := os.WriteFile(schemaPath, []byte(finalSchema), 0o644); err != nil {
+		t.Fatalf("write schema: %v", err)
+	}
+	sv, err := NewSchemaValidator(schemaPath, SchemaModeEnforce)
+	if err != nil {
+		t.Fatalf("NewSchemaValidator error: %v", err)
+	}
+
+	runID := "run-salvage"
+	artifactDir := filepath.Join(outDir, "artifacts", runID)
+	if err := os.MkdirAll(artifactDir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		"s0-snapshot-pointer.json": `{"status":"ok","url":"https://shopee.tw/i.1.2","captured_at":"2026-01-01T00:00:00Z"}`,
+		"core_extract.json":        `{"status":"ok","title":"t","description":"d","currency":"TWD","price":"199","notes":"","error":""}`,
+		"images_extract.json":      `{"status":"ok","images":["https://cf.shopee.tw/a.jpg"],"error":""}`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(artifactDir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	r := &Runner{
+		logger:    zap.NewNop().Sugar(),
+		runners:   map[string]ToolRunner{"codex": &stubToolRunner{name: "codex", runErr: fmt.Errorf("codex failed: %w", ErrToolTimeout)}},
+		validator: validator.New(),
+		schema:    sv,
+	}
+	_, res, err := r.RunOnce(context.Background(), Options{
+		URL:       "https://shopee.tw/i.1.2",
+		OutDir:    outDir,
+		Tool:      "codex",
+		SkillName: shopeeOrchestratorPipelineSkill,
+		RunID:     runID,
+	})
+	if err != nil {
+		t.Fatalf("RunOnce error: %v (schema_errors %#v)", err, res["schema_errors"])
+	}
+	if res["status"] != "ok" {
+		t.Fatalf("unexpected status: %#v", res["status"])
+	}
+	if _, ok := res["stages"]; !ok {
+		t.Fatalf("salvaged result should carry stages: %#v", res)
+	}
+}
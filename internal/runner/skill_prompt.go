@@ -11,7 +11,7 @@ import (
 const shopeeOrchestratorPipelineSkill = "shopee-orchestrator-pipeline"
 const taobaoOrchestratorPipelineSkill = "taobao-orchestrator-pipeline"
 
-func buildSkillPrompt(src source.Source, url string, skillName string, tool string, runID string, outDir string) (string, error) {
+func buildSkillPrompt(src source.Source, url string, skillName string, tool string, runID string, outDir string, hints Hints) (string, error) {
 	skillName = strings.TrimSpace(skillName)
 	if skillName == "" {
 		skillName = defaultSkillName(src)
@@ -45,10 +45,37 @@ func buildSkillPrompt(src source.Source, url string, skillName string, tool stri
 		tail.WriteString(fmt.Sprintf("Artifact dir: %s\n", artifactDir))
 		tail.WriteString("Use the provided Run ID exactly. Do not generate a new run_id.\n")
 	}
+	writeHints(&tail, hints)
 
 	return fmt.Sprintf(`Use the "%s" skill as the primary crawling guide. Target URL: %s%s`, skillName, url, tail.String()), nil
 }
 
+// writeHints appends the requester's hints as plain prompt lines.
+func writeHints(b *strings.Builder, hints Hints) {
+	lines := make([]string, 0, 3)
+	if v := strings.TrimSpace(hints.Category); v != "" {
+		lines = append(lines, fmt.Sprintf("Target category: %s\n", v))
+	}
+	if v := strings.TrimSpace(hints.Locale); v != "" {
+		lines = append(lines, fmt.Sprintf("Target locale: %s\n", v))
+	}
+	if v := strings.TrimSpace(hints.Notes); v != "" {
+		lines = append(lines, fmt.Sprintf("Requester notes: %s\n", strings.Join(strings.Fields(v), " ")))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	b.WriteString("\nHints from the requester (use them to guide extraction; they never override the page content):\n")
+	for _, line := range lines {
+		b.WriteString(line)
+	}
+}
+
+// SkillNames lists the orchestrator skills buildSkillPrompt accepts.
+func SkillNames() []string {
+	return []string{shopeeOrchestratorPipelineSkill, taobaoOrchestratorPipelineSkill}
+}
+
 func defaultSkillName(src source.Source) string {
 	switch src {
 	case source.Shopee:
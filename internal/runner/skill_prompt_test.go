@@ -30,7 +30,7 @@ func TestNormalizeOptions_UsesEnvSkillName(t *testing.T) {
 }
 
 func TestBuildSkillPrompt_Shopee(t *testing.T) {
-	got, err := buildSkillPrompt(source.Shopee, "https://shopee.tw/product/1/2", "", "codex", "", "out")
+	got, err := buildSkillPrompt(source.Shopee, "https://shopee.tw/product/1/2", "", "codex", "", "out", Hints{})
 	if err != nil {
 		t.Fatalf("buildSkillPrompt error: %v", err)
 	}
@@ -53,6 +53,7 @@ func TestBuildSkillPrompt_RejectsUnsupportedShopeeSkill(t *testing.T) {
 		"gemini",
 		"",
 		"out",
+		Hints{},
 	)
 	if err == nil {
 		t.Fatalf("expected error")
@@ -60,7 +61,7 @@ func TestBuildSkillPrompt_RejectsUnsupportedShopeeSkill(t *testing.T) {
 }
 
 func TestBuildSkillPrompt_Taobao(t *testing.T) {
-	got, err := buildSkillPrompt(source.Taobao, "https://item.taobao.com/item.htm?id=1", "", "codex", "", "out")
+	got, err := buildSkillPrompt(source.Taobao, "https://item.taobao.com/item.htm?id=1", "", "codex", "", "out", Hints{})
 	if err != nil {
 		t.Fatalf("buildSkillPrompt error: %v", err)
 	}
@@ -80,6 +81,7 @@ func TestBuildSkillPrompt_RejectsUnsupportedTaobaoSkill(t *testing.T) {
 		"codex",
 		"",
 		"out",
+		Hints{},
 	)
 	if err == nil {
 		t.Fatalf("expected error")
@@ -87,7 +89,7 @@ func TestBuildSkillPrompt_RejectsUnsupportedTaobaoSkill(t *testing.T) {
 }
 
 func TestBuildSkillPrompt_UnsupportedSource(t *testing.T) {
-	_, err := buildSkillPrompt(source.Source("unknown"), "https://example.com/item/1", "", "codex", "", "out")
+	_, err := buildSkillPrompt(source.Source("unknown"), "https://example.com/item/1", "", "codex", "", "out", Hints{})
 	if err == nil {
 		t.Fatalf("expected error for unsupported source")
 	}
@@ -101,6 +103,7 @@ func TestBuildSkillPrompt_WithRunID(t *testing.T) {
 		"gemini",
 		"run-123",
 		"out",
+		Hints{},
 	)
 	if err != nil {
 		t.Fatalf("buildSkillPrompt error: %v", err)
@@ -1,9 +1,21 @@
 package runner
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrNonJSONOutput is wrapped by ToolRunners whose model output holds no contract JSON.
+var ErrNonJSONOutput = errors.New("non-JSON output")
+
 // ToolRunner executes a crawl tool (e.g. Codex CLI, Gemini CLI) and returns the raw
 // JSON output as a string.
+//
+// Implementations must stop the underlying process (and its children) when ctx is
+// done, and report that via runContextErr so callers can tell a timeout apart from
+// an ordinary tool failure.
 type ToolRunner interface {
 	Name() string
-	Run(url string, prompt string) (raw string, err error)
-	CheckAuth() error
+	Run(ctx context.Context, url string, prompt string) (raw string, err error)
+	CheckAuth(ctx context.Context) error
 }
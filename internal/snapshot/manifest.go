@@ -0,0 +1,132 @@
+package snapshot
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Files mirrors snapshot_files in the page-snapshot skill output.
+type Files struct {
+	Snapshot         string `json:"snapshot"`
+	Manifest         string `json:"manifest"`
+	InitialHTML      string `json:"initial_html"`
+	OverlayHTML      string `json:"overlay_html"`
+	VariationHTMLDir string `json:"variation_html_dir"`
+}
+
+type CreatedTab struct {
+	PageIdx  int    `json:"page_idx"`
+	TargetID string `json:"target_id"`
+	URL      string `json:"url"`
+}
+
+type TabTracking struct {
+	CreatedTab     CreatedTab `json:"created_tab"`
+	CloseAttempted bool       `json:"close_attempted"`
+	CloseSucceeded bool       `json:"close_succeeded"`
+	CloseError     string     `json:"close_error"`
+}
+
+// Entry is one HTML snapshot written during the session.
+type Entry struct {
+	Name          string `json:"name"`
+	Output        string `json:"output"`
+	Position      *int   `json:"position,omitempty"`
+	Label         string `json:"label,omitempty"`
+	Status        string `json:"status"`
+	CapturedAt    string `json:"captured_at"`
+	TargetID      string `json:"target_id"`
+	TargetURL     string `json:"target_url"`
+	Bytes         int64  `json:"bytes"`
+	SHA256        string `json:"sha256"`
+	Truncated     bool   `json:"truncated"`
+	OriginalBytes int    `json:"original_bytes"`
+	Error         string `json:"error"`
+}
+
+// Manifest is written to s0-manifest.json. The pointer file carries the same
+// fields minus Captures.
+type Manifest struct {
+	URL           string      `json:"url"`
+	Status        string      `json:"status"`
+	RunID         string      `json:"run_id"`
+	CapturedAt    string      `json:"captured_at"`
+	ArtifactDir   string      `json:"artifact_dir"`
+	SnapshotFiles Files       `json:"snapshot_files"`
+	TabTracking   TabTracking `json:"tab_tracking"`
+	Captures      []Entry     `json:"captures"`
+	Notes         string      `json:"notes"`
+	Error         string      `json:"error"`
+}
+
+// Pointer is written to s0-snapshot-pointer.json.
+type Pointer struct {
+	URL           string      `json:"url"`
+	Status        string      `json:"status"`
+	CapturedAt    string      `json:"captured_at"`
+	RunID         string      `json:"run_id"`
+	ArtifactDir   string      `json:"artifact_dir"`
+	SnapshotFiles Files       `json:"snapshot_files"`
+	TabTracking   TabTracking `json:"tab_tracking"`
+	Notes         string      `json:"notes"`
+	Error         string      `json:"error"`
+}
+
+func newManifest(url, runID, artifactDir string) *Manifest {
+	return &Manifest{
+		URL:         url,
+		Status:      StatusOK,
+		RunID:       runID,
+		CapturedAt:  utcNow(),
+		ArtifactDir: artifactDir,
+		SnapshotFiles: Files{
+			Snapshot:         filepath.Join(artifactDir, PointerFile),
+			Manifest:         filepath.Join(artifactDir, ManifestFile),
+			VariationHTMLDir: artifactDir,
+		},
+		TabTracking: TabTracking{CreatedTab: CreatedTab{PageIdx: -1}},
+		Captures:    []Entry{},
+	}
+}
+
+func (m *Manifest) addNote(note string) {
+	if m.Notes == "" {
+		m.Notes = note
+		return
+	}
+	m.Notes = strings.Join([]string{m.Notes, note}, "; ")
+}
+
+// Pointer returns the small pointer object for this manifest.
+func (m *Manifest) Pointer() Pointer {
+	return Pointer{
+		URL:           m.URL,
+		Status:        m.Status,
+		CapturedAt:    m.CapturedAt,
+		RunID:         m.RunID,
+		ArtifactDir:   m.ArtifactDir,
+		SnapshotFiles: m.SnapshotFiles,
+		TabTracking:   m.TabTracking,
+		Notes:         m.Notes,
+		Error:         m.Error,
+	}
+}
+
+// VariationCount returns the number of variation snapshots written.
+func (m *Manifest) VariationCount() int {
+	n := 0
+	for _, c := range m.Captures {
+		if c.Position != nil && c.Status == StatusOK {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Manifest) write(artifactDir string) error {
+	return errors.Join(
+		writeJSON(filepath.Join(artifactDir, ManifestFile), m),
+		writeJSON(filepath.Join(artifactDir, PointerFile), m.Pointer()),
+	)
+}
@@ -0,0 +1,112 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"peasydeal-product-miner/internal/source"
+)
+
+// Profile describes how to drive a marketplace page for S0 capture.
+type Profile struct {
+	// ReadySelectors: the page is ready once document.readyState is complete
+	// and any one of these selectors matches.
+	ReadySelectors []string
+	// VariationSelector matches the clickable option elements, in page order.
+	VariationSelector string
+	// WallMarkers are lower-case substrings of the URL or body text that signal a
+	// login / verification wall.
+	WallMarkers []string
+}
+
+var profiles = map[source.Source]Profile{
+	source.Shopee: {
+		ReadySelectors: []string{
+			`[class*="product-briefing"]`,
+			`button.selection-box`,
+			`[class*="IZPeQz"]`,
+		},
+		VariationSelector: `button.selection-box`,
+		WallMarkers: []string{
+			"/buyer/login",
+			"/verify/",
+			"captcha",
+		},
+	},
+	source.Taobao: {
+		ReadySelectors: []string{
+			`[class*="MainTitle"]`,
+			`[class*="mainTitle"]`,
+			`[class*="skuItem"]`,
+			`#J_Title`,
+		},
+		VariationSelector: `[class*="skuItem"]`,
+		WallMarkers: []string{
+			"login.taobao.com",
+			"login.tmall.com",
+			"验证码",
+			"安全验证",
+			"请登录",
+			"captcha",
+		},
+	},
+}
+
+// ProfileFor returns the built-in profile for src.
+func ProfileFor(src source.Source) (Profile, error) {
+	p, ok := profiles[src]
+	if !ok {
+		return Profile{}, fmt.Errorf("no snapshot profile for source %q", src)
+	}
+	return p, nil
+}
+
+type readyState struct {
+	Ready bool `json:"ready"`
+	Wall  bool `json:"wall"`
+}
+
+type selectResult struct {
+	Clicked  bool   `json:"clicked"`
+	Disabled bool   `json:"disabled"`
+	Label    string `json:"label"`
+}
+
+const htmlExpr = `document.documentElement ? document.documentElement.outerHTML : ""`
+
+func readyExpr(p Profile) string {
+	return fmt.Sprintf(`(() => {
+  const selectors = %s;
+  const markers = %s;
+  const ready = document.readyState === "complete" && selectors.some((s) => document.querySelector(s));
+  const text = document.body ? document.body.innerText.slice(0, 20000) : "";
+  const hay = (location.href + " " + text).toLowerCase();
+  return { ready: ready, wall: markers.some((m) => hay.includes(m)) };
+})()`, jsLiteral(p.ReadySelectors), jsLiteral(p.WallMarkers))
+}
+
+func countExpr(selector string) string {
+	return fmt.Sprintf(`document.querySelectorAll(%s).length`, jsLiteral(selector))
+}
+
+func selectExpr(selector string, index int) string {
+	return fmt.Sprintf(`(() => {
+  const el = document.querySelectorAll(%s)[%d];
+  if (!el) return { clicked: false, disabled: false, label: "" };
+  const label = (el.getAttribute("aria-label") || el.textContent || "").trim();
+  const disabled = el.disabled === true || el.getAttribute("aria-disabled") === "true" || /disabled/i.test(String(el.className));
+  if (disabled) return { clicked: false, disabled: true, label: label };
+  el.scrollIntoView({ block: "center" });
+  el.click();
+  return { clicked: true, disabled: false, label: label };
+})()`, jsLiteral(selector), index)
+}
+
+func jsLiteral(v any) string {
+	if ss, ok := v.([]string); ok && ss == nil {
+		v = []string{}
+	}
+	b, _ := json.Marshal(v)
+	return strings.TrimSpace(string(b))
+}
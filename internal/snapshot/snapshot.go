@@ -0,0 +1,346 @@
+// Package snapshot captures the S0 (snapshot_capture) stage deterministically
+// over the Chrome DevTools protocol: it opens a tab, navigates, waits for the
+// product to render, clicks through variation options and writes gzipped HTML
+// plus a manifest into out/artifacts/<run_id>/, the same layout the LLM-driven
+// page-snapshot skills produce.
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"peasydeal-product-miner/internal/pkg/chromedevtools"
+	"peasydeal-product-miner/internal/source"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusNeedsManual = "needs_manual"
+	StatusError       = "error"
+)
+
+const (
+	InitialHTMLFile = "s0-initial.html.gz"
+	ManifestFile    = "s0-manifest.json"
+	PointerFile     = "s0-snapshot-pointer.json"
+
+	// MaxVariations matches the hard cap used by the variation extractors.
+	MaxVariations = 20
+
+	defaultReadyTimeout = 15 * time.Second
+	defaultSettleDelay  = 800 * time.Millisecond
+	readyPollInterval   = 250 * time.Millisecond
+)
+
+// VariationHTMLFile returns the artifact file name for the option at position.
+func VariationHTMLFile(position int) string {
+	return fmt.Sprintf("s0-variation-%d.html.gz", position)
+}
+
+// ArtifactDir returns out/artifacts/<run_id>.
+func ArtifactDir(outDir, runID string) string {
+	return filepath.Join(outDir, "artifacts", runID)
+}
+
+type Options struct {
+	URL         string
+	RunID       string
+	OutDir      string
+	DevToolsURL string // http://host:port
+
+	// Profile overrides the built-in profile for the URL's source.
+	Profile *Profile
+
+	ReadyTimeout  time.Duration
+	SettleDelay   time.Duration
+	MaxVariations int
+}
+
+var nowFunc = time.Now
+
+// Capture runs one S0 session and always writes s0-manifest.json and
+// s0-snapshot-pointer.json once the artifact directory exists. Page-level
+// problems (wall, not ready, a failed variation click) are reported through the
+// manifest status and notes; the returned error is reserved for failures that
+// prevent a session (bad options, DevTools unreachable).
+func Capture(ctx context.Context, opts Options) (*Manifest, error) {
+	opts.URL = strings.TrimSpace(opts.URL)
+	opts.RunID = strings.TrimSpace(opts.RunID)
+	if opts.URL == "" {
+		return nil, errors.New("missing url")
+	}
+	if opts.RunID == "" {
+		return nil, errors.New("missing run id")
+	}
+	if strings.TrimSpace(opts.OutDir) == "" {
+		opts.OutDir = "out"
+	}
+	if strings.TrimSpace(opts.DevToolsURL) == "" {
+		opts.DevToolsURL = chromedevtools.BaseURL("", "")
+	}
+	if opts.ReadyTimeout <= 0 {
+		opts.ReadyTimeout = defaultReadyTimeout
+	}
+	if opts.SettleDelay <= 0 {
+		opts.SettleDelay = defaultSettleDelay
+	}
+	if opts.MaxVariations <= 0 || opts.MaxVariations > MaxVariations {
+		opts.MaxVariations = MaxVariations
+	}
+
+	profile := opts.Profile
+	if profile == nil {
+		src, err := source.Detect(opts.URL)
+		if err != nil {
+			return nil, err
+		}
+		p, err := ProfileFor(src)
+		if err != nil {
+			return nil, err
+		}
+		profile = &p
+	}
+
+	artifactDir := ArtifactDir(opts.OutDir, opts.RunID)
+	if err := os.MkdirAll(artifactDir, 0o755); err != nil {
+		return nil, fmt.Errorf("create artifact dir: %w", err)
+	}
+
+	m := newManifest(opts.URL, opts.RunID, artifactDir)
+	s := &session{opts: opts, profile: *profile, manifest: m, artifactDir: artifactDir}
+
+	runErr := s.run(ctx)
+	if runErr != nil {
+		m.Status = StatusError
+		m.Error = runErr.Error()
+	}
+
+	if err := m.write(artifactDir); err != nil {
+		return m, errors.Join(runErr, err)
+	}
+	return m, runErr
+}
+
+type session struct {
+	opts        Options
+	profile     Profile
+	manifest    *Manifest
+	artifactDir string
+
+	conn   *chromedevtools.Conn
+	target chromedevtools.Target
+}
+
+func (s *session) run(ctx context.Context) error {
+	target, err := chromedevtools.NewTarget(ctx, s.opts.DevToolsURL, "about:blank")
+	if err != nil {
+		return fmt.Errorf("open tab: %w", err)
+	}
+	s.target = target
+	s.manifest.TabTracking.CreatedTab = CreatedTab{PageIdx: -1, TargetID: target.ID, URL: s.opts.URL}
+	defer s.closeTab()
+
+	conn, err := chromedevtools.Dial(ctx, target.WebSocketDebuggerURL)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	defer conn.Close()
+
+	var nav struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := conn.Call(ctx, "Page.navigate", map[string]any{"url": s.opts.URL}, &nav); err != nil {
+		return err
+	}
+	if nav.ErrorText != "" {
+		return fmt.Errorf("navigate: %s", nav.ErrorText)
+	}
+
+	state, err := s.waitReady(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !state.Ready && state.Wall:
+		s.manifest.Status = StatusNeedsManual
+		s.manifest.addNote("login/verification wall detected before product content rendered")
+	case !state.Ready:
+		s.manifest.addNote(fmt.Sprintf("page not ready after %s; capturing current state", s.opts.ReadyTimeout))
+	}
+
+	initial := s.capture(ctx, "initial", InitialHTMLFile, nil, "")
+	if initial.Status != StatusOK {
+		if s.manifest.Status == StatusOK {
+			s.manifest.Status = StatusError
+			s.manifest.Error = "initial snapshot failed: " + initial.Error
+		}
+		return nil
+	}
+	s.manifest.SnapshotFiles.InitialHTML = initial.Output
+	s.manifest.addNote("overlay snapshot not captured")
+
+	if s.manifest.Status == StatusOK && s.profile.VariationSelector != "" {
+		s.captureVariations(ctx)
+	}
+	return nil
+}
+
+func (s *session) waitReady(ctx context.Context) (readyState, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.ReadyTimeout)
+	defer cancel()
+
+	expr := readyExpr(s.profile)
+	var last readyState
+	for {
+		var st readyState
+		// Evaluate fails transiently while the page is navigating; keep polling.
+		if err := s.conn.Evaluate(ctx, expr, &st); err == nil {
+			last = st
+			if st.Ready {
+				return st, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if cause := context.Cause(ctx); !errors.Is(cause, context.DeadlineExceeded) {
+				return last, cause
+			}
+			return last, nil
+		case <-time.After(readyPollInterval):
+		}
+	}
+}
+
+func (s *session) captureVariations(ctx context.Context) {
+	var count int
+	if err := s.conn.Evaluate(ctx, countExpr(s.profile.VariationSelector), &count); err != nil {
+		s.manifest.addNote("count variation options: " + err.Error())
+		return
+	}
+	if count == 0 {
+		s.manifest.addNote("no variation options found")
+		return
+	}
+	if count > s.opts.MaxVariations {
+		s.manifest.addNote(fmt.Sprintf("found %d variation options; capturing first %d", count, s.opts.MaxVariations))
+		count = s.opts.MaxVariations
+	}
+
+	for i := 0; i < count; i++ {
+		if ctx.Err() != nil {
+			s.manifest.addNote("variation capture interrupted: " + ctx.Err().Error())
+			return
+		}
+
+		var sel selectResult
+		if err := s.conn.Evaluate(ctx, selectExpr(s.profile.VariationSelector, i), &sel); err != nil {
+			s.manifest.addNote(fmt.Sprintf("variation %d: select failed: %v", i, err))
+			continue
+		}
+		if !sel.Clicked {
+			if sel.Disabled {
+				s.manifest.addNote(fmt.Sprintf("variation %d (%s): option disabled", i, sel.Label))
+			} else {
+				s.manifest.addNote(fmt.Sprintf("variation %d: option not found", i))
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			continue
+		case <-time.After(s.opts.SettleDelay):
+		}
+
+		position := i
+		s.capture(ctx, fmt.Sprintf("variation-%d", i), VariationHTMLFile(i), &position, sel.Label)
+	}
+}
+
+// capture writes the current outerHTML to name and records it in the manifest.
+func (s *session) capture(ctx context.Context, name, file string, position *int, label string) Entry {
+	c := Entry{
+		Name:       name,
+		Output:     filepath.Join(s.artifactDir, file),
+		Position:   position,
+		Label:      label,
+		Status:     StatusOK,
+		CapturedAt: utcNow(),
+		TargetID:   s.target.ID,
+	}
+
+	var page struct {
+		URL  string `json:"url"`
+		HTML string `json:"html"`
+	}
+	err := s.conn.Evaluate(ctx, `({ url: location.href, html: `+htmlExpr+` })`, &page)
+	if err == nil {
+		c.TargetURL = page.URL
+		c.OriginalBytes = len(page.HTML)
+		c.Bytes, c.SHA256, err = writeGzip(c.Output, page.HTML)
+	}
+	if err != nil {
+		c.Status = StatusError
+		c.Error = err.Error()
+		c.Output = ""
+		s.manifest.addNote(fmt.Sprintf("%s: snapshot failed: %v", name, err))
+	}
+
+	s.manifest.Captures = append(s.manifest.Captures, c)
+	return c
+}
+
+func (s *session) closeTab() {
+	// The caller's context may already be done; closing the tab must still happen.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tt := &s.manifest.TabTracking
+	tt.CloseAttempted = true
+	if err := chromedevtools.CloseTarget(ctx, s.opts.DevToolsURL, s.target.ID); err != nil {
+		tt.CloseError = err.Error()
+		return
+	}
+	tt.CloseSucceeded = true
+}
+
+func writeGzip(path, content string) (int64, string, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		return 0, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, "", err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return 0, "", err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return int64(buf.Len()), hex.EncodeToString(sum[:]), nil
+}
+
+func utcNow() string {
+	return nowFunc().UTC().Truncate(time.Second).Format(time.RFC3339)
+}
+
+func writeJSON(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
@@ -0,0 +1,285 @@
+package snapshot
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// fakePage is the scripted state of the single tab served by fakeCDP.
+type fakePage struct {
+	ready      bool
+	wall       bool
+	options    []string // variation labels; "" marks a disabled option
+	selected   int
+	navigateTo string
+}
+
+func (p *fakePage) html() string {
+	if p.selected < 0 {
+		return "<html><body>initial</body></html>"
+	}
+	return fmt.Sprintf("<html><body>selected %s</body></html>", p.options[p.selected])
+}
+
+type fakeCDP struct {
+	t      *testing.T
+	srv    *httptest.Server
+	mu     sync.Mutex
+	page   *fakePage
+	closed []string
+}
+
+var indexRE = regexp.MustCompile(`\)\[(\d+)\]`)
+
+func newFakeCDP(t *testing.T, page *fakePage) *fakeCDP {
+	t.Helper()
+
+	f := &fakeCDP{t: t, page: page}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/new", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "use PUT", http.StatusMethodNotAllowed)
+			return
+		}
+		ws := "ws" + strings.TrimPrefix(f.srv.URL, "http") + "/devtools/page/T1"
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"id":                   "T1",
+			"type":                 "page",
+			"url":                  "about:blank",
+			"webSocketDebuggerUrl": ws,
+		})
+	})
+	mux.HandleFunc("/json/close/", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.closed = append(f.closed, strings.TrimPrefix(r.URL.Path, "/json/close/"))
+		f.mu.Unlock()
+		_, _ = io.WriteString(w, "Target is closing")
+	})
+	mux.HandleFunc("/devtools/page/", f.serveWS)
+
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeCDP) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+
+	ctx := r.Context()
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		var req struct {
+			ID     int64          `json:"id"`
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+		}
+		if err := json.Unmarshal(data, &req); err != nil {
+			return
+		}
+
+		// Interleave an event to make sure the client ignores it.
+		_ = conn.Write(ctx, websocket.MessageText, []byte(`{"method":"Page.frameNavigated","params":{}}`))
+
+		result := f.handle(req.Method, req.Params)
+		b, _ := json.Marshal(map[string]any{"id": req.ID, "result": result})
+		if err := conn.Write(ctx, websocket.MessageText, b); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeCDP) handle(method string, params map[string]any) any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := f.page
+	switch method {
+	case "Page.navigate":
+		p.navigateTo, _ = params["url"].(string)
+		return map[string]any{"frameId": "F1"}
+	case "Runtime.evaluate":
+		expr, _ := params["expression"].(string)
+		return map[string]any{"result": map[string]any{"type": "object", "value": f.evaluate(expr)}}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (f *fakeCDP) evaluate(expr string) any {
+	p := f.page
+	switch {
+	case strings.Contains(expr, "el.click()"):
+		m := indexRE.FindStringSubmatch(expr)
+		i, _ := strconv.Atoi(m[1])
+		if i >= len(p.options) {
+			return map[string]any{"clicked": false, "disabled": false, "label": ""}
+		}
+		if p.options[i] == "" {
+			return map[string]any{"clicked": false, "disabled": true, "label": ""}
+		}
+		p.selected = i
+		return map[string]any{"clicked": true, "disabled": false, "label": p.options[i]}
+	case strings.Contains(expr, "readyState"):
+		return map[string]any{"ready": p.ready, "wall": p.wall}
+	case strings.Contains(expr, "outerHTML"):
+		return map[string]any{"url": p.navigateTo, "html": p.html()}
+	case strings.Contains(expr, ".length"):
+		return len(p.options)
+	}
+	f.t.Errorf("unexpected expression: %s", expr)
+	return nil
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip %s: %v", path, err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(b)
+}
+
+func TestCapture_WritesInitialVariationsAndManifest(t *testing.T) {
+	page := &fakePage{ready: true, options: []string{"Red", "", "Blue"}, selected: -1}
+	cdp := newFakeCDP(t, page)
+	outDir := t.TempDir()
+
+	m, err := Capture(context.Background(), Options{
+		URL:         "https://shopee.tw/product/1/2",
+		RunID:       "run-1",
+		OutDir:      outDir,
+		DevToolsURL: cdp.srv.URL,
+		SettleDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Capture error: %v", err)
+	}
+	if m.Status != StatusOK {
+		t.Fatalf("expected status ok, got %q (error=%q notes=%q)", m.Status, m.Error, m.Notes)
+	}
+
+	dir := filepath.Join(outDir, "artifacts", "run-1")
+	if got := readGzip(t, filepath.Join(dir, InitialHTMLFile)); !strings.Contains(got, "initial") {
+		t.Fatalf("unexpected initial html: %q", got)
+	}
+	if got := readGzip(t, filepath.Join(dir, "s0-variation-0.html.gz")); !strings.Contains(got, "selected Red") {
+		t.Fatalf("unexpected variation-0 html: %q", got)
+	}
+	if got := readGzip(t, filepath.Join(dir, "s0-variation-2.html.gz")); !strings.Contains(got, "selected Blue") {
+		t.Fatalf("unexpected variation-2 html: %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "s0-variation-1.html.gz")); !os.IsNotExist(err) {
+		t.Fatalf("disabled option should not be captured, stat err=%v", err)
+	}
+	if !strings.Contains(m.Notes, "variation 1") {
+		t.Fatalf("expected note for disabled option, got %q", m.Notes)
+	}
+	if m.VariationCount() != 2 {
+		t.Fatalf("expected 2 variation captures, got %d", m.VariationCount())
+	}
+	if page.navigateTo != "https://shopee.tw/product/1/2" {
+		t.Fatalf("unexpected navigate url: %q", page.navigateTo)
+	}
+	if len(cdp.closed) != 1 || cdp.closed[0] != "T1" || !m.TabTracking.CloseSucceeded {
+		t.Fatalf("expected tab T1 closed, got %v (tracking=%+v)", cdp.closed, m.TabTracking)
+	}
+
+	var onDisk Manifest
+	b, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if err := json.Unmarshal(b, &onDisk); err != nil {
+		t.Fatalf("decode manifest: %v", err)
+	}
+	if len(onDisk.Captures) != 3 || onDisk.SnapshotFiles.InitialHTML == "" {
+		t.Fatalf("unexpected manifest: %s", b)
+	}
+	for _, c := range onDisk.Captures {
+		if c.SHA256 == "" || c.Bytes == 0 {
+			t.Fatalf("capture missing hash/bytes: %+v", c)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, PointerFile)); err != nil {
+		t.Fatalf("missing pointer file: %v", err)
+	}
+}
+
+func TestCapture_WallReturnsNeedsManual(t *testing.T) {
+	page := &fakePage{ready: false, wall: true, options: []string{"Red"}, selected: -1}
+	cdp := newFakeCDP(t, page)
+	outDir := t.TempDir()
+
+	m, err := Capture(context.Background(), Options{
+		URL:          "https://item.taobao.com/item.htm?id=1",
+		RunID:        "run-2",
+		OutDir:       outDir,
+		DevToolsURL:  cdp.srv.URL,
+		ReadyTimeout: 300 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Capture error: %v", err)
+	}
+	if m.Status != StatusNeedsManual {
+		t.Fatalf("expected needs_manual, got %q", m.Status)
+	}
+	if m.VariationCount() != 0 {
+		t.Fatalf("variations should not be captured behind a wall")
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "artifacts", "run-2", InitialHTMLFile)); err != nil {
+		t.Fatalf("initial snapshot should still be written: %v", err)
+	}
+}
+
+func TestCapture_DevToolsUnreachableWritesErrorManifest(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	outDir := t.TempDir()
+
+	m, err := Capture(context.Background(), Options{
+		URL:         "https://shopee.tw/product/1/2",
+		RunID:       "run-3",
+		OutDir:      outDir,
+		DevToolsURL: srv.URL,
+	})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if m == nil || m.Status != StatusError || m.Error == "" {
+		t.Fatalf("expected error manifest, got %+v", m)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "artifacts", "run-3", ManifestFile)); err != nil {
+		t.Fatalf("manifest should be written on failure: %v", err)
+	}
+}